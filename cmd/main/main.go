@@ -9,6 +9,7 @@ import (
 
 	"github.com/Sanchir01/go-shortener/internal/app"
 	httphandlers "github.com/Sanchir01/go-shortener/internal/handlers"
+	"github.com/Sanchir01/go-shortener/internal/handlers/customiddleware"
 )
 
 // @title 🚀 URL-SHORTENER
@@ -39,7 +40,12 @@ func main() {
 	}
 
 	go func() {
-		if err := application.HttpServer.Run(httphandlers.StartHTTTPHandlers(application.Handlers, application.Cfg.Domain, application.Log)); err != nil {
+		if err := application.HttpServer.Run(httphandlers.StartHTTTPHandlers(application.Handlers, customiddleware.Authenticators{
+			Tokens:   application.Services.KeyManager,
+			Keys:     application.Services.ApiKeyService,
+			Sessions: application.Services.SessionService,
+			Revoked:  application.Services.SessionService,
+		}, application.Log)); err != nil {
 			application.Log.Error("Error while running http server", slog.String("error", err.Error()))
 			cancel()
 		}
@@ -51,8 +57,17 @@ func main() {
 		}
 	}()
 	go func() {
-		application.Bot.Bot.Start(ctx)
+		if err := application.Bot.Run(ctx); err != nil {
+			application.Log.Error("Error while starting telegram bot", slog.String("error", err.Error()))
+			cancel()
+		}
 	}()
+	if application.Cfg.Health.Enabled {
+		go application.Services.HealthService.Run(ctx)
+	}
+	go application.Services.WebhookService.Run(ctx)
+	go application.Services.ClickBroker.Run(ctx)
+	go application.Services.KeyManager.Run(ctx)
 	<-ctx.Done()
 
 	if err := application.HttpServer.Gracefull(ctx); err != nil {
@@ -4,12 +4,17 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"os"
 
 	tgbot "github.com/Sanchir01/go-shortener/internal/bot"
 	"github.com/Sanchir01/go-shortener/internal/config"
+	"github.com/Sanchir01/go-shortener/internal/feature/healthcheck"
+	"github.com/Sanchir01/go-shortener/internal/feature/oauth"
+	"github.com/Sanchir01/go-shortener/internal/feature/signingkey"
 	httpserver "github.com/Sanchir01/go-shortener/internal/server/http"
 	"github.com/Sanchir01/go-shortener/pkg/db"
 	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/mailer"
 )
 
 type App struct {
@@ -33,15 +38,53 @@ func NewApp(ctx context.Context) (*App, error) {
 		return nil, err
 	}
 	repo := NewRepositories(database, l)
-	services := NewServices(repo, database, l)
+	mail, err := mailer.New(cfg.Mail, l)
+	if err != nil {
+		l.Error("mailer error", slog.String("error", err.Error()))
+		return nil, err
+	}
+	providers, err := oauth.NewRegistry(cfg.OAuth, l)
+	if err != nil {
+		l.Error("oauth providers error", slog.String("error", err.Error()))
+		return nil, err
+	}
+	sealer, err := signingkey.NewSealer(os.Getenv("JWT_KEY_ENCRYPTION_KEY"))
+	if err != nil {
+		l.Error("signing key encryption error", slog.String("error", err.Error()))
+		return nil, err
+	}
+	services := NewServices(repo, database, mail, providers, sealer, cfg, l)
+	if err := services.KeyManager.Load(ctx); err != nil {
+		l.Error("signing keys error", slog.String("error", err.Error()))
+		return nil, err
+	}
 
-	botInstance, err := tgbot.New(ctx, services.UrlService, services.UserService, l)
+	botInstance, err := tgbot.New(
+		ctx,
+		cfg.Telegram,
+		services.UrlService,
+		services.UserService,
+		services.Telegram,
+		services.ClickBroker,
+		tgbot.NewConfirmStore(database.RedisDB, cfg.Telegram.ConfirmTTL),
+		tgbot.NewConversationStore(database.RedisDB, cfg.Telegram.ConversationTTL),
+		tgbot.NewLanguageStore(database.RedisDB),
+		cfg.ShortLinkBase(),
+		l,
+	)
 	if err != nil {
 		l.Error("bot connect error:", slog.String("error", err.Error()))
 		return nil, err
 	}
 
-	handlers := NewHandlers(services, l)
+	services.HealthService = healthcheck.NewService(
+		repo.HealthRepository,
+		healthcheck.NewChecker(nil, cfg.Health.Timeout, cfg.Health.PerHostDelay, cfg.Health.UserAgent),
+		healthcheck.Notifiers{healthcheck.NewLogNotifier(l), botInstance},
+		cfg.Health, l,
+	)
+
+	handlers := NewHandlers(services, botInstance, cfg, l)
 	httpServer := httpserver.NewHTTPServer(cfg.HttpServer.Host, cfg.HttpServer.Port, cfg.HttpServer.Timeout, cfg.HttpServer.IdleTimeout)
 	prometheusServer := httpserver.NewHTTPServer(cfg.Prometheus.Host, cfg.Prometheus.Port, cfg.Prometheus.Timeout, cfg.Prometheus.IdleTimeout)
 
@@ -3,18 +3,61 @@ package app
 import (
 	"log/slog"
 
+	tgbot "github.com/Sanchir01/go-shortener/internal/bot"
+	"github.com/Sanchir01/go-shortener/internal/config"
+	"github.com/Sanchir01/go-shortener/internal/feature/account"
+	"github.com/Sanchir01/go-shortener/internal/feature/apikey"
+	"github.com/Sanchir01/go-shortener/internal/feature/clickstream"
+	"github.com/Sanchir01/go-shortener/internal/feature/customdomain"
+	"github.com/Sanchir01/go-shortener/internal/feature/folder"
+	"github.com/Sanchir01/go-shortener/internal/feature/healthcheck"
+	"github.com/Sanchir01/go-shortener/internal/feature/oauth"
+	"github.com/Sanchir01/go-shortener/internal/feature/session"
+	"github.com/Sanchir01/go-shortener/internal/feature/signingkey"
+	"github.com/Sanchir01/go-shortener/internal/feature/tag"
+	"github.com/Sanchir01/go-shortener/internal/feature/telegram"
+	"github.com/Sanchir01/go-shortener/internal/feature/twofactor"
 	"github.com/Sanchir01/go-shortener/internal/feature/url"
 	"github.com/Sanchir01/go-shortener/internal/feature/user"
+	"github.com/Sanchir01/go-shortener/internal/feature/webhook"
 )
 
 type Handlers struct {
-	UserHandler *user.Handler
-	UrlHandler  *url.Handler
+	UserHandler    *user.Handler
+	UrlHandler     *url.Handler
+	TagHandler     *tag.Handler
+	FolderHandler  *folder.Handler
+	DomainHandler  *customdomain.Handler
+	HealthHandler  *healthcheck.Handler
+	WebhookHandler *webhook.Handler
+	ClickHandler   *clickstream.Handler
+	ApiKeyHandler  *apikey.Handler
+	SessionHandler *session.Handler
+	JWKSHandler    *signingkey.Handler
+	AccountHandler *account.Handler
+	TwoFactor      *twofactor.Handler
+	OAuthHandler   *oauth.Handler
+	Telegram       *telegram.Handler
+	TelegramBot    *tgbot.TGBot
 }
 
-func NewHandlers(services *Services, l *slog.Logger) *Handlers {
+func NewHandlers(services *Services, botInstance *tgbot.TGBot, cfg *config.Config, l *slog.Logger) *Handlers {
 	return &Handlers{
-		UserHandler: user.NewHandler(services.UserService, l),
-		UrlHandler:  url.NewHandler(services.UrlService, l),
+		UserHandler:    user.NewHandler(services.UserService, services.SessionService, services.AccountService, services.TwoFactor, cfg.Accounts, l),
+		UrlHandler:     url.NewHandler(services.UrlService, cfg.Redirect, cfg.ShortLinkBase(), l),
+		TagHandler:     tag.NewHandler(services.TagService, l),
+		FolderHandler:  folder.NewHandler(services.FolderService, l),
+		DomainHandler:  customdomain.NewHandler(services.DomainService, l),
+		HealthHandler:  healthcheck.NewHandler(services.HealthService, l),
+		WebhookHandler: webhook.NewHandler(services.WebhookService, l),
+		ClickHandler:   clickstream.NewHandler(services.ClickBroker, cfg.Clicks, l),
+		ApiKeyHandler:  apikey.NewHandler(services.ApiKeyService, l),
+		SessionHandler: session.NewHandler(services.SessionService, l),
+		JWKSHandler:    signingkey.NewHandler(services.KeyManager, l),
+		AccountHandler: account.NewHandler(services.AccountService, l),
+		TwoFactor:      twofactor.NewHandler(services.TwoFactor, services.SessionService, l),
+		OAuthHandler:   oauth.NewHandler(services.OAuthService, services.SessionService, services.TwoFactor, l),
+		Telegram:       telegram.NewHandler(services.Telegram, services.SessionService, services.TwoFactor, l),
+		TelegramBot:    botInstance,
 	}
 }
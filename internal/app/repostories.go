@@ -3,19 +3,55 @@ package app
 import (
 	"log/slog"
 
+	"github.com/Sanchir01/go-shortener/internal/feature/account"
+	"github.com/Sanchir01/go-shortener/internal/feature/apikey"
+	"github.com/Sanchir01/go-shortener/internal/feature/customdomain"
+	"github.com/Sanchir01/go-shortener/internal/feature/folder"
+	"github.com/Sanchir01/go-shortener/internal/feature/healthcheck"
+	"github.com/Sanchir01/go-shortener/internal/feature/oauth"
+	"github.com/Sanchir01/go-shortener/internal/feature/session"
+	"github.com/Sanchir01/go-shortener/internal/feature/signingkey"
+	"github.com/Sanchir01/go-shortener/internal/feature/tag"
+	"github.com/Sanchir01/go-shortener/internal/feature/telegram"
+	"github.com/Sanchir01/go-shortener/internal/feature/twofactor"
 	"github.com/Sanchir01/go-shortener/internal/feature/url"
 	"github.com/Sanchir01/go-shortener/internal/feature/user"
+	"github.com/Sanchir01/go-shortener/internal/feature/webhook"
 	"github.com/Sanchir01/go-shortener/pkg/db"
 )
 
 type Repositories struct {
-	UserRepository *user.Repository
-	UrlRepository  *url.Repository
+	UserRepository       *user.Repository
+	UrlRepository        *url.Repository
+	TagRepository        *tag.Repository
+	FolderRepository     *folder.Repository
+	DomainRepository     *customdomain.Repository
+	HealthRepository     *healthcheck.Repository
+	WebhookRepository    *webhook.Repository
+	ApiKeyRepository     *apikey.Repository
+	SessionRepository    *session.Repository
+	SigningKeyRepository *signingkey.Repository
+	AccountRepository    *account.Repository
+	TwoFactorRepository  *twofactor.Repository
+	OAuthRepository      *oauth.Repository
+	TelegramRepository   *telegram.Repository
 }
 
 func NewRepositories(databases *db.Database, l *slog.Logger) *Repositories {
 	return &Repositories{
-		UserRepository: user.NewRepository(databases.PrimaryDB, l),
-		UrlRepository:  url.NewRepository(databases.PrimaryDB, l),
+		UserRepository:       user.NewRepository(databases.PrimaryDB, l),
+		UrlRepository:        url.NewRepository(databases.PrimaryDB, l),
+		TagRepository:        tag.NewRepository(databases.PrimaryDB, l),
+		FolderRepository:     folder.NewRepository(databases.PrimaryDB, l),
+		DomainRepository:     customdomain.NewRepository(databases.PrimaryDB, l),
+		HealthRepository:     healthcheck.NewRepository(databases.PrimaryDB, l),
+		WebhookRepository:    webhook.NewRepository(databases.PrimaryDB, l),
+		ApiKeyRepository:     apikey.NewRepository(databases.PrimaryDB, l),
+		SessionRepository:    session.NewRepository(databases.PrimaryDB, l),
+		SigningKeyRepository: signingkey.NewRepository(databases.PrimaryDB, l),
+		AccountRepository:    account.NewRepository(databases.PrimaryDB, l),
+		TwoFactorRepository:  twofactor.NewRepository(databases.PrimaryDB, l),
+		OAuthRepository:      oauth.NewRepository(databases.PrimaryDB, l),
+		TelegramRepository:   telegram.NewRepository(databases.PrimaryDB, l),
 	}
 }
@@ -2,20 +2,91 @@ package app
 
 import (
 	"log/slog"
+	"os"
 
+	"github.com/Sanchir01/go-shortener/internal/config"
+	"github.com/Sanchir01/go-shortener/internal/feature/account"
+	"github.com/Sanchir01/go-shortener/internal/feature/apikey"
+	"github.com/Sanchir01/go-shortener/internal/feature/clickstream"
+	"github.com/Sanchir01/go-shortener/internal/feature/customdomain"
+	"github.com/Sanchir01/go-shortener/internal/feature/folder"
+	"github.com/Sanchir01/go-shortener/internal/feature/healthcheck"
+	"github.com/Sanchir01/go-shortener/internal/feature/oauth"
+	"github.com/Sanchir01/go-shortener/internal/feature/session"
+	"github.com/Sanchir01/go-shortener/internal/feature/signingkey"
+	"github.com/Sanchir01/go-shortener/internal/feature/tag"
+	"github.com/Sanchir01/go-shortener/internal/feature/telegram"
+	"github.com/Sanchir01/go-shortener/internal/feature/twofactor"
 	"github.com/Sanchir01/go-shortener/internal/feature/url"
 	"github.com/Sanchir01/go-shortener/internal/feature/user"
+	"github.com/Sanchir01/go-shortener/internal/feature/webhook"
 	"github.com/Sanchir01/go-shortener/pkg/db"
+	"github.com/Sanchir01/go-shortener/pkg/mailer"
 )
 
 type Services struct {
-	UserService *user.Service
-	UrlService  *url.Service
+	UserService    *user.Service
+	UrlService     *url.Service
+	TagService     *tag.Service
+	FolderService  *folder.Service
+	DomainService  *customdomain.Service
+	HealthService  *healthcheck.Service
+	WebhookService *webhook.Service
+	ClickBroker    *clickstream.Broker
+	ApiKeyService  *apikey.Service
+	SessionService *session.Service
+	KeyManager     *signingkey.Manager
+	AccountService *account.Service
+	TwoFactor      *twofactor.Service
+	OAuthService   *oauth.Service
+	Telegram       *telegram.Service
 }
 
-func NewServices(repo *Repositories, db *db.Database, l *slog.Logger) *Services {
+func NewServices(repo *Repositories, db *db.Database, mail mailer.Mailer, providers *oauth.Registry, sealer *signingkey.Sealer, cfg *config.Config, l *slog.Logger) *Services {
+	webhookService := webhook.NewService(
+		repo.WebhookRepository,
+		webhook.NewSender(nil, cfg.Webhooks.Timeout),
+		cfg.Webhooks, l,
+	)
+	clickBroker := clickstream.NewBroker(db.RedisDB, cfg.Clicks, l)
+	keyManager := signingkey.NewManager(repo.SigningKeyRepository, db.PrimaryDB, sealer, cfg.Jwt, l)
+	userService := user.NewService(repo.UserRepository, db.PrimaryDB, cfg.Accounts, l)
+	sessionService := session.NewService(
+		repo.SessionRepository, db.PrimaryDB, keyManager,
+		session.NewDenylist(db.RedisDB, cfg.Sessions.AccessTTL),
+		cfg.Sessions, cfg.Domain, l,
+	)
 	return &Services{
-		UserService: user.NewService(repo.UserRepository, db.PrimaryDB, l),
-		UrlService:  url.NewService(repo.UrlRepository, db.PrimaryDB, l),
+		UserService:   userService,
+		UrlService:    url.NewService(repo.UrlRepository, db.PrimaryDB, cfg.Redirect, url.Publishers{webhookService, clickBroker}, l),
+		TagService:    tag.NewService(repo.TagRepository, l),
+		FolderService: folder.NewService(repo.FolderRepository, l),
+		DomainService: customdomain.NewService(
+			repo.DomainRepository,
+			customdomain.NewVerifier(nil, cfg.Domains.VerificationScheme, cfg.Domains.VerificationPath, cfg.Domains.VerificationTimeout),
+			cfg.Domain, l,
+		),
+		WebhookService: webhookService,
+		ClickBroker:    clickBroker,
+		ApiKeyService:  apikey.NewService(repo.ApiKeyRepository, l),
+		SessionService: sessionService,
+		KeyManager:     keyManager,
+		AccountService: account.NewService(repo.AccountRepository, db.PrimaryDB, mail, sessionService, cfg.Accounts, l),
+		TwoFactor: twofactor.NewService(
+			repo.TwoFactorRepository, db.PrimaryDB,
+			twofactor.NewLockout(db.RedisDB, cfg.TwoFactor.MaxFailures, cfg.TwoFactor.LockoutTTL),
+			cfg.TwoFactor, l,
+		),
+		OAuthService: oauth.NewService(
+			repo.OAuthRepository, db.PrimaryDB, providers,
+			oauth.NewStateStore(db.RedisDB, cfg.OAuth.StateTTL),
+			sessionService, l,
+		),
+		Telegram: telegram.NewService(
+			repo.TelegramRepository, db.PrimaryDB,
+			telegram.NewCodeStore(db.RedisDB, cfg.Telegram.LinkCodeTTL),
+			telegram.NewVerifier(os.Getenv("BOT_TOKEN"), cfg.Telegram.AuthMaxAge),
+			userService, cfg.Telegram, l,
+		),
 	}
 }
@@ -2,34 +2,110 @@ package tgbot
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
+	"strings"
+	"time"
 
-	"github.com/Sanchir01/go-shortener/internal/feature/user"
+	"github.com/Sanchir01/go-shortener/internal/config"
+	domain "github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/internal/feature/clickstream"
+	"github.com/Sanchir01/go-shortener/internal/feature/healthcheck"
+	"github.com/Sanchir01/go-shortener/internal/feature/telegram"
+	urls "github.com/Sanchir01/go-shortener/internal/feature/url"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"github.com/google/uuid"
 )
 
 type TGBot struct {
-	Bot  *bot.Bot
-	url  UrlCreator
-	user UserService
-	l    *slog.Logger
+	Bot           *bot.Bot
+	cfg           config.Telegram
+	secret        string
+	links         LinkManager
+	user          UserService
+	linker        TelegramLinker
+	stats         ClickStats
+	confirms      *ConfirmStore
+	conversations *ConversationStore
+	languages     *LanguageStore
+	baseURL       string
+	l             *slog.Logger
 }
 
-type UrlCreator interface {
-	CreateUrl(ctx context.Context, userId uuid.UUID, url string) error
+// LinkManager is the URL service behind /short and the link commands. Every
+// call is scoped to the account of the sender.
+type LinkManager interface {
+	CheckUrl(rawUrl string) error
+	CreateUrl(ctx context.Context, userId uuid.UUID, p urls.CreateUrlParams) (string, error)
+	GetUrlByUser(ctx context.Context, userId uuid.UUID, filter urls.UrlFilter) ([]domain.Url, error)
+	GetUserUrlByAlias(ctx context.Context, userId uuid.UUID, alias string) (*domain.Url, error)
+	UpdateUrl(ctx context.Context, id, userId uuid.UUID, p urls.UpdateUrlParams) (*domain.Url, error)
+	DeleteUrl(ctx context.Context, id, userId uuid.UUID) error
+}
+
+// ClickStats gives the approximate live click counters behind /stats.
+type ClickStats interface {
+	LiveCounts(ctx context.Context, urlId uuid.UUID, top int64) (*clickstream.LiveCounts, error)
 }
 type UserService interface {
-	Register(ctx context.Context, p user.RegisterParams) (*uuid.UUID, error)
+	TelegramUser(ctx context.Context, tgId int64, title string) (*uuid.UUID, error)
+}
+
+// TelegramLinker links the bot user with a web account by a one-time code.
+type TelegramLinker interface {
+	LinkFromBot(ctx context.Context, tgId int64, code string) error
+	CreateBotCode(ctx context.Context, tgId int64) (string, time.Time, error)
 }
 
-func New(ctx context.Context, url UrlCreator, user UserService, l *slog.Logger) (*TGBot, error) {
-	t := &TGBot{url: url, user: user, l: l}
+// New starts the bot. baseURL is where short links are opened, the alias
+// is appended to it.
+func New(
+	ctx context.Context,
+	cfg config.Telegram,
+	links LinkManager,
+	user UserService,
+	linker TelegramLinker,
+	stats ClickStats,
+	confirms *ConfirmStore,
+	conversations *ConversationStore,
+	languages *LanguageStore,
+	baseURL string,
+	l *slog.Logger,
+) (*TGBot, error) {
+	t := &TGBot{
+		cfg:           cfg,
+		secret:        os.Getenv("TELEGRAM_WEBHOOK_SECRET"),
+		links:         links,
+		user:          user,
+		linker:        linker,
+		stats:         stats,
+		confirms:      confirms,
+		conversations: conversations,
+		languages:     languages,
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		l:             l,
+	}
 	opts := []bot.Option{
 		bot.WithDefaultHandler(t.UnknownCommand),
 	}
+	switch cfg.Mode {
+	case ModePolling:
+	case ModeWebhook:
+		if cfg.WebhookURL == "" || t.secret == "" {
+			return nil, errors.New("webhook mode needs webhook_url and TELEGRAM_WEBHOOK_SECRET")
+		}
+		// the update is handled before Telegram gets the response
+		opts = append(opts, bot.WithNotAsyncHandlers())
+	default:
+		return nil, fmt.Errorf("unknown bot mode %q", cfg.Mode)
+	}
+	if cfg.ServerURL != "" {
+		opts = append(opts, bot.WithServerURL(cfg.ServerURL))
+	}
 
 	b, err := bot.New(os.Getenv("BOT_TOKEN"), opts...)
 	if err != nil {
@@ -38,84 +114,219 @@ func New(ctx context.Context, url UrlCreator, user UserService, l *slog.Logger)
 
 	t.Bot = b
 
-	b.RegisterHandler(bot.HandlerTypeMessageText, "/start", bot.MatchTypeExact, t.Start)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "start", bot.MatchTypeCommandStartOnly, t.Start)
 	b.RegisterHandler(bot.HandlerTypeMessageText, "/help", bot.MatchTypeExact, t.Help)
 	b.RegisterHandler(bot.HandlerTypeMessageText, "/ping", bot.MatchTypeExact, t.Ping)
-	b.RegisterHandler(bot.HandlerTypeMessageText, "/short", bot.MatchTypePrefix, t.Short)
-
-	_, err = b.SetMyCommands(ctx, &bot.SetMyCommandsParams{
-		Commands: []models.BotCommand{
-			{Command: "start", Description: "Начать"},
-			{Command: "help", Description: "Помощь"},
-			{Command: "ping", Description: "Проверка связи"},
-			{Command: "short", Description: "Сократить ссылку: /short <url>"},
-		},
-		Scope: &models.BotCommandScopeDefault{},
-	})
-	if err != nil {
-		return nil, err
+	b.RegisterHandler(bot.HandlerTypeMessageText, "short", bot.MatchTypeCommandStartOnly, t.Short)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "new", bot.MatchTypeCommandStartOnly, t.NewLink)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "cancel", bot.MatchTypeCommandStartOnly, t.CancelConversation)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "link", bot.MatchTypeCommandStartOnly, t.Link)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "language", bot.MatchTypeCommandStartOnly, t.Language)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "mylinks", bot.MatchTypeCommandStartOnly, t.MyLinks)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "stats", bot.MatchTypeCommandStartOnly, t.Stats)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "delete", bot.MatchTypeCommandStartOnly, t.Delete)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "edit", bot.MatchTypeCommandStartOnly, t.Edit)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, callbackLinks, bot.MatchTypePrefix, t.LinksPage)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, callbackConfirm, bot.MatchTypePrefix, t.Confirm)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, callbackCancel, bot.MatchTypePrefix, t.Cancel)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, callbackNew, bot.MatchTypePrefix, t.ConversationButton)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, callbackLanguage, bot.MatchTypePrefix, t.SetLanguage)
+	b.RegisterHandlerMatchFunc(isInlineQuery, t.Inline)
+	b.RegisterHandlerMatchFunc(isBulkDocument, t.ShortenFile)
+
+	// Russian is the default, other languages get their own list
+	for _, lang := range i18n.Languages {
+		params := &bot.SetMyCommandsParams{
+			Commands: commands(lang),
+			Scope:    &models.BotCommandScopeDefault{},
+		}
+		if lang != i18n.RU {
+			params.LanguageCode = string(lang)
+		}
+		if _, err := b.SetMyCommands(ctx, params); err != nil {
+			return nil, err
+		}
 	}
 	return t, nil
 }
 
-func (t *TGBot) Start(ctx context.Context, b *bot.Bot, update *models.Update) {
+func commands(lang i18n.Lang) []models.BotCommand {
+	return []models.BotCommand{
+		{Command: "start", Description: i18n.T(lang, i18n.BotCmdStart)},
+		{Command: "help", Description: i18n.T(lang, i18n.BotCmdHelp)},
+		{Command: "ping", Description: i18n.T(lang, i18n.BotCmdPing)},
+		{Command: "short", Description: i18n.T(lang, i18n.BotCmdShort)},
+		{Command: "new", Description: i18n.T(lang, i18n.BotCmdNew)},
+		{Command: "cancel", Description: i18n.T(lang, i18n.BotCmdCancel)},
+		{Command: "mylinks", Description: i18n.T(lang, i18n.BotCmdMyLinks)},
+		{Command: "stats", Description: i18n.T(lang, i18n.BotCmdStats)},
+		{Command: "edit", Description: i18n.T(lang, i18n.BotCmdEdit)},
+		{Command: "delete", Description: i18n.T(lang, i18n.BotCmdDelete)},
+		{Command: "link", Description: i18n.T(lang, i18n.BotCmdLink)},
+		{Command: "language", Description: i18n.T(lang, i18n.BotCmdLanguage)},
+	}
+}
 
-	user, err := t.user.Register(ctx, user.RegisterParams{
-		TGID:  &update.Message.From.ID,
-		Title: update.Message.From.Username,
+func (t *TGBot) Start(ctx context.Context, b *bot.Bot, update *models.Update) {
+	from := update.Message.From
+	// t.me/<bot>?start=link-<code> opened from the web
+	if _, payload, _ := strings.Cut(update.Message.Text, " "); strings.HasPrefix(payload, telegram.StartLinkPrefix) {
+		t.link(ctx, update, strings.TrimPrefix(payload, telegram.StartLinkPrefix))
+		return
+	}
+	id, err := t.user.TelegramUser(ctx, from.ID, telegramTitle(from))
+	if err != nil {
+		t.l.Error("failed register error", slog.Any("err", err))
+		t.Bot.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   i18n.T(t.lang(ctx, from), i18n.BotStartFailed),
+		})
+		return
+	}
+	t.l.Info("user register by tg", slog.Any("user", id))
+	t.Bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   i18n.T(t.lang(ctx, from), i18n.BotStart),
 	})
+}
+
+// Link with a code from the web links this Telegram account to the web
+// account. Without a code it answers with a code to enter on the web.
+func (t *TGBot) Link(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if _, code, _ := strings.Cut(update.Message.Text, " "); strings.TrimSpace(code) != "" {
+		t.link(ctx, update, code)
+		return
+	}
+	lang := t.lang(ctx, update.Message.From)
+	code, expiresAt, err := t.linker.CreateBotCode(ctx, update.Message.From.ID)
 	if err != nil {
-		t.l.Error("failed register error")
+		t.l.Error("failed to create link code", slog.Any("err", err))
 		t.Bot.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: update.Message.Chat.ID,
-			Text:   "Нажмите комманду /start еще раз произошла ошибка",
+			Text:   i18n.T(lang, i18n.BotLinkCodeFailed),
 		})
+		return
+	}
+	t.Bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   i18n.T(lang, i18n.BotLinkCode, code, expiresAt.UTC().Format("15:04")),
+	})
+}
+
+func (t *TGBot) link(ctx context.Context, update *models.Update, code string) {
+	err := t.linker.LinkFromBot(ctx, update.Message.From.ID, code)
+	key := i18n.BotLinked
+	switch {
+	case errors.Is(err, telegram.ErrInvalidCode):
+		key = i18n.BotInvalidLinkCode
+	case errors.Is(err, telegram.ErrTelegramTaken):
+		key = i18n.BotTelegramTaken
+	case errors.Is(err, telegram.ErrOtherTelegram):
+		key = i18n.BotOtherTelegram
+	case err != nil:
+		t.l.Error("failed to link telegram", slog.Any("err", err))
+		key = i18n.BotLinkFailed
 	}
-	t.l.Info("user register by tg", user)
 	t.Bot.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID: update.Message.Chat.ID,
-		Text:   "Привет! Доступные команды:\n/start — Начать\n/help — Помощь\n/ping — Проверка\n/short <url> — Сократить ссылку",
+		Text:   i18n.T(t.lang(ctx, update.Message.From), key),
 	})
 }
 
+// telegramTitle names the account after the Telegram username or first name.
+func telegramTitle(u *models.User) string {
+	if u.Username != "" {
+		return u.Username
+	}
+	return strings.TrimSpace(u.FirstName + " " + u.LastName)
+}
+
 func (t *TGBot) Help(ctx context.Context, b *bot.Bot, update *models.Update) {
 	t.Bot.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID: update.Message.Chat.ID,
-		Text:   "Помощь:\n/short <url> — отправьте ссылку, чтобы получить короткий вариант.",
+		Text:   i18n.T(t.lang(ctx, update.Message.From), i18n.BotHelp),
 	})
 }
 
 func (t *TGBot) Ping(ctx context.Context, b *bot.Bot, update *models.Update) {
 	t.Bot.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID: update.Message.Chat.ID,
-		Text:   "pong",
+		Text:   i18n.T(t.lang(ctx, update.Message.From), i18n.BotPong),
 	})
 }
 
+// Short shortens the url for the sender, a url the sender already shortened
+// answers with the link they have. The account is created on first use like
+// with /start.
 func (t *TGBot) Short(ctx context.Context, b *bot.Bot, update *models.Update) {
-	text := update.Message.Text
-	if len(text) <= len("/short ") {
+	from := update.Message.From
+	lang := t.lang(ctx, from)
+	_, url, _ := strings.Cut(update.Message.Text, " ")
+	url = strings.TrimSpace(url)
+	if url == "" {
 		t.Bot.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: update.Message.Chat.ID,
-			Text:   "Использование: /short <url>",
+			Text:   i18n.T(lang, i18n.BotShortUsage),
 		})
 		return
 	}
-
-	url := text[len("/short "):]
-	if t.url != nil {
-		_ = t.url.CreateUrl(ctx, uuid.New(), url)
+	userId, err := t.user.TelegramUser(ctx, from.ID, telegramTitle(from))
+	if err != nil {
+		t.l.Error("failed to resolve telegram user", slog.Any("err", err))
+		t.Bot.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   i18n.T(lang, i18n.BotUserNotFound),
+		})
+		return
 	}
 
+	alias, err := t.userAlias(ctx, *userId, url)
+	key, known := urlErrorKey(err)
+	text := i18n.T(lang, key)
+	switch {
+	case err == nil:
+		text = i18n.T(lang, i18n.BotShortLink, t.shortLink(alias))
+	case !known:
+		t.l.Error("failed to create url", slog.Any("err", err))
+		text = i18n.T(lang, i18n.BotShortenFailed)
+	}
 	t.Bot.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID: update.Message.Chat.ID,
-		Text:   "Принял ссылку: " + url,
+		Text:   text,
 	})
 }
 
+func (t *TGBot) shortLink(alias string) string {
+	return t.baseURL + "/" + alias
+}
+
 func (t *TGBot) UnknownCommand(ctx context.Context, b *bot.Bot, update *models.Update) {
+	// callback queries and other updates nobody handles
+	if update.Message == nil {
+		return
+	}
+	if t.converse(ctx, update) || t.shortenText(ctx, update) {
+		return
+	}
 	t.Bot.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID: update.Message.Chat.ID,
-		Text:   "Неизвестная команда. Введите /help",
+		Text:   i18n.T(t.lang(ctx, update.Message.From), i18n.BotUnknownCommand),
+	})
+}
+
+// LinkBroken tells the link owner in Telegram that the destination stopped
+// responding. Owners without a linked Telegram account are skipped.
+func (t *TGBot) LinkBroken(ctx context.Context, target healthcheck.Target, res healthcheck.Result) error {
+	if target.OwnerTGID == nil {
+		return nil
+	}
+	reason := fmt.Sprintf("HTTP %d", res.StatusCode)
+	if res.Err != nil {
+		reason = res.Err.Error()
+	}
+	_, err := t.Bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: *target.OwnerTGID,
+		Text:   i18n.T(t.userLang(ctx, *target.OwnerTGID, ""), i18n.BotLinkBroken, target.Alias, reason, target.Url),
 	})
+	return err
 }
@@ -0,0 +1,253 @@
+package tgbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	urls "github.com/Sanchir01/go-shortener/internal/feature/url"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/google/uuid"
+)
+
+const (
+	// textLimit is the longest message Telegram accepts, in UTF-16 units.
+	textLimit       = 4096
+	downloadTimeout = 30 * time.Second
+	bulkFileName    = "short_links.csv"
+)
+
+var errFileTooLarge = errors.New("file is too large")
+
+// bulkLink is a url found in a message or a file and its short link, or
+// why it has none.
+type bulkLink struct {
+	Url   string
+	Short string
+	Err   error
+}
+
+// shortenText answers a forwarded message, or any text with urls that is not
+// a command, with the text rewritten to short links. It reports whether the
+// message was answered.
+func (t *TGBot) shortenText(ctx context.Context, update *models.Update) bool {
+	msg := update.Message
+	text := msg.Text
+	if text == "" {
+		text = msg.Caption
+	}
+	if msg.From == nil || strings.HasPrefix(text, "/") {
+		return false
+	}
+	lang := t.lang(ctx, msg.From)
+	found := urls.FindUrls(text)
+	if len(found) == 0 {
+		if msg.ForwardOrigin == nil {
+			return false
+		}
+		t.reply(ctx, update, i18n.T(lang, i18n.BotNoUrlsInMessage))
+		return true
+	}
+	links, note, err := t.shortenAll(ctx, msg.From, lang, found)
+	if err != nil {
+		t.l.Error("failed to resolve telegram user", slog.Any("err", err))
+		t.reply(ctx, update, i18n.T(lang, i18n.BotUserNotFound))
+		return true
+	}
+	shorts := make(map[string]string, len(links))
+	for _, link := range links {
+		if link.Err == nil {
+			shorts[link.Url] = link.Short
+		}
+	}
+	rewritten := urls.ReplaceUrls(text, func(u string) string {
+		if short, ok := shorts[u]; ok {
+			return short
+		}
+		return u
+	})
+	if len(utf16.Encode([]rune(rewritten))) > textLimit {
+		t.sendLinksCSV(ctx, update, lang, links, note)
+		return true
+	}
+	t.reply(ctx, update, rewritten)
+	if note != "" {
+		t.reply(ctx, update, note)
+	}
+	return true
+}
+
+// isBulkDocument matches uploaded .txt and .csv files.
+func isBulkDocument(update *models.Update) bool {
+	if update.Message == nil || update.Message.Document == nil {
+		return false
+	}
+	name := strings.ToLower(update.Message.Document.FileName)
+	return strings.HasSuffix(name, ".txt") || strings.HasSuffix(name, ".csv")
+}
+
+// ShortenFile shortens every url in an uploaded .txt or .csv file and
+// answers with a CSV of the urls and their short links.
+func (t *TGBot) ShortenFile(ctx context.Context, b *bot.Bot, update *models.Update) {
+	doc := update.Message.Document
+	lang := t.lang(ctx, update.Message.From)
+	tooLarge := i18n.T(lang, i18n.BotFileTooLarge, t.cfg.BulkMaxFileSize/1024)
+	if doc.FileSize > t.cfg.BulkMaxFileSize {
+		t.reply(ctx, update, tooLarge)
+		return
+	}
+	data, err := t.download(ctx, doc.FileID)
+	if errors.Is(err, errFileTooLarge) {
+		t.reply(ctx, update, tooLarge)
+		return
+	}
+	if err != nil {
+		t.l.Error("failed to download file", slog.Any("err", err))
+		t.reply(ctx, update, i18n.T(lang, i18n.BotDownloadFailed))
+		return
+	}
+	found := urls.FindUrls(string(data))
+	if len(found) == 0 {
+		t.reply(ctx, update, i18n.T(lang, i18n.BotNoUrlsInFile))
+		return
+	}
+	links, note, err := t.shortenAll(ctx, update.Message.From, lang, found)
+	if err != nil {
+		t.l.Error("failed to resolve telegram user", slog.Any("err", err))
+		t.reply(ctx, update, i18n.T(lang, i18n.BotUserNotFound))
+		return
+	}
+	t.sendLinksCSV(ctx, update, lang, links, note)
+}
+
+// shortenAll shortens the first BulkMaxUrls of found for the sender. The
+// note, in lang, says how many were left out or failed.
+func (t *TGBot) shortenAll(ctx context.Context, from *models.User, lang i18n.Lang, found []string) ([]bulkLink, string, error) {
+	userId, err := t.user.TelegramUser(ctx, from.ID, telegramTitle(from))
+	if err != nil {
+		return nil, "", err
+	}
+	var notes []string
+	if len(found) > t.cfg.BulkMaxUrls {
+		notes = append(notes, i18n.T(lang, i18n.BotBulkLimited, t.cfg.BulkMaxUrls, len(found)))
+		found = found[:t.cfg.BulkMaxUrls]
+	}
+	links := make([]bulkLink, 0, len(found))
+	failed := 0
+	for _, u := range found {
+		link := bulkLink{Url: u}
+		alias, err := t.userAlias(ctx, *userId, u)
+		if err != nil {
+			if _, known := urlErrorKey(err); !known {
+				t.l.Error("failed to shorten url", slog.String("url", u), slog.Any("err", err))
+			}
+			link.Err = err
+			failed++
+		} else {
+			link.Short = t.shortLink(alias)
+		}
+		links = append(links, link)
+	}
+	if failed > 0 {
+		notes = append(notes, i18n.T(lang, i18n.BotBulkFailed, failed))
+	}
+	return links, strings.Join(notes, "\n"), nil
+}
+
+// userAlias returns the alias of the link the user has for rawUrl and
+// creates it when there is none. A link created by a concurrent request in
+// between is looked up again.
+func (t *TGBot) userAlias(ctx context.Context, userId uuid.UUID, rawUrl string) (string, error) {
+	alias, found, err := t.existingAlias(ctx, userId, rawUrl)
+	if err != nil || found {
+		return alias, err
+	}
+	alias, err = t.links.CreateUrl(ctx, userId, urls.CreateUrlParams{Url: rawUrl})
+	if !errors.Is(err, utils.ErrorUrlExists) {
+		return alias, err
+	}
+	alias, found, lookupErr := t.existingAlias(ctx, userId, rawUrl)
+	if lookupErr != nil || !found {
+		return "", err
+	}
+	return alias, nil
+}
+
+func (t *TGBot) existingAlias(ctx context.Context, userId uuid.UUID, rawUrl string) (string, bool, error) {
+	links, err := t.links.GetUrlByUser(ctx, userId, urls.UrlFilter{Url: rawUrl, Limit: 1})
+	if err != nil || len(links) == 0 {
+		return "", false, err
+	}
+	return links[0].Alias, true, nil
+}
+
+func (t *TGBot) download(ctx context.Context, fileId string) ([]byte, error) {
+	f, err := t.Bot.GetFile(ctx, &bot.GetFileParams{FileID: fileId})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.Bot.FileDownloadLink(f), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download file: status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, t.cfg.BulkMaxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > t.cfg.BulkMaxFileSize {
+		return nil, errFileTooLarge
+	}
+	return data, nil
+}
+
+// sendLinksCSV answers with a CSV of url, short_url and error columns.
+func (t *TGBot) sendLinksCSV(ctx context.Context, update *models.Update, lang i18n.Lang, links []bulkLink, note string) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"url", "short_url", "error"})
+	for _, link := range links {
+		reason := ""
+		if link.Err != nil {
+			key, known := urlErrorKey(link.Err)
+			if !known {
+				key = i18n.BotBulkUrlFailed
+			}
+			reason = i18n.T(lang, key)
+		}
+		w.Write([]string{link.Url, link.Short, reason})
+	}
+	w.Flush()
+
+	caption := i18n.T(lang, i18n.BotBulkCaption, len(links))
+	if note != "" {
+		caption += "\n" + note
+	}
+	if _, err := t.Bot.SendDocument(ctx, &bot.SendDocumentParams{
+		ChatID:   update.Message.Chat.ID,
+		Document: &models.InputFileUpload{Filename: bulkFileName, Data: &buf},
+		Caption:  caption,
+	}); err != nil {
+		t.l.Error("failed to send links file", slog.Any("err", err))
+	}
+}
@@ -0,0 +1,88 @@
+package tgbot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var errConfirmExpired = errors.New("confirmation expired")
+
+const (
+	actionDelete = "delete"
+	actionEdit   = "edit"
+)
+
+// confirmation is a change waiting for the button press of TGID. Url is the
+// new destination of an edit.
+type confirmation struct {
+	Action string    `json:"action"`
+	TGID   int64     `json:"tg_id"`
+	UrlID  uuid.UUID `json:"url_id"`
+	Alias  string    `json:"alias"`
+	Url    string    `json:"url,omitempty"`
+}
+
+// ConfirmStore keeps confirmations in Redis under a random token that goes
+// into the callback data, Telegram limits it to 64 bytes.
+type ConfirmStore struct {
+	rdb *redis.Client
+	ttl time.Duration
+}
+
+func NewConfirmStore(rdb *redis.Client, ttl time.Duration) *ConfirmStore {
+	return &ConfirmStore{rdb: rdb, ttl: ttl}
+}
+
+func confirmKey(token string) string {
+	return "bot:confirm:" + token
+}
+
+func (s *ConfirmStore) Save(ctx context.Context, c confirmation) (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	if err := s.rdb.Set(ctx, confirmKey(token), data, s.ttl).Err(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Take returns the confirmation once, and only to the user it was made for.
+func (s *ConfirmStore) Take(ctx context.Context, token string, tgId int64) (*confirmation, error) {
+	data, err := s.rdb.Get(ctx, confirmKey(token)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, errConfirmExpired
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c confirmation
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	if c.TGID != tgId {
+		return nil, errConfirmExpired
+	}
+	// a concurrent press already took it
+	n, err := s.rdb.Del(ctx, confirmKey(token)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, errConfirmExpired
+	}
+	return &c, nil
+}
@@ -0,0 +1,357 @@
+package tgbot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	urls "github.com/Sanchir01/go-shortener/internal/feature/url"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const callbackNew = "new:"
+
+// Steps of the /new dialog. Text is expected in stepUrl and stepAlias, the
+// other steps wait for a button. There is no password step: links can not be
+// password protected yet, the redirect would ignore it.
+const (
+	stepUrl     = "url"
+	stepOptions = "options"
+	stepAlias   = "alias"
+	stepExpiry  = "expiry"
+	stepConfirm = "confirm"
+)
+
+// expiryDays are the lifetimes offered for a new link.
+var expiryDays = []struct {
+	Days  int
+	Label i18n.Key
+}{
+	{1, i18n.BotNewExpiry1Day},
+	{7, i18n.BotNewExpiry7Days},
+	{30, i18n.BotNewExpiry30Days},
+}
+
+// conversation is the /new dialog of TGID in a chat. Empty Alias means a
+// generated one.
+type conversation struct {
+	Step      string     `json:"step"`
+	TGID      int64      `json:"tg_id"`
+	Url       string     `json:"url,omitempty"`
+	Alias     string     `json:"alias,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// ConversationStore keeps the dialog of a chat in Redis, so it survives
+// restarts and any replica can take the next update. Every step renews ttl.
+type ConversationStore struct {
+	rdb *redis.Client
+	ttl time.Duration
+}
+
+func NewConversationStore(rdb *redis.Client, ttl time.Duration) *ConversationStore {
+	return &ConversationStore{rdb: rdb, ttl: ttl}
+}
+
+func conversationKey(chatId int64) string {
+	return "bot:conversation:" + strconv.FormatInt(chatId, 10)
+}
+
+// Get returns nil when the chat has no dialog.
+func (s *ConversationStore) Get(ctx context.Context, chatId int64) (*conversation, error) {
+	data, err := s.rdb.Get(ctx, conversationKey(chatId)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c conversation
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (s *ConversationStore) Save(ctx context.Context, chatId int64, c *conversation) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return s.rdb.Set(ctx, conversationKey(chatId), data, s.ttl).Err()
+}
+
+// Delete reports whether there was a dialog to drop.
+func (s *ConversationStore) Delete(ctx context.Context, chatId int64) (bool, error) {
+	n, err := s.rdb.Del(ctx, conversationKey(chatId)).Result()
+	return n > 0, err
+}
+
+// NewLink starts the dialog that creates a link step by step: the url, then
+// the options, then a confirmation. The url may follow the command.
+func (t *TGBot) NewLink(ctx context.Context, b *bot.Bot, update *models.Update) {
+	c := &conversation{Step: stepUrl, TGID: update.Message.From.ID}
+	if args := commandArgs(update.Message.Text); len(args) > 0 {
+		if err := t.links.CheckUrl(args[0]); err != nil {
+			key, _ := urlErrorKey(err)
+			t.reply(ctx, update, i18n.T(t.lang(ctx, update.Message.From), key))
+			return
+		}
+		c.Url, c.Step = args[0], stepOptions
+	}
+	t.sendConversation(ctx, update, c)
+}
+
+// CancelConversation drops the dialog of the chat at any step.
+func (t *TGBot) CancelConversation(ctx context.Context, b *bot.Bot, update *models.Update) {
+	lang := t.lang(ctx, update.Message.From)
+	deleted, err := t.conversations.Delete(ctx, update.Message.Chat.ID)
+	switch {
+	case err != nil:
+		t.l.Error("failed to delete conversation", slog.Any("err", err))
+		t.reply(ctx, update, i18n.T(lang, i18n.BotCommandFailed))
+	case deleted:
+		t.reply(ctx, update, i18n.T(lang, i18n.BotNewCanceled))
+	default:
+		t.reply(ctx, update, i18n.T(lang, i18n.BotNothingToCancel))
+	}
+}
+
+// converse takes the url or alias the dialog waits for. It reports whether
+// the message belonged to a dialog.
+func (t *TGBot) converse(ctx context.Context, update *models.Update) bool {
+	msg := update.Message
+	if msg.From == nil || msg.Text == "" || strings.HasPrefix(msg.Text, "/") {
+		return false
+	}
+	c, err := t.conversations.Get(ctx, msg.Chat.ID)
+	if err != nil {
+		t.l.Error("failed to get conversation", slog.Any("err", err))
+		return false
+	}
+	if c == nil || c.TGID != msg.From.ID {
+		return false
+	}
+	lang := t.lang(ctx, msg.From)
+	text := strings.TrimSpace(msg.Text)
+	switch c.Step {
+	case stepUrl:
+		if err := t.links.CheckUrl(text); err != nil {
+			hint, _ := urlErrorKey(err)
+			t.reply(ctx, update, i18n.T(lang, hint))
+			return true
+		}
+		c.Url = text
+	case stepAlias:
+		if err := urls.ValidateAlias(text); err != nil {
+			hint, _ := urlErrorKey(err)
+			t.reply(ctx, update, i18n.T(lang, hint))
+			return true
+		}
+		c.Alias = text
+	default:
+		t.reply(ctx, update, i18n.T(lang, i18n.BotNewUseButtons))
+		return true
+	}
+	c.Step = stepOptions
+	t.sendConversation(ctx, update, c)
+	return true
+}
+
+// ConversationButton moves the dialog on a button press.
+func (t *TGBot) ConversationButton(ctx context.Context, b *bot.Bot, update *models.Update) {
+	cq := update.CallbackQuery
+	if cq.Message.Message == nil {
+		t.answer(ctx, cq, "")
+		return
+	}
+	lang := t.lang(ctx, &cq.From)
+	chatId := cq.Message.Message.Chat.ID
+	c, err := t.conversations.Get(ctx, chatId)
+	if err != nil {
+		t.l.Error("failed to get conversation", slog.Any("err", err))
+		t.answer(ctx, cq, i18n.T(lang, i18n.BotCommandFailed))
+		return
+	}
+	if c == nil || c.TGID != cq.From.ID {
+		t.answer(ctx, cq, i18n.T(lang, i18n.BotNewExpired))
+		return
+	}
+
+	action := strings.TrimPrefix(cq.Data, callbackNew)
+	switch {
+	case action == "cancel":
+		if _, err := t.conversations.Delete(ctx, chatId); err != nil {
+			t.l.Error("failed to delete conversation", slog.Any("err", err))
+		}
+		t.answer(ctx, cq, "")
+		t.edit(ctx, cq, i18n.T(lang, i18n.BotNewCanceled))
+		return
+	case action == "create" && c.Step == stepConfirm:
+		t.createFromConversation(ctx, cq, lang, chatId, c)
+		return
+	case action == "alias":
+		c.Step = stepAlias
+	case action == "random":
+		c.Alias, c.Step = "", stepOptions
+	case action == "expiry":
+		c.Step = stepExpiry
+	case action == "back":
+		c.Step = stepOptions
+	case action == "done" && c.Step == stepOptions:
+		c.Step = stepConfirm
+	case strings.HasPrefix(action, "expires:") && c.Step == stepExpiry:
+		days, err := strconv.Atoi(strings.TrimPrefix(action, "expires:"))
+		if err != nil || days < 0 {
+			t.answer(ctx, cq, "")
+			return
+		}
+		c.ExpiresAt = nil
+		if days > 0 {
+			expiresAt := time.Now().Add(time.Duration(days) * 24 * time.Hour).UTC()
+			c.ExpiresAt = &expiresAt
+		}
+		c.Step = stepOptions
+	default:
+		t.answer(ctx, cq, i18n.T(lang, i18n.BotButtonExpired))
+		return
+	}
+	if err := t.conversations.Save(ctx, chatId, c); err != nil {
+		t.l.Error("failed to save conversation", slog.Any("err", err))
+		t.answer(ctx, cq, i18n.T(lang, i18n.BotCommandFailed))
+		return
+	}
+	t.answer(ctx, cq, "")
+	text, markup := conversationView(lang, c)
+	t.show(ctx, cq, text, markup)
+}
+
+// createFromConversation creates the link. The dialog is dropped first, so
+// a second press on another replica does not create it twice.
+func (t *TGBot) createFromConversation(ctx context.Context, cq *models.CallbackQuery, lang i18n.Lang, chatId int64, c *conversation) {
+	deleted, err := t.conversations.Delete(ctx, chatId)
+	if err != nil {
+		t.l.Error("failed to delete conversation", slog.Any("err", err))
+		t.answer(ctx, cq, i18n.T(lang, i18n.BotCommandFailed))
+		return
+	}
+	if !deleted {
+		t.answer(ctx, cq, i18n.T(lang, i18n.BotNewExpired))
+		return
+	}
+	t.answer(ctx, cq, "")
+
+	userId, err := t.user.TelegramUser(ctx, cq.From.ID, telegramTitle(&cq.From))
+	if err != nil {
+		t.l.Error("failed to resolve telegram user", slog.Any("err", err))
+		t.edit(ctx, cq, i18n.T(lang, i18n.BotUserNotFound))
+		return
+	}
+	alias, err := t.links.CreateUrl(ctx, *userId, urls.CreateUrlParams{
+		Url:       c.Url,
+		Alias:     c.Alias,
+		ExpiresAt: c.ExpiresAt,
+	})
+	if err == nil {
+		t.edit(ctx, cq, i18n.T(lang, i18n.BotShortLink, t.shortLink(alias)))
+		return
+	}
+	key, known := urlErrorKey(err)
+	if !known {
+		t.l.Error("failed to create url", slog.Any("err", err))
+		key = i18n.BotShortenFailed
+	}
+	text := i18n.T(lang, key)
+	// a taken alias or a passed expiry can be changed without starting over
+	c.Step = stepOptions
+	if err := t.conversations.Save(ctx, chatId, c); err != nil {
+		t.l.Error("failed to save conversation", slog.Any("err", err))
+		t.edit(ctx, cq, text)
+		return
+	}
+	view, markup := conversationView(lang, c)
+	t.show(ctx, cq, text+"\n\n"+view, markup)
+}
+
+// sendConversation saves the dialog and sends its current step.
+func (t *TGBot) sendConversation(ctx context.Context, update *models.Update, c *conversation) {
+	lang := t.lang(ctx, update.Message.From)
+	if err := t.conversations.Save(ctx, update.Message.Chat.ID, c); err != nil {
+		t.l.Error("failed to save conversation", slog.Any("err", err))
+		t.reply(ctx, update, i18n.T(lang, i18n.BotCommandFailed))
+		return
+	}
+	text, markup := conversationView(lang, c)
+	t.Bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:             update.Message.Chat.ID,
+		Text:               text,
+		ReplyMarkup:        markup,
+		LinkPreviewOptions: noPreview(),
+	})
+}
+
+// conversationView is the text and the buttons of the step of c in lang.
+func conversationView(lang i18n.Lang, c *conversation) (string, models.ReplyMarkup) {
+	cancel := newButton(i18n.T(lang, i18n.BotCancelButton), "cancel")
+	back := newButton(i18n.T(lang, i18n.BotBack), "back")
+	switch c.Step {
+	case stepUrl:
+		return i18n.T(lang, i18n.BotNewAskUrl), newKeyboard([]models.InlineKeyboardButton{cancel})
+	case stepAlias:
+		return i18n.T(lang, i18n.BotNewAskAlias),
+			newKeyboard([]models.InlineKeyboardButton{newButton(i18n.T(lang, i18n.BotNewRandom), "random"), back}, []models.InlineKeyboardButton{cancel})
+	case stepExpiry:
+		row := make([]models.InlineKeyboardButton, 0, len(expiryDays))
+		for _, e := range expiryDays {
+			row = append(row, newButton(i18n.T(lang, e.Label), "expires:"+strconv.Itoa(e.Days)))
+		}
+		return i18n.T(lang, i18n.BotNewAskExpiry),
+			newKeyboard(row, []models.InlineKeyboardButton{newButton(i18n.T(lang, i18n.BotNewNever), "expires:0"), back})
+	case stepConfirm:
+		return i18n.T(lang, i18n.BotNewConfirm, c.summary(lang)),
+			newKeyboard([]models.InlineKeyboardButton{newButton(i18n.T(lang, i18n.BotNewCreate), "create"), back}, []models.InlineKeyboardButton{cancel})
+	}
+	return i18n.T(lang, i18n.BotNewOptions, c.summary(lang)),
+		newKeyboard(
+			[]models.InlineKeyboardButton{newButton(i18n.T(lang, i18n.BotNewCustomAlias), "alias"), newButton(i18n.T(lang, i18n.BotNewExpiry), "expiry")},
+			[]models.InlineKeyboardButton{newButton(i18n.T(lang, i18n.BotNewContinue), "done"), cancel},
+		)
+}
+
+func (c *conversation) summary(lang i18n.Lang) string {
+	alias := i18n.T(lang, i18n.BotNewRandomAlias)
+	if c.Alias != "" {
+		alias = c.Alias
+	}
+	expires := i18n.T(lang, i18n.BotNewNoExpiry)
+	if c.ExpiresAt != nil {
+		expires = i18n.T(lang, i18n.BotNewExpiresAt, c.ExpiresAt.UTC().Format("02.01.2006 15:04"))
+	}
+	return i18n.T(lang, i18n.BotNewSummary, c.Url, alias, expires)
+}
+
+func newButton(text, action string) models.InlineKeyboardButton {
+	return models.InlineKeyboardButton{Text: text, CallbackData: callbackNew + action}
+}
+
+func newKeyboard(rows ...[]models.InlineKeyboardButton) models.ReplyMarkup {
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// show replaces the message with the buttons by text and markup.
+func (t *TGBot) show(ctx context.Context, cq *models.CallbackQuery, text string, markup models.ReplyMarkup) {
+	t.Bot.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:             cq.Message.Message.Chat.ID,
+		MessageID:          cq.Message.Message.ID,
+		Text:               text,
+		ReplyMarkup:        markup,
+		LinkPreviewOptions: noPreview(),
+	})
+}
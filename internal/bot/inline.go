@@ -0,0 +1,146 @@
+package tgbot
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+
+	urls "github.com/Sanchir01/go-shortener/internal/feature/url"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// inlineStartParameter opens the bot from the button shown instead of results.
+const inlineStartParameter = "inline"
+
+// inlineAliasSize is the length of the alias an inline result offers.
+const inlineAliasSize = 10
+
+// Inline answers "@bot <url>" in any chat with the short link, its QR code
+// and both together. The link is created while answering, so the results
+// work whether or not Telegram reports the one that was sent. Its alias is
+// derived from the user and the url, and repeated queries get the same link.
+func (t *TGBot) Inline(ctx context.Context, b *bot.Bot, update *models.Update) {
+	q := update.InlineQuery
+	lang := t.lang(ctx, q.From)
+	query := strings.TrimSpace(q.Query)
+	if !typedUrl(query) {
+		t.answerInline(ctx, q, nil, i18n.T(lang, i18n.BotInlineHint))
+		return
+	}
+	alias, err := t.inlineLink(ctx, q.From, query)
+	if err != nil {
+		key, known := urlErrorKey(err)
+		if !known {
+			t.l.Error("failed to create inline link", slog.Any("err", err))
+			key = i18n.BotShortenFailed
+		}
+		t.answerInline(ctx, q, nil, i18n.T(lang, key))
+		return
+	}
+	link := t.shortLink(alias)
+	qr := t.baseURL + "/qr/" + alias
+	t.answerInline(ctx, q, []models.InlineQueryResult{
+		&models.InlineQueryResultArticle{
+			ID:          "link:" + alias,
+			Title:       link,
+			Description: query,
+			InputMessageContent: &models.InputTextMessageContent{
+				MessageText:        link,
+				LinkPreviewOptions: noPreview(),
+			},
+		},
+		&models.InlineQueryResultPhoto{
+			ID:           "qr:" + alias,
+			PhotoURL:     qr,
+			ThumbnailURL: qr,
+			Title:        i18n.T(lang, i18n.BotInlineQR),
+		},
+		&models.InlineQueryResultPhoto{
+			ID:           "qrlink:" + alias,
+			PhotoURL:     qr,
+			ThumbnailURL: qr,
+			Title:        i18n.T(lang, i18n.BotInlineQRLink),
+			Caption:      link,
+		},
+	}, "")
+}
+
+// inlineLink returns the alias of the link the user has for rawUrl and
+// creates it under the inline alias when there is none.
+func (t *TGBot) inlineLink(ctx context.Context, from *models.User, rawUrl string) (string, error) {
+	if err := t.links.CheckUrl(rawUrl); err != nil {
+		return "", err
+	}
+	userId, err := t.user.TelegramUser(ctx, from.ID, telegramTitle(from))
+	if err != nil {
+		return "", err
+	}
+	alias, found, err := t.existingAlias(ctx, *userId, rawUrl)
+	if err != nil || found {
+		return alias, err
+	}
+	alias = t.inlineAlias(from.ID, rawUrl)
+	_, err = t.links.CreateUrl(ctx, *userId, urls.CreateUrlParams{Url: rawUrl, Alias: alias})
+	if errors.Is(err, utils.ErrorUrlExists) {
+		// a concurrent query created it first
+		if existing, found, lookupErr := t.existingAlias(ctx, *userId, rawUrl); lookupErr == nil && found {
+			return existing, nil
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return alias, nil
+}
+
+// inlineAlias derives the alias of the link a user gets for rawUrl. It is
+// keyed with the bot token, so nobody can guess and take it first.
+func (t *TGBot) inlineAlias(tgId int64, rawUrl string) string {
+	mac := hmac.New(sha256.New, []byte(t.Bot.Token()))
+	mac.Write([]byte(strconv.FormatInt(tgId, 10) + ":" + rawUrl))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))[:inlineAliasSize]
+}
+
+// answerInline sends results, or a button with hint that opens the bot when
+// there are none.
+func (t *TGBot) answerInline(ctx context.Context, q *models.InlineQuery, results []models.InlineQueryResult, hint string) {
+	params := &bot.AnswerInlineQueryParams{
+		InlineQueryID: q.ID,
+		Results:       results,
+		CacheTime:     int(t.cfg.InlineCacheTTL.Seconds()),
+		IsPersonal:    true,
+	}
+	// errors may be temporary, they are not cached
+	if results == nil {
+		params.Results = []models.InlineQueryResult{}
+		params.CacheTime = 0
+		params.Button = &models.InlineQueryResultsButton{Text: hint, StartParameter: inlineStartParameter}
+	}
+	if _, err := t.Bot.AnswerInlineQuery(ctx, params); err != nil {
+		t.l.Error("failed to answer inline query", slog.Any("err", err))
+	}
+}
+
+// typedUrl reports whether the query looks like a whole url. Telegram sends
+// a query per keystroke and "https://exa" should not become a link.
+func typedUrl(query string) bool {
+	if urls.ValidateUrl(query, nil) != nil {
+		return false
+	}
+	u, _ := url.Parse(query)
+	return strings.Contains(strings.Trim(u.Hostname(), "."), ".")
+}
+
+// isInlineQuery matches updates with an inline query.
+func isInlineQuery(update *models.Update) bool {
+	return update.InlineQuery != nil
+}
@@ -0,0 +1,101 @@
+package tgbot
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const callbackLanguage = "lang:"
+
+// LanguageStore keeps the language a user chose with /language. The choice
+// has no ttl, it lasts until the user changes it.
+type LanguageStore struct {
+	rdb *redis.Client
+}
+
+func NewLanguageStore(rdb *redis.Client) *LanguageStore {
+	return &LanguageStore{rdb: rdb}
+}
+
+func languageKey(tgId int64) string {
+	return "bot:lang:" + strconv.FormatInt(tgId, 10)
+}
+
+// Get returns an empty language when the user has not chosen one.
+func (s *LanguageStore) Get(ctx context.Context, tgId int64) (i18n.Lang, error) {
+	value, err := s.rdb.Get(ctx, languageKey(tgId)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	lang, _ := i18n.Parse(value)
+	return lang, nil
+}
+
+func (s *LanguageStore) Set(ctx context.Context, tgId int64, lang i18n.Lang) error {
+	return s.rdb.Set(ctx, languageKey(tgId), string(lang), 0).Err()
+}
+
+// lang is the language to answer u in.
+func (t *TGBot) lang(ctx context.Context, u *models.User) i18n.Lang {
+	return t.userLang(ctx, u.ID, u.LanguageCode)
+}
+
+// userLang is the language chosen with /language, otherwise the one of the
+// Telegram app when it is supported, otherwise Russian.
+func (t *TGBot) userLang(ctx context.Context, tgId int64, languageCode string) i18n.Lang {
+	lang, err := t.languages.Get(ctx, tgId)
+	if err != nil {
+		t.l.Warn("language store error", slog.Any("err", err))
+	}
+	if lang != "" {
+		return lang
+	}
+	if lang, ok := i18n.Parse(languageCode); ok {
+		return lang
+	}
+	return i18n.RU
+}
+
+// Language offers the supported languages as buttons.
+func (t *TGBot) Language(ctx context.Context, b *bot.Bot, update *models.Update) {
+	row := make([]models.InlineKeyboardButton, 0, len(i18n.Languages))
+	for _, lang := range i18n.Languages {
+		row = append(row, models.InlineKeyboardButton{
+			Text:         i18n.T(lang, i18n.BotLanguageName),
+			CallbackData: callbackLanguage + string(lang),
+		})
+	}
+	t.Bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      update.Message.Chat.ID,
+		Text:        i18n.T(t.lang(ctx, update.Message.From), i18n.BotLanguagePrompt),
+		ReplyMarkup: &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{row}},
+	})
+}
+
+// SetLanguage saves the language behind the button.
+func (t *TGBot) SetLanguage(ctx context.Context, b *bot.Bot, update *models.Update) {
+	cq := update.CallbackQuery
+	lang, ok := i18n.Parse(strings.TrimPrefix(cq.Data, callbackLanguage))
+	if !ok {
+		t.answer(ctx, cq, "")
+		return
+	}
+	if err := t.languages.Set(ctx, cq.From.ID, lang); err != nil {
+		t.l.Error("failed to save language", slog.Any("err", err))
+		t.answer(ctx, cq, i18n.T(t.lang(ctx, &cq.From), i18n.BotCommandFailed))
+		return
+	}
+	t.answer(ctx, cq, "")
+	t.edit(ctx, cq, i18n.T(lang, i18n.BotLanguageSet))
+}
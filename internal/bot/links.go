@@ -0,0 +1,353 @@
+package tgbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	domain "github.com/Sanchir01/go-shortener/internal/domain/models"
+	urls "github.com/Sanchir01/go-shortener/internal/feature/url"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/google/uuid"
+)
+
+const (
+	linksPageSize = 5
+	topReferrers  = 5
+
+	callbackLinks   = "links:"
+	callbackConfirm = "confirm:"
+	callbackCancel  = "cancel:"
+)
+
+// MyLinks lists the links of the sender, newest first, with buttons to page
+// through them.
+func (t *TGBot) MyLinks(ctx context.Context, b *bot.Bot, update *models.Update) {
+	text, markup, err := t.linksPage(ctx, update.Message.From, 0)
+	if err != nil {
+		t.l.Error("failed to list links", slog.Any("err", err))
+		text = i18n.T(t.lang(ctx, update.Message.From), i18n.BotLinksFailed)
+	}
+	t.Bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:             update.Message.Chat.ID,
+		Text:               text,
+		ReplyMarkup:        markup,
+		LinkPreviewOptions: noPreview(),
+	})
+}
+
+// LinksPage turns the page of the /mylinks message.
+func (t *TGBot) LinksPage(ctx context.Context, b *bot.Bot, update *models.Update) {
+	cq := update.CallbackQuery
+	defer t.answer(ctx, cq, "")
+
+	page, err := strconv.Atoi(strings.TrimPrefix(cq.Data, callbackLinks))
+	if err != nil || page < 0 || cq.Message.Message == nil {
+		return
+	}
+	text, markup, err := t.linksPage(ctx, &cq.From, page)
+	if err != nil {
+		t.l.Error("failed to list links", slog.Any("err", err))
+		return
+	}
+	t.Bot.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:             cq.Message.Message.Chat.ID,
+		MessageID:          cq.Message.Message.ID,
+		Text:               text,
+		ReplyMarkup:        markup,
+		LinkPreviewOptions: noPreview(),
+	})
+}
+
+func (t *TGBot) linksPage(ctx context.Context, from *models.User, page int) (string, models.ReplyMarkup, error) {
+	lang := t.lang(ctx, from)
+	userId, err := t.user.TelegramUser(ctx, from.ID, telegramTitle(from))
+	if err != nil {
+		return "", nil, err
+	}
+	links, err := t.links.GetUrlByUser(ctx, *userId, urls.UrlFilter{
+		Limit:  linksPageSize + 1,
+		Offset: uint64(page * linksPageSize),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if len(links) == 0 && page == 0 {
+		return i18n.T(lang, i18n.BotNoLinks), nil, nil
+	}
+	if len(links) == 0 {
+		return i18n.T(lang, i18n.BotEmptyPage), pageKeyboard(lang, page, false), nil
+	}
+	hasNext := len(links) > linksPageSize
+	if hasNext {
+		links = links[:linksPageSize]
+	}
+	var sb strings.Builder
+	sb.WriteString(i18n.T(lang, i18n.BotLinksPage, page+1))
+	for i, link := range links {
+		fmt.Fprintf(&sb, "\n%d. %s\n%s\n", page*linksPageSize+i+1, t.shortLink(link.Alias), link.Url)
+	}
+	return sb.String(), pageKeyboard(lang, page, hasNext), nil
+}
+
+func pageKeyboard(lang i18n.Lang, page int, hasNext bool) models.ReplyMarkup {
+	var row []models.InlineKeyboardButton
+	if page > 0 {
+		row = append(row, models.InlineKeyboardButton{Text: i18n.T(lang, i18n.BotBack), CallbackData: callbackLinks + strconv.Itoa(page-1)})
+	}
+	if hasNext {
+		row = append(row, models.InlineKeyboardButton{Text: i18n.T(lang, i18n.BotForward), CallbackData: callbackLinks + strconv.Itoa(page+1)})
+	}
+	if len(row) == 0 {
+		return nil
+	}
+	return &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{row}}
+}
+
+// Stats answers with the approximate clicks of a link of the sender and
+// where they came from.
+func (t *TGBot) Stats(ctx context.Context, b *bot.Bot, update *models.Update) {
+	lang := t.lang(ctx, update.Message.From)
+	args := commandArgs(update.Message.Text)
+	if len(args) != 1 {
+		t.reply(ctx, update, i18n.T(lang, i18n.BotStatsUsage))
+		return
+	}
+	_, link, ok := t.userLink(ctx, update, args[0])
+	if !ok {
+		return
+	}
+	stats, err := t.stats.LiveCounts(ctx, link.ID, topReferrers)
+	if err != nil {
+		t.l.Error("failed to get click stats", slog.Any("err", err))
+		t.reply(ctx, update, i18n.T(lang, i18n.BotStatsFailed))
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString(i18n.T(lang, i18n.BotStatsClicks, t.shortLink(link.Alias), stats.Clicks))
+	if len(stats.Referrers) > 0 {
+		sb.WriteString(i18n.T(lang, i18n.BotStatsReferrers))
+		for _, r := range stats.Referrers {
+			fmt.Fprintf(&sb, "%s — %d\n", r.Host, r.Clicks)
+		}
+	}
+	t.reply(ctx, update, sb.String())
+}
+
+// Delete asks to confirm deleting a link of the sender.
+func (t *TGBot) Delete(ctx context.Context, b *bot.Bot, update *models.Update) {
+	lang := t.lang(ctx, update.Message.From)
+	args := commandArgs(update.Message.Text)
+	if len(args) != 1 {
+		t.reply(ctx, update, i18n.T(lang, i18n.BotDeleteUsage))
+		return
+	}
+	_, link, ok := t.userLink(ctx, update, args[0])
+	if !ok {
+		return
+	}
+	t.confirm(ctx, update, confirmation{
+		Action: actionDelete,
+		TGID:   update.Message.From.ID,
+		UrlID:  link.ID,
+		Alias:  link.Alias,
+	}, i18n.T(lang, i18n.BotDeleteQuestion, t.shortLink(link.Alias), link.Url), i18n.T(lang, i18n.BotDeleteButton))
+}
+
+// Edit asks to confirm a new destination for a link of the sender.
+func (t *TGBot) Edit(ctx context.Context, b *bot.Bot, update *models.Update) {
+	lang := t.lang(ctx, update.Message.From)
+	args := commandArgs(update.Message.Text)
+	if len(args) != 2 {
+		t.reply(ctx, update, i18n.T(lang, i18n.BotEditUsage))
+		return
+	}
+	if err := t.links.CheckUrl(args[1]); err != nil {
+		key, _ := urlErrorKey(err)
+		t.reply(ctx, update, i18n.T(lang, key))
+		return
+	}
+	_, link, ok := t.userLink(ctx, update, args[0])
+	if !ok {
+		return
+	}
+	t.confirm(ctx, update, confirmation{
+		Action: actionEdit,
+		TGID:   update.Message.From.ID,
+		UrlID:  link.ID,
+		Alias:  link.Alias,
+		Url:    args[1],
+	}, i18n.T(lang, i18n.BotEditQuestion, t.shortLink(link.Alias), link.Url, args[1]), i18n.T(lang, i18n.BotEditButton))
+}
+
+func (t *TGBot) confirm(ctx context.Context, update *models.Update, c confirmation, question, action string) {
+	lang := t.lang(ctx, update.Message.From)
+	token, err := t.confirms.Save(ctx, c)
+	if err != nil {
+		t.l.Error("failed to save confirmation", slog.Any("err", err))
+		t.reply(ctx, update, i18n.T(lang, i18n.BotCommandFailed))
+		return
+	}
+	t.Bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   question,
+		ReplyMarkup: &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{{
+			{Text: action, CallbackData: callbackConfirm + token},
+			{Text: i18n.T(lang, i18n.BotCancelButton), CallbackData: callbackCancel + token},
+		}}},
+		LinkPreviewOptions: noPreview(),
+	})
+}
+
+// Confirm applies the change behind a confirmation button.
+func (t *TGBot) Confirm(ctx context.Context, b *bot.Bot, update *models.Update) {
+	cq := update.CallbackQuery
+	c, err := t.confirms.Take(ctx, strings.TrimPrefix(cq.Data, callbackConfirm), cq.From.ID)
+	if err != nil {
+		t.expired(ctx, cq, err)
+		return
+	}
+	t.answer(ctx, cq, "")
+
+	lang := t.lang(ctx, &cq.From)
+	text, err := t.apply(ctx, &cq.From, lang, c)
+	if key, ok := urlErrorKey(err); ok {
+		text = i18n.T(lang, key)
+	} else if err != nil {
+		t.l.Error("failed to apply confirmation", slog.String("action", c.Action), slog.Any("err", err))
+		text = i18n.T(lang, i18n.BotCommandFailed)
+	}
+	t.edit(ctx, cq, text)
+}
+
+func (t *TGBot) apply(ctx context.Context, from *models.User, lang i18n.Lang, c *confirmation) (string, error) {
+	userId, err := t.user.TelegramUser(ctx, from.ID, telegramTitle(from))
+	if err != nil {
+		return "", err
+	}
+	switch c.Action {
+	case actionDelete:
+		if err := t.links.DeleteUrl(ctx, c.UrlID, *userId); err != nil {
+			return "", err
+		}
+		return i18n.T(lang, i18n.BotDeleted, t.shortLink(c.Alias)), nil
+	case actionEdit:
+		if _, err := t.links.UpdateUrl(ctx, c.UrlID, *userId, urls.UpdateUrlParams{Url: &c.Url}); err != nil {
+			return "", err
+		}
+		return i18n.T(lang, i18n.BotEdited, t.shortLink(c.Alias), c.Url), nil
+	}
+	return "", fmt.Errorf("unknown action %q", c.Action)
+}
+
+// Cancel drops the confirmation behind the button.
+func (t *TGBot) Cancel(ctx context.Context, b *bot.Bot, update *models.Update) {
+	cq := update.CallbackQuery
+	if _, err := t.confirms.Take(ctx, strings.TrimPrefix(cq.Data, callbackCancel), cq.From.ID); err != nil {
+		t.expired(ctx, cq, err)
+		return
+	}
+	t.answer(ctx, cq, "")
+	t.edit(ctx, cq, i18n.T(t.lang(ctx, &cq.From), i18n.BotCanceled))
+}
+
+func (t *TGBot) expired(ctx context.Context, cq *models.CallbackQuery, err error) {
+	if !errors.Is(err, errConfirmExpired) {
+		t.l.Error("failed to take confirmation", slog.Any("err", err))
+	}
+	t.answer(ctx, cq, i18n.T(t.lang(ctx, &cq.From), i18n.BotButtonExpired))
+}
+
+// userLink finds the link of the sender by alias. When there is none it
+// answers in the chat and ok is false.
+func (t *TGBot) userLink(ctx context.Context, update *models.Update, alias string) (*uuid.UUID, *domain.Url, bool) {
+	from := update.Message.From
+	lang := t.lang(ctx, from)
+	userId, err := t.user.TelegramUser(ctx, from.ID, telegramTitle(from))
+	if err != nil {
+		t.l.Error("failed to resolve telegram user", slog.Any("err", err))
+		t.reply(ctx, update, i18n.T(lang, i18n.BotUserNotFound))
+		return nil, nil, false
+	}
+	link, err := t.links.GetUserUrlByAlias(ctx, *userId, alias)
+	if errors.Is(err, utils.ErrorUrlNotFound) {
+		t.reply(ctx, update, i18n.T(lang, i18n.BotNoSuchLink, alias))
+		return nil, nil, false
+	}
+	if err != nil {
+		t.l.Error("failed to get url", slog.Any("err", err))
+		t.reply(ctx, update, i18n.T(lang, i18n.BotLinkLookupFailed))
+		return nil, nil, false
+	}
+	return userId, link, true
+}
+
+// urlErrorKey names the message explaining errors of the URL service the
+// user can fix.
+func urlErrorKey(err error) (i18n.Key, bool) {
+	switch {
+	case errors.Is(err, utils.ErrorInvalidUrl):
+		return i18n.BotInvalidUrl, true
+	case errors.Is(err, utils.ErrorUrlBlocked):
+		return i18n.BotUrlBlocked, true
+	case errors.Is(err, utils.ErrorUrlExists):
+		return i18n.BotUrlExists, true
+	case errors.Is(err, utils.ErrorInvalidAlias):
+		return i18n.BotInvalidAlias, true
+	case errors.Is(err, utils.ErrorAliasTaken):
+		return i18n.BotAliasTaken, true
+	case errors.Is(err, utils.ErrorInvalidExpiry):
+		return i18n.BotInvalidExpiry, true
+	case errors.Is(err, utils.ErrorUrlNotFound):
+		return i18n.BotUrlNotFound, true
+	}
+	return "", false
+}
+
+// commandArgs splits the text after the command.
+func commandArgs(text string) []string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields[1:]
+}
+
+func (t *TGBot) reply(ctx context.Context, update *models.Update, text string) {
+	t.Bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:             update.Message.Chat.ID,
+		Text:               text,
+		LinkPreviewOptions: noPreview(),
+	})
+}
+
+func (t *TGBot) answer(ctx context.Context, cq *models.CallbackQuery, text string) {
+	t.Bot.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: cq.ID,
+		Text:            text,
+	})
+}
+
+// edit replaces the message with the buttons by text, dropping the buttons.
+func (t *TGBot) edit(ctx context.Context, cq *models.CallbackQuery, text string) {
+	if cq.Message.Message == nil {
+		return
+	}
+	t.Bot.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:             cq.Message.Message.Chat.ID,
+		MessageID:          cq.Message.Message.ID,
+		Text:               text,
+		LinkPreviewOptions: noPreview(),
+	})
+}
+
+func noPreview() *models.LinkPreviewOptions {
+	disabled := true
+	return &models.LinkPreviewOptions{IsDisabled: &disabled}
+}
@@ -0,0 +1,82 @@
+package tgbot
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const (
+	ModePolling = "polling"
+	ModeWebhook = "webhook"
+
+	secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+)
+
+// allowedUpdates are the update types the bot has handlers for.
+var allowedUpdates = []string{"message", "callback_query", "inline_query"}
+
+// Run receives updates until ctx is done. In webhook mode it registers the
+// webhook and returns, updates arrive at WebhookHandler.
+func (t *TGBot) Run(ctx context.Context) error {
+	if t.cfg.Mode == ModeWebhook {
+		_, err := t.Bot.SetWebhook(ctx, &bot.SetWebhookParams{
+			URL:            t.cfg.WebhookURL,
+			SecretToken:    t.secret,
+			AllowedUpdates: allowedUpdates,
+		})
+		return err
+	}
+	// getUpdates is refused while a webhook is set
+	if _, err := t.Bot.DeleteWebhook(ctx, &bot.DeleteWebhookParams{}); err != nil {
+		return err
+	}
+	t.Bot.Start(ctx)
+	return nil
+}
+
+// @Summary  WebhookHandler
+// @Tags telegram
+// @Description Updates pushed by Telegram in webhook mode, the secret token set with setWebhook is required
+// @Accept json
+// @Param X-Telegram-Bot-Api-Secret-Token header string true "secret token"
+// @Success 200
+// @Failure 400,401,404 {object}  api.Response
+// @Router /bot/webhook [post]
+func (t *TGBot) WebhookHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Bot.WebhookHandler"
+	log := t.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	if t.cfg.Mode != ModeWebhook {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrNotFound))
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(secretTokenHeader)), []byte(t.secret)) != 1 {
+		log.Warn("invalid webhook secret token")
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidSecretToken))
+		return
+	}
+	var update models.Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		log.Error("failed to decode update", slog.Any("err", err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidUpdate))
+		return
+	}
+	// finish the update even if Telegram stops waiting, a retry would repeat it
+	t.Bot.ProcessUpdate(context.WithoutCancel(r.Context()), &update)
+	w.WriteHeader(http.StatusOK)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"log/slog"
+	"net"
 	"os"
 	"time"
 
@@ -19,6 +20,18 @@ type Config struct {
 	Prometheus Prometheus `yaml:"prometheus"`
 	RedisDB    Redis      `yaml:"redis"`
 	DB         DataBase   `yaml:"database"`
+	Redirect   Redirect   `yaml:"redirect"`
+	Domains    Domains    `yaml:"domains"`
+	Health     Health     `yaml:"health"`
+	Webhooks   Webhooks   `yaml:"webhooks"`
+	Clicks     Clicks     `yaml:"click_stream"`
+	Sessions   Sessions   `yaml:"sessions"`
+	Jwt        Jwt        `yaml:"jwt"`
+	Mail       Mail       `yaml:"mail"`
+	Accounts   Accounts   `yaml:"accounts"`
+	TwoFactor  TwoFactor  `yaml:"two_factor"`
+	OAuth      OAuth      `yaml:"oauth"`
+	Telegram   Telegram   `yaml:"telegram"`
 }
 type DataBase struct {
 	Host        string `yaml:"host"`
@@ -41,6 +54,153 @@ type Redis struct {
 	Retries  int    `yaml:"retries"`
 	DBNumber int    `yaml:"dbnumber"`
 }
+type Redirect struct {
+	Timezone          string `yaml:"timezone"  env-default:"UTC"`
+	ComingSoonURL     string `yaml:"coming_soon_url"`
+	ComingSoonStatus  int    `yaml:"coming_soon_status"  env-default:"503"`
+	ComingSoonMessage string `yaml:"coming_soon_message"  env-default:"link is not active yet"`
+	// BlockedHosts can not be shortened, subdomains included
+	BlockedHosts []string `yaml:"blocked_hosts"`
+}
+type Domains struct {
+	VerificationScheme  string        `yaml:"verification_scheme"  env-default:"https"`
+	VerificationPath    string        `yaml:"verification_path"  env-default:"/.well-known/go-shortener-verification"`
+	VerificationTimeout time.Duration `yaml:"verification_timeout"  env-default:"10s"`
+}
+type Health struct {
+	Enabled          bool          `yaml:"enabled"  env-default:"false"`
+	Interval         time.Duration `yaml:"interval"  env-default:"10m"`
+	Timeout          time.Duration `yaml:"timeout"  env-default:"10s"`
+	Concurrency      int           `yaml:"concurrency"  env-default:"8"`
+	PerHostDelay     time.Duration `yaml:"per_host_delay"  env-default:"2s"`
+	FailureThreshold int           `yaml:"failure_threshold"  env-default:"3"`
+	UserAgent        string        `yaml:"user_agent"  env-default:"go-shortener-healthcheck/1.0"`
+}
+type Webhooks struct {
+	QueueSize    int           `yaml:"queue_size"  env-default:"1024"`
+	Workers      int           `yaml:"workers"  env-default:"4"`
+	PollInterval time.Duration `yaml:"poll_interval"  env-default:"2s"`
+	Timeout      time.Duration `yaml:"timeout"  env-default:"10s"`
+	MaxAttempts  int           `yaml:"max_attempts"  env-default:"8"`
+	BaseBackoff  time.Duration `yaml:"base_backoff"  env-default:"30s"`
+	MaxBackoff   time.Duration `yaml:"max_backoff"  env-default:"6h"`
+}
+type Clicks struct {
+	QueueSize   int           `yaml:"queue_size"  env-default:"4096"`
+	Heartbeat   time.Duration `yaml:"heartbeat"  env-default:"15s"`
+	Retry       time.Duration `yaml:"retry"  env-default:"3s"`
+	HistorySize int64         `yaml:"history_size"  env-default:"1000"`
+	HistoryTTL  time.Duration `yaml:"history_ttl"  env-default:"24h"`
+}
+type Sessions struct {
+	AccessTTL  time.Duration `yaml:"access_ttl"  env-default:"15m"`
+	RefreshTTL time.Duration `yaml:"refresh_ttl"  env-default:"336h"`
+	ReuseGrace time.Duration `yaml:"reuse_grace"  env-default:"10s"`
+}
+
+// Jwt configures the access token signing keys. Their private parts are
+// stored encrypted with the base64 encoded 32 byte key from
+// JWT_KEY_ENCRYPTION_KEY, GracePeriod must cover AccessTTL plus
+// ReloadInterval.
+type Jwt struct {
+	Algorithm      string        `yaml:"algorithm"  env-default:"EdDSA"`
+	RotateEvery    time.Duration `yaml:"rotate_every"  env-default:"720h"`
+	GracePeriod    time.Duration `yaml:"grace_period"  env-default:"24h"`
+	ReloadInterval time.Duration `yaml:"reload_interval"  env-default:"1m"`
+}
+type Mail struct {
+	Driver string `yaml:"driver"  env-default:"log"`
+	From   string `yaml:"from"  env-default:"go-shortener <no-reply@localhost>"`
+	Dir    string `yaml:"dir"  env-default:"tmp/mail"`
+	SMTP   SMTP   `yaml:"smtp"`
+}
+type SMTP struct {
+	Host     string        `yaml:"host"`
+	Port     string        `yaml:"port"  env-default:"587"`
+	Username string        `yaml:"username"`
+	Timeout  time.Duration `yaml:"timeout"  env-default:"10s"`
+}
+type Accounts struct {
+	AppURL               string        `yaml:"app_url"  env-default:"http://localhost:3000"`
+	VerifyTTL            time.Duration `yaml:"verify_ttl"  env-default:"48h"`
+	ResetTTL             time.Duration `yaml:"reset_ttl"  env-default:"1h"`
+	RequireVerifiedEmail bool          `yaml:"require_verified_email"  env-default:"false"`
+}
+type TwoFactor struct {
+	Issuer        string        `yaml:"issuer"  env-default:"go-shortener"`
+	ChallengeTTL  time.Duration `yaml:"challenge_ttl"  env-default:"5m"`
+	MaxAttempts   int           `yaml:"max_attempts"  env-default:"5"`
+	RecoveryCodes int           `yaml:"recovery_codes"  env-default:"10"`
+	MaxFailures   int           `yaml:"max_failures"  env-default:"10"`
+	LockoutTTL    time.Duration `yaml:"lockout_ttl"  env-default:"15m"`
+}
+
+// OAuth lists the sign-in providers by the name used in /auth/{provider}.
+type OAuth struct {
+	StateTTL  time.Duration            `yaml:"state_ttl"  env-default:"10m"`
+	Timeout   time.Duration            `yaml:"timeout"  env-default:"10s"`
+	Providers map[string]OAuthProvider `yaml:"providers"`
+}
+
+// OAuthProvider is an OpenID Connect provider whose endpoints are discovered
+// from Issuer, or with Type oauth2 a plain OAuth2 provider whose profile is
+// read from UserInfoURL through Mapping. Endpoints set here override the
+// discovered ones. The client id and secret are read from the environment
+// variables named by ClientIDEnv and ClientSecretEnv.
+type OAuthProvider struct {
+	Type            string            `yaml:"type"`
+	Issuer          string            `yaml:"issuer"`
+	Issuers         []string          `yaml:"issuers"`
+	AuthURL         string            `yaml:"auth_url"`
+	TokenURL        string            `yaml:"token_url"`
+	JWKSURL         string            `yaml:"jwks_url"`
+	UserInfoURL     string            `yaml:"userinfo_url"`
+	EmailsURL       string            `yaml:"emails_url"`
+	Scopes          []string          `yaml:"scopes"`
+	AuthParams      map[string]string `yaml:"auth_params"`
+	ClientIDEnv     string            `yaml:"client_id_env"`
+	ClientSecretEnv string            `yaml:"client_secret_env"`
+	RedirectURI     string            `yaml:"redirect_uri"`
+	Mapping         OAuthMapping      `yaml:"mapping"`
+}
+
+// OAuthMapping names the dot separated user-info fields of a plain OAuth2
+// provider. TrustEmail treats the mapped email as verified when the provider
+// has no verified flag.
+type OAuthMapping struct {
+	Subject       string `yaml:"subject"`
+	Email         string `yaml:"email"`
+	EmailVerified string `yaml:"email_verified"`
+	Name          string `yaml:"name"`
+	TrustEmail    bool   `yaml:"trust_email"`
+}
+
+// Telegram configures linking bot accounts with web accounts and signing in
+// with Telegram. BotUsername builds the t.me deep link a web user opens to
+// send the code. AuthMaxAge is how old the auth_date of login data may be.
+// ConfirmTTL is how long the bot waits for a press on a confirmation button,
+// InlineCacheTTL how long Telegram caches the answer to an inline query and
+// ConversationTTL how long a /new dialog waits for the next step.
+// BulkMaxUrls caps the urls shortened from one forwarded message or file,
+// BulkMaxFileSize the size in bytes of an uploaded .txt or .csv file.
+//
+// Mode is "polling" or "webhook". Polling works on a single replica only, in
+// webhook mode WebhookURL is registered at startup and Telegram pushes updates
+// to /api/v1/bot/webhook with the secret token from TELEGRAM_WEBHOOK_SECRET.
+// ServerURL points the bot at a self-hosted Bot API server.
+type Telegram struct {
+	BotUsername     string        `yaml:"bot_username"`
+	LinkCodeTTL     time.Duration `yaml:"link_code_ttl"  env-default:"10m"`
+	AuthMaxAge      time.Duration `yaml:"auth_max_age"  env-default:"1h"`
+	ConfirmTTL      time.Duration `yaml:"confirm_ttl"  env-default:"10m"`
+	InlineCacheTTL  time.Duration `yaml:"inline_cache_ttl"  env-default:"5m"`
+	ConversationTTL time.Duration `yaml:"conversation_ttl"  env-default:"30m"`
+	BulkMaxUrls     int           `yaml:"bulk_max_urls"  env-default:"100"`
+	BulkMaxFileSize int64         `yaml:"bulk_max_file_size"  env-default:"1048576"`
+	Mode            string        `yaml:"mode"  env-default:"polling"`
+	WebhookURL      string        `yaml:"webhook_url"`
+	ServerURL       string        `yaml:"server_url"`
+}
 type HttpServer struct {
 	Timeout     time.Duration `yaml:"timeout"  env-default:"4s"`
 	Host        string        `yaml:"host"  env-default:"localhost"`
@@ -57,6 +217,26 @@ type Prometheus struct {
 	IdleTimeout time.Duration `yaml:"idle_timeout"  env-default:"60s"`
 }
 
+// ShortLinkBase is where the redirect handler is reachable: the shared domain
+// when it is set, the local API otherwise.
+func (c *Config) ShortLinkBase() string {
+	if c.Domain != "" {
+		return "https://" + c.Domain
+	}
+	return "http://" + net.JoinHostPort("localhost", c.HttpServer.Port)
+}
+
+// Validate rejects settings that break each other. A replaced signing key
+// must keep verifying until every access token it signed has expired, and
+// the other instances may only start signing with the next key up to
+// ReloadInterval after it was created.
+func (c *Config) Validate() error {
+	if least := c.Sessions.AccessTTL + c.Jwt.ReloadInterval; c.Jwt.GracePeriod < least {
+		return fmt.Errorf("jwt.grace_period %s is shorter than sessions.access_ttl plus jwt.reload_interval, %s", c.Jwt.GracePeriod, least)
+	}
+	return nil
+}
+
 func InitConfig() *Config {
 	envFile := os.Getenv("ENV_FILE")
 	if envFile == "" {
@@ -75,6 +255,9 @@ func InitConfig() *Config {
 	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
 		log.Fatalf("Failed to read config: %v", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
 
 	return &cfg
 }
@@ -24,3 +24,24 @@ const (
 	RoleUser      UserRole = "user"
 	RoleModerator UserRole = "moderator"
 )
+
+type EventType string
+
+const (
+	EventLinkCreated EventType = "link.created"
+	EventLinkUpdated EventType = "link.updated"
+	EventLinkDeleted EventType = "link.deleted"
+	EventLinkClicked EventType = "link.clicked"
+)
+
+var EventTypes = []EventType{EventLinkCreated, EventLinkUpdated, EventLinkDeleted, EventLinkClicked}
+
+type Scope string
+
+const (
+	ScopeLinksRead  Scope = "links:read"
+	ScopeLinksWrite Scope = "links:write"
+	ScopeStatsRead  Scope = "stats:read"
+)
+
+var Scopes = []Scope{ScopeLinksRead, ScopeLinksWrite, ScopeStatsRead}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
+	"github.com/google/uuid"
+)
+
+// ApiKey is a personal access token. Only its prefix and a hash of the whole
+// key are stored.
+type ApiKey struct {
+	ID         uuid.UUID          `db:"id" json:"id"`
+	UserID     uuid.UUID          `db:"user_id" json:"user_id"`
+	Name       string             `db:"name" json:"name"`
+	Prefix     string             `db:"prefix" json:"prefix"`
+	Hash       string             `db:"hash" json:"-"`
+	Scopes     []contextkey.Scope `db:"scopes" json:"scopes"`
+	ExpiresAt  *time.Time         `db:"expires_at" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time         `db:"last_used_at" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time         `db:"revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time          `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Domain struct {
+	ID         uuid.UUID  `db:"id" json:"id"`
+	UserID     uuid.UUID  `db:"user_id" json:"user_id"`
+	Host       string     `db:"host" json:"host"`
+	Token      string     `db:"token" json:"token"`
+	VerifiedAt *time.Time `db:"verified_at" json:"verified_at,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+}
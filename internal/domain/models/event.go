@@ -0,0 +1,53 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
+	"github.com/google/uuid"
+)
+
+// LinkEvent is something that happened to a link, delivered to webhooks and
+// other subscribers of the link owner.
+type LinkEvent struct {
+	ID         uuid.UUID            `json:"id"`
+	Type       contextkey.EventType `json:"type"`
+	UserID     uuid.UUID            `json:"user_id"`
+	UrlID      uuid.UUID            `json:"url_id"`
+	Alias      string               `json:"alias"`
+	OccurredAt time.Time            `json:"occurred_at"`
+	Data       map[string]any       `json:"data,omitempty"`
+}
+
+type Webhook struct {
+	ID        uuid.UUID              `db:"id" json:"id"`
+	UserID    uuid.UUID              `db:"user_id" json:"user_id"`
+	Url       string                 `db:"url" json:"url"`
+	Secret    string                 `db:"secret" json:"-"`
+	Events    []contextkey.EventType `db:"events" json:"events"`
+	Active    bool                   `db:"active" json:"active"`
+	CreatedAt time.Time              `db:"created_at" json:"created_at"`
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	DeliveryPending   WebhookDeliveryStatus = "pending"
+	DeliveryDelivered WebhookDeliveryStatus = "delivered"
+	DeliveryDead      WebhookDeliveryStatus = "dead"
+)
+
+type WebhookDelivery struct {
+	ID             uuid.UUID             `db:"id" json:"id"`
+	WebhookID      uuid.UUID             `db:"webhook_id" json:"webhook_id"`
+	Event          contextkey.EventType  `db:"event" json:"event"`
+	Payload        json.RawMessage       `db:"payload" json:"payload" swaggertype:"object"`
+	Status         WebhookDeliveryStatus `db:"status" json:"status"`
+	Attempts       int                   `db:"attempts" json:"attempts"`
+	NextAttemptAt  time.Time             `db:"next_attempt_at" json:"next_attempt_at"`
+	LastStatusCode int                   `db:"last_status_code" json:"last_status_code"`
+	LastError      string                `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt      time.Time             `db:"created_at" json:"created_at"`
+	DeliveredAt    *time.Time            `db:"delivered_at" json:"delivered_at,omitempty"`
+}
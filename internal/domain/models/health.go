@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UrlHealth struct {
+	UrlID               uuid.UUID  `db:"url_id" json:"url_id"`
+	StatusCode          int        `db:"status_code" json:"status_code"`
+	LatencyMs           int64      `db:"latency_ms" json:"latency_ms"`
+	Error               string     `db:"error" json:"error,omitempty"`
+	ConsecutiveFailures int        `db:"consecutive_failures" json:"consecutive_failures"`
+	Broken              bool       `db:"broken" json:"broken"`
+	FailingSince        *time.Time `db:"failing_since" json:"failing_since,omitempty"`
+	CheckedAt           time.Time  `db:"checked_at" json:"checked_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Identity is an external account, such as Google or GitHub, the user signs
+// in with.
+type Identity struct {
+	Provider  string    `db:"provider" json:"provider"`
+	UserID    uuid.UUID `db:"user_id" json:"-"`
+	Email     string    `db:"email" json:"email"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
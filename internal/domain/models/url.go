@@ -7,10 +7,49 @@ import (
 )
 
 type Url struct {
-	ID        uuid.UUID `db:"id"`
-	Alias     string    `db:"alias"`
-	Url       string    `db:"url"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
-	UserID    uuid.UUID `db:"user_id"`
+	ID        uuid.UUID   `db:"id"`
+	Alias     string      `db:"alias"`
+	Url       string      `db:"url"`
+	CreatedAt time.Time   `db:"created_at"`
+	UpdatedAt time.Time   `db:"updated_at"`
+	UserID    uuid.UUID   `db:"user_id"`
+	NotBefore *time.Time  `db:"not_before"`
+	ExpiresAt *time.Time  `db:"expires_at"`
+	Timezone  string      `db:"timezone"`
+	TimeRules []TimeRule  `db:"time_rules"`
+	FolderID  *uuid.UUID  `db:"folder_id"`
+	TagIDs    []uuid.UUID `db:"tag_ids"`
+	DomainID  *uuid.UUID  `db:"domain_id"`
+	// FallbackUrl replaces Url while the health checker marks it Broken
+	FallbackUrl *string `db:"fallback_url"`
+	Broken      bool    `db:"broken"`
+}
+
+// TimeRule sends visitors to Url while the local time in the link timezone
+// is inside [Start, End). Start and End use the "15:04" layout, a window with
+// End before Start wraps past midnight. Empty Days means every day.
+type TimeRule struct {
+	Days  []time.Weekday `json:"days,omitempty" swaggertype:"array,integer"`
+	Start string         `json:"start" validate:"required"`
+	End   string         `json:"end" validate:"required"`
+	Url   string         `json:"url" validate:"required,url"`
+}
+
+// UrlRouting is the part of a link that decides where visitors end up, it is
+// what url_revisions keep before and after every change.
+type UrlRouting struct {
+	Url         string     `json:"url"`
+	NotBefore   *time.Time `json:"not_before"`
+	Timezone    string     `json:"timezone"`
+	TimeRules   []TimeRule `json:"time_rules"`
+	FallbackUrl *string    `json:"fallback_url"`
+}
+
+type UrlRevision struct {
+	ID        uuid.UUID   `db:"id" json:"id"`
+	UrlID     uuid.UUID   `db:"url_id" json:"url_id"`
+	ChangedBy *uuid.UUID  `db:"changed_by" json:"changed_by"`
+	Before    *UrlRouting `db:"before" json:"before"`
+	After     UrlRouting  `db:"after" json:"after"`
+	CreatedAt time.Time   `db:"created_at" json:"created_at"`
 }
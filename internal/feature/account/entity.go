@@ -0,0 +1,27 @@
+package account
+
+import "github.com/google/uuid"
+
+type EmailRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6"`
+}
+
+type accountUser struct {
+	ID       uuid.UUID
+	Email    string
+	Verified bool
+}
+
+type consumedToken struct {
+	UserID uuid.UUID
+	Email  string
+}
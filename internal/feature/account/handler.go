@@ -0,0 +1,175 @@
+package account
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Handler struct {
+	service *Service
+	l       *slog.Logger
+}
+
+func NewHandler(service *Service, l *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		l:       l,
+	}
+}
+
+// @Summary  ResendVerificationHandler
+// @Tags auth
+// @Description Mail a new email verification link. The answer is the same for unknown emails
+// @Accept json
+// @Produce json
+// @Param input body EmailRequest true "email body"
+// @Success 200 {object}  api.Response
+// @Failure 400 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /auth/verify-email/resend [post]
+func (h *Handler) ResendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Account.Handler.ResendVerification"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	var req EmailRequest
+	if !decode(w, r, log, &req) {
+		return
+	}
+	if err := h.service.ResendVerification(r.Context(), req.Email); err != nil {
+		log.Error("failed to send verification", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrSendVerificationEmail))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, api.OK())
+}
+
+// @Summary  VerifyEmailHandler
+// @Tags auth
+// @Description Confirm the email address with the token from the verification link
+// @Accept json
+// @Produce json
+// @Param input body VerifyEmailRequest true "token body"
+// @Success 200 {object}  api.Response
+// @Failure 400 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /auth/verify-email [post]
+func (h *Handler) VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Account.Handler.VerifyEmail"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	var req VerifyEmailRequest
+	if !decode(w, r, log, &req) {
+		return
+	}
+	err := h.service.VerifyEmail(r.Context(), req.Token)
+	if errors.Is(err, utils.ErrorInvalidToken) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidToken))
+		return
+	}
+	if err != nil {
+		log.Error("failed to verify email", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrVerifyEmail))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, api.OK())
+}
+
+// @Summary  ForgotPasswordHandler
+// @Tags auth
+// @Description Mail a password reset link. The answer is the same for unknown emails
+// @Accept json
+// @Produce json
+// @Param input body EmailRequest true "email body"
+// @Success 200 {object}  api.Response
+// @Failure 400 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /auth/password/forgot [post]
+func (h *Handler) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Account.Handler.ForgotPassword"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	var req EmailRequest
+	if !decode(w, r, log, &req) {
+		return
+	}
+	if err := h.service.RequestPasswordReset(r.Context(), req.Email); err != nil {
+		log.Error("failed to request password reset", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrSendPasswordResetEmail))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, api.OK())
+}
+
+// @Summary  ResetPasswordHandler
+// @Tags auth
+// @Description Set a new password with the token from the reset link, every session of the user is revoked
+// @Accept json
+// @Produce json
+// @Param input body ResetPasswordRequest true "reset body"
+// @Success 200 {object}  api.Response
+// @Failure 400 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /auth/password/reset [post]
+func (h *Handler) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Account.Handler.ResetPassword"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	var req ResetPasswordRequest
+	if !decode(w, r, log, &req) {
+		return
+	}
+	err := h.service.ResetPassword(r.Context(), req.Token, req.Password)
+	if errors.Is(err, utils.ErrorInvalidToken) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidToken))
+		return
+	}
+	if err != nil {
+		log.Error("failed to reset password", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrResetPassword))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, api.OK())
+}
+
+func decode(w http.ResponseWriter, r *http.Request, log *slog.Logger, req any) bool {
+	if err := render.DecodeJSON(r.Body, req); err != nil {
+		log.Error("failed to decode request body", slog.Any("err", err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidBody))
+		return false
+	}
+	if err := validator.New().Struct(req); err != nil {
+		log.Error("invalid request", logger.Err(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidRequest))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,206 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository struct {
+	primaryDB *pgxpool.Pool
+	l         *slog.Logger
+}
+
+func NewRepository(primaryDB *pgxpool.Pool, l *slog.Logger) *Repository {
+	return &Repository{
+		primaryDB: primaryDB,
+		l:         l,
+	}
+}
+
+func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*accountUser, error) {
+	const op = "Account.Repository.GetUserByEmail"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select("id, email, email_verified_at IS NOT NULL").
+		From("users").
+		Where(sq.Eq{"email": email}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	var u accountUser
+	if err := r.primaryDB.QueryRow(ctx, query, args...).Scan(&u.ID, &u.Email, &u.Verified); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.ErrorUserNotFound
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return &u, nil
+}
+
+// InvalidateTokens marks the unused tokens of the user for purpose as used so
+// only the link sent last works.
+func (r *Repository) InvalidateTokens(ctx context.Context, userId uuid.UUID, purpose string, tx pgx.Tx) error {
+	const op = "Account.Repository.InvalidateTokens"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Update("account_tokens").
+		Set("used_at", sq.Expr("now()")).
+		Where(sq.Eq{"user_id": userId, "purpose": purpose, "used_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) CreateToken(ctx context.Context, userId uuid.UUID, purpose, email, hash string, expiresAt time.Time, tx pgx.Tx) error {
+	const op = "Account.Repository.CreateToken"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Insert("account_tokens").
+		Columns("hash", "user_id", "purpose", "email", "expires_at").
+		Values(hash, userId, purpose, email, expiresAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+// ConsumeToken marks the token as used and returns whom it was issued for.
+// Unknown, expired and already used tokens are all ErrorInvalidToken.
+func (r *Repository) ConsumeToken(ctx context.Context, hash, purpose string, tx pgx.Tx) (*consumedToken, error) {
+	const op = "Account.Repository.ConsumeToken"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Update("account_tokens").
+		Set("used_at", sq.Expr("now()")).
+		Where(sq.And{
+			sq.Eq{"hash": hash, "purpose": purpose, "used_at": nil},
+			sq.Expr("expires_at > now()"),
+		}).
+		Suffix("RETURNING user_id, email").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	var t consumedToken
+	if err := tx.QueryRow(ctx, query, args...).Scan(&t.UserID, &t.Email); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.ErrorInvalidToken
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return &t, nil
+}
+
+// SetEmailVerified confirms email for the user, nothing changes when the
+// user switched to another address after the link was sent.
+func (r *Repository) SetEmailVerified(ctx context.Context, userId uuid.UUID, email string, tx pgx.Tx) error {
+	const op = "Account.Repository.SetEmailVerified"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Update("users").
+		Set("email_verified_at", sq.Expr("COALESCE(email_verified_at, now())")).
+		Where(sq.Eq{"id": userId, "email": email}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) SetPassword(ctx context.Context, userId uuid.UUID, password []byte, tx pgx.Tx) error {
+	const op = "Account.Repository.SetPassword"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Update("users").
+		Set("password", password).
+		Set("version", sq.Expr("version + 1")).
+		Where(sq.Eq{"id": userId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	tag, err := tx.Exec(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return utils.ErrorUserNotFound
+	}
+	return nil
+}
+
+// RevokeSessions revokes every active session of the user in tx and returns
+// their ids.
+func (r *Repository) RevokeSessions(ctx context.Context, userId uuid.UUID, tx pgx.Tx) ([]uuid.UUID, error) {
+	const op = "Account.Repository.RevokeSessions"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Update("sessions").
+		Set("revoked_at", sq.Expr("now()")).
+		Where(sq.Eq{"user_id": userId, "revoked_at": nil}).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,269 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/config"
+	"github.com/Sanchir01/go-shortener/internal/feature/user"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/mailer"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const sendTimeout = 30 * time.Second
+
+type AccountService interface {
+	GetUserByEmail(ctx context.Context, email string) (*accountUser, error)
+	InvalidateTokens(ctx context.Context, userId uuid.UUID, purpose string, tx pgx.Tx) error
+	CreateToken(ctx context.Context, userId uuid.UUID, purpose, email, hash string, expiresAt time.Time, tx pgx.Tx) error
+	ConsumeToken(ctx context.Context, hash, purpose string, tx pgx.Tx) (*consumedToken, error)
+	SetEmailVerified(ctx context.Context, userId uuid.UUID, email string, tx pgx.Tx) error
+	SetPassword(ctx context.Context, userId uuid.UUID, password []byte, tx pgx.Tx) error
+	RevokeSessions(ctx context.Context, userId uuid.UUID, tx pgx.Tx) ([]uuid.UUID, error)
+}
+
+// SessionRevoker cuts off the access tokens of the sessions a password reset
+// revoked.
+type SessionRevoker interface {
+	Deny(ctx context.Context, ids ...uuid.UUID) error
+}
+
+type Service struct {
+	repo      AccountService
+	primaryDB *pgxpool.Pool
+	mailer    mailer.Mailer
+	sessions  SessionRevoker
+	cfg       config.Accounts
+	l         *slog.Logger
+}
+
+func NewService(repo AccountService, primaryDB *pgxpool.Pool, m mailer.Mailer, sessions SessionRevoker, cfg config.Accounts, l *slog.Logger) *Service {
+	return &Service{
+		repo:      repo,
+		primaryDB: primaryDB,
+		mailer:    m,
+		sessions:  sessions,
+		cfg:       cfg,
+		l:         l,
+	}
+}
+
+// SendVerification mails a new verification link, links sent before stop
+// working.
+func (s *Service) SendVerification(ctx context.Context, userId uuid.UUID, email string) error {
+	token, err := s.issue(ctx, userId, purposeVerifyEmail, email, s.cfg.VerifyTTL)
+	if err != nil {
+		return err
+	}
+	s.send(mailer.Message{
+		To:      email,
+		Subject: "Confirm your email",
+		Text: fmt.Sprintf(
+			"Open the link below to confirm your email address:\n\n%s\n\nThe link is valid for %s. If you did not sign up, ignore this message.\n",
+			s.link("verify-email", token), s.cfg.VerifyTTL,
+		),
+	})
+	return nil
+}
+
+// ResendVerification answers the same way whether the email is known or not
+// so it can't be used to find accounts.
+func (s *Service) ResendVerification(ctx context.Context, email string) error {
+	u, err := s.repo.GetUserByEmail(ctx, email)
+	if errors.Is(err, utils.ErrorUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if u.Verified {
+		return nil
+	}
+	return s.SendVerification(ctx, u.ID, u.Email)
+}
+
+func (s *Service) VerifyEmail(ctx context.Context, token string) error {
+	const op = "Account.Service.VerifyEmail"
+	log := s.l.With(slog.String("op", op))
+
+	conn, err := s.primaryDB.Acquire(ctx)
+	if err != nil {
+		log.Error("error init acquire", logger.Err(err))
+		return err
+	}
+	defer conn.Release()
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		log.Error("error init tx", logger.Err(err))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Error("rollback error", logger.Err(rollbackErr))
+			}
+		}
+	}()
+
+	t, err := s.repo.ConsumeToken(ctx, hashToken(token), purposeVerifyEmail, tx)
+	if err != nil {
+		return err
+	}
+	if err = s.repo.SetEmailVerified(ctx, t.UserID, t.Email, tx); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Error("commit error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+// RequestPasswordReset mails a reset link. Unknown emails are ignored
+// silently, like in ResendVerification.
+func (s *Service) RequestPasswordReset(ctx context.Context, email string) error {
+	u, err := s.repo.GetUserByEmail(ctx, email)
+	if errors.Is(err, utils.ErrorUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	token, err := s.issue(ctx, u.ID, purposeResetPassword, u.Email, s.cfg.ResetTTL)
+	if err != nil {
+		return err
+	}
+	s.send(mailer.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Text: fmt.Sprintf(
+			"Open the link below to choose a new password:\n\n%s\n\nThe link is valid for %s and works once. If you did not ask for it, ignore this message.\n",
+			s.link("reset-password", token), s.cfg.ResetTTL,
+		),
+	})
+	return nil
+}
+
+// ResetPassword sets a new password and revokes every session of the user
+// in the transaction that consumes the token, so a used link never leaves
+// sessions behind. Following the link proves access to the inbox, so the
+// email counts as verified too.
+func (s *Service) ResetPassword(ctx context.Context, token, password string) error {
+	const op = "Account.Service.ResetPassword"
+	log := s.l.With(slog.String("op", op))
+
+	hash, err := user.GeneratePasswordHash(password)
+	if err != nil {
+		log.Error("error generating password hash", logger.Err(err))
+		return err
+	}
+	conn, err := s.primaryDB.Acquire(ctx)
+	if err != nil {
+		log.Error("error init acquire", logger.Err(err))
+		return err
+	}
+	defer conn.Release()
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		log.Error("error init tx", logger.Err(err))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Error("rollback error", logger.Err(rollbackErr))
+			}
+		}
+	}()
+
+	t, err := s.repo.ConsumeToken(ctx, hashToken(token), purposeResetPassword, tx)
+	if err != nil {
+		return err
+	}
+	if err = s.repo.SetPassword(ctx, t.UserID, hash, tx); err != nil {
+		return err
+	}
+	if err = s.repo.SetEmailVerified(ctx, t.UserID, t.Email, tx); err != nil {
+		return err
+	}
+	revoked, err := s.repo.RevokeSessions(ctx, t.UserID, tx)
+	if err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Error("commit error", logger.Err(err))
+		return err
+	}
+	// the sessions can not be refreshed anymore, a failed deny only lets
+	// their access tokens live until they expire
+	if err := s.sessions.Deny(ctx, revoked...); err != nil {
+		log.Error("deny sessions error", logger.Err(err))
+	}
+	return nil
+}
+
+func (s *Service) issue(ctx context.Context, userId uuid.UUID, purpose, email string, ttl time.Duration) (string, error) {
+	const op = "Account.Service.issue"
+	log := s.l.With(slog.String("op", op))
+
+	token, hash, err := newToken()
+	if err != nil {
+		log.Error("generate token error", logger.Err(err))
+		return "", err
+	}
+	conn, err := s.primaryDB.Acquire(ctx)
+	if err != nil {
+		log.Error("error init acquire", logger.Err(err))
+		return "", err
+	}
+	defer conn.Release()
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		log.Error("error init tx", logger.Err(err))
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Error("rollback error", logger.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.repo.InvalidateTokens(ctx, userId, purpose, tx); err != nil {
+		return "", err
+	}
+	if err = s.repo.CreateToken(ctx, userId, purpose, email, hash, time.Now().Add(ttl), tx); err != nil {
+		return "", err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Error("commit error", logger.Err(err))
+		return "", err
+	}
+	return token, nil
+}
+
+// send delivers mail in the background so a slow SMTP server does not hold
+// the request.
+func (s *Service) send(msg mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		defer cancel()
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			s.l.Error("send mail error", slog.String("subject", msg.Subject), logger.Err(err))
+		}
+	}()
+}
+
+func (s *Service) link(path, token string) string {
+	return strings.TrimRight(s.cfg.AppURL, "/") + "/" + path + "?token=" + url.QueryEscape(token)
+}
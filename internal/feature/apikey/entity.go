@@ -0,0 +1,38 @@
+package apikey
+
+import (
+	"time"
+
+	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/google/uuid"
+)
+
+type CreateApiKeyRequest struct {
+	Name      string             `json:"name" validate:"required,min=1,max=64"`
+	Scopes    []contextkey.Scope `json:"scopes" validate:"required,min=1,dive,oneof=links:read links:write stats:read"`
+	ExpiresAt *time.Time         `json:"expires_at,omitempty"`
+}
+
+// CreateApiKeyResponse is the only place the key itself is shown.
+type CreateApiKeyResponse struct {
+	api.Response
+	ApiKey models.ApiKey `json:"api_key"`
+	Key    string        `json:"key"`
+}
+
+type GetAllApiKeysResponse struct {
+	api.Response
+	ApiKeys []models.ApiKey `json:"api_keys"`
+}
+
+// KeyOwner is a stored key joined with the role of its owner.
+type KeyOwner struct {
+	UserID    uuid.UUID
+	Role      string
+	Hash      string
+	Scopes    []contextkey.Scope
+	ExpiresAt *time.Time
+	RevokedAt *time.Time
+}
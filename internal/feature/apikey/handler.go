@@ -0,0 +1,159 @@
+package apikey
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sanchir01/go-shortener/internal/handlers/customiddleware"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	service *Service
+	l       *slog.Logger
+}
+
+func NewHandler(service *Service, l *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		l:       l,
+	}
+}
+
+// @Summary  CreateApiKeyHandler
+// @Tags apikey
+// @Description Create a personal api key, the key is returned only once. Send it as "Authorization: Bearer <key>"
+// @Accept json
+// @Produce json
+// @Param input body CreateApiKeyRequest true "api key body"
+// @Success 201 {object}  CreateApiKeyResponse
+// @Failure 400,401,403 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /apikey [post]
+func (h *Handler) CreateApiKeyHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "ApiKey.Handler.CreateApiKey"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	var req CreateApiKeyRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		log.Error("failed to decode request body", slog.Any("err", err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidBody))
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		log.Error("invalid request", logger.Err(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidRequest))
+		return
+	}
+	apiKey, key, err := h.service.CreateApiKey(r.Context(), claims.ID, req)
+	if errors.Is(err, utils.ErrorInvalidExpiresAt) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	}
+	if err != nil {
+		log.Error("failed to create api key", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrCreateApiKey))
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, CreateApiKeyResponse{
+		Response: api.OK(),
+		ApiKey:   *apiKey,
+		Key:      key,
+	})
+}
+
+// @Summary  GetApiKeysHandler
+// @Tags apikey
+// @Description Get api keys of the user, revoked ones included
+// @Produce json
+// @Success 200 {object}  GetAllApiKeysResponse
+// @Failure 401,403 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /apikey [get]
+func (h *Handler) GetApiKeysHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "ApiKey.Handler.GetApiKeys"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	keys, err := h.service.GetApiKeys(r.Context(), claims.ID)
+	if err != nil {
+		log.Error("failed to get api keys", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrGetApiKeys))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, GetAllApiKeysResponse{
+		Response: api.OK(),
+		ApiKeys:  keys,
+	})
+}
+
+// @Summary  RevokeApiKeyHandler
+// @Tags apikey
+// @Description Revoke api key, requests with it are rejected right away
+// @Produce json
+// @Param id path string true "api key id"
+// @Success 200 {object}  api.Response
+// @Failure 400,401,403,404 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /apikey/{id} [delete]
+func (h *Handler) RevokeApiKeyHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "ApiKey.Handler.RevokeApiKey"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidApiKeyID))
+		return
+	}
+	err = h.service.RevokeApiKey(r.Context(), id, claims.ID)
+	if errors.Is(err, utils.ErrorApiKeyNotFound) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	}
+	if err != nil {
+		log.Error("failed to revoke api key", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrRevokeApiKey))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, api.OK())
+}
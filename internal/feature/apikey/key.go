@@ -0,0 +1,54 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+// KeyPrefix marks our keys, so they are easy to tell from JWTs and to find
+// by secret scanners.
+const KeyPrefix = "gsk_"
+
+// GenerateKey returns a new key "gsk_<id>_<secret>" and its lookup prefix
+// "gsk_<id>".
+func GenerateKey() (key, prefix string, err error) {
+	id := make([]byte, 4)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	prefix = KeyPrefix + hex.EncodeToString(id)
+	return prefix + "_" + hex.EncodeToString(secret), prefix, nil
+}
+
+// IsKey reports whether token looks like one of our api keys.
+func IsKey(token string) bool {
+	return strings.HasPrefix(token, KeyPrefix)
+}
+
+// SplitKey returns the lookup prefix of key.
+func SplitKey(key string) (string, bool) {
+	if !IsKey(key) {
+		return "", false
+	}
+	i := strings.LastIndexByte(key, '_')
+	if i <= len(KeyPrefix) || i == len(key)-1 {
+		return "", false
+	}
+	return key[:i], true
+}
+
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func matches(key, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashKey(key)), []byte(hash)) == 1
+}
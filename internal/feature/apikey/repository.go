@@ -0,0 +1,202 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const apiKeyColumns = "id, user_id, name, prefix, hash, scopes, expires_at, last_used_at, revoked_at, created_at"
+
+type Repository struct {
+	primaryDB *pgxpool.Pool
+	l         *slog.Logger
+}
+
+func NewRepository(primaryDB *pgxpool.Pool, l *slog.Logger) *Repository {
+	return &Repository{
+		primaryDB: primaryDB,
+		l:         l,
+	}
+}
+
+func scanApiKey(row pgx.Row) (*models.ApiKey, error) {
+	var k models.ApiKey
+	var scopes []string
+	if err := row.Scan(
+		&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.Hash, &scopes,
+		&k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt, &k.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	k.Scopes = toScopes(scopes)
+	return &k, nil
+}
+
+func toScopes(values []string) []contextkey.Scope {
+	scopes := make([]contextkey.Scope, 0, len(values))
+	for _, v := range values {
+		scopes = append(scopes, contextkey.Scope(v))
+	}
+	return scopes
+}
+
+func scopeStrings(scopes []contextkey.Scope) []string {
+	out := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		out = append(out, string(s))
+	}
+	return out
+}
+
+func (r *Repository) CreateApiKey(ctx context.Context, userId uuid.UUID, name, prefix, hash string, scopes []contextkey.Scope, expiresAt *time.Time) (*models.ApiKey, error) {
+	const op = "ApiKey.Repository.CreateApiKey"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Insert("api_keys").
+		Columns("user_id", "name", "prefix", "hash", "scopes", "expires_at").
+		Values(userId, name, prefix, hash, scopeStrings(scopes), expiresAt).
+		Suffix("RETURNING " + apiKeyColumns).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+
+	key, err := scanApiKey(r.primaryDB.QueryRow(ctx, query, args...))
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return key, nil
+}
+
+func (r *Repository) GetApiKeysByUserId(ctx context.Context, userId uuid.UUID) ([]models.ApiKey, error) {
+	const op = "ApiKey.Repository.GetApiKeysByUserId"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select(apiKeyColumns).
+		From("api_keys").
+		Where(sq.Eq{"user_id": userId}).
+		OrderBy("created_at DESC").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+
+	rows, err := r.primaryDB.Query(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]models.ApiKey, 0, 8)
+	for rows.Next() {
+		key, err := scanApiKey(rows)
+		if err != nil {
+			log.Error("error", logger.Err(err))
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *Repository) RevokeApiKey(ctx context.Context, id, userId uuid.UUID) error {
+	const op = "ApiKey.Repository.RevokeApiKey"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Update("api_keys").
+		Set("revoked_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id, "user_id": userId, "revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	tag, err := r.primaryDB.Exec(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return utils.ErrorApiKeyNotFound
+	}
+	return nil
+}
+
+func (r *Repository) GetKeyOwner(ctx context.Context, prefix string) (*KeyOwner, error) {
+	const op = "ApiKey.Repository.GetKeyOwner"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select("k.user_id, u.role::text, k.hash, k.scopes, k.expires_at, k.revoked_at").
+		From("api_keys k").
+		Join("users u ON u.id = k.user_id").
+		Where(sq.Eq{"k.prefix": prefix}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+
+	var owner KeyOwner
+	var scopes []string
+	if err := r.primaryDB.QueryRow(ctx, query, args...).Scan(
+		&owner.UserID, &owner.Role, &owner.Hash, &scopes, &owner.ExpiresAt, &owner.RevokedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.ErrorApiKeyNotFound
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	owner.Scopes = toScopes(scopes)
+	return &owner, nil
+}
+
+// TouchApiKey records the use of the key, at most once a minute.
+func (r *Repository) TouchApiKey(ctx context.Context, prefix string) error {
+	const op = "ApiKey.Repository.TouchApiKey"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Update("api_keys").
+		Set("last_used_at", sq.Expr("now()")).
+		Where(sq.Eq{"prefix": prefix}).
+		Where("(last_used_at IS NULL OR last_used_at < now() - interval '1 minute')").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := r.primaryDB.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package apikey
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/internal/feature/user"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+)
+
+type ApiKeyService interface {
+	CreateApiKey(ctx context.Context, userId uuid.UUID, name, prefix, hash string, scopes []contextkey.Scope, expiresAt *time.Time) (*models.ApiKey, error)
+	GetApiKeysByUserId(ctx context.Context, userId uuid.UUID) ([]models.ApiKey, error)
+	RevokeApiKey(ctx context.Context, id, userId uuid.UUID) error
+	GetKeyOwner(ctx context.Context, prefix string) (*KeyOwner, error)
+	TouchApiKey(ctx context.Context, prefix string) error
+}
+
+type Service struct {
+	repo ApiKeyService
+	l    *slog.Logger
+}
+
+func NewService(repo ApiKeyService, l *slog.Logger) *Service {
+	return &Service{
+		repo: repo,
+		l:    l,
+	}
+}
+
+// CreateApiKey stores a new key and returns it in plain text, it can not be
+// recovered later. A key that would be born expired is refused.
+func (s *Service) CreateApiKey(ctx context.Context, userId uuid.UUID, req CreateApiKeyRequest) (*models.ApiKey, string, error) {
+	const op = "ApiKey.Service.CreateApiKey"
+	log := s.l.With(slog.String("op", op))
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, "", utils.ErrorInvalidExpiresAt
+	}
+	key, prefix, err := GenerateKey()
+	if err != nil {
+		log.Error("generate key error", logger.Err(err))
+		return nil, "", err
+	}
+	apiKey, err := s.repo.CreateApiKey(ctx, userId, strings.TrimSpace(req.Name), prefix, HashKey(key), req.Scopes, req.ExpiresAt)
+	if err != nil {
+		log.Error("create api key error", logger.Err(err))
+		return nil, "", err
+	}
+	return apiKey, key, nil
+}
+
+func (s *Service) GetApiKeys(ctx context.Context, userId uuid.UUID) ([]models.ApiKey, error) {
+	const op = "ApiKey.Service.GetApiKeys"
+	log := s.l.With(slog.String("op", op))
+
+	keys, err := s.repo.GetApiKeysByUserId(ctx, userId)
+	if err != nil {
+		log.Error("get api keys error", logger.Err(err))
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (s *Service) RevokeApiKey(ctx context.Context, id, userId uuid.UUID) error {
+	const op = "ApiKey.Service.RevokeApiKey"
+	log := s.l.With(slog.String("op", op))
+
+	if err := s.repo.RevokeApiKey(ctx, id, userId); err != nil {
+		log.Error("revoke api key error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+// AuthenticateKey turns a bearer api key into claims limited to the scopes
+// of the key.
+func (s *Service) AuthenticateKey(ctx context.Context, key string) (*user.Claims, error) {
+	const op = "ApiKey.Service.AuthenticateKey"
+	log := s.l.With(slog.String("op", op))
+
+	prefix, ok := SplitKey(key)
+	if !ok {
+		return nil, utils.ErrorInvalidApiKey
+	}
+	owner, err := s.repo.GetKeyOwner(ctx, prefix)
+	if err != nil {
+		return nil, utils.ErrorInvalidApiKey
+	}
+	if !matches(key, owner.Hash) || owner.RevokedAt != nil {
+		return nil, utils.ErrorInvalidApiKey
+	}
+	if owner.ExpiresAt != nil && time.Now().After(*owner.ExpiresAt) {
+		return nil, utils.ErrorInvalidApiKey
+	}
+	if err := s.repo.TouchApiKey(ctx, prefix); err != nil {
+		log.Warn("touch api key error", logger.Err(err))
+	}
+	scopes := owner.Scopes
+	if scopes == nil {
+		scopes = []contextkey.Scope{}
+	}
+	return &user.Claims{
+		ID:     owner.UserID,
+		Role:   owner.Role,
+		Scopes: scopes,
+	}, nil
+}
+
+func (s *Service) IsKey(token string) bool {
+	return IsKey(token)
+}
@@ -0,0 +1,236 @@
+package clickstream
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/Sanchir01/go-shortener/internal/config"
+	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Message is a click together with the id of its entry in the history stream,
+// which doubles as the SSE event id.
+type Message struct {
+	ID    string           `json:"id"`
+	Event models.LinkEvent `json:"event"`
+}
+
+// Broker fans clicks out to every instance through Redis. Each owner has a
+// pub/sub channel for live clicks and a capped stream with recent history so
+// clients can catch up after a reconnect.
+type Broker struct {
+	rdb   *redis.Client
+	queue chan models.LinkEvent
+	cfg   config.Clicks
+	l     *slog.Logger
+}
+
+func NewBroker(rdb *redis.Client, cfg config.Clicks, l *slog.Logger) *Broker {
+	return &Broker{
+		rdb:   rdb,
+		queue: make(chan models.LinkEvent, cfg.QueueSize),
+		cfg:   cfg,
+		l:     l,
+	}
+}
+
+func historyKey(userId uuid.UUID) string {
+	return "clicks:history:" + userId.String()
+}
+
+func channelKey(userId uuid.UUID) string {
+	return "clicks:live:" + userId.String()
+}
+
+// Publish queues clicks and deletions, which drop the counters of the link,
+// and ignores the rest. It never blocks, when Redis falls behind the event is
+// dropped.
+func (b *Broker) Publish(_ context.Context, e models.LinkEvent) {
+	if e.Type != contextkey.EventLinkClicked && e.Type != contextkey.EventLinkDeleted {
+		return
+	}
+	select {
+	case b.queue <- e:
+	default:
+		b.l.Warn("click stream queue is full, event dropped", slog.String("url_id", e.UrlID.String()))
+	}
+}
+
+// Run writes queued clicks to Redis until ctx is done.
+func (b *Broker) Run(ctx context.Context) {
+	const op = "ClickStream.Broker.Run"
+	log := b.l.With(slog.String("op", op))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-b.queue:
+			if err := b.send(ctx, e); err != nil {
+				log.Error("publish click error", logger.Err(err))
+			}
+		}
+	}
+}
+
+func (b *Broker) send(ctx context.Context, e models.LinkEvent) error {
+	if e.Type == contextkey.EventLinkDeleted {
+		return b.forget(ctx, e.UrlID)
+	}
+	if err := b.count(ctx, e); err != nil {
+		return fmt.Errorf("count: %w", err)
+	}
+	event, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	key := historyKey(e.UserID)
+	id, err := b.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		MaxLen: b.cfg.HistorySize,
+		Approx: true,
+		Values: map[string]any{"event": event},
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("xadd: %w", err)
+	}
+	if err := b.rdb.Expire(ctx, key, b.cfg.HistoryTTL).Err(); err != nil {
+		return fmt.Errorf("expire: %w", err)
+	}
+	msg, err := json.Marshal(Message{ID: id, Event: e})
+	if err != nil {
+		return err
+	}
+	return b.rdb.Publish(ctx, channelKey(e.UserID), msg).Err()
+}
+
+// Subscribe streams clicks on the links of userId. When lastEventID is set
+// the clicks recorded after it are replayed first. The channel is closed
+// when ctx is done.
+func (b *Broker) Subscribe(ctx context.Context, userId uuid.UUID, lastEventID string) (<-chan Message, error) {
+	const op = "ClickStream.Broker.Subscribe"
+	log := b.l.With(slog.String("op", op))
+
+	// subscribe before reading the history so nothing falls in between
+	pubsub := b.rdb.Subscribe(ctx, channelKey(userId))
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		log.Error("subscribe error", logger.Err(err))
+		return nil, err
+	}
+
+	var history []Message
+	if lastEventID != "" {
+		var err error
+		history, err = b.history(ctx, userId, lastEventID)
+		if err != nil {
+			pubsub.Close()
+			log.Error("read history error", logger.Err(err))
+			return nil, err
+		}
+	}
+
+	out := make(chan Message)
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+
+		last := lastEventID
+		for _, msg := range history {
+			select {
+			case out <- msg:
+				last = msg.ID
+			case <-ctx.Done():
+				return
+			}
+		}
+		live := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-live:
+				if !ok {
+					return
+				}
+				var msg Message
+				if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+					log.Error("decode click error", logger.Err(err))
+					continue
+				}
+				if last != "" && CompareIDs(msg.ID, last) <= 0 {
+					continue
+				}
+				select {
+				case out <- msg:
+					last = msg.ID
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (b *Broker) history(ctx context.Context, userId uuid.UUID, lastEventID string) ([]Message, error) {
+	if _, _, err := parseID(lastEventID); err != nil {
+		return nil, nil
+	}
+	entries, err := b.rdb.XRangeN(ctx, historyKey(userId), "("+lastEventID, "+", b.cfg.HistorySize).Result()
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]Message, 0, len(entries))
+	for _, entry := range entries {
+		raw, ok := entry.Values["event"].(string)
+		if !ok {
+			continue
+		}
+		var e models.LinkEvent
+		if err := json.Unmarshal([]byte(raw), &e); err != nil {
+			continue
+		}
+		messages = append(messages, Message{ID: entry.ID, Event: e})
+	}
+	return messages, nil
+}
+
+// CompareIDs orders two Redis stream ids ("<ms>-<seq>").
+func CompareIDs(a, b string) int {
+	ams, aseq, aerr := parseID(a)
+	bms, bseq, berr := parseID(b)
+	if aerr != nil || berr != nil {
+		return strings.Compare(a, b)
+	}
+	if ams != bms {
+		return cmp.Compare(ams, bms)
+	}
+	return cmp.Compare(aseq, bseq)
+}
+
+func parseID(id string) (uint64, uint64, error) {
+	msPart, seqPart, found := strings.Cut(id, "-")
+	if !found {
+		return 0, 0, errors.New("invalid stream id")
+	}
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return ms, seq, nil
+}
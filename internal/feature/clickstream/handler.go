@@ -0,0 +1,138 @@
+package clickstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/config"
+	"github.com/Sanchir01/go-shortener/internal/handlers/customiddleware"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	broker *Broker
+	cfg    config.Clicks
+	l      *slog.Logger
+}
+
+func NewHandler(broker *Broker, cfg config.Clicks, l *slog.Logger) *Handler {
+	return &Handler{
+		broker: broker,
+		cfg:    cfg,
+		l:      l,
+	}
+}
+
+// @Summary  StreamClicksHandler
+// @Tags url
+// @Description Server-Sent Events stream of clicks on the links of the user, send Last-Event-ID to resume after a reconnect
+// @Produce text/event-stream
+// @Param Last-Event-ID header string false "id of the last received event"
+// @Success 200 {object}  Message
+// @Failure 401 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /url/clicks [get]
+func (h *Handler) StreamClicksHandler(w http.ResponseWriter, r *http.Request) {
+	h.stream(w, r, uuid.Nil)
+}
+
+// @Summary  StreamUrlClicksHandler
+// @Tags url
+// @Description Server-Sent Events stream of clicks on one link, send Last-Event-ID to resume after a reconnect
+// @Produce text/event-stream
+// @Param id path string true "url id"
+// @Param Last-Event-ID header string false "id of the last received event"
+// @Success 200 {object}  Message
+// @Failure 400,401 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /url/{id}/clicks [get]
+func (h *Handler) StreamUrlClicksHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidUrlID))
+		return
+	}
+	h.stream(w, r, id)
+}
+
+func (h *Handler) stream(w http.ResponseWriter, r *http.Request, urlId uuid.UUID) {
+	const op = "ClickStream.Handler.Stream"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	rc := http.NewResponseController(w)
+	// the stream outlives the server write timeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Warn("failed to reset write deadline", logger.Err(err))
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	messages, err := h.broker.Subscribe(r.Context(), claims.ID, lastEventID)
+	if err != nil {
+		log.Error("failed to subscribe", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrSubscribe))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", h.cfg.Retry.Milliseconds())
+	if err := rc.Flush(); err != nil {
+		log.Error("streaming is not supported", logger.Err(err))
+		return
+	}
+
+	heartbeat := time.NewTicker(h.cfg.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			if urlId != uuid.Nil && msg.Event.UrlID != urlId {
+				continue
+			}
+			data, err := json.Marshal(msg.Event)
+			if err != nil {
+				log.Error("failed to encode click", logger.Err(err))
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: click\ndata: %s\n\n", msg.ID, data); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
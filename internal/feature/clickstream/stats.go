@@ -0,0 +1,86 @@
+package clickstream
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// directReferrer counts clicks that came without a Referer header.
+const directReferrer = "direct"
+
+// LiveCounts are approximate counters of one link kept next to the live
+// stream. They are not click totals: events are dropped when the broker
+// queue is full or Redis is down, nothing replays them and the counters live
+// only as long as the Redis data does.
+type LiveCounts struct {
+	Clicks    int64           `json:"clicks"`
+	Referrers []ReferrerCount `json:"referrers"`
+}
+
+type ReferrerCount struct {
+	Host   string `json:"host"`
+	Clicks int64  `json:"clicks"`
+}
+
+func totalKey(urlId uuid.UUID) string {
+	return "clicks:total:" + urlId.String()
+}
+
+func referrersKey(urlId uuid.UUID) string {
+	return "clicks:referrers:" + urlId.String()
+}
+
+// count adds the click to the counter of the link and to its referrer host.
+func (b *Broker) count(ctx context.Context, e models.LinkEvent) error {
+	referer, _ := e.Data["referer"].(string)
+	_, err := b.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, totalKey(e.UrlID))
+		pipe.ZIncrBy(ctx, referrersKey(e.UrlID), 1, referrerHost(referer))
+		return nil
+	})
+	return err
+}
+
+func (b *Broker) forget(ctx context.Context, urlId uuid.UUID) error {
+	return b.rdb.Del(ctx, totalKey(urlId), referrersKey(urlId)).Err()
+}
+
+// LiveCounts returns the approximate click count of the link and its top
+// referrer hosts.
+func (b *Broker) LiveCounts(ctx context.Context, urlId uuid.UUID, top int64) (*LiveCounts, error) {
+	var (
+		total     *redis.StringCmd
+		referrers *redis.ZSliceCmd
+	)
+	_, err := b.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		total = pipe.Get(ctx, totalKey(urlId))
+		referrers = pipe.ZRevRangeWithScores(ctx, referrersKey(urlId), 0, top-1)
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	counts := &LiveCounts{Referrers: make([]ReferrerCount, 0, top)}
+	if counts.Clicks, err = total.Int64(); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	for _, z := range referrers.Val() {
+		host, _ := z.Member.(string)
+		counts.Referrers = append(counts.Referrers, ReferrerCount{Host: host, Clicks: int64(z.Score)})
+	}
+	return counts, nil
+}
+
+func referrerHost(referer string) string {
+	u, err := url.Parse(referer)
+	if err != nil || u.Hostname() == "" {
+		return directReferrer
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
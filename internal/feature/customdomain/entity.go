@@ -0,0 +1,22 @@
+package customdomain
+
+import (
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+)
+
+type CreateDomainRequest struct {
+	Host string `json:"host" validate:"required,fqdn"`
+}
+
+type DomainResponse struct {
+	api.Response
+	Domain models.Domain `json:"domain"`
+	// VerificationURL is where the token has to be served from
+	VerificationURL string `json:"verification_url"`
+}
+
+type GetAllDomainsResponse struct {
+	api.Response
+	Domains []models.Domain `json:"domains"`
+}
@@ -0,0 +1,221 @@
+package customdomain
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sanchir01/go-shortener/internal/handlers/customiddleware"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	service *Service
+	l       *slog.Logger
+}
+
+func NewHandler(service *Service, l *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		l:       l,
+	}
+}
+
+// @Summary  CreateDomainHandler
+// @Tags domain
+// @Description Register a branded domain, serve the returned token at verification_url to prove ownership
+// @Accept json
+// @Produce json
+// @Param input body CreateDomainRequest true "domain body"
+// @Success 201 {object}  DomainResponse
+// @Failure 400,409 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /domain [post]
+func (h *Handler) CreateDomainHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Domain.Handler.CreateDomain"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	var req CreateDomainRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		log.Error("failed to decode request body", slog.Any("err", err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidBody))
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		log.Error("invalid request", logger.Err(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidRequest))
+		return
+	}
+	d, err := h.service.CreateDomain(r.Context(), claims.ID, req.Host)
+	switch {
+	case errors.Is(err, utils.ErrorInvalidDomain):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	case errors.Is(err, utils.ErrorDomainExists):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	case err != nil:
+		log.Error("failed to create domain", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrCreateDomain))
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, DomainResponse{
+		Response:        api.OK(),
+		Domain:          *d,
+		VerificationURL: h.service.VerificationURL(d.Host),
+	})
+}
+
+// @Summary  GetDomainsHandler
+// @Tags domain
+// @Description Get all branded domains of the user
+// @Produce json
+// @Success 200 {object}  GetAllDomainsResponse
+// @Failure 401 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /domain [get]
+func (h *Handler) GetDomainsHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Domain.Handler.GetDomains"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	domains, err := h.service.GetDomains(r.Context(), claims.ID)
+	if err != nil {
+		log.Error("failed to get domains", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrGetDomains))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, GetAllDomainsResponse{
+		Response: api.OK(),
+		Domains:  domains,
+	})
+}
+
+// @Summary  VerifyDomainHandler
+// @Tags domain
+// @Description Check the verification token on the domain and mark it verified
+// @Produce json
+// @Param id path string true "domain id"
+// @Success 200 {object}  DomainResponse
+// @Failure 400,404,409,422 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /domain/{id}/verify [post]
+func (h *Handler) VerifyDomainHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Domain.Handler.VerifyDomain"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidDomainID))
+		return
+	}
+	d, err := h.service.VerifyDomain(r.Context(), id, claims.ID)
+	switch {
+	case errors.Is(err, utils.ErrorDomainNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	case errors.Is(err, utils.ErrorDomainNotVerified):
+		render.Status(r, http.StatusUnprocessableEntity)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	case errors.Is(err, utils.ErrorDomainExists):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	case err != nil:
+		log.Error("failed to verify domain", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrVerifyDomain))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, DomainResponse{
+		Response:        api.OK(),
+		Domain:          *d,
+		VerificationURL: h.service.VerificationURL(d.Host),
+	})
+}
+
+// @Summary  DeleteDomainHandler
+// @Tags domain
+// @Description Delete branded domain, links on it have to be deleted first
+// @Produce json
+// @Param id path string true "domain id"
+// @Success 200 {object}  api.Response
+// @Failure 400,404,409 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /domain/{id} [delete]
+func (h *Handler) DeleteDomainHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Domain.Handler.DeleteDomain"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidDomainID))
+		return
+	}
+	err = h.service.DeleteDomain(r.Context(), id, claims.ID)
+	switch {
+	case errors.Is(err, utils.ErrorDomainNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	case errors.Is(err, utils.ErrorDomainInUse):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	case err != nil:
+		log.Error("failed to delete domain", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrDeleteDomain))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, api.OK())
+}
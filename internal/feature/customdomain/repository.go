@@ -0,0 +1,185 @@
+package customdomain
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const domainColumns = "id, user_id, host, token, verified_at, created_at"
+
+type Repository struct {
+	primaryDB *pgxpool.Pool
+	l         *slog.Logger
+}
+
+func NewRepository(primaryDB *pgxpool.Pool, l *slog.Logger) *Repository {
+	return &Repository{
+		primaryDB: primaryDB,
+		l:         l,
+	}
+}
+
+func scanDomain(row pgx.Row) (*models.Domain, error) {
+	var d models.Domain
+	if err := row.Scan(&d.ID, &d.UserID, &d.Host, &d.Token, &d.VerifiedAt, &d.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *Repository) CreateDomain(ctx context.Context, userId uuid.UUID, host, token string) (*models.Domain, error) {
+	const op = "Domain.Repository.CreateDomain"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Insert("domains").
+		Columns("user_id", "host", "token").
+		Values(userId, host, token).
+		Suffix("RETURNING " + domainColumns).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+
+	d, err := scanDomain(r.primaryDB.QueryRow(ctx, query, args...))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, utils.ErrorDomainExists
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *Repository) GetDomainByID(ctx context.Context, id, userId uuid.UUID) (*models.Domain, error) {
+	const op = "Domain.Repository.GetDomainByID"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select(domainColumns).
+		From("domains").
+		Where(sq.Eq{"id": id, "user_id": userId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+
+	d, err := scanDomain(r.primaryDB.QueryRow(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.ErrorDomainNotFound
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *Repository) GetDomainsByUserId(ctx context.Context, userId uuid.UUID) ([]models.Domain, error) {
+	const op = "Domain.Repository.GetDomainsByUserId"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select(domainColumns).
+		From("domains").
+		Where(sq.Eq{"user_id": userId}).
+		OrderBy("host").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+
+	rows, err := r.primaryDB.Query(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	domains := make([]models.Domain, 0, 4)
+	for rows.Next() {
+		d, err := scanDomain(rows)
+		if err != nil {
+			log.Error("error", logger.Err(err))
+			return nil, err
+		}
+		domains = append(domains, *d)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return domains, nil
+}
+
+func (r *Repository) MarkVerified(ctx context.Context, id uuid.UUID, at time.Time) error {
+	const op = "Domain.Repository.MarkVerified"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Update("domains").
+		Set("verified_at", at).
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := r.primaryDB.Exec(ctx, query, args...); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "domains_verified_host_unique" {
+			return utils.ErrorDomainExists
+		}
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) DeleteDomain(ctx context.Context, id, userId uuid.UUID) error {
+	const op = "Domain.Repository.DeleteDomain"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Delete("domains").
+		Where(sq.Eq{"id": id, "user_id": userId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	tag, err := r.primaryDB.Exec(ctx, query, args...)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return utils.ErrorDomainInUse
+		}
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return utils.ErrorDomainNotFound
+	}
+	return nil
+}
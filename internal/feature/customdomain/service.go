@@ -0,0 +1,142 @@
+package customdomain
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+)
+
+type DomainService interface {
+	CreateDomain(ctx context.Context, userId uuid.UUID, host, token string) (*models.Domain, error)
+	GetDomainByID(ctx context.Context, id, userId uuid.UUID) (*models.Domain, error)
+	GetDomainsByUserId(ctx context.Context, userId uuid.UUID) ([]models.Domain, error)
+	MarkVerified(ctx context.Context, id uuid.UUID, at time.Time) error
+	DeleteDomain(ctx context.Context, id, userId uuid.UUID) error
+}
+
+type Service struct {
+	repo         DomainService
+	verifier     *Verifier
+	sharedDomain string
+	l            *slog.Logger
+}
+
+func NewService(repo DomainService, verifier *Verifier, sharedDomain string, l *slog.Logger) *Service {
+	return &Service{
+		repo:         repo,
+		verifier:     verifier,
+		sharedDomain: NormalizeHost(sharedDomain),
+		l:            l,
+	}
+}
+
+// NormalizeHost lowercases the host and strips the port and trailing dot.
+func NormalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+func (s *Service) VerificationURL(host string) string {
+	return s.verifier.URL(host)
+}
+
+func (s *Service) CreateDomain(ctx context.Context, userId uuid.UUID, host string) (*models.Domain, error) {
+	const op = "Domain.Service.CreateDomain"
+	log := s.l.With(slog.String("op", op))
+
+	host = NormalizeHost(host)
+	// single label names like localhost only resolve inside our network
+	if host == "" || host == s.sharedDomain || net.ParseIP(host) != nil || !strings.Contains(host, ".") {
+		return nil, fmt.Errorf("%w: %s", utils.ErrorInvalidDomain, host)
+	}
+	token, err := newToken()
+	if err != nil {
+		log.Error("generate token error", logger.Err(err))
+		return nil, err
+	}
+	d, err := s.repo.CreateDomain(ctx, userId, host, token)
+	if err != nil {
+		log.Error("create domain error", logger.Err(err))
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *Service) GetDomains(ctx context.Context, userId uuid.UUID) ([]models.Domain, error) {
+	const op = "Domain.Service.GetDomains"
+	log := s.l.With(slog.String("op", op))
+
+	domains, err := s.repo.GetDomainsByUserId(ctx, userId)
+	if err != nil {
+		log.Error("get domains error", logger.Err(err))
+		return nil, err
+	}
+	return domains, nil
+}
+
+// VerifyDomain fetches the verification token from the domain itself and
+// marks the domain as verified when it matches.
+func (s *Service) VerifyDomain(ctx context.Context, id, userId uuid.UUID) (*models.Domain, error) {
+	const op = "Domain.Service.VerifyDomain"
+	log := s.l.With(slog.String("op", op))
+
+	d, err := s.repo.GetDomainByID(ctx, id, userId)
+	if err != nil {
+		log.Error("get domain error", logger.Err(err))
+		return nil, err
+	}
+	if d.VerifiedAt != nil {
+		return d, nil
+	}
+	if err := s.verifier.Verify(ctx, d.Host, d.Token); err != nil {
+		// the reason stays in the log, telling it to the caller would let them
+		// probe what answers behind a host
+		log.Warn("domain verification failed", slog.String("host", d.Host), logger.Err(err))
+		return nil, utils.ErrorDomainNotVerified
+	}
+	now := time.Now()
+	if err := s.repo.MarkVerified(ctx, d.ID, now); err != nil {
+		// another user verified the same host first
+		if errors.Is(err, utils.ErrorDomainExists) {
+			return nil, err
+		}
+		log.Error("mark verified error", logger.Err(err))
+		return nil, err
+	}
+	d.VerifiedAt = &now
+	log.Info("domain verified", slog.String("host", d.Host))
+	return d, nil
+}
+
+func (s *Service) DeleteDomain(ctx context.Context, id, userId uuid.UUID) error {
+	const op = "Domain.Service.DeleteDomain"
+	log := s.l.With(slog.String("op", op))
+
+	if err := s.repo.DeleteDomain(ctx, id, userId); err != nil {
+		log.Error("delete domain error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "go-shortener-verification=" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,66 @@
+package customdomain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/pkg/netguard"
+)
+
+var ErrorTokenMismatch = errors.New("verification token mismatch")
+
+// Verifier checks that a host serves the expected token at the well-known
+// verification path.
+type Verifier struct {
+	client *http.Client
+	scheme string
+	path   string
+}
+
+// NewVerifier uses a netguard client when client is nil, so hosts resolving
+// to internal addresses are never fetched and redirects are not followed.
+func NewVerifier(client *http.Client, scheme, path string, timeout time.Duration) *Verifier {
+	if client == nil {
+		client = netguard.NewClient(timeout)
+	}
+	return &Verifier{
+		client: client,
+		scheme: scheme,
+		path:   path,
+	}
+}
+
+func (v *Verifier) URL(host string) string {
+	u := url.URL{Scheme: v.scheme, Host: host, Path: v.path}
+	return u.String()
+}
+
+func (v *Verifier) Verify(ctx context.Context, host, token string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.URL(host), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return fmt.Errorf("failed to read body: %w", err)
+	}
+	if strings.TrimSpace(string(body)) != token {
+		return ErrorTokenMismatch
+	}
+	return nil
+}
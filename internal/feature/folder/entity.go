@@ -0,0 +1,20 @@
+package folder
+
+import (
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+)
+
+type FolderRequest struct {
+	Title string `json:"title" validate:"required,min=1,max=64"`
+}
+
+type FolderResponse struct {
+	api.Response
+	Folder models.Folder `json:"folder"`
+}
+
+type GetAllFoldersResponse struct {
+	api.Response
+	Folders []models.Folder `json:"folders"`
+}
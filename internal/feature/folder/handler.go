@@ -0,0 +1,223 @@
+package folder
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sanchir01/go-shortener/internal/handlers/customiddleware"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	service *Service
+	l       *slog.Logger
+}
+
+func NewHandler(service *Service, l *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		l:       l,
+	}
+}
+
+// @Summary  CreateFolderHandler
+// @Tags folder
+// @Description Create folder
+// @Accept json
+// @Produce json
+// @Param input body FolderRequest true "folder body"
+// @Success 201 {object}  FolderResponse
+// @Failure 400,409 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /folder [post]
+func (h *Handler) CreateFolderHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Folder.Handler.CreateFolder"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	var req FolderRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		log.Error("failed to decode request body", slog.Any("err", err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidBody))
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		log.Error("invalid request", logger.Err(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidRequest))
+		return
+	}
+	folder, err := h.service.CreateFolder(r.Context(), claims.ID, req.Title)
+	if errors.Is(err, utils.ErrorFolderExists) {
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	}
+	if err != nil {
+		log.Error("failed to create folder", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrCreateFolder))
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, FolderResponse{
+		Response: api.OK(),
+		Folder:   *folder,
+	})
+}
+
+// @Summary  GetFoldersHandler
+// @Tags folder
+// @Description Get all folders of the user
+// @Accept json
+// @Produce json
+// @Success 200 {object}  GetAllFoldersResponse
+// @Failure 401 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /folder [get]
+func (h *Handler) GetFoldersHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Folder.Handler.GetFolders"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	folders, err := h.service.GetFolders(r.Context(), claims.ID)
+	if err != nil {
+		log.Error("failed to get folders", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrGetFolders))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, GetAllFoldersResponse{
+		Response: api.OK(),
+		Folders:  folders,
+	})
+}
+
+// @Summary  UpdateFolderHandler
+// @Tags folder
+// @Description Rename folder
+// @Accept json
+// @Produce json
+// @Param id path string true "folder id"
+// @Param input body FolderRequest true "folder body"
+// @Success 200 {object}  FolderResponse
+// @Failure 400,404,409 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /folder/{id} [patch]
+func (h *Handler) UpdateFolderHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Folder.Handler.UpdateFolder"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidFolderID))
+		return
+	}
+	var req FolderRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		log.Error("failed to decode request body", slog.Any("err", err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidBody))
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		log.Error("invalid request", logger.Err(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidRequest))
+		return
+	}
+	folder, err := h.service.UpdateFolder(r.Context(), id, claims.ID, req.Title)
+	switch {
+	case errors.Is(err, utils.ErrorFolderNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	case errors.Is(err, utils.ErrorFolderExists):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	case err != nil:
+		log.Error("failed to update folder", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrUpdateFolder))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, FolderResponse{
+		Response: api.OK(),
+		Folder:   *folder,
+	})
+}
+
+// @Summary  DeleteFolderHandler
+// @Tags folder
+// @Description Delete folder, links keep existing without it
+// @Produce json
+// @Param id path string true "folder id"
+// @Success 200 {object}  api.Response
+// @Failure 400,404 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /folder/{id} [delete]
+func (h *Handler) DeleteFolderHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Folder.Handler.DeleteFolder"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidFolderID))
+		return
+	}
+	err = h.service.DeleteFolder(r.Context(), id, claims.ID)
+	if errors.Is(err, utils.ErrorFolderNotFound) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	}
+	if err != nil {
+		log.Error("failed to delete folder", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrDeleteFolder))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, api.OK())
+}
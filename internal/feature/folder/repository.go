@@ -0,0 +1,154 @@
+package folder
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository struct {
+	primaryDB *pgxpool.Pool
+	l         *slog.Logger
+}
+
+func NewRepository(primaryDB *pgxpool.Pool, l *slog.Logger) *Repository {
+	return &Repository{
+		primaryDB: primaryDB,
+		l:         l,
+	}
+}
+
+func (r *Repository) CreateFolder(ctx context.Context, userId uuid.UUID, title string) (*models.Folder, error) {
+	const op = "Folder.Repository.CreateFolder"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Insert("folders").
+		Columns("user_id", "title").
+		Values(userId, title).
+		Suffix("RETURNING id, user_id, title, created_at, updated_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+
+	var folder models.Folder
+	if err := r.primaryDB.QueryRow(ctx, query, args...).Scan(&folder.ID, &folder.UserID, &folder.Title, &folder.CreatedAt, &folder.UpdatedAt); err != nil {
+		if isUniqueViolation(err) {
+			return nil, utils.ErrorFolderExists
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return &folder, nil
+}
+
+func (r *Repository) GetFoldersByUserId(ctx context.Context, userId uuid.UUID) ([]models.Folder, error) {
+	const op = "Folder.Repository.GetFoldersByUserId"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select("id, user_id, title, created_at, updated_at").
+		From("folders").
+		Where(sq.Eq{"user_id": userId}).
+		OrderBy("title").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+
+	rows, err := r.primaryDB.Query(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := make([]models.Folder, 0, 16)
+	for rows.Next() {
+		var folder models.Folder
+		if err := rows.Scan(&folder.ID, &folder.UserID, &folder.Title, &folder.CreatedAt, &folder.UpdatedAt); err != nil {
+			log.Error("error", logger.Err(err))
+			return nil, err
+		}
+		folders = append(folders, folder)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return folders, nil
+}
+
+func (r *Repository) UpdateFolder(ctx context.Context, id, userId uuid.UUID, title string) (*models.Folder, error) {
+	const op = "Folder.Repository.UpdateFolder"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Update("folders").
+		Set("title", title).
+		Where(sq.Eq{"id": id, "user_id": userId}).
+		Suffix("RETURNING id, user_id, title, created_at, updated_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+
+	var folder models.Folder
+	if err := r.primaryDB.QueryRow(ctx, query, args...).Scan(&folder.ID, &folder.UserID, &folder.Title, &folder.CreatedAt, &folder.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.ErrorFolderNotFound
+		}
+		if isUniqueViolation(err) {
+			return nil, utils.ErrorFolderExists
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return &folder, nil
+}
+
+func (r *Repository) DeleteFolder(ctx context.Context, id, userId uuid.UUID) error {
+	const op = "Folder.Repository.DeleteFolder"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Delete("folders").
+		Where(sq.Eq{"id": id, "user_id": userId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+
+	folder, err := r.primaryDB.Exec(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	if folder.RowsAffected() == 0 {
+		return utils.ErrorFolderNotFound
+	}
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,77 @@
+package folder
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/google/uuid"
+)
+
+type FolderService interface {
+	CreateFolder(ctx context.Context, userId uuid.UUID, title string) (*models.Folder, error)
+	GetFoldersByUserId(ctx context.Context, userId uuid.UUID) ([]models.Folder, error)
+	UpdateFolder(ctx context.Context, id, userId uuid.UUID, title string) (*models.Folder, error)
+	DeleteFolder(ctx context.Context, id, userId uuid.UUID) error
+}
+
+type Service struct {
+	repo FolderService
+	l    *slog.Logger
+}
+
+func NewService(repo FolderService, l *slog.Logger) *Service {
+	return &Service{
+		repo: repo,
+		l:    l,
+	}
+}
+
+func (s *Service) CreateFolder(ctx context.Context, userId uuid.UUID, title string) (*models.Folder, error) {
+	const op = "Folder.Service.CreateFolder"
+	log := s.l.With(slog.String("op", op))
+
+	folder, err := s.repo.CreateFolder(ctx, userId, strings.TrimSpace(title))
+	if err != nil {
+		log.Error("create folder error", logger.Err(err))
+		return nil, err
+	}
+	return folder, nil
+}
+
+func (s *Service) GetFolders(ctx context.Context, userId uuid.UUID) ([]models.Folder, error) {
+	const op = "Folder.Service.GetFolders"
+	log := s.l.With(slog.String("op", op))
+
+	folders, err := s.repo.GetFoldersByUserId(ctx, userId)
+	if err != nil {
+		log.Error("get folders error", logger.Err(err))
+		return nil, err
+	}
+	return folders, nil
+}
+
+func (s *Service) UpdateFolder(ctx context.Context, id, userId uuid.UUID, title string) (*models.Folder, error) {
+	const op = "Folder.Service.UpdateFolder"
+	log := s.l.With(slog.String("op", op))
+
+	folder, err := s.repo.UpdateFolder(ctx, id, userId, strings.TrimSpace(title))
+	if err != nil {
+		log.Error("update folder error", logger.Err(err))
+		return nil, err
+	}
+	return folder, nil
+}
+
+func (s *Service) DeleteFolder(ctx context.Context, id, userId uuid.UUID) error {
+	const op = "Folder.Service.DeleteFolder"
+	log := s.l.With(slog.String("op", op))
+
+	if err := s.repo.DeleteFolder(ctx, id, userId); err != nil {
+		log.Error("delete folder error", logger.Err(err))
+		return err
+	}
+	return nil
+}
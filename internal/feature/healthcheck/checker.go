@@ -0,0 +1,126 @@
+package healthcheck
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/pkg/netguard"
+)
+
+type Result struct {
+	StatusCode int
+	Latency    time.Duration
+	Err        error
+	CheckedAt  time.Time
+}
+
+func (r Result) Healthy() bool {
+	return r.Err == nil && r.StatusCode > 0 && r.StatusCode < http.StatusBadRequest
+}
+
+// Checker probes destinations with HEAD and retries with GET when the server
+// refuses HEAD or answers it with an error status.
+type Checker struct {
+	client    *http.Client
+	userAgent string
+	hosts     *hostLimiter
+}
+
+// NewChecker dials through netguard when client is nil. Redirects are
+// followed, every hop is dialed through the same guard.
+func NewChecker(client *http.Client, timeout, perHostDelay time.Duration, userAgent string) *Checker {
+	if client == nil {
+		client = &http.Client{Timeout: timeout, Transport: netguard.NewTransport(timeout)}
+	}
+	return &Checker{
+		client:    client,
+		userAgent: userAgent,
+		hosts:     newHostLimiter(perHostDelay),
+	}
+}
+
+func (c *Checker) Check(ctx context.Context, destination string) Result {
+	u, err := url.Parse(destination)
+	if err != nil {
+		return Result{Err: err, CheckedAt: time.Now()}
+	}
+	res := c.probe(ctx, http.MethodHead, u)
+	if res.Healthy() || ctx.Err() != nil {
+		return res
+	}
+	return c.probe(ctx, http.MethodGet, u)
+}
+
+func (c *Checker) probe(ctx context.Context, method string, u *url.URL) Result {
+	if err := c.hosts.Wait(ctx, u.Host); err != nil {
+		return Result{Err: err, CheckedAt: time.Now()}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return Result{Err: err, CheckedAt: time.Now()}
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	res := Result{Latency: time.Since(start), CheckedAt: start}
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	res.StatusCode = resp.StatusCode
+	return res
+}
+
+// hostLimiter keeps at least delay between two requests to the same host.
+type hostLimiter struct {
+	delay time.Duration
+	mu    sync.Mutex
+	next  map[string]time.Time
+}
+
+func newHostLimiter(delay time.Duration) *hostLimiter {
+	return &hostLimiter{delay: delay, next: make(map[string]time.Time)}
+}
+
+func (h *hostLimiter) Wait(ctx context.Context, host string) error {
+	if h.delay <= 0 {
+		return nil
+	}
+	h.mu.Lock()
+	now := time.Now()
+	// hosts whose turn has passed need no entry, this keeps the map as small
+	// as the set of hosts probed within the last delay
+	for k, t := range h.next {
+		if !t.After(now) {
+			delete(h.next, k)
+		}
+	}
+	at := h.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	h.next[host] = at.Add(h.delay)
+	h.mu.Unlock()
+
+	wait := time.Until(at)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
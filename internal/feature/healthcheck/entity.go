@@ -0,0 +1,23 @@
+package healthcheck
+
+import (
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/google/uuid"
+)
+
+// Target is an active link together with its previous health state.
+type Target struct {
+	UrlID               uuid.UUID
+	Alias               string
+	Url                 string
+	UserID              uuid.UUID
+	OwnerTGID           *int64
+	Broken              bool
+	ConsecutiveFailures int
+}
+
+type UrlHealthResponse struct {
+	api.Response
+	Health *models.UrlHealth `json:"health"`
+}
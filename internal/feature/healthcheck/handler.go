@@ -0,0 +1,74 @@
+package healthcheck
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sanchir01/go-shortener/internal/handlers/customiddleware"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	service *Service
+	l       *slog.Logger
+}
+
+func NewHandler(service *Service, l *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		l:       l,
+	}
+}
+
+// @Summary  GetUrlHealthHandler
+// @Tags url
+// @Description Last health check result of the url destination
+// @Produce json
+// @Param id path string true "url id"
+// @Success 200 {object}  UrlHealthResponse
+// @Failure 400,404 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /url/{id}/health [get]
+func (h *Handler) GetUrlHealthHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Health.Handler.GetUrlHealth"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidUrlID))
+		return
+	}
+	health, err := h.service.GetHealth(r.Context(), id, claims.ID)
+	if errors.Is(err, utils.ErrorUrlNotFound) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrUrlNotChecked))
+		return
+	}
+	if err != nil {
+		log.Error("failed to get url health", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrGetUrlHealth))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, UrlHealthResponse{
+		Response: api.OK(),
+		Health:   health,
+	})
+}
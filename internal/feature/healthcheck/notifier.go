@@ -0,0 +1,45 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// Notifier tells the owner that one of their links stopped responding.
+type Notifier interface {
+	LinkBroken(ctx context.Context, t Target, res Result) error
+}
+
+// Notifiers fans a notification out to every notifier in the list.
+type Notifiers []Notifier
+
+func (n Notifiers) LinkBroken(ctx context.Context, t Target, res Result) error {
+	var errs []error
+	for _, notifier := range n {
+		if err := notifier.LinkBroken(ctx, t, res); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type LogNotifier struct {
+	l *slog.Logger
+}
+
+func NewLogNotifier(l *slog.Logger) *LogNotifier {
+	return &LogNotifier{l: l}
+}
+
+func (n *LogNotifier) LinkBroken(_ context.Context, t Target, res Result) error {
+	n.l.Warn("link is broken",
+		slog.String("url_id", t.UrlID.String()),
+		slog.String("alias", t.Alias),
+		slog.String("url", t.Url),
+		slog.String("user_id", t.UserID.String()),
+		slog.Int("status_code", res.StatusCode),
+		slog.Any("error", res.Err),
+	)
+	return nil
+}
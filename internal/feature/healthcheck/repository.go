@@ -0,0 +1,128 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository struct {
+	primaryDB *pgxpool.Pool
+	l         *slog.Logger
+}
+
+func NewRepository(primaryDB *pgxpool.Pool, l *slog.Logger) *Repository {
+	return &Repository{
+		primaryDB: primaryDB,
+		l:         l,
+	}
+}
+
+// GetTargets returns every link that is already active and not expired.
+func (r *Repository) GetTargets(ctx context.Context) ([]Target, error) {
+	const op = "Health.Repository.GetTargets"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select("url.id, url.alias, url.url, url.user_id, users.tg_id, COALESCE(h.broken, false), COALESCE(h.consecutive_failures, 0)").
+		From("url").
+		LeftJoin("users ON users.id = url.user_id").
+		LeftJoin("url_health h ON h.url_id = url.id").
+		Where("url.not_before IS NULL OR url.not_before <= now()").
+		Where("url.expires_at IS NULL OR url.expires_at > now()").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+
+	rows, err := r.primaryDB.Query(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	targets := make([]Target, 0, 100)
+	for rows.Next() {
+		var t Target
+		if err := rows.Scan(&t.UrlID, &t.Alias, &t.Url, &t.UserID, &t.OwnerTGID, &t.Broken, &t.ConsecutiveFailures); err != nil {
+			log.Error("error", logger.Err(err))
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return targets, nil
+}
+
+func (r *Repository) SaveHealth(ctx context.Context, h models.UrlHealth) error {
+	const op = "Health.Repository.SaveHealth"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Insert("url_health").
+		Columns("url_id", "status_code", "latency_ms", "error", "consecutive_failures", "broken", "failing_since", "checked_at").
+		Values(h.UrlID, h.StatusCode, h.LatencyMs, h.Error, h.ConsecutiveFailures, h.Broken, h.FailingSince, h.CheckedAt).
+		Suffix(`ON CONFLICT (url_id) DO UPDATE SET
+			status_code = EXCLUDED.status_code,
+			latency_ms = EXCLUDED.latency_ms,
+			error = EXCLUDED.error,
+			consecutive_failures = EXCLUDED.consecutive_failures,
+			broken = EXCLUDED.broken,
+			failing_since = CASE WHEN EXCLUDED.consecutive_failures = 0 THEN NULL
+				ELSE COALESCE(url_health.failing_since, EXCLUDED.failing_since) END,
+			checked_at = EXCLUDED.checked_at`).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := r.primaryDB.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) GetHealth(ctx context.Context, urlId, userId uuid.UUID) (*models.UrlHealth, error) {
+	const op = "Health.Repository.GetHealth"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select("h.url_id, h.status_code, h.latency_ms, h.error, h.consecutive_failures, h.broken, h.failing_since, h.checked_at").
+		From("url_health h").
+		Join("url ON url.id = h.url_id").
+		Where(sq.Eq{"h.url_id": urlId, "url.user_id": userId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+
+	var h models.UrlHealth
+	if err := r.primaryDB.QueryRow(ctx, query, args...).Scan(
+		&h.UrlID, &h.StatusCode, &h.LatencyMs, &h.Error, &h.ConsecutiveFailures, &h.Broken, &h.FailingSince, &h.CheckedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.ErrorUrlNotFound
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return &h, nil
+}
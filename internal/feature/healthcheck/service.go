@@ -0,0 +1,138 @@
+package healthcheck
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/config"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/google/uuid"
+)
+
+type HealthService interface {
+	GetTargets(ctx context.Context) ([]Target, error)
+	SaveHealth(ctx context.Context, h models.UrlHealth) error
+	GetHealth(ctx context.Context, urlId, userId uuid.UUID) (*models.UrlHealth, error)
+}
+
+type Service struct {
+	repo     HealthService
+	checker  *Checker
+	notifier Notifier
+	cfg      config.Health
+	l        *slog.Logger
+}
+
+func NewService(repo HealthService, checker *Checker, notifier Notifier, cfg config.Health, l *slog.Logger) *Service {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 1
+	}
+	return &Service{
+		repo:     repo,
+		checker:  checker,
+		notifier: notifier,
+		cfg:      cfg,
+		l:        l,
+	}
+}
+
+// Run checks all links every cfg.Interval until ctx is done.
+func (s *Service) Run(ctx context.Context) {
+	const op = "Health.Service.Run"
+	log := s.l.With(slog.String("op", op))
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if err := s.CheckAll(ctx); err != nil {
+			log.Error("health check round failed", logger.Err(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) CheckAll(ctx context.Context) error {
+	const op = "Health.Service.CheckAll"
+	log := s.l.With(slog.String("op", op))
+
+	targets, err := s.repo.GetTargets(ctx)
+	if err != nil {
+		return err
+	}
+
+	sem := make(chan struct{}, s.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(t Target) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := s.check(ctx, t); err != nil {
+				log.Error("check failed", slog.String("url_id", t.UrlID.String()), logger.Err(err))
+			}
+		}(t)
+	}
+	wg.Wait()
+	log.Info("health check round completed", slog.Int("links", len(targets)))
+	return nil
+}
+
+func (s *Service) check(ctx context.Context, t Target) error {
+	res := s.checker.Check(ctx, t.Url)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	h := models.UrlHealth{
+		UrlID:      t.UrlID,
+		StatusCode: res.StatusCode,
+		LatencyMs:  res.Latency.Milliseconds(),
+		CheckedAt:  res.CheckedAt,
+	}
+	if res.Err != nil {
+		h.Error = res.Err.Error()
+	}
+	if !res.Healthy() {
+		h.ConsecutiveFailures = t.ConsecutiveFailures + 1
+		h.Broken = h.ConsecutiveFailures >= s.cfg.FailureThreshold
+		h.FailingSince = &res.CheckedAt
+	}
+	if err := s.repo.SaveHealth(ctx, h); err != nil {
+		return err
+	}
+
+	if h.Broken && !t.Broken {
+		if err := s.notifier.LinkBroken(ctx, t, res); err != nil {
+			s.l.Error("notify owner failed", slog.String("url_id", t.UrlID.String()), logger.Err(err))
+		}
+	}
+	return nil
+}
+
+func (s *Service) GetHealth(ctx context.Context, urlId, userId uuid.UUID) (*models.UrlHealth, error) {
+	const op = "Health.Service.GetHealth"
+	log := s.l.With(slog.String("op", op))
+
+	h, err := s.repo.GetHealth(ctx, urlId, userId)
+	if err != nil {
+		log.Error("get health error", logger.Err(err))
+		return nil, err
+	}
+	return h, nil
+}
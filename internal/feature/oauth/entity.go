@@ -0,0 +1,31 @@
+package oauth
+
+import (
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/google/uuid"
+)
+
+type CallbackRequest struct {
+	Code  string `json:"code" validate:"required"`
+	State string `json:"state" validate:"required"`
+}
+
+type GetProvidersResponse struct {
+	api.Response
+	Providers []string `json:"providers"`
+}
+
+type GetIdentitiesResponse struct {
+	api.Response
+	Identities []models.Identity `json:"identities"`
+}
+
+type linkedUser struct {
+	ID               uuid.UUID
+	Email            string
+	Name             string
+	Role             string
+	EmailVerified    bool
+	TwoFactorEnabled bool
+}
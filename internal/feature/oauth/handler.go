@@ -0,0 +1,291 @@
+package oauth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/feature/user"
+	"github.com/Sanchir01/go-shortener/internal/handlers/customiddleware"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// stateCookie binds a flow to the browser that started it, so a victim can
+// not be made to finish a flow started by someone else.
+const stateCookie = "oauthState"
+
+type Handler struct {
+	service  *Service
+	sessions user.SessionIssuer
+	twoFA    user.TwoFactorChallenger
+	l        *slog.Logger
+}
+
+func NewHandler(service *Service, sessions user.SessionIssuer, twoFA user.TwoFactorChallenger, l *slog.Logger) *Handler {
+	return &Handler{
+		service:  service,
+		sessions: sessions,
+		twoFA:    twoFA,
+		l:        l,
+	}
+}
+
+// @Summary GetProviders
+// @Tags auth
+// @Description Names of the enabled sign-in providers
+// @Produce json
+// @Success 200 {object}  GetProvidersResponse
+// @Router /auth/providers [get]
+func (h *Handler) GetProvidersHandler(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, GetProvidersResponse{
+		Response:  api.OK(),
+		Providers: h.service.Providers(),
+	})
+}
+
+// @Summary OAuthLogin
+// @Tags auth
+// @Description Redirect to the consent page of the provider. The provider sends the user back to the redirect uri with code and state
+// @Produce json
+// @Param provider path string true "provider name, e.g. google or github"
+// @Success 307
+// @Failure 404 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /auth/{provider} [get]
+func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	h.start(w, r, "OAuth.Handler.LoginHandler", nil)
+}
+
+// @Summary LinkIdentity
+// @Tags auth
+// @Description Redirect to the consent page of the provider to link it to the signed in account. Finish with the callback
+// @Produce json
+// @Param provider path string true "provider name"
+// @Success 307
+// @Failure 401,404 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /auth/identities/{provider}/link [get]
+func (h *Handler) LinkHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	h.start(w, r, "OAuth.Handler.LinkHandler", &claims.ID)
+}
+
+func (h *Handler) start(w http.ResponseWriter, r *http.Request, op string, linkUserId *uuid.UUID) {
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	url, state, err := h.service.Start(r.Context(), chi.URLParam(r, "provider"), linkUserId)
+	if errors.Is(err, ErrUnknownProvider) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrUnknownProvider))
+		return
+	}
+	if err != nil {
+		log.Error("failed to generate oauth url", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInternal))
+		return
+	}
+	http.SetCookie(w, user.GenerateCookie(stateCookie, time.Now().Add(h.service.states.TTL()), true, state, ""))
+	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+}
+
+// @Summary OAuthCallback
+// @Tags auth
+// @Description Sign in with the code and state the provider redirected with. Unknown identities are linked to the account with the same verified email or registered. A link flow links the identity to the signed in account
+// @Accept json
+// @Produce json
+// @Param input body CallbackRequest true "callback body"
+// @Success 200 {object}  user.LoginResponse
+// @Failure 400,401,403,409,429 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /auth/callback [post]
+func (h *Handler) CallbackHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "OAuth.Handler.CallbackHandler"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	var req CallbackRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		log.Error("failed to decode request body", slog.Any("err", err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidBody))
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		log.Error("invalid request", logger.Err(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidRequest))
+		return
+	}
+	cookie, err := r.Cookie(stateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(req.State)) != 1 {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidOAuthState))
+		return
+	}
+	http.SetCookie(w, user.GenerateCookie(stateCookie, time.Unix(0, 0), true, "", ""))
+
+	var currentUserId *uuid.UUID
+	if claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context()); err == nil && claims.Scopes == nil {
+		currentUserId = &claims.ID
+	}
+	u, linked, err := h.service.Callback(r.Context(), req.State, req.Code, currentUserId)
+	switch {
+	case errors.Is(err, ErrInvalidState), errors.Is(err, ErrUnknownProvider):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidOAuthState))
+		return
+	case errors.Is(err, ErrInvalidIDToken):
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrSignInFailed))
+		return
+	case errors.Is(err, ErrEmailNotVerified):
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrProviderEmailNotVerified))
+		return
+	case errors.Is(err, utils.ErrorIdentityLinked):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrIdentityLinked))
+		return
+	case errors.Is(err, utils.ErrorUserAlreadyExists):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrUserExists))
+		return
+	case err != nil:
+		log.Error("failed oauth callback", logger.Err(err))
+		render.Status(r, http.StatusBadGateway)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrExchangeCode))
+		return
+	}
+
+	if linked {
+		render.JSON(w, r, user.LoginResponse{
+			Response: api.OK(),
+			Email:    u.Email,
+			Username: u.Name,
+		})
+		return
+	}
+	if u.TwoFactorEnabled {
+		challenge, err := h.twoFA.StartChallenge(r.Context(), u.ID)
+		if errors.Is(err, utils.ErrorTwoFactorLocked) {
+			render.Status(r, http.StatusTooManyRequests)
+			render.JSON(w, r, api.FailErr(r.Context(), err))
+			return
+		}
+		if err != nil {
+			log.Error("failed to start two-factor challenge", logger.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInternal))
+			return
+		}
+		render.JSON(w, r, user.LoginResponse{
+			Response:          api.OK(),
+			Email:             u.Email,
+			Username:          u.Name,
+			TwoFactorRequired: true,
+			Challenge:         challenge,
+		})
+		return
+	}
+	if err := h.sessions.Issue(w, r, u.ID, u.Role); err != nil {
+		log.Error("register cookie errors", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrCookie))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, user.LoginResponse{
+		Response: api.OK(),
+		Email:    u.Email,
+		Username: u.Name,
+	})
+}
+
+// @Summary GetIdentities
+// @Tags auth
+// @Description External accounts linked to the signed in user
+// @Produce json
+// @Success 200 {object}  GetIdentitiesResponse
+// @Failure 401 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /auth/identities [get]
+func (h *Handler) GetIdentitiesHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "OAuth.Handler.GetIdentitiesHandler"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	identities, err := h.service.Identities(r.Context(), claims.ID)
+	if err != nil {
+		log.Error("failed to get identities", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInternal))
+		return
+	}
+	render.JSON(w, r, GetIdentitiesResponse{
+		Response:   api.OK(),
+		Identities: identities,
+	})
+}
+
+// @Summary UnlinkIdentity
+// @Tags auth
+// @Description Unlink the provider. The last way to sign in can not be removed
+// @Produce json
+// @Param provider path string true "provider name"
+// @Success 200 {object}  api.Response
+// @Failure 401,404,409 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /auth/identities/{provider} [delete]
+func (h *Handler) UnlinkHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "OAuth.Handler.UnlinkHandler"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	err = h.service.Unlink(r.Context(), claims.ID, chi.URLParam(r, "provider"))
+	switch {
+	case errors.Is(err, utils.ErrorIdentityNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrIdentityNotFound))
+		return
+	case errors.Is(err, utils.ErrorLastLoginMethod):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrLastLoginMethod))
+		return
+	case err != nil:
+		log.Error("failed to unlink identity", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInternal))
+		return
+	}
+	render.JSON(w, r, api.OK())
+}
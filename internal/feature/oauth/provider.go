@@ -0,0 +1,328 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/config"
+)
+
+const (
+	TypeOIDC   = "oidc"
+	TypeOAuth2 = "oauth2"
+)
+
+var (
+	ErrUnknownProvider  = errors.New("unknown oauth provider")
+	ErrInvalidIDToken   = errors.New("invalid id token")
+	ErrEmailNotVerified = errors.New("provider email is not verified")
+)
+
+type Credentials struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURI  string
+}
+
+type TokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int    `json:"expires_in"`
+	Scope            string `json:"scope"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Identity is the account at the provider. Subject is stable, the email may
+// change and is only trusted for linking when EmailVerified is set.
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider runs the authorization code flow with PKCE against one provider.
+// OIDC providers are verified through their id_token, plain OAuth2 providers
+// through the user-info endpoint.
+type Provider struct {
+	name  string
+	cfg   config.OAuthProvider
+	creds Credentials
+	http  *http.Client
+
+	mu        sync.Mutex
+	meta      *metadata
+	keys      map[string]any
+	fetchedAt time.Time
+}
+
+func NewProvider(name string, cfg config.OAuthProvider, creds Credentials, client *http.Client) (*Provider, error) {
+	if cfg.Type == "" {
+		cfg.Type = TypeOIDC
+	}
+	switch cfg.Type {
+	case TypeOIDC:
+		if cfg.Issuer == "" && (cfg.AuthURL == "" || cfg.TokenURL == "" || cfg.JWKSURL == "") {
+			return nil, fmt.Errorf("oauth provider %s: issuer or auth, token and jwks urls are required", name)
+		}
+	case TypeOAuth2:
+		if cfg.AuthURL == "" || cfg.TokenURL == "" || cfg.UserInfoURL == "" {
+			return nil, fmt.Errorf("oauth provider %s: auth, token and userinfo urls are required", name)
+		}
+	default:
+		return nil, fmt.Errorf("oauth provider %s: unsupported type %q", name, cfg.Type)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Provider{
+		name:  name,
+		cfg:   cfg,
+		creds: creds,
+		http:  client,
+		keys:  make(map[string]any),
+	}, nil
+}
+
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// AuthURL is the consent page the user is redirected to.
+func (p *Provider) AuthURL(ctx context.Context, state, challenge, nonce string) (string, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	params := url.Values{}
+	for k, v := range p.cfg.AuthParams {
+		params.Set(k, v)
+	}
+	params.Set("client_id", p.creds.ClientID)
+	params.Set("response_type", "code")
+	params.Set("redirect_uri", p.creds.RedirectURI)
+	params.Set("state", state)
+	params.Set("code_challenge", challenge)
+	params.Set("code_challenge_method", "S256")
+	if len(p.cfg.Scopes) > 0 {
+		params.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	}
+	if p.cfg.Type == TypeOIDC {
+		params.Set("nonce", nonce)
+	}
+
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// Exchange trades the authorization code for tokens, verifier proves the
+// code was requested by us.
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (*TokenResponse, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	params.Set("code", code)
+	params.Set("client_id", p.creds.ClientID)
+	params.Set("client_secret", p.creds.ClientSecret)
+	params.Set("redirect_uri", p.creds.RedirectURI)
+	params.Set("grant_type", "authorization_code")
+	params.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return nil, fmt.Errorf("unexpected status code: %d: %s", resp.StatusCode, body)
+	}
+	var token TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	// GitHub answers errors with 200
+	if token.Error != "" {
+		return nil, fmt.Errorf("token error: %s: %s", token.Error, token.ErrorDescription)
+	}
+	if p.cfg.Type == TypeOIDC && token.IDToken == "" {
+		return nil, ErrInvalidIDToken
+	}
+	if p.cfg.Type == TypeOAuth2 && token.AccessToken == "" {
+		return nil, errors.New("token response without access_token")
+	}
+	return &token, nil
+}
+
+// Identity resolves the account the tokens were issued for.
+func (p *Provider) Identity(ctx context.Context, token *TokenResponse, nonce string) (*Identity, error) {
+	if p.cfg.Type == TypeOAuth2 {
+		return p.userInfoIdentity(ctx, token.AccessToken)
+	}
+	return p.oidcIdentity(ctx, token, nonce)
+}
+
+// metadata are the endpoints of the provider, discovered once from the
+// issuer and overridden by the configured ones.
+func (p *Provider) metadata(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+	meta := &metadata{}
+	if p.cfg.Type == TypeOIDC && p.cfg.Issuer != "" {
+		discovered, err := p.discover(ctx)
+		if err != nil {
+			return nil, err
+		}
+		meta = discovered
+	}
+	override := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	override(&meta.AuthorizationEndpoint, p.cfg.AuthURL)
+	override(&meta.TokenEndpoint, p.cfg.TokenURL)
+	override(&meta.JWKSURI, p.cfg.JWKSURL)
+	override(&meta.UserInfoEndpoint, p.cfg.UserInfoURL)
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" {
+		return nil, fmt.Errorf("oauth provider %s: missing endpoints", p.name)
+	}
+	p.meta = meta
+	return meta, nil
+}
+
+// issuers are the accepted iss values of id tokens.
+func (p *Provider) issuers() []string {
+	issuers := slices.Clone(p.cfg.Issuers)
+	if p.cfg.Issuer != "" {
+		issuers = append(issuers, p.cfg.Issuer)
+	}
+	return issuers
+}
+
+func (p *Provider) getJSON(ctx context.Context, rawURL, accessToken string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("get %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("get %s: unexpected status code: %d", rawURL, resp.StatusCode)
+	}
+	dec := json.NewDecoder(io.LimitReader(resp.Body, 1<<20))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("get %s: invalid JSON: %w", rawURL, err)
+	}
+	return nil
+}
+
+// Registry holds the configured providers by name.
+type Registry struct {
+	providers map[string]*Provider
+}
+
+// NewRegistry builds the providers of cfg. Providers without a client id in
+// the environment are skipped so a deployment only enables what it has
+// credentials for.
+func NewRegistry(cfg config.OAuth, l *slog.Logger) (*Registry, error) {
+	client := &http.Client{Timeout: cfg.Timeout}
+	r := &Registry{providers: make(map[string]*Provider, len(cfg.Providers))}
+	for name, pc := range cfg.Providers {
+		creds := Credentials{
+			ClientID:     os.Getenv(pc.ClientIDEnv),
+			ClientSecret: os.Getenv(pc.ClientSecretEnv),
+			RedirectURI:  pc.RedirectURI,
+		}
+		if pc.ClientIDEnv == "" || creds.ClientID == "" {
+			l.Warn("oauth provider disabled, no client id", slog.String("provider", name))
+			continue
+		}
+		p, err := NewProvider(name, pc, creds, client)
+		if err != nil {
+			return nil, err
+		}
+		r.Register(p)
+	}
+	return r, nil
+}
+
+func (r *Registry) Register(p *Provider) {
+	if r.providers == nil {
+		r.providers = make(map[string]*Provider)
+	}
+	r.providers[p.name] = p
+}
+
+func (r *Registry) Get(name string) (*Provider, error) {
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// Names lists the enabled providers in a stable order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// NewPKCE returns a code verifier and its S256 challenge.
+func NewPKCE() (verifier, challenge string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	verifier = base64.RawURLEncoding.EncodeToString(b)
+	return verifier, PKCEChallenge(verifier), nil
+}
+
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,353 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const userColumns = `users.id, COALESCE(users.email, ''), users.title, users.role::text, users.email_verified_at IS NOT NULL,
+	EXISTS(SELECT 1 FROM user_totp t WHERE t.user_id = users.id AND t.enabled_at IS NOT NULL)`
+
+type Repository struct {
+	primaryDB *pgxpool.Pool
+	l         *slog.Logger
+}
+
+func NewRepository(primaryDB *pgxpool.Pool, l *slog.Logger) *Repository {
+	return &Repository{
+		primaryDB: primaryDB,
+		l:         l,
+	}
+}
+
+func (r *Repository) GetUserByIdentity(ctx context.Context, provider, subject string) (*linkedUser, error) {
+	const op = "OAuth.Repository.GetUserByIdentity"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select(userColumns).
+		From("users").
+		Join("user_identities i ON i.user_id = users.id").
+		Where(sq.Eq{"i.provider": provider, "i.subject": subject}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	u, err := scanUser(r.primaryDB.QueryRow(ctx, query, args...))
+	if err != nil && !errors.Is(err, utils.ErrorUserNotFound) {
+		log.Error("error", logger.Err(err))
+	}
+	return u, err
+}
+
+// GetUserByEmailForUpdate locks the account with the lowercase email until
+// the end of tx.
+func (r *Repository) GetUserByEmailForUpdate(ctx context.Context, email string, tx pgx.Tx) (*linkedUser, error) {
+	const op = "OAuth.Repository.GetUserByEmailForUpdate"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select(userColumns).
+		From("users").
+		Where(sq.Expr("lower(email) = ?", email)).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	u, err := scanUser(tx.QueryRow(ctx, query, args...))
+	if err != nil && !errors.Is(err, utils.ErrorUserNotFound) {
+		log.Error("error", logger.Err(err))
+	}
+	return u, err
+}
+
+// GetUserForUpdate locks the account until the end of tx.
+func (r *Repository) GetUserForUpdate(ctx context.Context, userId uuid.UUID, tx pgx.Tx) (*linkedUser, error) {
+	const op = "OAuth.Repository.GetUserForUpdate"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select(userColumns).
+		From("users").
+		Where(sq.Eq{"id": userId}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	u, err := scanUser(tx.QueryRow(ctx, query, args...))
+	if err != nil && !errors.Is(err, utils.ErrorUserNotFound) {
+		log.Error("error", logger.Err(err))
+	}
+	return u, err
+}
+
+// CreateIdentity links the external account. A user has one identity per
+// provider and an identity belongs to one user.
+func (r *Repository) CreateIdentity(ctx context.Context, userId uuid.UUID, id *Identity, tx pgx.Tx) error {
+	const op = "OAuth.Repository.CreateIdentity"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Insert("user_identities").
+		Columns("provider", "subject", "user_id", "email").
+		Values(id.Provider, id.Subject, userId, id.Email).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return utils.ErrorIdentityLinked
+		}
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+// VerifyEmail marks the email as verified by a provider. When it was not
+// verified before, whoever registered it could be anyone, so the password
+// is dropped.
+func (r *Repository) VerifyEmail(ctx context.Context, userId uuid.UUID, dropPassword bool, tx pgx.Tx) error {
+	const op = "OAuth.Repository.VerifyEmail"
+	log := r.l.With(slog.String("op", op))
+
+	update := sq.
+		Update("users").
+		Set("email_verified_at", sq.Expr("COALESCE(email_verified_at, now())")).
+		Where(sq.Eq{"id": userId})
+	if dropPassword {
+		update = update.Set("password", nil)
+	}
+	query, args, err := update.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+// squatterTables hold credentials whoever registered an unverified email
+// could have left behind to get back into the account.
+var squatterTables = []string{
+	"api_keys",
+	"user_totp",
+	"user_recovery_codes",
+	"two_factor_challenges",
+	"account_tokens",
+	"user_identities",
+}
+
+// DropCredentials removes every way into the account except the email,
+// unlinks its Telegram account and revokes its sessions. It returns the ids
+// of the revoked sessions.
+func (r *Repository) DropCredentials(ctx context.Context, userId uuid.UUID, tx pgx.Tx) ([]uuid.UUID, error) {
+	const op = "OAuth.Repository.DropCredentials"
+	log := r.l.With(slog.String("op", op))
+
+	for _, table := range squatterTables {
+		query, args, err := sq.
+			Delete(table).
+			Where(sq.Eq{"user_id": userId}).
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			log.Error("error", logger.Err(err))
+			return nil, utils.ErrorQueryString
+		}
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			log.Error("error", logger.Err(err), slog.String("table", table))
+			return nil, err
+		}
+	}
+
+	query, args, err := sq.
+		Update("users").
+		Set("tg_id", nil).
+		Where(sq.Eq{"id": userId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+
+	query, args, err = sq.
+		Update("sessions").
+		Set("revoked_at", sq.Expr("now()")).
+		Where(sq.Eq{"user_id": userId, "revoked_at": nil}).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *Repository) CreateUser(ctx context.Context, email, title string, tx pgx.Tx) (*uuid.UUID, error) {
+	const op = "OAuth.Repository.CreateUser"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Insert("users").
+		Columns("email", "title", "email_verified_at").
+		Values(email, title, sq.Expr("now()")).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	var id uuid.UUID
+	if err := tx.QueryRow(ctx, query, args...).Scan(&id); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, utils.ErrorUserAlreadyExists
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return &id, nil
+}
+
+func (r *Repository) GetIdentities(ctx context.Context, userId uuid.UUID) ([]models.Identity, error) {
+	const op = "OAuth.Repository.GetIdentities"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select("provider", "user_id", "COALESCE(email, '')", "created_at").
+		From("user_identities").
+		Where(sq.Eq{"user_id": userId}).
+		OrderBy("created_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	rows, err := r.primaryDB.Query(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+	identities := make([]models.Identity, 0)
+	for rows.Next() {
+		var i models.Identity
+		if err := rows.Scan(&i.Provider, &i.UserID, &i.Email, &i.CreatedAt); err != nil {
+			log.Error("error", logger.Err(err))
+			return nil, err
+		}
+		identities = append(identities, i)
+	}
+	return identities, rows.Err()
+}
+
+// CountLoginMethods locks the account and tells whether it has a password
+// and how many identities.
+func (r *Repository) CountLoginMethods(ctx context.Context, userId uuid.UUID, tx pgx.Tx) (bool, int, error) {
+	const op = "OAuth.Repository.CountLoginMethods"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select("password IS NOT NULL",
+			"(SELECT count(*) FROM user_identities i WHERE i.user_id = users.id)").
+		From("users").
+		Where(sq.Eq{"id": userId}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return false, 0, utils.ErrorQueryString
+	}
+	var (
+		hasPassword bool
+		identities  int
+	)
+	if err := tx.QueryRow(ctx, query, args...).Scan(&hasPassword, &identities); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, 0, utils.ErrorUserNotFound
+		}
+		log.Error("error", logger.Err(err))
+		return false, 0, err
+	}
+	return hasPassword, identities, nil
+}
+
+func (r *Repository) DeleteIdentity(ctx context.Context, userId uuid.UUID, provider string, tx pgx.Tx) error {
+	const op = "OAuth.Repository.DeleteIdentity"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Delete("user_identities").
+		Where(sq.Eq{"user_id": userId, "provider": provider}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	tag, err := tx.Exec(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return utils.ErrorIdentityNotFound
+	}
+	return nil
+}
+
+func scanUser(row pgx.Row) (*linkedUser, error) {
+	var u linkedUser
+	if err := row.Scan(&u.ID, &u.Email, &u.Name, &u.Role, &u.EmailVerified, &u.TwoFactorEnabled); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.ErrorUserNotFound
+		}
+		return nil, err
+	}
+	return &u, nil
+}
@@ -0,0 +1,324 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OAuthService interface {
+	GetUserByIdentity(ctx context.Context, provider, subject string) (*linkedUser, error)
+	GetUserByEmailForUpdate(ctx context.Context, email string, tx pgx.Tx) (*linkedUser, error)
+	GetUserForUpdate(ctx context.Context, userId uuid.UUID, tx pgx.Tx) (*linkedUser, error)
+	CreateIdentity(ctx context.Context, userId uuid.UUID, id *Identity, tx pgx.Tx) error
+	VerifyEmail(ctx context.Context, userId uuid.UUID, dropPassword bool, tx pgx.Tx) error
+	DropCredentials(ctx context.Context, userId uuid.UUID, tx pgx.Tx) ([]uuid.UUID, error)
+	CreateUser(ctx context.Context, email, title string, tx pgx.Tx) (*uuid.UUID, error)
+	GetIdentities(ctx context.Context, userId uuid.UUID) ([]models.Identity, error)
+	CountLoginMethods(ctx context.Context, userId uuid.UUID, tx pgx.Tx) (bool, int, error)
+	DeleteIdentity(ctx context.Context, userId uuid.UUID, provider string, tx pgx.Tx) error
+}
+
+// SessionRevoker cuts off the access tokens of sessions revoked when an
+// identity was linked to an account with an unverified email.
+type SessionRevoker interface {
+	Deny(ctx context.Context, ids ...uuid.UUID) error
+}
+
+type Service struct {
+	repo      OAuthService
+	primaryDB *pgxpool.Pool
+	providers *Registry
+	states    *StateStore
+	sessions  SessionRevoker
+	l         *slog.Logger
+}
+
+func NewService(repo OAuthService, primaryDB *pgxpool.Pool, providers *Registry, states *StateStore, sessions SessionRevoker, l *slog.Logger) *Service {
+	return &Service{
+		repo:      repo,
+		primaryDB: primaryDB,
+		providers: providers,
+		states:    states,
+		sessions:  sessions,
+		l:         l,
+	}
+}
+
+func (s *Service) Providers() []string {
+	return s.providers.Names()
+}
+
+// Start remembers a PKCE verifier and a nonce under a new state and returns
+// the consent URL of the provider together with the state. With linkUserId
+// the callback links the identity to that user instead of signing in.
+func (s *Service) Start(ctx context.Context, provider string, linkUserId *uuid.UUID) (string, string, error) {
+	const op = "OAuth.Service.Start"
+	log := s.l.With(slog.String("op", op), slog.String("provider", provider))
+
+	p, err := s.providers.Get(provider)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, challenge, err := NewPKCE()
+	if err != nil {
+		log.Error("pkce error", logger.Err(err))
+		return "", "", err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		log.Error("nonce error", logger.Err(err))
+		return "", "", err
+	}
+	state, err := s.states.Save(ctx, flow{
+		Provider:   provider,
+		Verifier:   verifier,
+		Nonce:      nonce,
+		LinkUserID: linkUserId,
+	})
+	if err != nil {
+		log.Error("save state error", logger.Err(err))
+		return "", "", err
+	}
+	url, err := p.AuthURL(ctx, state, challenge, nonce)
+	if err != nil {
+		log.Error("auth url error", logger.Err(err))
+		return "", "", err
+	}
+	return url, state, nil
+}
+
+// Callback finishes the flow started by Start. currentUserId is the signed
+// in user, a link flow must be finished by the user who started it. The
+// identity signs in the user it is linked to, otherwise it is linked to the
+// account with the same verified email or a new account is registered. The
+// returned flag tells that the flow only linked an identity.
+func (s *Service) Callback(ctx context.Context, state, code string, currentUserId *uuid.UUID) (*linkedUser, bool, error) {
+	const op = "OAuth.Service.Callback"
+	log := s.l.With(slog.String("op", op))
+
+	f, err := s.states.Take(ctx, state)
+	if err != nil {
+		return nil, false, err
+	}
+	if f.LinkUserID != nil && (currentUserId == nil || *currentUserId != *f.LinkUserID) {
+		return nil, false, ErrInvalidState
+	}
+	p, err := s.providers.Get(f.Provider)
+	if err != nil {
+		return nil, false, err
+	}
+	log = log.With(slog.String("provider", f.Provider))
+	token, err := p.Exchange(ctx, code, f.Verifier)
+	if err != nil {
+		log.Error("exchange code error", logger.Err(err))
+		return nil, false, err
+	}
+	id, err := p.Identity(ctx, token, f.Nonce)
+	if err != nil {
+		log.Error("identity error", logger.Err(err))
+		return nil, false, err
+	}
+
+	if f.LinkUserID != nil {
+		u, err := s.link(ctx, *f.LinkUserID, id)
+		return u, true, err
+	}
+	u, err := s.repo.GetUserByIdentity(ctx, id.Provider, id.Subject)
+	if err == nil {
+		return u, false, nil
+	}
+	if !errors.Is(err, utils.ErrorUserNotFound) {
+		return nil, false, err
+	}
+	if id.Email == "" || !id.EmailVerified {
+		return nil, false, ErrEmailNotVerified
+	}
+	u, err = s.linkOrRegister(ctx, id)
+	return u, false, err
+}
+
+// link attaches the identity to the signed in user.
+func (s *Service) link(ctx context.Context, userId uuid.UUID, id *Identity) (*linkedUser, error) {
+	const op = "OAuth.Service.link"
+	log := s.l.With(slog.String("op", op))
+
+	owner, err := s.repo.GetUserByIdentity(ctx, id.Provider, id.Subject)
+	switch {
+	case err == nil && owner.ID == userId:
+		return owner, nil
+	case err == nil:
+		return nil, utils.ErrorIdentityLinked
+	case !errors.Is(err, utils.ErrorUserNotFound):
+		return nil, err
+	}
+
+	conn, err := s.primaryDB.Acquire(ctx)
+	if err != nil {
+		log.Error("error init acquire", logger.Err(err))
+		return nil, err
+	}
+	defer conn.Release()
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		log.Error("error init tx", logger.Err(err))
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Error("rollback error", logger.Err(rollbackErr))
+			}
+		}
+	}()
+
+	var u *linkedUser
+	if u, err = s.repo.GetUserForUpdate(ctx, userId, tx); err != nil {
+		return nil, err
+	}
+	if err = s.repo.CreateIdentity(ctx, userId, id, tx); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Error("commit error", logger.Err(err))
+		return nil, err
+	}
+	log.Info("identity linked", slog.String("user_id", userId.String()), slog.String("provider", id.Provider))
+	return u, nil
+}
+
+func (s *Service) linkOrRegister(ctx context.Context, id *Identity) (*linkedUser, error) {
+	const op = "OAuth.Service.linkOrRegister"
+	log := s.l.With(slog.String("op", op))
+
+	conn, err := s.primaryDB.Acquire(ctx)
+	if err != nil {
+		log.Error("error init acquire", logger.Err(err))
+		return nil, err
+	}
+	defer conn.Release()
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		log.Error("error init tx", logger.Err(err))
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Error("rollback error", logger.Err(rollbackErr))
+			}
+		}
+	}()
+
+	email := strings.ToLower(id.Email)
+	u, err := s.repo.GetUserByEmailForUpdate(ctx, email, tx)
+	switch {
+	case err == nil:
+		// an unverified email could have been registered by anyone, the
+		// provider proved the mailbox is not theirs
+		dropPassword := !u.EmailVerified
+		var revoked []uuid.UUID
+		if dropPassword {
+			if revoked, err = s.repo.DropCredentials(ctx, u.ID, tx); err != nil {
+				return nil, err
+			}
+		}
+		if err = s.repo.CreateIdentity(ctx, u.ID, id, tx); err != nil {
+			return nil, err
+		}
+		if err = s.repo.VerifyEmail(ctx, u.ID, dropPassword, tx); err != nil {
+			return nil, err
+		}
+		if err = tx.Commit(ctx); err != nil {
+			log.Error("commit error", logger.Err(err))
+			return nil, err
+		}
+		if err := s.sessions.Deny(ctx, revoked...); err != nil {
+			log.Error("deny sessions error", logger.Err(err))
+		}
+		log.Info("identity linked", slog.String("user_id", u.ID.String()), slog.String("provider", id.Provider))
+		u.EmailVerified = true
+		return u, nil
+	case errors.Is(err, utils.ErrorUserNotFound):
+		title := id.Name
+		if title == "" {
+			title, _, _ = strings.Cut(email, "@")
+		}
+		var userId *uuid.UUID
+		if userId, err = s.repo.CreateUser(ctx, email, title, tx); err != nil {
+			return nil, err
+		}
+		if err = s.repo.CreateIdentity(ctx, *userId, id, tx); err != nil {
+			return nil, err
+		}
+		if err = tx.Commit(ctx); err != nil {
+			log.Error("commit error", logger.Err(err))
+			return nil, err
+		}
+		return &linkedUser{
+			ID:            *userId,
+			Email:         email,
+			Name:          title,
+			Role:          string(contextkey.RoleUser),
+			EmailVerified: true,
+		}, nil
+	default:
+		return nil, err
+	}
+}
+
+func (s *Service) Identities(ctx context.Context, userId uuid.UUID) ([]models.Identity, error) {
+	return s.repo.GetIdentities(ctx, userId)
+}
+
+// Unlink removes the identity of provider unless it is the only way left to
+// sign in.
+func (s *Service) Unlink(ctx context.Context, userId uuid.UUID, provider string) error {
+	const op = "OAuth.Service.Unlink"
+	log := s.l.With(slog.String("op", op))
+
+	conn, err := s.primaryDB.Acquire(ctx)
+	if err != nil {
+		log.Error("error init acquire", logger.Err(err))
+		return err
+	}
+	defer conn.Release()
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		log.Error("error init tx", logger.Err(err))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Error("rollback error", logger.Err(rollbackErr))
+			}
+		}
+	}()
+
+	hasPassword, identities, err := s.repo.CountLoginMethods(ctx, userId, tx)
+	if err != nil {
+		return err
+	}
+	if err = s.repo.DeleteIdentity(ctx, userId, provider, tx); err != nil {
+		return err
+	}
+	if !hasPassword && identities <= 1 {
+		err = utils.ErrorLastLoginMethod
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Error("commit error", logger.Err(err))
+		return err
+	}
+	return nil
+}
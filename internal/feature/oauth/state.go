@@ -0,0 +1,81 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrInvalidState = errors.New("invalid or expired oauth state")
+
+// flow is what we remember between the redirect to the provider and the
+// callback. LinkUserID is set when a signed in user links another provider.
+type flow struct {
+	Provider   string     `json:"provider"`
+	Verifier   string     `json:"verifier"`
+	Nonce      string     `json:"nonce"`
+	LinkUserID *uuid.UUID `json:"link_user_id,omitempty"`
+}
+
+// StateStore keeps flows server-side under the random state parameter. Take
+// returns a flow once.
+type StateStore struct {
+	rdb *redis.Client
+	ttl time.Duration
+}
+
+func NewStateStore(rdb *redis.Client, ttl time.Duration) *StateStore {
+	return &StateStore{rdb: rdb, ttl: ttl}
+}
+
+func (s *StateStore) TTL() time.Duration {
+	return s.ttl
+}
+
+func stateKey(state string) string {
+	return "oauth:state:" + state
+}
+
+func (s *StateStore) Save(ctx context.Context, f flow) (string, error) {
+	state, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		return "", err
+	}
+	if err := s.rdb.Set(ctx, stateKey(state), data, s.ttl).Err(); err != nil {
+		return "", err
+	}
+	return state, nil
+}
+
+func (s *StateStore) Take(ctx context.Context, state string) (*flow, error) {
+	data, err := s.rdb.GetDel(ctx, stateKey(state)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrInvalidState
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f flow
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
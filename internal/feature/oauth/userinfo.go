@@ -0,0 +1,96 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// userInfoIdentity reads the profile of a plain OAuth2 provider and maps it
+// with the configured field paths.
+func (p *Provider) userInfoIdentity(ctx context.Context, accessToken string) (*Identity, error) {
+	var info map[string]any
+	if err := p.getJSON(ctx, p.cfg.UserInfoURL, accessToken, &info); err != nil {
+		return nil, err
+	}
+	m := p.cfg.Mapping
+	id := &Identity{
+		Provider: p.name,
+		Subject:  jsonString(lookup(info, orDefault(m.Subject, "sub"))),
+		Email:    jsonString(lookup(info, orDefault(m.Email, "email"))),
+		Name:     jsonString(lookup(info, orDefault(m.Name, "name"))),
+	}
+	if id.Subject == "" {
+		return nil, errors.New("userinfo without subject")
+	}
+	switch v := lookup(info, orDefault(m.EmailVerified, "email_verified")).(type) {
+	case bool:
+		id.EmailVerified = v
+	case string:
+		id.EmailVerified = v == "true"
+	}
+	if m.TrustEmail && id.Email != "" {
+		id.EmailVerified = true
+	}
+	if p.cfg.EmailsURL != "" {
+		email, err := p.primaryEmail(ctx, accessToken)
+		if err != nil {
+			return nil, err
+		}
+		if email != "" {
+			id.Email, id.EmailVerified = email, true
+		}
+	}
+	return id, nil
+}
+
+// primaryEmail picks the verified primary address from a GitHub style list
+// of emails.
+func (p *Provider) primaryEmail(ctx context.Context, accessToken string) (string, error) {
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.getJSON(ctx, p.cfg.EmailsURL, accessToken, &emails); err != nil {
+		return "", err
+	}
+	for _, e := range emails {
+		if e.Primary && e.Verified {
+			return e.Email, nil
+		}
+	}
+	return "", nil
+}
+
+// lookup follows a dot separated path through nested objects.
+func lookup(v any, path string) any {
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = obj[key]
+	}
+	return v
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+// jsonString renders ids that some providers send as numbers.
+func jsonString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		return ""
+	}
+}
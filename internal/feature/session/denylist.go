@@ -0,0 +1,43 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Denylist remembers revoked sessions for as long as an access token issued
+// for them can still be valid, so revocation takes effect before the tokens
+// expire.
+type Denylist struct {
+	rdb *redis.Client
+	ttl time.Duration
+}
+
+func NewDenylist(rdb *redis.Client, accessTTL time.Duration) *Denylist {
+	return &Denylist{rdb: rdb, ttl: accessTTL}
+}
+
+func denyKey(id uuid.UUID) string {
+	return "session:revoked:" + id.String()
+}
+
+func (d *Denylist) Deny(ctx context.Context, ids ...uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := d.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, id := range ids {
+			pipe.Set(ctx, denyKey(id), 1, d.ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+func (d *Denylist) IsDenied(ctx context.Context, id uuid.UUID) (bool, error) {
+	n, err := d.rdb.Exists(ctx, denyKey(id)).Result()
+	return n > 0, err
+}
@@ -0,0 +1,52 @@
+package session
+
+import (
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/google/uuid"
+)
+
+type GetSessionsResponse struct {
+	api.Response
+	Sessions []models.Session `json:"sessions"`
+}
+
+// TokenSession is a refresh token locked together with its session.
+type TokenSession struct {
+	SessionID uuid.UUID
+	UserID    uuid.UUID
+	Role      string
+	UsedAt    *time.Time
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+// Rotation is what presenting a refresh token leads to.
+type Rotation int
+
+const (
+	// RotationIssue is the first use, the token is swapped for a new one.
+	RotationIssue Rotation = iota
+	// RotationGrace is a second use within ReuseGrace, a parallel request of
+	// the same browser gets a new access token only.
+	RotationGrace
+	// RotationReused is a later second use, the session is revoked.
+	RotationReused
+	// RotationInvalid is a token of a revoked or expired session.
+	RotationInvalid
+)
+
+// Rotation tells what presenting the token at now leads to.
+func (t *TokenSession) Rotation(now time.Time, grace time.Duration) Rotation {
+	switch {
+	case t.RevokedAt != nil || now.After(t.ExpiresAt):
+		return RotationInvalid
+	case t.UsedAt == nil:
+		return RotationIssue
+	case now.Sub(*t.UsedAt) <= grace:
+		return RotationGrace
+	}
+	return RotationReused
+}
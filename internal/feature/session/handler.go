@@ -0,0 +1,164 @@
+package session
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sanchir01/go-shortener/internal/handlers/customiddleware"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	service *Service
+	l       *slog.Logger
+}
+
+func NewHandler(service *Service, l *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		l:       l,
+	}
+}
+
+// @Summary  LogoutHandler
+// @Tags auth
+// @Description Revoke the current session and clear its cookies
+// @Produce json
+// @Success 200 {object}  api.Response
+// @Failure 401 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /auth/logout [post]
+func (h *Handler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Session.Handler.Logout"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	if err := h.service.Logout(r.Context(), w, claims); err != nil {
+		log.Error("failed to logout", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrLogout))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, api.OK())
+}
+
+// @Summary  LogoutAllHandler
+// @Tags auth
+// @Description Revoke every session of the user on all devices
+// @Produce json
+// @Success 200 {object}  api.Response
+// @Failure 401 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /auth/logout-all [post]
+func (h *Handler) LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Session.Handler.LogoutAll"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	n, err := h.service.LogoutAll(r.Context(), w, claims)
+	if err != nil {
+		log.Error("failed to logout everywhere", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrLogout))
+		return
+	}
+	log.Info("all sessions revoked", slog.Int64("count", n))
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, api.OK())
+}
+
+// @Summary  GetSessionsHandler
+// @Tags auth
+// @Description Active sessions of the user, the one making the request is marked current
+// @Produce json
+// @Success 200 {object}  GetSessionsResponse
+// @Failure 401 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /auth/sessions [get]
+func (h *Handler) GetSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Session.Handler.GetSessions"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	sessions, err := h.service.GetSessions(r.Context(), claims)
+	if err != nil {
+		log.Error("failed to get sessions", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrGetSessions))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, GetSessionsResponse{
+		Response: api.OK(),
+		Sessions: sessions,
+	})
+}
+
+// @Summary  RevokeSessionHandler
+// @Tags auth
+// @Description Sign out one device
+// @Produce json
+// @Param id path string true "session id"
+// @Success 200 {object}  api.Response
+// @Failure 400,401,404 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /auth/sessions/{id} [delete]
+func (h *Handler) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Session.Handler.RevokeSession"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidSessionID))
+		return
+	}
+	err = h.service.RevokeSession(r.Context(), claims.ID, id)
+	if errors.Is(err, utils.ErrorSessionNotFound) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	}
+	if err != nil {
+		log.Error("failed to revoke session", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrRevokeSession))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, api.OK())
+}
@@ -0,0 +1,270 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository struct {
+	primaryDB *pgxpool.Pool
+	l         *slog.Logger
+}
+
+func NewRepository(primaryDB *pgxpool.Pool, l *slog.Logger) *Repository {
+	return &Repository{
+		primaryDB: primaryDB,
+		l:         l,
+	}
+}
+
+func (r *Repository) CreateSession(ctx context.Context, userId uuid.UUID, userAgent, ip string, expiresAt time.Time, tx pgx.Tx) (*uuid.UUID, error) {
+	const op = "Session.Repository.CreateSession"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Insert("sessions").
+		Columns("user_id", "user_agent", "ip", "expires_at").
+		Values(userId, userAgent, ip, expiresAt).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	var id uuid.UUID
+	if err := tx.QueryRow(ctx, query, args...).Scan(&id); err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return &id, nil
+}
+
+func (r *Repository) AddToken(ctx context.Context, sessionId uuid.UUID, hash string, tx pgx.Tx) error {
+	const op = "Session.Repository.AddToken"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Insert("session_tokens").
+		Columns("hash", "session_id").
+		Values(hash, sessionId).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+// GetTokenForUpdate locks the refresh token and its session until the end of tx.
+func (r *Repository) GetTokenForUpdate(ctx context.Context, hash string, tx pgx.Tx) (*TokenSession, error) {
+	const op = "Session.Repository.GetTokenForUpdate"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select("s.id, s.user_id, u.role::text, t.used_at, s.expires_at, s.revoked_at").
+		From("session_tokens t").
+		Join("sessions s ON s.id = t.session_id").
+		Join("users u ON u.id = s.user_id").
+		Where(sq.Eq{"t.hash": hash}).
+		Suffix("FOR UPDATE OF t, s").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	var t TokenSession
+	if err := tx.QueryRow(ctx, query, args...).Scan(
+		&t.SessionID, &t.UserID, &t.Role, &t.UsedAt, &t.ExpiresAt, &t.RevokedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.ErrorInvalidSession
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *Repository) MarkTokenUsed(ctx context.Context, hash string, tx pgx.Tx) error {
+	const op = "Session.Repository.MarkTokenUsed"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Update("session_tokens").
+		Set("used_at", sq.Expr("now()")).
+		Where(sq.Eq{"hash": hash}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) TouchSession(ctx context.Context, id uuid.UUID, userAgent, ip string, expiresAt time.Time, tx pgx.Tx) error {
+	const op = "Session.Repository.TouchSession"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Update("sessions").
+		Set("user_agent", userAgent).
+		Set("ip", ip).
+		Set("last_used_at", sq.Expr("now()")).
+		Set("expires_at", expiresAt).
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+// RevokeSessions revokes the active sessions of the user and returns their
+// ids. A nil id revokes all of them.
+func (r *Repository) RevokeSessions(ctx context.Context, userId uuid.UUID, id *uuid.UUID) ([]uuid.UUID, error) {
+	const op = "Session.Repository.RevokeSessions"
+	log := r.l.With(slog.String("op", op))
+
+	builder := sq.
+		Update("sessions").
+		Set("revoked_at", sq.Expr("now()")).
+		Where(sq.Eq{"user_id": userId, "revoked_at": nil}).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar)
+	if id != nil {
+		builder = builder.Where(sq.Eq{"id": *id})
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+
+	rows, err := r.primaryDB.Query(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return ids, nil
+}
+
+// RevokeSessionTx revokes a session whose row is locked by tx.
+func (r *Repository) RevokeSessionTx(ctx context.Context, id uuid.UUID, tx pgx.Tx) error {
+	const op = "Session.Repository.RevokeSessionTx"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Update("sessions").
+		Set("revoked_at", sq.Expr("COALESCE(revoked_at, now())")).
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+// IsSessionRevoked reads the revocation state of a session from the
+// database. Unknown sessions count as revoked.
+func (r *Repository) IsSessionRevoked(ctx context.Context, id uuid.UUID) (bool, error) {
+	const op = "Session.Repository.IsSessionRevoked"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select("revoked_at IS NOT NULL").
+		From("sessions").
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return false, utils.ErrorQueryString
+	}
+	var revoked bool
+	if err := r.primaryDB.QueryRow(ctx, query, args...).Scan(&revoked); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return true, nil
+		}
+		log.Error("error", logger.Err(err))
+		return false, err
+	}
+	return revoked, nil
+}
+
+func (r *Repository) GetActiveSessions(ctx context.Context, userId uuid.UUID) ([]models.Session, error) {
+	const op = "Session.Repository.GetActiveSessions"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select("id, user_id, user_agent, ip, created_at, last_used_at, expires_at, revoked_at").
+		From("sessions").
+		Where(sq.Eq{"user_id": userId, "revoked_at": nil}).
+		Where("expires_at > now()").
+		OrderBy("last_used_at DESC").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	rows, err := r.primaryDB.Query(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]models.Session, 0, 4)
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt, &s.RevokedAt); err != nil {
+			log.Error("error", logger.Err(err))
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return sessions, nil
+}
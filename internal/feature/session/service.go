@@ -0,0 +1,308 @@
+package session
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/config"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/internal/feature/user"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SessionService interface {
+	CreateSession(ctx context.Context, userId uuid.UUID, userAgent, ip string, expiresAt time.Time, tx pgx.Tx) (*uuid.UUID, error)
+	AddToken(ctx context.Context, sessionId uuid.UUID, hash string, tx pgx.Tx) error
+	GetTokenForUpdate(ctx context.Context, hash string, tx pgx.Tx) (*TokenSession, error)
+	MarkTokenUsed(ctx context.Context, hash string, tx pgx.Tx) error
+	TouchSession(ctx context.Context, id uuid.UUID, userAgent, ip string, expiresAt time.Time, tx pgx.Tx) error
+	RevokeSessionTx(ctx context.Context, id uuid.UUID, tx pgx.Tx) error
+	RevokeSessions(ctx context.Context, userId uuid.UUID, id *uuid.UUID) ([]uuid.UUID, error)
+	GetActiveSessions(ctx context.Context, userId uuid.UUID) ([]models.Session, error)
+	IsSessionRevoked(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+// Revocations make revoked sessions known to the auth middleware before
+// their access tokens expire.
+type Revocations interface {
+	Deny(ctx context.Context, ids ...uuid.UUID) error
+	IsDenied(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+type Service struct {
+	repo      SessionService
+	primaryDB *pgxpool.Pool
+	tokens    user.TokenSigner
+	revoked   Revocations
+	cfg       config.Sessions
+	domain    string
+	l         *slog.Logger
+}
+
+func NewService(repo SessionService, primaryDB *pgxpool.Pool, tokens user.TokenSigner, revoked Revocations, cfg config.Sessions, domain string, l *slog.Logger) *Service {
+	return &Service{
+		repo:      repo,
+		primaryDB: primaryDB,
+		tokens:    tokens,
+		revoked:   revoked,
+		cfg:       cfg,
+		domain:    domain,
+		l:         l,
+	}
+}
+
+// Issue starts a new session for the user and sets its cookies.
+func (s *Service) Issue(w http.ResponseWriter, r *http.Request, userId uuid.UUID, role string) error {
+	const op = "Session.Service.Issue"
+	ctx := r.Context()
+	log := s.l.With(slog.String("op", op))
+
+	refresh, hash, err := newRefreshToken()
+	if err != nil {
+		log.Error("generate refresh token error", logger.Err(err))
+		return err
+	}
+	conn, err := s.primaryDB.Acquire(ctx)
+	if err != nil {
+		log.Error("error init acquire", logger.Err(err))
+		return err
+	}
+	defer conn.Release()
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		log.Error("error init tx", logger.Err(err))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Error("rollback error", logger.Err(rollbackErr))
+			}
+		}
+	}()
+
+	refreshExpire := time.Now().Add(s.cfg.RefreshTTL)
+	id, err := s.repo.CreateSession(ctx, userId, r.UserAgent(), r.RemoteAddr, refreshExpire, tx)
+	if err != nil {
+		return err
+	}
+	if err = s.repo.AddToken(ctx, *id, hash, tx); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Error("commit error", logger.Err(err))
+		return err
+	}
+	return s.setCookies(w, userId, *id, role, refresh, refreshExpire)
+}
+
+// Refresh rotates the refresh token of a session and issues a new access
+// token. A token that was already rotated is a sign of theft, the session is
+// revoked unless the second use comes within the grace period, which covers
+// parallel requests of the same browser.
+func (s *Service) Refresh(w http.ResponseWriter, r *http.Request, refreshToken string) (*user.Claims, error) {
+	const op = "Session.Service.Refresh"
+	ctx := r.Context()
+	log := s.l.With(slog.String("op", op))
+
+	conn, err := s.primaryDB.Acquire(ctx)
+	if err != nil {
+		log.Error("error init acquire", logger.Err(err))
+		return nil, err
+	}
+	defer conn.Release()
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		log.Error("error init tx", logger.Err(err))
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Error("rollback error", logger.Err(rollbackErr))
+			}
+		}
+	}()
+
+	hash := hashToken(refreshToken)
+	t, err := s.repo.GetTokenForUpdate(ctx, hash, tx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	claims := &user.Claims{ID: t.UserID, Role: t.Role, SessionID: t.SessionID}
+
+	switch t.Rotation(now, s.cfg.ReuseGrace) {
+	case RotationInvalid:
+		err = utils.ErrorInvalidSession
+		return nil, err
+	case RotationGrace:
+		if err = tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+		if err = s.setAccessCookie(w, claims); err != nil {
+			return nil, err
+		}
+		return claims, nil
+	case RotationReused:
+		if err = s.repo.RevokeSessionTx(ctx, t.SessionID, tx); err != nil {
+			return nil, err
+		}
+		if err = tx.Commit(ctx); err != nil {
+			log.Error("commit error", logger.Err(err))
+			return nil, err
+		}
+		if denyErr := s.Deny(ctx, t.SessionID); denyErr != nil {
+			log.Error("deny session error", logger.Err(denyErr))
+		}
+		log.Warn("refresh token reused, session revoked",
+			slog.String("session_id", t.SessionID.String()),
+			slog.String("user_id", t.UserID.String()),
+			slog.String("ip", r.RemoteAddr),
+		)
+		return nil, utils.ErrorSessionReused
+	}
+
+	refresh, newHash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	refreshExpire := now.Add(s.cfg.RefreshTTL)
+	if err = s.repo.MarkTokenUsed(ctx, hash, tx); err != nil {
+		return nil, err
+	}
+	if err = s.repo.AddToken(ctx, t.SessionID, newHash, tx); err != nil {
+		return nil, err
+	}
+	if err = s.repo.TouchSession(ctx, t.SessionID, r.UserAgent(), r.RemoteAddr, refreshExpire, tx); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Error("commit error", logger.Err(err))
+		return nil, err
+	}
+	if err = s.setCookies(w, t.UserID, t.SessionID, t.Role, refresh, refreshExpire); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Logout revokes the current session and clears its cookies.
+func (s *Service) Logout(ctx context.Context, w http.ResponseWriter, claims *user.Claims) error {
+	const op = "Session.Service.Logout"
+	log := s.l.With(slog.String("op", op))
+
+	user.ClearAuthCookies(w, s.domain)
+	if claims.SessionID == uuid.Nil {
+		return nil
+	}
+	ids, err := s.repo.RevokeSessions(ctx, claims.ID, &claims.SessionID)
+	if err != nil {
+		log.Error("revoke session error", logger.Err(err))
+		return err
+	}
+	return s.Deny(ctx, ids...)
+}
+
+// LogoutAll revokes every session of the user, this device included.
+func (s *Service) LogoutAll(ctx context.Context, w http.ResponseWriter, claims *user.Claims) (int64, error) {
+	const op = "Session.Service.LogoutAll"
+	log := s.l.With(slog.String("op", op))
+
+	user.ClearAuthCookies(w, s.domain)
+	ids, err := s.repo.RevokeSessions(ctx, claims.ID, nil)
+	if err != nil {
+		log.Error("revoke sessions error", logger.Err(err))
+		return 0, err
+	}
+	return int64(len(ids)), s.Deny(ctx, ids...)
+}
+
+func (s *Service) RevokeSession(ctx context.Context, userId, id uuid.UUID) error {
+	const op = "Session.Service.RevokeSession"
+	log := s.l.With(slog.String("op", op))
+
+	ids, err := s.repo.RevokeSessions(ctx, userId, &id)
+	if err != nil {
+		log.Error("revoke session error", logger.Err(err))
+		return err
+	}
+	if len(ids) == 0 {
+		return utils.ErrorSessionNotFound
+	}
+	return s.Deny(ctx, ids...)
+}
+
+// Deny cuts off the access tokens of sessions already revoked in the
+// database. Callers revoking sessions in their own transaction call it after
+// the commit.
+func (s *Service) Deny(ctx context.Context, ids ...uuid.UUID) error {
+	const op = "Session.Service.Deny"
+	log := s.l.With(slog.String("op", op))
+
+	if err := s.revoked.Deny(ctx, ids...); err != nil {
+		log.Error("deny sessions error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+// IsRevoked reports whether the session of an access token was revoked. The
+// database is asked when the denylist can not answer.
+func (s *Service) IsRevoked(ctx context.Context, id uuid.UUID) (bool, error) {
+	const op = "Session.Service.IsRevoked"
+	log := s.l.With(slog.String("op", op))
+
+	revoked, err := s.revoked.IsDenied(ctx, id)
+	if err == nil {
+		return revoked, nil
+	}
+	log.Warn("denylist lookup error, checking the database", logger.Err(err))
+	revoked, err = s.repo.IsSessionRevoked(ctx, id)
+	if err != nil {
+		log.Error("session lookup error", logger.Err(err))
+		return false, err
+	}
+	return revoked, nil
+}
+
+func (s *Service) GetSessions(ctx context.Context, claims *user.Claims) ([]models.Session, error) {
+	const op = "Session.Service.GetSessions"
+	log := s.l.With(slog.String("op", op))
+
+	sessions, err := s.repo.GetActiveSessions(ctx, claims.ID)
+	if err != nil {
+		log.Error("get sessions error", logger.Err(err))
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == claims.SessionID
+	}
+	return sessions, nil
+}
+
+func (s *Service) setCookies(w http.ResponseWriter, userId, sessionId uuid.UUID, role, refresh string, refreshExpire time.Time) error {
+	accessExpire := time.Now().Add(s.cfg.AccessTTL)
+	access, err := user.GenerateJwtToken(s.tokens, userId, sessionId, role, accessExpire)
+	if err != nil {
+		return err
+	}
+	user.SetAuthCookies(w, access, accessExpire, refresh, refreshExpire, s.domain)
+	return nil
+}
+
+func (s *Service) setAccessCookie(w http.ResponseWriter, claims *user.Claims) error {
+	accessExpire := time.Now().Add(s.cfg.AccessTTL)
+	access, err := user.GenerateJwtToken(s.tokens, claims.ID, claims.SessionID, claims.Role, accessExpire)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, user.GenerateCookie("accessToken", accessExpire, false, access, s.domain))
+	return nil
+}
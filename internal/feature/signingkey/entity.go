@@ -0,0 +1,35 @@
+package signingkey
+
+import "time"
+
+// StoredKey is a signing key as kept in the database, PKCS#8 encoded and
+// sealed by a Sealer once it left GenerateKey.
+type StoredKey struct {
+	ID         string
+	Algorithm  string
+	PrivateKey []byte
+	CreatedAt  time.Time
+	RetiresAt  *time.Time
+}
+
+// RotationDue reports whether the key must be replaced, because it is older
+// than every or the configured algorithm changed.
+func (k *StoredKey) RotationDue(algorithm string, every time.Duration, now time.Time) bool {
+	return k.Algorithm != algorithm || now.Sub(k.CreatedAt) >= every
+}
+
+// JWK is the public part of a signing key as described in RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
@@ -0,0 +1,32 @@
+package signingkey
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+)
+
+type Handler struct {
+	manager *Manager
+	l       *slog.Logger
+}
+
+func NewHandler(manager *Manager, l *slog.Logger) *Handler {
+	return &Handler{
+		manager: manager,
+		l:       l,
+	}
+}
+
+// @Summary  JWKSHandler
+// @Tags auth
+// @Description Public keys that verify access tokens, other services pick the key by the kid header of the token
+// @Produce json
+// @Success 200 {object}  JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *Handler) JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, h.manager.JWKS())
+}
@@ -0,0 +1,128 @@
+package signingkey
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+
+	rsaKeyBits = 2048
+)
+
+var Algorithms = []string{AlgorithmRS256, AlgorithmEdDSA}
+
+var errUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+
+type Key struct {
+	ID        string
+	Algorithm string
+	CreatedAt time.Time
+	RetiresAt *time.Time
+	private   crypto.Signer
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+func (k *Key) public() crypto.PublicKey {
+	return k.private.Public()
+}
+
+// retired reports whether the grace period of a replaced key is over.
+func (k *Key) retired(now time.Time) bool {
+	return k.RetiresAt != nil && !now.Before(*k.RetiresAt)
+}
+
+func (k *Key) jwk() JWK {
+	key := JWK{Kid: k.ID, Use: "sig", Alg: k.Algorithm}
+	switch pub := k.public().(type) {
+	case ed25519.PublicKey:
+		key.Kty = "OKP"
+		key.Crv = "Ed25519"
+		key.X = base64.RawURLEncoding.EncodeToString(pub)
+	case *rsa.PublicKey:
+		key.Kty = "RSA"
+		key.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		key.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	}
+	return key
+}
+
+// GenerateKey creates a key for algorithm with a random key id.
+func GenerateKey(algorithm string) (*StoredKey, error) {
+	var private crypto.Signer
+	switch algorithm {
+	case AlgorithmEdDSA:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		private = priv
+	case AlgorithmRS256:
+		priv, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, err
+		}
+		private = priv
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedAlgorithm, algorithm)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	return &StoredKey{
+		ID:         hex.EncodeToString(id),
+		Algorithm:  algorithm,
+		PrivateKey: der,
+	}, nil
+}
+
+func parseKey(stored StoredKey) (*Key, error) {
+	parsed, err := x509.ParsePKCS8PrivateKey(stored.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errUnsupportedAlgorithm
+	}
+	switch private.(type) {
+	case ed25519.PrivateKey:
+		if stored.Algorithm != AlgorithmEdDSA {
+			return nil, fmt.Errorf("%w: %s key is ed25519", errUnsupportedAlgorithm, stored.Algorithm)
+		}
+	case *rsa.PrivateKey:
+		if stored.Algorithm != AlgorithmRS256 {
+			return nil, fmt.Errorf("%w: %s key is rsa", errUnsupportedAlgorithm, stored.Algorithm)
+		}
+	default:
+		return nil, errUnsupportedAlgorithm
+	}
+	return &Key{
+		ID:        stored.ID,
+		Algorithm: stored.Algorithm,
+		CreatedAt: stored.CreatedAt,
+		RetiresAt: stored.RetiresAt,
+		private:   private,
+	}, nil
+}
@@ -0,0 +1,252 @@
+package signingkey
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/config"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrNoSigningKey = errors.New("no signing key loaded")
+	ErrUnknownKey   = errors.New("token signed with unknown key")
+)
+
+type SigningKeyService interface {
+	LockKeys(ctx context.Context, tx pgx.Tx) error
+	GetNewestKey(ctx context.Context, tx pgx.Tx) (*StoredKey, error)
+	CreateKey(ctx context.Context, key *StoredKey, tx pgx.Tx) error
+	RetireKeys(ctx context.Context, exceptId string, retiresAt time.Time, tx pgx.Tx) error
+	GetKeys(ctx context.Context) ([]StoredKey, error)
+}
+
+// Manager signs and verifies access tokens. Keys live in the database so
+// every instance uses the same set, each instance reloads them every
+// ReloadInterval and the one that notices the current key is older than
+// RotateEvery creates the next one. Private keys are stored sealed.
+type Manager struct {
+	repo      SigningKeyService
+	primaryDB *pgxpool.Pool
+	sealer    *Sealer
+	cfg       config.Jwt
+	l         *slog.Logger
+
+	mu   sync.RWMutex
+	keys []*Key
+}
+
+func NewManager(repo SigningKeyService, primaryDB *pgxpool.Pool, sealer *Sealer, cfg config.Jwt, l *slog.Logger) *Manager {
+	return &Manager{
+		repo:      repo,
+		primaryDB: primaryDB,
+		sealer:    sealer,
+		cfg:       cfg,
+		l:         l,
+	}
+}
+
+// Run reloads the keys until ctx is done. Load must have succeeded once
+// before, a failed reload keeps the keys already loaded.
+func (m *Manager) Run(ctx context.Context) {
+	const op = "SigningKey.Manager.Run"
+	log := m.l.With(slog.String("op", op))
+
+	ticker := time.NewTicker(m.cfg.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Load(ctx); err != nil {
+				log.Error("reload signing keys error", logger.Err(err))
+			}
+		}
+	}
+}
+
+// Load rotates the current key when it is due and reads the keys that still
+// verify tokens.
+func (m *Manager) Load(ctx context.Context) error {
+	if err := m.rotate(ctx); err != nil {
+		return err
+	}
+	return m.Reload(ctx)
+}
+
+// Reload reads the keys that still verify tokens without rotating.
+func (m *Manager) Reload(ctx context.Context) error {
+	const op = "SigningKey.Manager.Reload"
+	log := m.l.With(slog.String("op", op))
+
+	stored, err := m.repo.GetKeys(ctx)
+	if err != nil {
+		return err
+	}
+	keys := make([]*Key, 0, len(stored))
+	for _, s := range stored {
+		opened, err := m.sealer.Open(s)
+		if err != nil {
+			log.Error("skip undecryptable signing key", slog.String("kid", s.ID), logger.Err(err))
+			continue
+		}
+		key, err := parseKey(opened)
+		if err != nil {
+			log.Error("skip broken signing key", slog.String("kid", s.ID), logger.Err(err))
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return ErrNoSigningKey
+	}
+	m.mu.Lock()
+	m.keys = keys
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Manager) rotate(ctx context.Context) error {
+	const op = "SigningKey.Manager.rotate"
+	log := m.l.With(slog.String("op", op))
+
+	conn, err := m.primaryDB.Acquire(ctx)
+	if err != nil {
+		log.Error("error init acquire", logger.Err(err))
+		return err
+	}
+	defer conn.Release()
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		log.Error("error init tx", logger.Err(err))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Error("rollback error", logger.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = m.repo.LockKeys(ctx, tx); err != nil {
+		return err
+	}
+	current, err := m.repo.GetNewestKey(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if current != nil && !current.RotationDue(m.cfg.Algorithm, m.cfg.RotateEvery, time.Now()) {
+		return tx.Commit(ctx)
+	}
+
+	next, err := GenerateKey(m.cfg.Algorithm)
+	if err != nil {
+		log.Error("generate signing key error", logger.Err(err))
+		return err
+	}
+	sealed, err := m.sealer.Seal(*next)
+	if err != nil {
+		log.Error("seal signing key error", logger.Err(err))
+		return err
+	}
+	if err = m.repo.CreateKey(ctx, &sealed, tx); err != nil {
+		return err
+	}
+	if err = m.repo.RetireKeys(ctx, next.ID, time.Now().Add(m.cfg.GracePeriod), tx); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Error("commit error", logger.Err(err))
+		return err
+	}
+	log.Info("signing key rotated", slog.String("kid", next.ID), slog.String("algorithm", next.Algorithm))
+	return nil
+}
+
+// signingKey is the newest key that every instance has had the chance to
+// load, so tokens it signs verify everywhere. Right after the first start
+// that is simply the newest key.
+func (m *Manager) signingKey() (*Key, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.keys) == 0 {
+		return nil, ErrNoSigningKey
+	}
+	published := time.Now().Add(-m.cfg.ReloadInterval)
+	for _, key := range m.keys {
+		if !key.CreatedAt.After(published) {
+			return key, nil
+		}
+	}
+	return m.keys[0], nil
+}
+
+// Sign returns the token for claims signed with the current key, the key id
+// is sent in the kid header.
+func (m *Manager) Sign(claims jwt.Claims) (string, error) {
+	key, err := m.signingKey()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.private)
+}
+
+// Parse verifies the token with the key named in its kid header and fills
+// claims. Keys past their grace period are rejected.
+func (m *Manager) Parse(tokenString string, claims jwt.Claims) error {
+	token, err := jwt.ParseWithClaims(tokenString, claims, m.keyFunc,
+		jwt.WithValidMethods(Algorithms),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return jwt.ErrTokenSignatureInvalid
+	}
+	return nil
+}
+
+func (m *Manager) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, key := range m.keys {
+		if key.ID != kid {
+			continue
+		}
+		if key.retired(time.Now()) || token.Method.Alg() != key.Algorithm {
+			return nil, ErrUnknownKey
+		}
+		return key.public(), nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// JWKS returns the public keys that verify tokens right now.
+func (m *Manager) JWKS() JWKS {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	set := JWKS{Keys: make([]JWK, 0, len(m.keys))}
+	for _, key := range m.keys {
+		if key.retired(now) {
+			continue
+		}
+		set.Keys = append(set.Keys, key.jwk())
+	}
+	return set
+}
@@ -0,0 +1,166 @@
+package signingkey
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository struct {
+	primaryDB *pgxpool.Pool
+	l         *slog.Logger
+}
+
+func NewRepository(primaryDB *pgxpool.Pool, l *slog.Logger) *Repository {
+	return &Repository{
+		primaryDB: primaryDB,
+		l:         l,
+	}
+}
+
+// LockKeys serializes rotations of all instances until the end of tx.
+func (r *Repository) LockKeys(ctx context.Context, tx pgx.Tx) error {
+	const op = "SigningKey.Repository.LockKeys"
+	log := r.l.With(slog.String("op", op))
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('signing_keys'))"); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+// GetNewestKey returns the key created last or nil when there is none yet.
+func (r *Repository) GetNewestKey(ctx context.Context, tx pgx.Tx) (*StoredKey, error) {
+	const op = "SigningKey.Repository.GetNewestKey"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select("kid, algorithm, private_key, created_at, retires_at").
+		From("signing_keys").
+		OrderBy("created_at DESC").
+		Limit(1).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	var key StoredKey
+	if err := tx.QueryRow(ctx, query, args...).Scan(
+		&key.ID, &key.Algorithm, &key.PrivateKey, &key.CreatedAt, &key.RetiresAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *Repository) CreateKey(ctx context.Context, key *StoredKey, tx pgx.Tx) error {
+	const op = "SigningKey.Repository.CreateKey"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Insert("signing_keys").
+		Columns("kid", "algorithm", "private_key").
+		Values(key.ID, key.Algorithm, key.PrivateKey).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+// RetireKeys starts the grace period of every key except the new one.
+func (r *Repository) RetireKeys(ctx context.Context, exceptId string, retiresAt time.Time, tx pgx.Tx) error {
+	const op = "SigningKey.Repository.RetireKeys"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Update("signing_keys").
+		Set("retires_at", retiresAt).
+		Where(sq.And{
+			sq.NotEq{"kid": exceptId},
+			sq.Eq{"retires_at": nil},
+		}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+// GetKeys returns the keys that still verify tokens, newest first. Keys past
+// their grace period are deleted.
+func (r *Repository) GetKeys(ctx context.Context) ([]StoredKey, error) {
+	const op = "SigningKey.Repository.GetKeys"
+	log := r.l.With(slog.String("op", op))
+
+	deleteQuery, deleteArgs, err := sq.
+		Delete("signing_keys").
+		Where("retires_at <= now()").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	if _, err := r.primaryDB.Exec(ctx, deleteQuery, deleteArgs...); err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+
+	query, args, err := sq.
+		Select("kid, algorithm, private_key, created_at, retires_at").
+		From("signing_keys").
+		OrderBy("created_at DESC").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	rows, err := r.primaryDB.Query(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]StoredKey, 0)
+	for rows.Next() {
+		var key StoredKey
+		if err := rows.Scan(&key.ID, &key.Algorithm, &key.PrivateKey, &key.CreatedAt, &key.RetiresAt); err != nil {
+			log.Error("error", logger.Err(err))
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return keys, nil
+}
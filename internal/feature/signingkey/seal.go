@@ -0,0 +1,66 @@
+package signingkey
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+)
+
+var (
+	ErrNoEncryptionKey  = errors.New("signing key encryption key is not set")
+	errEncryptionKeyLen = errors.New("signing key encryption key must be 32 bytes, base64 encoded")
+	errSealedKey        = errors.New("sealed signing key is malformed")
+)
+
+// Sealer encrypts private keys with AES-256-GCM before they reach the
+// database. The key id is authenticated along, so a sealed key can not be
+// moved to another row.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// NewSealer takes the base64 encoded 32 byte key from the environment.
+func NewSealer(encoded string) (*Sealer, error) {
+	if encoded == "" {
+		return nil, ErrNoEncryptionKey
+	}
+	secret, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(secret) != 32 {
+		return nil, errEncryptionKeyLen
+	}
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{aead: aead}, nil
+}
+
+// Seal returns key with its private key encrypted, the nonce in front.
+func (s *Sealer) Seal(key StoredKey) (StoredKey, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return StoredKey{}, err
+	}
+	key.PrivateKey = s.aead.Seal(nonce, nonce, key.PrivateKey, []byte(key.ID))
+	return key, nil
+}
+
+// Open reverses Seal.
+func (s *Sealer) Open(key StoredKey) (StoredKey, error) {
+	size := s.aead.NonceSize()
+	if len(key.PrivateKey) < size {
+		return StoredKey{}, errSealedKey
+	}
+	plain, err := s.aead.Open(nil, key.PrivateKey[:size], key.PrivateKey[size:], []byte(key.ID))
+	if err != nil {
+		return StoredKey{}, err
+	}
+	key.PrivateKey = plain
+	return key, nil
+}
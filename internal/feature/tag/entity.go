@@ -0,0 +1,20 @@
+package tag
+
+import (
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+)
+
+type TagRequest struct {
+	Title string `json:"title" validate:"required,min=1,max=64"`
+}
+
+type TagResponse struct {
+	api.Response
+	Tag models.Tag `json:"tag"`
+}
+
+type GetAllTagsResponse struct {
+	api.Response
+	Tags []models.Tag `json:"tags"`
+}
@@ -0,0 +1,223 @@
+package tag
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sanchir01/go-shortener/internal/handlers/customiddleware"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	service *Service
+	l       *slog.Logger
+}
+
+func NewHandler(service *Service, l *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		l:       l,
+	}
+}
+
+// @Summary  CreateTagHandler
+// @Tags tag
+// @Description Create tag
+// @Accept json
+// @Produce json
+// @Param input body TagRequest true "tag body"
+// @Success 201 {object}  TagResponse
+// @Failure 400,409 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /tag [post]
+func (h *Handler) CreateTagHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Tag.Handler.CreateTag"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	var req TagRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		log.Error("failed to decode request body", slog.Any("err", err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidBody))
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		log.Error("invalid request", logger.Err(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidRequest))
+		return
+	}
+	tag, err := h.service.CreateTag(r.Context(), claims.ID, req.Title)
+	if errors.Is(err, utils.ErrorTagAlreadyExists) {
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	}
+	if err != nil {
+		log.Error("failed to create tag", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrCreateTag))
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, TagResponse{
+		Response: api.OK(),
+		Tag:      *tag,
+	})
+}
+
+// @Summary  GetTagsHandler
+// @Tags tag
+// @Description Get all tags of the user
+// @Accept json
+// @Produce json
+// @Success 200 {object}  GetAllTagsResponse
+// @Failure 401 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /tag [get]
+func (h *Handler) GetTagsHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Tag.Handler.GetTags"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	tags, err := h.service.GetTags(r.Context(), claims.ID)
+	if err != nil {
+		log.Error("failed to get tags", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrGetTags))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, GetAllTagsResponse{
+		Response: api.OK(),
+		Tags:     tags,
+	})
+}
+
+// @Summary  UpdateTagHandler
+// @Tags tag
+// @Description Rename tag
+// @Accept json
+// @Produce json
+// @Param id path string true "tag id"
+// @Param input body TagRequest true "tag body"
+// @Success 200 {object}  TagResponse
+// @Failure 400,404,409 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /tag/{id} [patch]
+func (h *Handler) UpdateTagHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Tag.Handler.UpdateTag"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidTagID))
+		return
+	}
+	var req TagRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		log.Error("failed to decode request body", slog.Any("err", err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidBody))
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		log.Error("invalid request", logger.Err(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidRequest))
+		return
+	}
+	tag, err := h.service.UpdateTag(r.Context(), id, claims.ID, req.Title)
+	switch {
+	case errors.Is(err, utils.ErrorTagNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	case errors.Is(err, utils.ErrorTagAlreadyExists):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	case err != nil:
+		log.Error("failed to update tag", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrUpdateTag))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, TagResponse{
+		Response: api.OK(),
+		Tag:      *tag,
+	})
+}
+
+// @Summary  DeleteTagHandler
+// @Tags tag
+// @Description Delete tag, links keep existing without it
+// @Produce json
+// @Param id path string true "tag id"
+// @Success 200 {object}  api.Response
+// @Failure 400,404 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /tag/{id} [delete]
+func (h *Handler) DeleteTagHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Tag.Handler.DeleteTag"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidTagID))
+		return
+	}
+	err = h.service.DeleteTag(r.Context(), id, claims.ID)
+	if errors.Is(err, utils.ErrorTagNotFound) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	}
+	if err != nil {
+		log.Error("failed to delete tag", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrDeleteTag))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, api.OK())
+}
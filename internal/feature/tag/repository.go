@@ -0,0 +1,154 @@
+package tag
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository struct {
+	primaryDB *pgxpool.Pool
+	l         *slog.Logger
+}
+
+func NewRepository(primaryDB *pgxpool.Pool, l *slog.Logger) *Repository {
+	return &Repository{
+		primaryDB: primaryDB,
+		l:         l,
+	}
+}
+
+func (r *Repository) CreateTag(ctx context.Context, userId uuid.UUID, title string) (*models.Tag, error) {
+	const op = "Tag.Repository.CreateTag"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Insert("tags").
+		Columns("user_id", "title").
+		Values(userId, title).
+		Suffix("RETURNING id, user_id, title, created_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+
+	var tag models.Tag
+	if err := r.primaryDB.QueryRow(ctx, query, args...).Scan(&tag.ID, &tag.UserID, &tag.Title, &tag.CreatedAt); err != nil {
+		if isUniqueViolation(err) {
+			return nil, utils.ErrorTagAlreadyExists
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (r *Repository) GetTagsByUserId(ctx context.Context, userId uuid.UUID) ([]models.Tag, error) {
+	const op = "Tag.Repository.GetTagsByUserId"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select("id, user_id, title, created_at").
+		From("tags").
+		Where(sq.Eq{"user_id": userId}).
+		OrderBy("title").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+
+	rows, err := r.primaryDB.Query(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]models.Tag, 0, 16)
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.ID, &tag.UserID, &tag.Title, &tag.CreatedAt); err != nil {
+			log.Error("error", logger.Err(err))
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *Repository) UpdateTag(ctx context.Context, id, userId uuid.UUID, title string) (*models.Tag, error) {
+	const op = "Tag.Repository.UpdateTag"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Update("tags").
+		Set("title", title).
+		Where(sq.Eq{"id": id, "user_id": userId}).
+		Suffix("RETURNING id, user_id, title, created_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+
+	var tag models.Tag
+	if err := r.primaryDB.QueryRow(ctx, query, args...).Scan(&tag.ID, &tag.UserID, &tag.Title, &tag.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.ErrorTagNotFound
+		}
+		if isUniqueViolation(err) {
+			return nil, utils.ErrorTagAlreadyExists
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (r *Repository) DeleteTag(ctx context.Context, id, userId uuid.UUID) error {
+	const op = "Tag.Repository.DeleteTag"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Delete("tags").
+		Where(sq.Eq{"id": id, "user_id": userId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+
+	tag, err := r.primaryDB.Exec(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return utils.ErrorTagNotFound
+	}
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,77 @@
+package tag
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/google/uuid"
+)
+
+type TagService interface {
+	CreateTag(ctx context.Context, userId uuid.UUID, title string) (*models.Tag, error)
+	GetTagsByUserId(ctx context.Context, userId uuid.UUID) ([]models.Tag, error)
+	UpdateTag(ctx context.Context, id, userId uuid.UUID, title string) (*models.Tag, error)
+	DeleteTag(ctx context.Context, id, userId uuid.UUID) error
+}
+
+type Service struct {
+	repo TagService
+	l    *slog.Logger
+}
+
+func NewService(repo TagService, l *slog.Logger) *Service {
+	return &Service{
+		repo: repo,
+		l:    l,
+	}
+}
+
+func (s *Service) CreateTag(ctx context.Context, userId uuid.UUID, title string) (*models.Tag, error) {
+	const op = "Tag.Service.CreateTag"
+	log := s.l.With(slog.String("op", op))
+
+	tag, err := s.repo.CreateTag(ctx, userId, strings.TrimSpace(title))
+	if err != nil {
+		log.Error("create tag error", logger.Err(err))
+		return nil, err
+	}
+	return tag, nil
+}
+
+func (s *Service) GetTags(ctx context.Context, userId uuid.UUID) ([]models.Tag, error) {
+	const op = "Tag.Service.GetTags"
+	log := s.l.With(slog.String("op", op))
+
+	tags, err := s.repo.GetTagsByUserId(ctx, userId)
+	if err != nil {
+		log.Error("get tags error", logger.Err(err))
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (s *Service) UpdateTag(ctx context.Context, id, userId uuid.UUID, title string) (*models.Tag, error) {
+	const op = "Tag.Service.UpdateTag"
+	log := s.l.With(slog.String("op", op))
+
+	tag, err := s.repo.UpdateTag(ctx, id, userId, strings.TrimSpace(title))
+	if err != nil {
+		log.Error("update tag error", logger.Err(err))
+		return nil, err
+	}
+	return tag, nil
+}
+
+func (s *Service) DeleteTag(ctx context.Context, id, userId uuid.UUID) error {
+	const op = "Tag.Service.DeleteTag"
+	log := s.l.With(slog.String("op", op))
+
+	if err := s.repo.DeleteTag(ctx, id, userId); err != nil {
+		log.Error("delete tag error", logger.Err(err))
+		return err
+	}
+	return nil
+}
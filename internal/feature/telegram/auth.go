@@ -0,0 +1,135 @@
+package telegram
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// authClockSkew tolerates auth_date slightly ahead of our clock.
+const authClockSkew = time.Minute
+
+var ErrInvalidAuthData = errors.New("invalid telegram auth data")
+
+// TGUser is the Telegram account vouched for by the login data.
+type TGUser struct {
+	ID        int64  `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Username  string `json:"username"`
+	PhotoURL  string `json:"photo_url"`
+}
+
+// Title names a new account after the Telegram username or full name.
+func (u TGUser) Title() string {
+	if u.Username != "" {
+		return u.Username
+	}
+	if title := strings.TrimSpace(u.FirstName + " " + u.LastName); title != "" {
+		return title
+	}
+	return "user"
+}
+
+// Verifier checks data signed by Telegram with the bot token: the Login
+// Widget callback and the initData of a Mini App.
+type Verifier struct {
+	botToken string
+	maxAge   time.Duration
+}
+
+func NewVerifier(botToken string, maxAge time.Duration) *Verifier {
+	return &Verifier{botToken: botToken, maxAge: maxAge}
+}
+
+// VerifyWidget checks the fields the Login Widget passes to its callback.
+// The secret is SHA256 of the bot token.
+func (v *Verifier) VerifyWidget(fields map[string]string) (*TGUser, error) {
+	secret := sha256.Sum256([]byte(v.botToken))
+	if err := v.verify(fields, secret[:]); err != nil {
+		return nil, err
+	}
+	id, err := strconv.ParseInt(fields["id"], 10, 64)
+	if err != nil || id == 0 {
+		return nil, fmt.Errorf("%w: id", ErrInvalidAuthData)
+	}
+	return &TGUser{
+		ID:        id,
+		FirstName: fields["first_name"],
+		LastName:  fields["last_name"],
+		Username:  fields["username"],
+		PhotoURL:  fields["photo_url"],
+	}, nil
+}
+
+// VerifyWebApp checks the raw initData query string of a Mini App. The
+// secret is HMAC-SHA256 of the bot token keyed with "WebAppData".
+func (v *Verifier) VerifyWebApp(initData string) (*TGUser, error) {
+	values, err := url.ParseQuery(initData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAuthData, err)
+	}
+	fields := make(map[string]string, len(values))
+	for k, vs := range values {
+		if len(vs) != 1 {
+			return nil, fmt.Errorf("%w: repeated %s", ErrInvalidAuthData, k)
+		}
+		fields[k] = vs[0]
+	}
+	mac := hmac.New(sha256.New, []byte("WebAppData"))
+	mac.Write([]byte(v.botToken))
+	if err := v.verify(fields, mac.Sum(nil)); err != nil {
+		return nil, err
+	}
+	var u TGUser
+	if err := json.Unmarshal([]byte(fields["user"]), &u); err != nil || u.ID == 0 {
+		return nil, fmt.Errorf("%w: user", ErrInvalidAuthData)
+	}
+	return &u, nil
+}
+
+// verify compares hash with the HMAC of the sorted key=value lines of the
+// other fields and checks that auth_date is fresh.
+func (v *Verifier) verify(fields map[string]string, secret []byte) error {
+	if v.botToken == "" {
+		return fmt.Errorf("%w: telegram login is not configured", ErrInvalidAuthData)
+	}
+	hash, err := hex.DecodeString(fields["hash"])
+	if err != nil || len(hash) != sha256.Size {
+		return fmt.Errorf("%w: hash", ErrInvalidAuthData)
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		if k != "hash" {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	lines := make([]string, len(keys))
+	for i, k := range keys {
+		lines[i] = k + "=" + fields[k]
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	if !hmac.Equal(mac.Sum(nil), hash) {
+		return fmt.Errorf("%w: hash mismatch", ErrInvalidAuthData)
+	}
+
+	authDate, err := strconv.ParseInt(fields["auth_date"], 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: auth_date", ErrInvalidAuthData)
+	}
+	age := time.Since(time.Unix(authDate, 0))
+	if age > v.maxAge || age < -authClockSkew {
+		return fmt.Errorf("%w: auth_date is not fresh", ErrInvalidAuthData)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// StartLinkPrefix marks a /start deep link payload that carries a link code.
+const StartLinkPrefix = "link-"
+
+// codeAlphabet leaves out 0, O, 1 and I so codes survive being typed.
+const (
+	codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	codeLength   = 8
+)
+
+// pending is the side of the link that created the code. A web user creates
+// a code for the bot, a Telegram user creates one for the web.
+type pending struct {
+	UserID *uuid.UUID `json:"user_id,omitempty"`
+	TGID   *int64     `json:"tg_id,omitempty"`
+}
+
+// CodeStore keeps one-time link codes in Redis. Take returns a code once.
+type CodeStore struct {
+	rdb *redis.Client
+	ttl time.Duration
+}
+
+func NewCodeStore(rdb *redis.Client, ttl time.Duration) *CodeStore {
+	return &CodeStore{rdb: rdb, ttl: ttl}
+}
+
+func codeKey(code string) string {
+	return "telegram:link:" + code
+}
+
+func (s *CodeStore) Save(ctx context.Context, p pending) (string, time.Time, error) {
+	code, err := newCode()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if err := s.rdb.Set(ctx, codeKey(code), data, s.ttl).Err(); err != nil {
+		return "", time.Time{}, err
+	}
+	return code, time.Now().Add(s.ttl), nil
+}
+
+func (s *CodeStore) Take(ctx context.Context, code string) (*pending, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != codeLength {
+		return nil, ErrInvalidCode
+	}
+	data, err := s.rdb.GetDel(ctx, codeKey(code)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrInvalidCode
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p pending
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func newCode() (string, error) {
+	b := make([]byte, codeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = codeAlphabet[int(b[i])%len(codeAlphabet)]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,84 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+)
+
+type LinkRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type LinkCodeResponse struct {
+	api.Response
+	Code string `json:"code"`
+	// Link opens the bot with the code already filled in.
+	Link      string    `json:"link,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// WidgetRequest is the object the Login Widget passes to its callback,
+// forwarded as is.
+type WidgetRequest map[string]any
+
+type WebAppRequest struct {
+	InitData string `json:"init_data" validate:"required"`
+}
+
+type loginUser struct {
+	ID               uuid.UUID
+	Email            string
+	Name             string
+	Role             string
+	TwoFactorEnabled bool
+}
+
+// Account is a user locked for linking, with the ways it can sign in.
+type Account struct {
+	ID            uuid.UUID
+	TGID          *int64
+	HasEmail      bool
+	HasPassword   bool
+	HasIdentities bool
+}
+
+// BotOnly accounts were created by /start or a Telegram login. Their only
+// credential is the Telegram account, so whoever proves it may merge them.
+func (a Account) BotOnly() bool {
+	return !a.HasEmail && !a.HasPassword && !a.HasIdentities
+}
+
+// LinkPlan is what linking a Telegram id to a user has to do.
+type LinkPlan struct {
+	// Linked is set when the user already has the Telegram id.
+	Linked bool
+	// Merge is the account with the Telegram id, folded into the user first.
+	Merge *Account
+}
+
+// PlanLink decides how tgId is linked to the user from the accounts
+// LockAccounts returned.
+func PlanLink(accounts []Account, userId uuid.UUID, tgId int64) (LinkPlan, error) {
+	var target, source *Account
+	for i := range accounts {
+		if accounts[i].ID == userId {
+			target = &accounts[i]
+		} else {
+			source = &accounts[i]
+		}
+	}
+	switch {
+	case target == nil:
+		return LinkPlan{}, utils.ErrorUserNotFound
+	case target.TGID != nil && *target.TGID == tgId:
+		return LinkPlan{Linked: true}, nil
+	case target.TGID != nil:
+		return LinkPlan{}, ErrOtherTelegram
+	case source != nil && !source.BotOnly():
+		return LinkPlan{}, ErrTelegramTaken
+	}
+	return LinkPlan{Merge: source}, nil
+}
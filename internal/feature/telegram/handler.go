@@ -0,0 +1,289 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sanchir01/go-shortener/internal/feature/user"
+	"github.com/Sanchir01/go-shortener/internal/handlers/customiddleware"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Handler struct {
+	service  *Service
+	sessions user.SessionIssuer
+	twoFA    user.TwoFactorChallenger
+	l        *slog.Logger
+}
+
+func NewHandler(service *Service, sessions user.SessionIssuer, twoFA user.TwoFactorChallenger, l *slog.Logger) *Handler {
+	return &Handler{
+		service:  service,
+		sessions: sessions,
+		twoFA:    twoFA,
+		l:        l,
+	}
+}
+
+// @Summary  WidgetLoginHandler
+// @Tags auth
+// @Description Sign in with the object the Telegram Login Widget passes to its callback. The account is created on first use
+// @Accept json
+// @Produce json
+// @Param input body WidgetRequest true "widget data with id, auth_date and hash"
+// @Success 200 {object}  user.LoginResponse
+// @Failure 400,401,429 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /auth/telegram/widget [post]
+func (h *Handler) WidgetLoginHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Telegram.Handler.WidgetLogin"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	// numbers are kept as sent, the hash covers their text
+	var req WidgetRequest
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&req); err != nil {
+		log.Error("failed to decode request body", slog.Any("err", err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidBody))
+		return
+	}
+	fields := make(map[string]string, len(req))
+	for k, v := range req {
+		switch v := v.(type) {
+		case string:
+			fields[k] = v
+		case json.Number:
+			fields[k] = v.String()
+		default:
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidRequest))
+			return
+		}
+	}
+	u, err := h.service.LoginWidget(r.Context(), fields)
+	h.login(w, r, log, u, err)
+}
+
+// @Summary  WebAppLoginHandler
+// @Tags auth
+// @Description Sign in from a Telegram Mini App with Telegram.WebApp.initData. The account is created on first use
+// @Accept json
+// @Produce json
+// @Param input body WebAppRequest true "raw initData"
+// @Success 200 {object}  user.LoginResponse
+// @Failure 400,401,429 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /auth/telegram/webapp [post]
+func (h *Handler) WebAppLoginHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Telegram.Handler.WebAppLogin"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	var req WebAppRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		log.Error("failed to decode request body", slog.Any("err", err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidBody))
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		log.Error("invalid request", logger.Err(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidRequest))
+		return
+	}
+	u, err := h.service.LoginWebApp(r.Context(), req.InitData)
+	h.login(w, r, log, u, err)
+}
+
+// login answers a Telegram sign-in like the password login, with a
+// two-factor challenge or new session cookies.
+func (h *Handler) login(w http.ResponseWriter, r *http.Request, log *slog.Logger, u *loginUser, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidAuthData):
+		log.Info("invalid telegram auth data", logger.Err(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidTelegramAuth))
+		return
+	case err != nil:
+		log.Error("failed telegram login", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInternal))
+		return
+	}
+	if u.TwoFactorEnabled {
+		challenge, err := h.twoFA.StartChallenge(r.Context(), u.ID)
+		if errors.Is(err, utils.ErrorTwoFactorLocked) {
+			render.Status(r, http.StatusTooManyRequests)
+			render.JSON(w, r, api.FailErr(r.Context(), err))
+			return
+		}
+		if err != nil {
+			log.Error("failed to start two-factor challenge", logger.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInternal))
+			return
+		}
+		render.JSON(w, r, user.LoginResponse{
+			Response:          api.OK(),
+			Email:             u.Email,
+			Username:          u.Name,
+			TwoFactorRequired: true,
+			Challenge:         challenge,
+		})
+		return
+	}
+	if err := h.sessions.Issue(w, r, u.ID, u.Role); err != nil {
+		log.Error("register cookie errors", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrCookie))
+		return
+	}
+	render.JSON(w, r, user.LoginResponse{
+		Response: api.OK(),
+		Email:    u.Email,
+		Username: u.Name,
+	})
+}
+
+// @Summary  CreateLinkCodeHandler
+// @Tags telegram
+// @Description One-time code to send to the bot, by opening the link or with /link <code>. The bot account is merged into this one
+// @Produce json
+// @Success 200 {object}  LinkCodeResponse
+// @Failure 401 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /telegram/code [post]
+func (h *Handler) CreateLinkCodeHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Telegram.Handler.CreateLinkCode"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	code, link, expiresAt, err := h.service.CreateWebCode(r.Context(), claims.ID)
+	if err != nil {
+		log.Error("failed to create link code", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInternal))
+		return
+	}
+	render.JSON(w, r, LinkCodeResponse{
+		Response:  api.OK(),
+		Code:      code,
+		Link:      link,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// @Summary  LinkHandler
+// @Tags telegram
+// @Description Link the Telegram account that got the code from /link in the bot
+// @Accept json
+// @Produce json
+// @Param input body LinkRequest true "code from the bot"
+// @Success 200 {object}  api.Response
+// @Failure 400,401,409 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /telegram/link [post]
+func (h *Handler) LinkHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Telegram.Handler.Link"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	var req LinkRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		log.Error("failed to decode request body", slog.Any("err", err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidBody))
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		log.Error("invalid request", logger.Err(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidRequest))
+		return
+	}
+	err = h.service.LinkFromWeb(r.Context(), claims.ID, req.Code)
+	switch {
+	case errors.Is(err, ErrInvalidCode):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidLinkCode))
+		return
+	case errors.Is(err, ErrTelegramTaken):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrTelegramTaken))
+		return
+	case errors.Is(err, ErrOtherTelegram):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrOtherTelegram))
+		return
+	case err != nil:
+		log.Error("failed to link telegram", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInternal))
+		return
+	}
+	render.JSON(w, r, api.OK())
+}
+
+// @Summary  UnlinkHandler
+// @Tags telegram
+// @Description Unlink the Telegram account
+// @Produce json
+// @Success 200 {object}  api.Response
+// @Failure 401,404,409 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /telegram [delete]
+func (h *Handler) UnlinkHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Telegram.Handler.Unlink"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	err = h.service.Unlink(r.Context(), claims.ID)
+	switch {
+	case errors.Is(err, ErrNotLinked):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrTelegramNotLinked))
+		return
+	case errors.Is(err, utils.ErrorLastLoginMethod):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrLastLoginMethod))
+		return
+	case err != nil:
+		log.Error("failed to unlink telegram", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInternal))
+		return
+	}
+	render.JSON(w, r, api.OK())
+}
@@ -0,0 +1,189 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// mergeQueries move everything a user owns from $1 to $2. Folders and tags
+// with a title the target already uses are folded into the target's one.
+var mergeQueries = []string{
+	`UPDATE url SET folder_id = t.id
+		FROM folders f JOIN folders t ON t.user_id = $2 AND t.title = f.title
+		WHERE f.user_id = $1 AND url.folder_id = f.id`,
+	`DELETE FROM folders f USING folders t
+		WHERE f.user_id = $1 AND t.user_id = $2 AND t.title = f.title`,
+	`UPDATE folders SET user_id = $2 WHERE user_id = $1`,
+	`INSERT INTO url_tags (url_id, tag_id)
+		SELECT ut.url_id, t.id FROM url_tags ut
+		JOIN tags f ON f.id = ut.tag_id
+		JOIN tags t ON t.user_id = $2 AND t.title = f.title
+		WHERE f.user_id = $1
+		ON CONFLICT DO NOTHING`,
+	`DELETE FROM tags f USING tags t
+		WHERE f.user_id = $1 AND t.user_id = $2 AND t.title = f.title`,
+	`UPDATE tags SET user_id = $2 WHERE user_id = $1`,
+	`UPDATE url SET user_id = $2 WHERE user_id = $1`,
+	`UPDATE domains SET user_id = $2 WHERE user_id = $1`,
+	`UPDATE webhooks SET user_id = $2 WHERE user_id = $1`,
+}
+
+type Repository struct {
+	primaryDB *pgxpool.Pool
+	l         *slog.Logger
+}
+
+func NewRepository(primaryDB *pgxpool.Pool, l *slog.Logger) *Repository {
+	return &Repository{
+		primaryDB: primaryDB,
+		l:         l,
+	}
+}
+
+// LockAccounts locks the web account and the account that has the Telegram
+// id, in id order so concurrent links can not deadlock.
+func (r *Repository) LockAccounts(ctx context.Context, userId uuid.UUID, tgId int64, tx pgx.Tx) ([]Account, error) {
+	const op = "Telegram.Repository.LockAccounts"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := selectAccounts().
+		Where(sq.Or{sq.Eq{"id": userId}, sq.Eq{"tg_id": tgId}}).
+		OrderBy("id").
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+	accounts := make([]Account, 0, 2)
+	for rows.Next() {
+		var a Account
+		if err := rows.Scan(&a.ID, &a.TGID, &a.HasEmail, &a.HasPassword, &a.HasIdentities); err != nil {
+			log.Error("error", logger.Err(err))
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+// LockAccount locks the user until the end of tx.
+func (r *Repository) LockAccount(ctx context.Context, userId uuid.UUID, tx pgx.Tx) (*Account, error) {
+	const op = "Telegram.Repository.LockAccount"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := selectAccounts().
+		Where(sq.Eq{"id": userId}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	var a Account
+	if err := tx.QueryRow(ctx, query, args...).Scan(&a.ID, &a.TGID, &a.HasEmail, &a.HasPassword, &a.HasIdentities); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.ErrorUserNotFound
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Merge moves the links of from into into and deletes from.
+func (r *Repository) Merge(ctx context.Context, from, into uuid.UUID, tx pgx.Tx) error {
+	const op = "Telegram.Repository.Merge"
+	log := r.l.With(slog.String("op", op))
+
+	for _, query := range mergeQueries {
+		if _, err := tx.Exec(ctx, query, from, into); err != nil {
+			log.Error("error", logger.Err(err))
+			return err
+		}
+	}
+	query, args, err := sq.
+		Delete("users").
+		Where(sq.Eq{"id": from}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+// SetTelegram attaches the Telegram id to the user, nil detaches it.
+func (r *Repository) SetTelegram(ctx context.Context, userId uuid.UUID, tgId *int64, tx pgx.Tx) error {
+	const op = "Telegram.Repository.SetTelegram"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Update("users").
+		Set("tg_id", tgId).
+		Where(sq.Eq{"id": userId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) GetLoginUser(ctx context.Context, userId uuid.UUID) (*loginUser, error) {
+	const op = "Telegram.Repository.GetLoginUser"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select(`id, COALESCE(email, ''), title, role::text,
+			EXISTS(SELECT 1 FROM user_totp t WHERE t.user_id = users.id AND t.enabled_at IS NOT NULL)`).
+		From("users").
+		Where(sq.Eq{"id": userId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	var u loginUser
+	if err := r.primaryDB.QueryRow(ctx, query, args...).Scan(&u.ID, &u.Email, &u.Name, &u.Role, &u.TwoFactorEnabled); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.ErrorUserNotFound
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return &u, nil
+}
+
+func selectAccounts() sq.SelectBuilder {
+	return sq.
+		Select("id", "tg_id", "email IS NOT NULL", "password IS NOT NULL",
+			"EXISTS(SELECT 1 FROM user_identities i WHERE i.user_id = users.id)").
+		From("users").
+		PlaceholderFormat(sq.Dollar)
+}
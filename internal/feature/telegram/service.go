@@ -0,0 +1,236 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/config"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrInvalidCode   = errors.New("invalid or expired link code")
+	ErrTelegramTaken = errors.New("telegram account is linked to another user")
+	ErrOtherTelegram = errors.New("account is linked to another telegram account")
+	ErrNotLinked     = errors.New("telegram account is not linked")
+)
+
+type TelegramService interface {
+	LockAccounts(ctx context.Context, userId uuid.UUID, tgId int64, tx pgx.Tx) ([]Account, error)
+	LockAccount(ctx context.Context, userId uuid.UUID, tx pgx.Tx) (*Account, error)
+	Merge(ctx context.Context, from, into uuid.UUID, tx pgx.Tx) error
+	SetTelegram(ctx context.Context, userId uuid.UUID, tgId *int64, tx pgx.Tx) error
+	GetLoginUser(ctx context.Context, userId uuid.UUID) (*loginUser, error)
+}
+
+// UserResolver finds the account of a Telegram user and creates it on first
+// use, like /start in the bot.
+type UserResolver interface {
+	TelegramUser(ctx context.Context, tgId int64, title string) (*uuid.UUID, error)
+}
+
+type Service struct {
+	repo      TelegramService
+	primaryDB *pgxpool.Pool
+	codes     *CodeStore
+	verifier  *Verifier
+	users     UserResolver
+	cfg       config.Telegram
+	l         *slog.Logger
+}
+
+func NewService(repo TelegramService, primaryDB *pgxpool.Pool, codes *CodeStore, verifier *Verifier, users UserResolver, cfg config.Telegram, l *slog.Logger) *Service {
+	return &Service{
+		repo:      repo,
+		primaryDB: primaryDB,
+		codes:     codes,
+		verifier:  verifier,
+		users:     users,
+		cfg:       cfg,
+		l:         l,
+	}
+}
+
+// LoginWidget signs in with the data of the Telegram Login Widget.
+func (s *Service) LoginWidget(ctx context.Context, fields map[string]string) (*loginUser, error) {
+	u, err := s.verifier.VerifyWidget(fields)
+	if err != nil {
+		return nil, err
+	}
+	return s.login(ctx, u)
+}
+
+// LoginWebApp signs in with the initData of a Telegram Mini App.
+func (s *Service) LoginWebApp(ctx context.Context, initData string) (*loginUser, error) {
+	u, err := s.verifier.VerifyWebApp(initData)
+	if err != nil {
+		return nil, err
+	}
+	return s.login(ctx, u)
+}
+
+func (s *Service) login(ctx context.Context, tg *TGUser) (*loginUser, error) {
+	const op = "Telegram.Service.login"
+	log := s.l.With(slog.String("op", op))
+
+	id, err := s.users.TelegramUser(ctx, tg.ID, tg.Title())
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.repo.GetLoginUser(ctx, *id)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("telegram login", slog.String("user_id", u.ID.String()))
+	return u, nil
+}
+
+// CreateWebCode creates the code a signed in web user sends to the bot.
+func (s *Service) CreateWebCode(ctx context.Context, userId uuid.UUID) (string, string, time.Time, error) {
+	code, expiresAt, err := s.codes.Save(ctx, pending{UserID: &userId})
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	var link string
+	if s.cfg.BotUsername != "" {
+		link = "https://t.me/" + s.cfg.BotUsername + "?start=" + StartLinkPrefix + code
+	}
+	return code, link, expiresAt, nil
+}
+
+// CreateBotCode creates the code a Telegram user enters on the web.
+func (s *Service) CreateBotCode(ctx context.Context, tgId int64) (string, time.Time, error) {
+	return s.codes.Save(ctx, pending{TGID: &tgId})
+}
+
+// LinkFromBot links the Telegram user to the web account that created code.
+func (s *Service) LinkFromBot(ctx context.Context, tgId int64, code string) error {
+	p, err := s.codes.Take(ctx, code)
+	if err != nil {
+		return err
+	}
+	if p.UserID == nil {
+		return ErrInvalidCode
+	}
+	return s.link(ctx, *p.UserID, tgId)
+}
+
+// LinkFromWeb links the Telegram user that created code to the web account.
+func (s *Service) LinkFromWeb(ctx context.Context, userId uuid.UUID, code string) error {
+	p, err := s.codes.Take(ctx, code)
+	if err != nil {
+		return err
+	}
+	if p.TGID == nil {
+		return ErrInvalidCode
+	}
+	return s.link(ctx, userId, *p.TGID)
+}
+
+// link attaches tgId to the user. The account /start or a Telegram login
+// created for tgId is merged into the user together with its links.
+// Accounts with any other way to sign in are never merged.
+func (s *Service) link(ctx context.Context, userId uuid.UUID, tgId int64) error {
+	const op = "Telegram.Service.link"
+	log := s.l.With(slog.String("op", op), slog.String("user_id", userId.String()))
+
+	conn, err := s.primaryDB.Acquire(ctx)
+	if err != nil {
+		log.Error("error init acquire", logger.Err(err))
+		return err
+	}
+	defer conn.Release()
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		log.Error("error init tx", logger.Err(err))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Error("rollback error", logger.Err(rollbackErr))
+			}
+		}
+	}()
+
+	var accounts []Account
+	if accounts, err = s.repo.LockAccounts(ctx, userId, tgId, tx); err != nil {
+		return err
+	}
+	var plan LinkPlan
+	if plan, err = PlanLink(accounts, userId, tgId); err != nil {
+		return err
+	}
+	if plan.Linked {
+		err = tx.Commit(ctx)
+		return err
+	}
+	if plan.Merge != nil {
+		if err = s.repo.Merge(ctx, plan.Merge.ID, userId, tx); err != nil {
+			return err
+		}
+		log.Info("telegram account merged", slog.String("from", plan.Merge.ID.String()))
+	}
+	if err = s.repo.SetTelegram(ctx, userId, &tgId, tx); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Error("commit error", logger.Err(err))
+		return err
+	}
+	log.Info("telegram account linked")
+	return nil
+}
+
+// Unlink detaches Telegram from the user unless it is the only way left to
+// sign in.
+func (s *Service) Unlink(ctx context.Context, userId uuid.UUID) error {
+	const op = "Telegram.Service.Unlink"
+	log := s.l.With(slog.String("op", op))
+
+	conn, err := s.primaryDB.Acquire(ctx)
+	if err != nil {
+		log.Error("error init acquire", logger.Err(err))
+		return err
+	}
+	defer conn.Release()
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		log.Error("error init tx", logger.Err(err))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Error("rollback error", logger.Err(rollbackErr))
+			}
+		}
+	}()
+
+	var a *Account
+	if a, err = s.repo.LockAccount(ctx, userId, tx); err != nil {
+		return err
+	}
+	switch {
+	case a.TGID == nil:
+		err = ErrNotLinked
+		return err
+	case a.BotOnly():
+		err = utils.ErrorLastLoginMethod
+		return err
+	}
+	if err = s.repo.SetTelegram(ctx, userId, nil, tx); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Error("commit error", logger.Err(err))
+		return err
+	}
+	return nil
+}
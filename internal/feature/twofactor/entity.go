@@ -0,0 +1,53 @@
+package twofactor
+
+import (
+	"time"
+
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/google/uuid"
+)
+
+type CodeRequest struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+type VerifyRequest struct {
+	Challenge string `json:"challenge" validate:"required"`
+	// Code is a code from the authenticator app or a recovery code.
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+type PasswordRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+type EnrollResponse struct {
+	api.Response
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+	// QRCode is a PNG of URI as a data: URL.
+	QRCode string `json:"qr_code"`
+}
+
+type RecoveryCodesResponse struct {
+	api.Response
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type totpState struct {
+	Secret   string
+	LastStep int64
+	Enabled  bool
+}
+
+type account struct {
+	Email    string
+	Password []byte
+}
+
+type challenge struct {
+	UserID    uuid.UUID
+	Role      string
+	Attempts  int
+	ExpiresAt time.Time
+}
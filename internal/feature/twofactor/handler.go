@@ -0,0 +1,239 @@
+package twofactor
+
+import (
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sanchir01/go-shortener/internal/feature/user"
+	"github.com/Sanchir01/go-shortener/internal/handlers/customiddleware"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Handler struct {
+	service  *Service
+	sessions user.SessionIssuer
+	l        *slog.Logger
+}
+
+func NewHandler(service *Service, sessions user.SessionIssuer, l *slog.Logger) *Handler {
+	return &Handler{
+		service:  service,
+		sessions: sessions,
+		l:        l,
+	}
+}
+
+// @Summary  EnrollHandler
+// @Tags auth
+// @Description Start TOTP enrollment, scan the QR code or enter the secret in an authenticator app and confirm with a code
+// @Produce json
+// @Success 200 {object}  EnrollResponse
+// @Failure 400,401,403,409 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /auth/2fa/enroll [post]
+func (h *Handler) EnrollHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "TwoFactor.Handler.Enroll"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	secret, uri, png, err := h.service.Enroll(r.Context(), claims.ID)
+	switch {
+	case errors.Is(err, utils.ErrorPasswordRequired):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrTwoFactorPassword))
+		return
+	case errors.Is(err, utils.ErrorTwoFactorEnabled):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrTwoFactorEnabled))
+		return
+	case err != nil:
+		log.Error("failed to enroll", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrStartEnrollment))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, EnrollResponse{
+		Response: api.OK(),
+		Secret:   secret,
+		URI:      uri,
+		QRCode:   "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	})
+}
+
+// @Summary  ConfirmHandler
+// @Tags auth
+// @Description Enable TOTP with the first code from the app, the recovery codes are returned only once
+// @Accept json
+// @Produce json
+// @Param input body CodeRequest true "code body"
+// @Success 200 {object}  RecoveryCodesResponse
+// @Failure 400,401,403,409 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /auth/2fa/confirm [post]
+func (h *Handler) ConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "TwoFactor.Handler.Confirm"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	var req CodeRequest
+	if !decode(w, r, log, &req) {
+		return
+	}
+	codes, err := h.service.Confirm(r.Context(), claims.ID, req.Code)
+	switch {
+	case errors.Is(err, utils.ErrorTwoFactorDisabled):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrEnrollmentNotStarted))
+		return
+	case errors.Is(err, utils.ErrorTwoFactorEnabled):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrTwoFactorEnabled))
+		return
+	case errors.Is(err, utils.ErrorInvalidCode):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidCode))
+		return
+	case err != nil:
+		log.Error("failed to confirm", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrEnableTwoFactor))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, RecoveryCodesResponse{
+		Response:      api.OK(),
+		RecoveryCodes: codes,
+	})
+}
+
+// @Summary  DisableHandler
+// @Tags auth
+// @Description Disable TOTP and delete the recovery codes, requires the password
+// @Accept json
+// @Produce json
+// @Param input body PasswordRequest true "password body"
+// @Success 200 {object}  api.Response
+// @Failure 400,401,403,404 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /auth/2fa/disable [post]
+func (h *Handler) DisableHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "TwoFactor.Handler.Disable"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	var req PasswordRequest
+	if !decode(w, r, log, &req) {
+		return
+	}
+	err = h.service.Disable(r.Context(), claims.ID, req.Password)
+	switch {
+	case errors.Is(err, utils.ErrorInvalidPassword):
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidPassword))
+		return
+	case errors.Is(err, utils.ErrorTwoFactorDisabled):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrTwoFactorDisabled))
+		return
+	case err != nil:
+		log.Error("failed to disable", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrDisableTwoFactor))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, api.OK())
+}
+
+// @Summary  VerifyHandler
+// @Tags auth
+// @Description Second step of the login with 2FA, exchanges the challenge from /login and a code from the app or a recovery code for the session cookies
+// @Accept json
+// @Produce json
+// @Param input body VerifyRequest true "verify body"
+// @Success 200 {object}  api.Response
+// @Failure 400,401,429 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /auth/2fa/verify [post]
+func (h *Handler) VerifyHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "TwoFactor.Handler.Verify"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	var req VerifyRequest
+	if !decode(w, r, log, &req) {
+		return
+	}
+	c, err := h.service.VerifyChallenge(r.Context(), req.Challenge, req.Code)
+	switch {
+	case errors.Is(err, utils.ErrorInvalidCode):
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidCode))
+		return
+	case errors.Is(err, utils.ErrorTwoFactorLocked):
+		render.Status(r, http.StatusTooManyRequests)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrTwoFactorLocked))
+		return
+	case errors.Is(err, utils.ErrorInvalidChallenge), errors.Is(err, utils.ErrorTwoFactorDisabled):
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrLoginAgain))
+		return
+	case err != nil:
+		log.Error("failed to verify challenge", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInternal))
+		return
+	}
+	if err := h.sessions.Issue(w, r, c.UserID, c.Role); err != nil {
+		log.Error("register cookie errors", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrCookie))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, api.OK())
+}
+
+func decode(w http.ResponseWriter, r *http.Request, log *slog.Logger, req any) bool {
+	if err := render.DecodeJSON(r.Body, req); err != nil {
+		log.Error("failed to decode request body", slog.Any("err", err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidBody))
+		return false
+	}
+	if err := validator.New().Struct(req); err != nil {
+		log.Error("invalid request", logger.Err(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidRequest))
+		return false
+	}
+	return true
+}
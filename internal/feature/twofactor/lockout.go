@@ -0,0 +1,50 @@
+package twofactor
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Lockout counts wrong codes per user across challenges. A fresh challenge
+// resets the attempts of a challenge, the counter here is what stops
+// guessing through new logins. It expires ttl after the first failure.
+type Lockout struct {
+	rdb *redis.Client
+	max int64
+	ttl time.Duration
+}
+
+func NewLockout(rdb *redis.Client, max int, ttl time.Duration) *Lockout {
+	return &Lockout{rdb: rdb, max: int64(max), ttl: ttl}
+}
+
+func failuresKey(userId uuid.UUID) string {
+	return "twofactor:failures:" + userId.String()
+}
+
+func (l *Lockout) Locked(ctx context.Context, userId uuid.UUID) (bool, error) {
+	n, err := l.rdb.Get(ctx, failuresKey(userId)).Int64()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return n >= l.max, nil
+}
+
+func (l *Lockout) Fail(ctx context.Context, userId uuid.UUID) error {
+	key := failuresKey(userId)
+	pipe := l.rdb.TxPipeline()
+	pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, l.ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (l *Lockout) Reset(ctx context.Context, userId uuid.UUID) error {
+	return l.rdb.Del(ctx, failuresKey(userId)).Err()
+}
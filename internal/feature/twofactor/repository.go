@@ -0,0 +1,316 @@
+package twofactor
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository struct {
+	primaryDB *pgxpool.Pool
+	l         *slog.Logger
+}
+
+func NewRepository(primaryDB *pgxpool.Pool, l *slog.Logger) *Repository {
+	return &Repository{
+		primaryDB: primaryDB,
+		l:         l,
+	}
+}
+
+func (r *Repository) GetAccount(ctx context.Context, userId uuid.UUID) (*account, error) {
+	const op = "TwoFactor.Repository.GetAccount"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select("COALESCE(email, ''), password").
+		From("users").
+		Where(sq.Eq{"id": userId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	var a account
+	if err := r.primaryDB.QueryRow(ctx, query, args...).Scan(&a.Email, &a.Password); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.ErrorUserNotFound
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return &a, nil
+}
+
+// SavePendingTOTP stores a new secret that is not enabled yet, replacing an
+// earlier unconfirmed one. An enabled secret is never replaced.
+func (r *Repository) SavePendingTOTP(ctx context.Context, userId uuid.UUID, secret string) error {
+	const op = "TwoFactor.Repository.SavePendingTOTP"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Insert("user_totp").
+		Columns("user_id", "secret").
+		Values(userId, secret).
+		Suffix(`ON CONFLICT (user_id) DO UPDATE
+			SET secret = EXCLUDED.secret, last_step = 0, created_at = now()
+			WHERE user_totp.enabled_at IS NULL`).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	tag, err := r.primaryDB.Exec(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return utils.ErrorTwoFactorEnabled
+	}
+	return nil
+}
+
+// GetTOTPForUpdate locks the secret of the user until the end of tx.
+func (r *Repository) GetTOTPForUpdate(ctx context.Context, userId uuid.UUID, tx pgx.Tx) (*totpState, error) {
+	const op = "TwoFactor.Repository.GetTOTPForUpdate"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select("secret, last_step, enabled_at IS NOT NULL").
+		From("user_totp").
+		Where(sq.Eq{"user_id": userId}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	var t totpState
+	if err := tx.QueryRow(ctx, query, args...).Scan(&t.Secret, &t.LastStep, &t.Enabled); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.ErrorTwoFactorDisabled
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return &t, nil
+}
+
+// UseStep remembers the step of an accepted code and enables 2FA when it
+// was the confirmation of the enrollment.
+func (r *Repository) UseStep(ctx context.Context, userId uuid.UUID, step int64, tx pgx.Tx) error {
+	const op = "TwoFactor.Repository.UseStep"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Update("user_totp").
+		Set("last_step", step).
+		Set("enabled_at", sq.Expr("COALESCE(enabled_at, now())")).
+		Where(sq.Eq{"user_id": userId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+// DeleteTOTP removes the secret and the recovery codes of the user.
+func (r *Repository) DeleteTOTP(ctx context.Context, userId uuid.UUID, tx pgx.Tx) error {
+	const op = "TwoFactor.Repository.DeleteTOTP"
+	log := r.l.With(slog.String("op", op))
+
+	for _, table := range []string{"user_recovery_codes", "user_totp"} {
+		query, args, err := sq.
+			Delete(table).
+			Where(sq.Eq{"user_id": userId}).
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			log.Error("error", logger.Err(err))
+			return utils.ErrorQueryString
+		}
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			log.Error("error", logger.Err(err))
+			return err
+		}
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes drops the old codes of the user and stores hashes.
+func (r *Repository) ReplaceRecoveryCodes(ctx context.Context, userId uuid.UUID, hashes []string, tx pgx.Tx) error {
+	const op = "TwoFactor.Repository.ReplaceRecoveryCodes"
+	log := r.l.With(slog.String("op", op))
+
+	deleteQuery, deleteArgs, err := sq.
+		Delete("user_recovery_codes").
+		Where(sq.Eq{"user_id": userId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := tx.Exec(ctx, deleteQuery, deleteArgs...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	insert := sq.Insert("user_recovery_codes").Columns("hash", "user_id")
+	for _, hash := range hashes {
+		insert = insert.Values(hash, userId)
+	}
+	query, args, err := insert.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+// UseRecoveryCode burns an unused recovery code of the user.
+func (r *Repository) UseRecoveryCode(ctx context.Context, userId uuid.UUID, hash string, tx pgx.Tx) error {
+	const op = "TwoFactor.Repository.UseRecoveryCode"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Update("user_recovery_codes").
+		Set("used_at", sq.Expr("now()")).
+		Where(sq.Eq{"user_id": userId, "hash": hash, "used_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	tag, err := tx.Exec(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return utils.ErrorInvalidCode
+	}
+	return nil
+}
+
+func (r *Repository) CreateChallenge(ctx context.Context, userId uuid.UUID, hash string, expiresAt time.Time) error {
+	const op = "TwoFactor.Repository.CreateChallenge"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Insert("two_factor_challenges").
+		Columns("hash", "user_id", "expires_at").
+		Values(hash, userId, expiresAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := r.primaryDB.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+// GetChallengeForUpdate locks the challenge until the end of tx so parallel
+// guesses are counted one by one.
+func (r *Repository) GetChallengeForUpdate(ctx context.Context, hash string, tx pgx.Tx) (*challenge, error) {
+	const op = "TwoFactor.Repository.GetChallengeForUpdate"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select("c.user_id, u.role::text, c.attempts, c.expires_at").
+		From("two_factor_challenges c").
+		Join("users u ON u.id = c.user_id").
+		Where(sq.Eq{"c.hash": hash}).
+		Suffix("FOR UPDATE OF c").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	var c challenge
+	if err := tx.QueryRow(ctx, query, args...).Scan(&c.UserID, &c.Role, &c.Attempts, &c.ExpiresAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.ErrorInvalidChallenge
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *Repository) AddChallengeAttempt(ctx context.Context, hash string, tx pgx.Tx) error {
+	const op = "TwoFactor.Repository.AddChallengeAttempt"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Update("two_factor_challenges").
+		Set("attempts", sq.Expr("attempts + 1")).
+		Where(sq.Eq{"hash": hash}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+// DeleteChallenge removes the challenge together with the expired ones of
+// other logins.
+func (r *Repository) DeleteChallenge(ctx context.Context, hash string, tx pgx.Tx) error {
+	const op = "TwoFactor.Repository.DeleteChallenge"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Delete("two_factor_challenges").
+		Where(sq.Or{
+			sq.Eq{"hash": hash},
+			sq.Expr("expires_at <= now()"),
+		}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+package twofactor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/config"
+	"github.com/Sanchir01/go-shortener/internal/feature/user"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skip2/go-qrcode"
+)
+
+const qrSize = 256
+
+type TwoFactorService interface {
+	GetAccount(ctx context.Context, userId uuid.UUID) (*account, error)
+	SavePendingTOTP(ctx context.Context, userId uuid.UUID, secret string) error
+	GetTOTPForUpdate(ctx context.Context, userId uuid.UUID, tx pgx.Tx) (*totpState, error)
+	UseStep(ctx context.Context, userId uuid.UUID, step int64, tx pgx.Tx) error
+	DeleteTOTP(ctx context.Context, userId uuid.UUID, tx pgx.Tx) error
+	ReplaceRecoveryCodes(ctx context.Context, userId uuid.UUID, hashes []string, tx pgx.Tx) error
+	UseRecoveryCode(ctx context.Context, userId uuid.UUID, hash string, tx pgx.Tx) error
+	CreateChallenge(ctx context.Context, userId uuid.UUID, hash string, expiresAt time.Time) error
+	GetChallengeForUpdate(ctx context.Context, hash string, tx pgx.Tx) (*challenge, error)
+	AddChallengeAttempt(ctx context.Context, hash string, tx pgx.Tx) error
+	DeleteChallenge(ctx context.Context, hash string, tx pgx.Tx) error
+}
+
+// Failures counts wrong codes of a user across challenges.
+type Failures interface {
+	Locked(ctx context.Context, userId uuid.UUID) (bool, error)
+	Fail(ctx context.Context, userId uuid.UUID) error
+	Reset(ctx context.Context, userId uuid.UUID) error
+}
+
+type Service struct {
+	repo      TwoFactorService
+	primaryDB *pgxpool.Pool
+	failures  Failures
+	cfg       config.TwoFactor
+	l         *slog.Logger
+}
+
+func NewService(repo TwoFactorService, primaryDB *pgxpool.Pool, failures Failures, cfg config.TwoFactor, l *slog.Logger) *Service {
+	return &Service{
+		repo:      repo,
+		primaryDB: primaryDB,
+		failures:  failures,
+		cfg:       cfg,
+		l:         l,
+	}
+}
+
+// Enroll creates a secret that becomes active after Confirm. It returns the
+// secret, its otpauth:// URI and the URI as a PNG QR code.
+func (s *Service) Enroll(ctx context.Context, userId uuid.UUID) (string, string, []byte, error) {
+	const op = "TwoFactor.Service.Enroll"
+	log := s.l.With(slog.String("op", op))
+
+	acc, err := s.repo.GetAccount(ctx, userId)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if len(acc.Password) == 0 {
+		return "", "", nil, utils.ErrorPasswordRequired
+	}
+	secret, err := newSecret()
+	if err != nil {
+		log.Error("generate secret error", logger.Err(err))
+		return "", "", nil, err
+	}
+	if err := s.repo.SavePendingTOTP(ctx, userId, secret); err != nil {
+		return "", "", nil, err
+	}
+	uri := provisioningURI(s.cfg.Issuer, acc.Email, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, qrSize)
+	if err != nil {
+		log.Error("qr code error", logger.Err(err))
+		return "", "", nil, err
+	}
+	return secret, uri, png, nil
+}
+
+// Confirm enables 2FA once the user proves the app produces valid codes and
+// returns the recovery codes. They are shown only this time.
+func (s *Service) Confirm(ctx context.Context, userId uuid.UUID, code string) ([]string, error) {
+	const op = "TwoFactor.Service.Confirm"
+	log := s.l.With(slog.String("op", op))
+
+	conn, err := s.primaryDB.Acquire(ctx)
+	if err != nil {
+		log.Error("error init acquire", logger.Err(err))
+		return nil, err
+	}
+	defer conn.Release()
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		log.Error("error init tx", logger.Err(err))
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Error("rollback error", logger.Err(rollbackErr))
+			}
+		}
+	}()
+
+	state, err := s.repo.GetTOTPForUpdate(ctx, userId, tx)
+	if err != nil {
+		return nil, err
+	}
+	if state.Enabled {
+		err = utils.ErrorTwoFactorEnabled
+		return nil, err
+	}
+	step, ok := VerifyTOTP(state.Secret, code, time.Now(), state.LastStep)
+	if !ok {
+		err = utils.ErrorInvalidCode
+		return nil, err
+	}
+	if err = s.repo.UseStep(ctx, userId, step, tx); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := s.recoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err = s.repo.ReplaceRecoveryCodes(ctx, userId, hashes, tx); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Error("commit error", logger.Err(err))
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable turns 2FA off, the password is asked again so a stolen session is
+// not enough.
+func (s *Service) Disable(ctx context.Context, userId uuid.UUID, password string) error {
+	const op = "TwoFactor.Service.Disable"
+	log := s.l.With(slog.String("op", op))
+
+	acc, err := s.repo.GetAccount(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !user.VerifyPassword(acc.Password, password) {
+		return utils.ErrorInvalidPassword
+	}
+	conn, err := s.primaryDB.Acquire(ctx)
+	if err != nil {
+		log.Error("error init acquire", logger.Err(err))
+		return err
+	}
+	defer conn.Release()
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		log.Error("error init tx", logger.Err(err))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Error("rollback error", logger.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if _, err = s.repo.GetTOTPForUpdate(ctx, userId, tx); err != nil {
+		return err
+	}
+	if err = s.repo.DeleteTOTP(ctx, userId, tx); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Error("commit error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+// StartChallenge is called after the password of a user with 2FA was
+// checked. The returned token is exchanged for a session by VerifyChallenge.
+// Users with too many wrong codes get no challenge until the lockout ends.
+func (s *Service) StartChallenge(ctx context.Context, userId uuid.UUID) (string, error) {
+	const op = "TwoFactor.Service.StartChallenge"
+	log := s.l.With(slog.String("op", op))
+
+	if err := s.checkLockout(ctx, userId); err != nil {
+		return "", err
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Error("generate challenge error", logger.Err(err))
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	if err := s.repo.CreateChallenge(ctx, userId, hashToken(token), time.Now().Add(s.cfg.ChallengeTTL)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// VerifyChallenge checks a code from the app or a recovery code for the
+// challenge and returns the user to start a session for. The challenge dies
+// after MaxAttempts wrong codes, the user is locked out after MaxFailures
+// wrong codes in all challenges.
+func (s *Service) VerifyChallenge(ctx context.Context, token, code string) (*challenge, error) {
+	const op = "TwoFactor.Service.VerifyChallenge"
+	log := s.l.With(slog.String("op", op))
+
+	conn, err := s.primaryDB.Acquire(ctx)
+	if err != nil {
+		log.Error("error init acquire", logger.Err(err))
+		return nil, err
+	}
+	defer conn.Release()
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		log.Error("error init tx", logger.Err(err))
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Error("rollback error", logger.Err(rollbackErr))
+			}
+		}
+	}()
+
+	hash := hashToken(token)
+	c, err := s.repo.GetChallengeForUpdate(ctx, hash, tx)
+	if err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(c.ExpiresAt) || c.Attempts >= s.cfg.MaxAttempts {
+		if err = s.repo.DeleteChallenge(ctx, hash, tx); err != nil {
+			return nil, err
+		}
+		if err = tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+		return nil, utils.ErrorInvalidChallenge
+	}
+	if err = s.checkLockout(ctx, c.UserID); err != nil {
+		return nil, err
+	}
+
+	valid, err := s.checkCode(ctx, c.UserID, code, tx)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		if err = s.repo.AddChallengeAttempt(ctx, hash, tx); err != nil {
+			return nil, err
+		}
+		if err = tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+		log.Warn("wrong two-factor code", slog.String("user_id", c.UserID.String()), slog.Int("attempt", c.Attempts+1))
+		if failErr := s.failures.Fail(ctx, c.UserID); failErr != nil {
+			log.Error("count two-factor failure error", logger.Err(failErr))
+		}
+		return nil, utils.ErrorInvalidCode
+	}
+	if err = s.repo.DeleteChallenge(ctx, hash, tx); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Error("commit error", logger.Err(err))
+		return nil, err
+	}
+	if err := s.failures.Reset(ctx, c.UserID); err != nil {
+		log.Error("reset two-factor failures error", logger.Err(err))
+	}
+	return c, nil
+}
+
+// checkLockout fails closed, an unknown counter is treated as a lockout.
+func (s *Service) checkLockout(ctx context.Context, userId uuid.UUID) error {
+	locked, err := s.failures.Locked(ctx, userId)
+	if err != nil {
+		s.l.Error("check two-factor lockout error", slog.String("user_id", userId.String()), logger.Err(err))
+		return utils.ErrorTwoFactorLocked
+	}
+	if locked {
+		return utils.ErrorTwoFactorLocked
+	}
+	return nil
+}
+
+func (s *Service) checkCode(ctx context.Context, userId uuid.UUID, code string, tx pgx.Tx) (bool, error) {
+	state, err := s.repo.GetTOTPForUpdate(ctx, userId, tx)
+	if err != nil {
+		return false, err
+	}
+	if !state.Enabled {
+		return false, utils.ErrorTwoFactorDisabled
+	}
+	if step, ok := VerifyTOTP(state.Secret, code, time.Now(), state.LastStep); ok {
+		return true, s.repo.UseStep(ctx, userId, step, tx)
+	}
+	if len(code) == totpDigits {
+		return false, nil
+	}
+	err = s.repo.UseRecoveryCode(ctx, userId, HashRecoveryCode(code), tx)
+	if errors.Is(err, utils.ErrorInvalidCode) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *Service) recoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, s.cfg.RecoveryCodes)
+	hashes := make([]string, 0, s.cfg.RecoveryCodes)
+	for range s.cfg.RecoveryCodes {
+		code, hash, err := NewRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hash)
+	}
+	return codes, hashes, nil
+}
@@ -1,8 +1,11 @@
 package url
 
 import (
-	"github.com/Sanchir01/currency-wallet/pkg/api"
+	"time"
+
 	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/google/uuid"
 )
 
 type GetAllUrlResponse struct {
@@ -13,6 +16,72 @@ type CreateUrlResponse struct {
 	api.Response
 	Url string `json:"url"`
 }
+
+// CreateUrlRequest gets a generated alias unless Alias is set.
 type CreateUrlRequest struct {
-	Url string `json:"url" validate:"required"`
+	Url         string            `json:"url" validate:"required"`
+	Alias       string            `json:"alias,omitempty"`
+	NotBefore   *time.Time        `json:"not_before,omitempty"`
+	ExpiresAt   *time.Time        `json:"expires_at,omitempty"`
+	Timezone    string            `json:"timezone,omitempty"`
+	TimeRules   []models.TimeRule `json:"time_rules,omitempty" validate:"omitempty,dive"`
+	FolderID    *uuid.UUID        `json:"folder_id,omitempty"`
+	TagIDs      []uuid.UUID       `json:"tag_ids,omitempty"`
+	DomainID    *uuid.UUID        `json:"domain_id,omitempty"`
+	FallbackUrl *string           `json:"fallback_url,omitempty" validate:"omitempty,url"`
+}
+type CreateUrlParams struct {
+	Url         string
+	Alias       string
+	NotBefore   *time.Time
+	ExpiresAt   *time.Time
+	Timezone    string
+	TimeRules   []models.TimeRule
+	FolderID    *uuid.UUID
+	TagIDs      []uuid.UUID
+	DomainID    *uuid.UUID
+	FallbackUrl *string
+}
+
+// UpdateUrlRequest changes only the fields that are present, folder_id or
+// domain_id set to the zero uuid moves the link out of its folder or back to
+// the shared domain.
+type UpdateUrlRequest struct {
+	Url       *string            `json:"url,omitempty" validate:"omitempty,url"`
+	NotBefore *time.Time         `json:"not_before,omitempty"`
+	Timezone  *string            `json:"timezone,omitempty"`
+	TimeRules *[]models.TimeRule `json:"time_rules,omitempty" validate:"omitempty,dive"`
+	FolderID  *uuid.UUID         `json:"folder_id,omitempty"`
+	TagIDs    *[]uuid.UUID       `json:"tag_ids,omitempty"`
+	DomainID  *uuid.UUID         `json:"domain_id,omitempty"`
+	// FallbackUrl set to an empty string removes the fallback
+	FallbackUrl *string `json:"fallback_url,omitempty" validate:"omitempty,url|len=0"`
+}
+type UpdateUrlParams struct {
+	Url         *string
+	NotBefore   *time.Time
+	Timezone    *string
+	TimeRules   *[]models.TimeRule
+	FolderID    *uuid.UUID
+	TagIDs      *[]uuid.UUID
+	DomainID    *uuid.UUID
+	FallbackUrl *string
+}
+type UpdateUrlResponse struct {
+	api.Response
+	Url models.Url `json:"url"`
+}
+type GetRevisionsResponse struct {
+	api.Response
+	Revisions []models.UrlRevision `json:"revisions"`
+}
+
+// UrlFilter narrows the links of a user, newest first. Limit 0 returns all.
+type UrlFilter struct {
+	TagID    *uuid.UUID
+	FolderID *uuid.UUID
+	Alias    string
+	Url      string
+	Limit    uint64
+	Offset   uint64
 }
@@ -0,0 +1,55 @@
+package url
+
+import (
+	"regexp"
+	"strings"
+)
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"'«»]+`)
+
+// urlSpans finds http and https urls in text. Punctuation that ends the
+// sentence and a closing bracket without an opening one are not part of them.
+func urlSpans(text string) [][]int {
+	spans := urlPattern.FindAllStringIndex(text, -1)
+	for _, s := range spans {
+		for s[1] > s[0] {
+			u := text[s[0]:s[1]]
+			last := u[len(u)-1]
+			if strings.IndexByte(".,;:!?", last) >= 0 ||
+				(last == ')' && !strings.Contains(u, "(")) ||
+				(last == ']' && !strings.Contains(u, "[")) {
+				s[1]--
+				continue
+			}
+			break
+		}
+	}
+	return spans
+}
+
+// FindUrls returns the distinct urls in text in the order they appear.
+func FindUrls(text string) []string {
+	var found []string
+	seen := make(map[string]bool)
+	for _, s := range urlSpans(text) {
+		u := text[s[0]:s[1]]
+		if !seen[u] {
+			seen[u] = true
+			found = append(found, u)
+		}
+	}
+	return found
+}
+
+// ReplaceUrls rewrites every url FindUrls finds in text with replace.
+func ReplaceUrls(text string, replace func(string) string) string {
+	var sb strings.Builder
+	last := 0
+	for _, s := range urlSpans(text) {
+		sb.WriteString(text[last:s[0]])
+		sb.WriteString(replace(text[s[0]:s[1]]))
+		last = s[1]
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
@@ -2,15 +2,22 @@ package url
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
-	"github.com/Sanchir01/currency-wallet/pkg/api"
-	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
+	"github.com/Sanchir01/go-shortener/internal/config"
 	"github.com/Sanchir01/go-shortener/internal/domain/models"
-	"github.com/Sanchir01/go-shortener/internal/feature/user"
+	"github.com/Sanchir01/go-shortener/internal/feature/customdomain"
+	"github.com/Sanchir01/go-shortener/internal/handlers/customiddleware"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
 	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
@@ -20,27 +27,31 @@ import (
 //go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=UrlHandler
 type UrlHandler interface {
 	GetAllUrl(ctx context.Context) ([]models.Url, error)
-	CreateUrl(ctx context.Context, userId uuid.UUID, url string) error
+	CreateUrl(ctx context.Context, userId uuid.UUID, p CreateUrlParams) (string, error)
 }
 type Handler struct {
-	service *Service
-	l       *slog.Logger
+	service  *Service
+	redirect config.Redirect
+	baseURL  string
+	l        *slog.Logger
 }
 
-func NewHandler(service *Service, l *slog.Logger) *Handler {
+func NewHandler(service *Service, redirect config.Redirect, baseURL string, l *slog.Logger) *Handler {
 	return &Handler{
-		service: service,
-		l:       l,
+		service:  service,
+		redirect: redirect,
+		baseURL:  baseURL,
+		l:        l,
 	}
 }
 
 // @Summary  GetAllUrlHandler
 // @Tags url
-// @Description Get all urls admin
+// @Description Get all urls, admin only
 // @Accept json
 // @Produce json
 // @Success 200 {object}  GetAllUrlResponse
-// @Failure 400,404 {object}  api.Response
+// @Failure 400,401,403,404 {object}  api.Response
 // @Failure 500 {object}  api.Response
 // @Router /url/all [get]
 func (h *Handler) GetAllUrlHandler(w http.ResponseWriter, r *http.Request) {
@@ -66,8 +77,8 @@ func (h *Handler) GetAllUrlHandler(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param input body CreateUrlRequest true "login body"
-// @Success 200 {object}  GetAllUrlResponse
-// @Failure 400,404 {object}  api.Response
+// @Success 201 {object}  CreateUrlResponse
+// @Failure 400,404,409 {object}  api.Response
 // @Failure 500 {object}  api.Response
 // @Router /url/save [post]
 func (h *Handler) CreateUrlHandler(w http.ResponseWriter, r *http.Request) {
@@ -80,32 +91,54 @@ func (h *Handler) CreateUrlHandler(w http.ResponseWriter, r *http.Request) {
 	if err := render.DecodeJSON(r.Body, &req); err != nil {
 		log.Error("failed to decode request body", slog.Any("err", err))
 		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, api.Error("Ошибка при валидации данных"))
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidBody))
 		return
 	}
 	if err := validator.New().Struct(req); err != nil {
 		log.Error("invalid request", logger.Err(err))
 		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, api.Error("invalid request"))
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidRequest))
+		return
+	}
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	alias, err := h.service.CreateUrl(r.Context(), claims.ID, CreateUrlParams{
+		Url:         req.Url,
+		Alias:       req.Alias,
+		NotBefore:   req.NotBefore,
+		ExpiresAt:   req.ExpiresAt,
+		Timezone:    req.Timezone,
+		TimeRules:   req.TimeRules,
+		FolderID:    req.FolderID,
+		TagIDs:      req.TagIDs,
+		DomainID:    req.DomainID,
+		FallbackUrl: req.FallbackUrl,
+	})
+	if isInvalidUrlParams(err) {
+		log.Error("invalid url params", logger.Err(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
 		return
 	}
-	claims, ok := r.Context().Value(contextkey.UserIDCtxKey).(*user.Claims)
-	if !ok {
-		log.Error("failed to parse product uuid")
-		render.Status(r, http.StatusUnauthorized)
-		render.JSON(w, r, api.Error("failed send coins"))
+	if errors.Is(err, utils.ErrorUrlExists) || errors.Is(err, utils.ErrorAliasTaken) {
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
 		return
 	}
-	if err := h.service.CreateUrl(r.Context(), claims.ID, req.Url); err != nil {
+	if err != nil {
 		log.Error("failed to create url", logger.Err(err))
 		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, api.Error("failed to create url"))
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrCreateUrl))
 		return
 	}
 
 	render.Status(r, http.StatusCreated)
 	render.JSON(w, r, CreateUrlResponse{
 		Response: api.OK(),
+		Url:      alias,
 	})
 }
 
@@ -114,6 +147,8 @@ func (h *Handler) CreateUrlHandler(w http.ResponseWriter, r *http.Request) {
 // @Description Get all urls by id
 // @Accept json
 // @Produce json
+// @Param tag query string false "tag id"
+// @Param folder query string false "folder id"
 // @Success 200 {object}  GetAllUrlResponse
 // @Failure 400,404 {object}  api.Response
 // @Failure 500 {object}  api.Response
@@ -124,18 +159,35 @@ func (h *Handler) GetAllUrlByUserId(w http.ResponseWriter, r *http.Request) {
 		slog.String("op", op),
 		slog.String("request_id", middleware.GetReqID(r.Context())),
 	)
-	claims, ok := r.Context().Value(contextkey.UserIDCtxKey).(*user.Claims)
-	if !ok {
-		log.Error("failed to parse product uuid")
-		render.Status(r, http.StatusUnauthorized)
-		render.JSON(w, r, api.Error("Unauthorized"))
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
 		return
 	}
-	url, err := h.service.GetUrlByUser(r.Context(), claims.ID)
+	var filter UrlFilter
+	if tag := r.URL.Query().Get("tag"); tag != "" {
+		id, err := uuid.Parse(tag)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidTagID))
+			return
+		}
+		filter.TagID = &id
+	}
+	if folder := r.URL.Query().Get("folder"); folder != "" {
+		id, err := uuid.Parse(folder)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidFolderID))
+			return
+		}
+		filter.FolderID = &id
+	}
+	url, err := h.service.GetUrlByUser(r.Context(), claims.ID, filter)
 	if err != nil {
-		log.Error("failed to parse product uuid")
+		log.Error("failed to get urls", logger.Err(err))
 		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, api.Error("failed send coins"))
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrGetUrls))
 		return
 	}
 	render.Status(r, http.StatusOK)
@@ -144,3 +196,342 @@ func (h *Handler) GetAllUrlByUserId(w http.ResponseWriter, r *http.Request) {
 		Urls:     url,
 	})
 }
+
+// @Summary  UpdateUrlHandler
+// @Tags url
+// @Description Update url destination, schedule, folder or tags
+// @Accept json
+// @Produce json
+// @Param id path string true "url id"
+// @Param input body UpdateUrlRequest true "update body"
+// @Success 200 {object}  UpdateUrlResponse
+// @Failure 400,404,409 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /url/{id} [patch]
+func (h *Handler) UpdateUrlHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Url.Handler.UpdateUrl"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidUrlID))
+		return
+	}
+	var req UpdateUrlRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		log.Error("failed to decode request body", slog.Any("err", err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidBody))
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		log.Error("invalid request", logger.Err(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidRequest))
+		return
+	}
+	url, err := h.service.UpdateUrl(r.Context(), id, claims.ID, UpdateUrlParams{
+		Url:         req.Url,
+		NotBefore:   req.NotBefore,
+		Timezone:    req.Timezone,
+		TimeRules:   req.TimeRules,
+		FolderID:    req.FolderID,
+		TagIDs:      req.TagIDs,
+		DomainID:    req.DomainID,
+		FallbackUrl: req.FallbackUrl,
+	})
+	if errors.Is(err, utils.ErrorUrlNotFound) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrUrlNotFound))
+		return
+	}
+	if isInvalidUrlParams(err) {
+		log.Error("invalid url params", logger.Err(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	}
+	if errors.Is(err, utils.ErrorUrlExists) {
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	}
+	if err != nil {
+		log.Error("failed to update url", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrUpdateUrl))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, UpdateUrlResponse{
+		Response: api.OK(),
+		Url:      *url,
+	})
+}
+
+// @Summary  DeleteUrlHandler
+// @Tags url
+// @Description Delete url together with its revisions
+// @Produce json
+// @Param id path string true "url id"
+// @Success 200 {object}  api.Response
+// @Failure 400,404 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /url/{id} [delete]
+func (h *Handler) DeleteUrlHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Url.Handler.DeleteUrl"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidUrlID))
+		return
+	}
+	err = h.service.DeleteUrl(r.Context(), id, claims.ID)
+	if errors.Is(err, utils.ErrorUrlNotFound) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	}
+	if err != nil {
+		log.Error("failed to delete url", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrDeleteUrl))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, api.OK())
+}
+
+// @Summary  GetRevisionsHandler
+// @Tags url
+// @Description History of destination and routing rule changes, newest first
+// @Produce json
+// @Param id path string true "url id"
+// @Success 200 {object}  GetRevisionsResponse
+// @Failure 400 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /url/{id}/revisions [get]
+func (h *Handler) GetRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Url.Handler.GetRevisions"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidUrlID))
+		return
+	}
+	revisions, err := h.service.GetRevisions(r.Context(), id, claims.ID)
+	if err != nil {
+		log.Error("failed to get revisions", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrGetRevisions))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, GetRevisionsResponse{
+		Response:  api.OK(),
+		Revisions: revisions,
+	})
+}
+
+// @Summary  RollbackHandler
+// @Tags url
+// @Description Restore the destination and routing rules the link had right after the revision
+// @Produce json
+// @Param id path string true "url id"
+// @Param revisionId path string true "revision id"
+// @Success 200 {object}  UpdateUrlResponse
+// @Failure 400,404,409 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /url/{id}/revisions/{revisionId}/rollback [post]
+func (h *Handler) RollbackHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Url.Handler.Rollback"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidUrlID))
+		return
+	}
+	revisionId, err := uuid.Parse(chi.URLParam(r, "revisionId"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidRevisionID))
+		return
+	}
+	url, err := h.service.Rollback(r.Context(), id, revisionId, claims.ID)
+	if errors.Is(err, utils.ErrorRevisionNotFound) || errors.Is(err, utils.ErrorUrlNotFound) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	}
+	if isInvalidUrlParams(err) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	}
+	if errors.Is(err, utils.ErrorUrlExists) {
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	}
+	if err != nil {
+		log.Error("failed to rollback url", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrRollbackUrl))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, UpdateUrlResponse{
+		Response: api.OK(),
+		Url:      *url,
+	})
+}
+
+// @Summary  QRHandler
+// @Tags url
+// @Description QR code of the short link on the shared domain as a JPEG image
+// @Produce jpeg
+// @Param alias path string true "short link alias"
+// @Success 200 {file} binary
+// @Failure 400,404 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /qr/{alias} [get]
+func (h *Handler) QRHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Url.Handler.QR"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	alias := chi.URLParam(r, "alias")
+	if !validAlias(alias) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidAlias))
+		return
+	}
+	_, err := h.service.GetSharedUrl(r.Context(), alias)
+	if errors.Is(err, utils.ErrorUrlNotFound) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrUrlNotFound))
+		return
+	}
+	if err != nil {
+		log.Error("failed to get url", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInternal))
+		return
+	}
+	img, err := QRCode(h.baseURL+"/"+alias, qrSize)
+	if err != nil {
+		log.Error("qr code error", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInternal))
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(img)
+}
+
+// @Summary  RedirectHandler
+// @Tags url
+// @Description Redirect to the destination of a short link, the alias is looked up on the domain from the Host header
+// @Param alias path string true "short link alias"
+// @Success 302
+// @Failure 404,410 {object}  api.Response
+// @Failure 503 {object}  api.Response
+// @Router /{alias} [get]
+func (h *Handler) RedirectHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Url.Handler.Redirect"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	alias := chi.URLParam(r, "alias")
+	host := customdomain.NormalizeHost(r.Host)
+	destination, url, err := h.service.Resolve(r.Context(), host, alias, time.Now())
+	if errors.Is(err, utils.ErrorUrlNotFound) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrUrlNotFound))
+		return
+	}
+	if errors.Is(err, utils.ErrorUrlNotActive) {
+		h.comingSoon(w, r, url)
+		return
+	}
+	if errors.Is(err, utils.ErrorUrlExpired) {
+		render.Status(r, http.StatusGone)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrUrlExpired))
+		return
+	}
+	if err != nil {
+		log.Error("failed to resolve url", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInternal))
+		return
+	}
+	h.service.Clicked(r.Context(), url, destination, r.Referer(), r.UserAgent())
+	http.Redirect(w, r, destination, http.StatusFound)
+}
+
+func (h *Handler) comingSoon(w http.ResponseWriter, r *http.Request, url *models.Url) {
+	if h.redirect.ComingSoonURL != "" {
+		http.Redirect(w, r, h.redirect.ComingSoonURL, http.StatusFound)
+		return
+	}
+	if url != nil && url.NotBefore != nil {
+		retry := int(time.Until(*url.NotBefore).Seconds()) + 1
+		w.Header().Set("Retry-After", strconv.Itoa(retry))
+	}
+	render.Status(r, h.redirect.ComingSoonStatus)
+	render.JSON(w, r, api.Response{
+		Status: api.StatusError,
+		Code:   string(i18n.ErrUrlNotActive),
+		Error:  h.redirect.ComingSoonMessage,
+	})
+}
+
+func isInvalidUrlParams(err error) bool {
+	return errors.Is(err, utils.ErrorInvalidUrl) ||
+		errors.Is(err, utils.ErrorUrlBlocked) ||
+		errors.Is(err, utils.ErrorInvalidAlias) ||
+		errors.Is(err, utils.ErrorInvalidExpiry) ||
+		errors.Is(err, utils.ErrorInvalidTimeRule) ||
+		errors.Is(err, utils.ErrorInvalidTimezone) ||
+		errors.Is(err, utils.ErrorTagNotFound) ||
+		errors.Is(err, utils.ErrorFolderNotFound) ||
+		errors.Is(err, utils.ErrorDomainNotVerified)
+}
@@ -8,6 +8,8 @@ import (
 	models "github.com/Sanchir01/go-shortener/internal/domain/models"
 	mock "github.com/stretchr/testify/mock"
 
+	url "github.com/Sanchir01/go-shortener/internal/feature/url"
+
 	uuid "github.com/google/uuid"
 )
 
@@ -16,22 +18,32 @@ type UrlHandler struct {
 	mock.Mock
 }
 
-// CreateUrl provides a mock function with given fields: ctx, userId, _a2
-func (_m *UrlHandler) CreateUrl(ctx context.Context, userId uuid.UUID, _a2 string) error {
-	ret := _m.Called(ctx, userId, _a2)
+// CreateUrl provides a mock function with given fields: ctx, userId, p
+func (_m *UrlHandler) CreateUrl(ctx context.Context, userId uuid.UUID, p url.CreateUrlParams) (string, error) {
+	ret := _m.Called(ctx, userId, p)
 
 	if len(ret) == 0 {
 		panic("no return value specified for CreateUrl")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string) error); ok {
-		r0 = rf(ctx, userId, _a2)
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, url.CreateUrlParams) (string, error)); ok {
+		return rf(ctx, userId, p)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, url.CreateUrlParams) string); ok {
+		r0 = rf(ctx, userId, p)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, url.CreateUrlParams) error); ok {
+		r1 = rf(ctx, userId, p)
 	} else {
-		r0 = ret.Error(0)
+		r1 = ret.Error(1)
 	}
 
-	return r0
+	return r0, r1
 }
 
 // GetAllUrl provides a mock function with given fields: ctx
@@ -0,0 +1,32 @@
+package url
+
+import (
+	"bytes"
+	"image/jpeg"
+	"regexp"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const qrSize = 512
+
+// aliasPattern covers generated aliases and custom ones.
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+func validAlias(alias string) bool {
+	return aliasPattern.MatchString(alias)
+}
+
+// QRCode encodes link as a JPEG, the format Telegram requires for photos
+// sent by url.
+func QRCode(link string, size int) ([]byte, error) {
+	qr, err := qrcode.New(link, qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, qr.Image(size), &jpeg.Options{Quality: 90}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
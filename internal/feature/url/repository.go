@@ -2,15 +2,23 @@ package url
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const urlColumns = "id, url, alias, created_at, updated_at, user_id, not_before, expires_at, timezone, time_rules, folder_id, domain_id, fallback_url, " +
+	"COALESCE((SELECT broken FROM url_health WHERE url_health.url_id = url.id), false) AS broken, " +
+	"COALESCE((SELECT array_agg(tag_id) FROM url_tags WHERE url_tags.url_id = url.id), '{}') AS tag_ids"
+
 type Repository struct {
 	primaryDB *pgxpool.Pool
 	l         *slog.Logger
@@ -23,72 +31,363 @@ func NewRepository(primaryDB *pgxpool.Pool, l *slog.Logger) *Repository {
 	}
 }
 
-func (r *Repository) CreateUrl(ctx context.Context, userId uuid.UUID, url, alias string, tx pgx.Tx) error {
+func scanUrl(row pgx.Row) (*models.Url, error) {
+	var url models.Url
+	if err := row.Scan(
+		&url.ID, &url.Url, &url.Alias, &url.CreatedAt, &url.UpdatedAt,
+		&url.UserID, &url.NotBefore, &url.ExpiresAt, &url.Timezone, &url.TimeRules,
+		&url.FolderID, &url.DomainID, &url.FallbackUrl, &url.Broken, &url.TagIDs,
+	); err != nil {
+		return nil, err
+	}
+	return &url, nil
+}
+
+func (r *Repository) CreateUrl(ctx context.Context, userId uuid.UUID, alias string, p CreateUrlParams, tx pgx.Tx) (*uuid.UUID, error) {
 	const op = "Url.Repository.CreateUrl"
 	log := r.l.With(slog.String("op", op))
 
-	log.Info("creating url repo", "url", url, "alias", alias)
+	log.Info("creating url repo", "url", p.Url, "alias", alias)
 	query, args, err := sq.
 		Insert("url").
-		Columns("user_id", "url", "alias").
-		Values(userId, url, alias).
+		Columns("user_id", "url", "alias", "not_before", "expires_at", "timezone", "time_rules", "folder_id", "domain_id", "fallback_url").
+		Values(userId, p.Url, alias, p.NotBefore, p.ExpiresAt, p.Timezone, p.TimeRules, p.FolderID, p.DomainID, p.FallbackUrl).
+		Suffix("RETURNING id").
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
 
 	if err != nil {
-		log.Error("error", err.Error())
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+
+	var id uuid.UUID
+	if err := tx.QueryRow(ctx, query, args...).Scan(&id); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, utils.ErrorFolderNotFound
+		}
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "url_user_url_unique" {
+			return nil, utils.ErrorUrlExists
+		}
+		if errors.As(err, &pgErr) && (pgErr.ConstraintName == "url_alias_shared_unique" || pgErr.ConstraintName == "url_alias_domain_unique") {
+			return nil, utils.ErrorAliasTaken
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+
+	log.Info("creating url repo")
+	return &id, nil
+}
+
+// UpdateUrl applies the present fields of p to the link owned by userId.
+func (r *Repository) UpdateUrl(ctx context.Context, id, userId uuid.UUID, p UpdateUrlParams, tx pgx.Tx) error {
+	const op = "Url.Repository.UpdateUrl"
+	log := r.l.With(slog.String("op", op))
+
+	builder := sq.
+		Update("url").
+		Where(sq.Eq{"id": id, "user_id": userId}).
+		PlaceholderFormat(sq.Dollar)
+	if p.Url != nil {
+		builder = builder.Set("url", *p.Url)
+	}
+	if p.NotBefore != nil {
+		builder = builder.Set("not_before", *p.NotBefore)
+	}
+	if p.Timezone != nil {
+		builder = builder.Set("timezone", *p.Timezone)
+	}
+	if p.TimeRules != nil {
+		builder = builder.Set("time_rules", *p.TimeRules)
+	}
+	if p.FolderID != nil {
+		if *p.FolderID == uuid.Nil {
+			builder = builder.Set("folder_id", nil)
+		} else {
+			builder = builder.Set("folder_id", *p.FolderID)
+		}
+	}
+	if p.DomainID != nil {
+		if *p.DomainID == uuid.Nil {
+			builder = builder.Set("domain_id", nil)
+		} else {
+			builder = builder.Set("domain_id", *p.DomainID)
+		}
+	}
+	if p.FallbackUrl != nil {
+		if *p.FallbackUrl == "" {
+			builder = builder.Set("fallback_url", nil)
+		} else {
+			builder = builder.Set("fallback_url", *p.FallbackUrl)
+		}
+	}
+	// touch updated_at so the statement is valid even when only tags change
+	builder = builder.Set("updated_at", sq.Expr("now()"))
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
 		return err
 	}
 
+	tag, err := tx.Exec(ctx, query, args...)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return utils.ErrorFolderNotFound
+		}
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "url_user_url_unique" {
+			return utils.ErrorUrlExists
+		}
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return utils.ErrorUrlNotFound
+	}
+	return nil
+}
+
+// SetUrlTags replaces the tags of a link, ignoring tags that belong to
+// another user. It returns ErrorTagNotFound when some of them were skipped.
+func (r *Repository) SetUrlTags(ctx context.Context, urlId, userId uuid.UUID, tagIDs []uuid.UUID, tx pgx.Tx) error {
+	const op = "Url.Repository.SetUrlTags"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Delete("url_tags").
+		Where(sq.Eq{"url_id": urlId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
 	if _, err := tx.Exec(ctx, query, args...); err != nil {
-		log.Error("error", err.Error())
+		log.Error("error", logger.Err(err))
 		return err
 	}
+	if len(tagIDs) == 0 {
+		return nil
+	}
 
-	log.Info("creating url repo")
+	query, args, err = sq.
+		Insert("url_tags").
+		Columns("url_id", "tag_id").
+		Select(sq.
+			Select().
+			Column("?::uuid", urlId).
+			Column("id").
+			From("tags").
+			Where(sq.Eq{"id": tagIDs, "user_id": userId})).
+		Suffix("ON CONFLICT DO NOTHING").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	tag, err := tx.Exec(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	if tag.RowsAffected() != int64(len(uniqueIDs(tagIDs))) {
+		return utils.ErrorTagNotFound
+	}
 	return nil
 }
 
-func (r *Repository) GetUrlByUserId(ctx context.Context, userId uuid.UUID) ([]models.Url, error) {
-	const op = "Url.Repository.GetUrlByUserId"
+// IsDomainVerified reports whether the domain belongs to userId and passed
+// ownership verification.
+func (r *Repository) IsDomainVerified(ctx context.Context, domainId, userId uuid.UUID, tx pgx.Tx) (bool, error) {
+	const op = "Url.Repository.IsDomainVerified"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select().
+		Column(sq.Expr("EXISTS (SELECT 1 FROM domains WHERE id = ? AND user_id = ? AND verified_at IS NOT NULL)", domainId, userId)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return false, err
+	}
+	var ok bool
+	if err := tx.QueryRow(ctx, query, args...).Scan(&ok); err != nil {
+		log.Error("error", logger.Err(err))
+		return false, err
+	}
+	return ok, nil
+}
+
+func (r *Repository) IsFolderOwned(ctx context.Context, folderId, userId uuid.UUID, tx pgx.Tx) (bool, error) {
+	const op = "Url.Repository.IsFolderOwned"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select().
+		Column(sq.Expr("EXISTS (SELECT 1 FROM folders WHERE id = ? AND user_id = ?)", folderId, userId)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return false, err
+	}
+	var ok bool
+	if err := tx.QueryRow(ctx, query, args...).Scan(&ok); err != nil {
+		log.Error("error", logger.Err(err))
+		return false, err
+	}
+	return ok, nil
+}
+
+// GetUrlByAlias looks the alias up on the branded domain with the given host,
+// or among the shared links when host is not a verified branded domain.
+func (r *Repository) GetUrlByAlias(ctx context.Context, host, alias string) (*models.Url, error) {
+	const op = "Url.Repository.GetUrlByAlias"
 	log := r.l.With(slog.String("op", op))
 	conn, err := r.primaryDB.Acquire(ctx)
 	if err != nil {
-		log.Error("error", err.Error())
+		log.Error("error", logger.Err(err))
 		return nil, err
 	}
 	defer conn.Release()
 
 	query, args, err := sq.
-		Select("id, url,alias, created_at, updated_at").
+		Select(urlColumns).
 		From("url").
-		Where(sq.Eq{"user_id": userId}).
+		Where(sq.Eq{"alias": alias}).
+		Where("domain_id IS NOT DISTINCT FROM (SELECT id FROM domains WHERE host = ? AND verified_at IS NOT NULL)", host).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
 
+	url, err := scanUrl(conn.QueryRow(ctx, query, args...))
 	if err != nil {
-		log.Error("error", err.Error())
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.ErrorUrlNotFound
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return url, nil
+}
+
+func (r *Repository) GetUrlByID(ctx context.Context, id uuid.UUID) (*models.Url, error) {
+	const op = "Url.Repository.GetUrlByID"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select(urlColumns).
+		From("url").
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+
+	url, err := scanUrl(r.primaryDB.QueryRow(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.ErrorUrlNotFound
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return url, nil
+}
+
+// DeleteUrl removes the link of the user and returns its alias.
+func (r *Repository) DeleteUrl(ctx context.Context, id, userId uuid.UUID) (string, error) {
+	const op = "Url.Repository.DeleteUrl"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Delete("url").
+		Where(sq.Eq{"id": id, "user_id": userId}).
+		Suffix("RETURNING alias").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return "", err
+	}
+
+	var alias string
+	if err := r.primaryDB.QueryRow(ctx, query, args...).Scan(&alias); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", utils.ErrorUrlNotFound
+		}
+		log.Error("error", logger.Err(err))
+		return "", err
+	}
+	return alias, nil
+}
+
+func (r *Repository) GetUrlByUserId(ctx context.Context, userId uuid.UUID, filter UrlFilter) ([]models.Url, error) {
+	const op = "Url.Repository.GetUrlByUserId"
+	log := r.l.With(slog.String("op", op))
+	conn, err := r.primaryDB.Acquire(ctx)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	defer conn.Release()
+
+	builder := sq.
+		Select(urlColumns).
+		From("url").
+		Where(sq.Eq{"user_id": userId}).
+		PlaceholderFormat(sq.Dollar)
+	if filter.FolderID != nil {
+		builder = builder.Where(sq.Eq{"folder_id": *filter.FolderID})
+	}
+	if filter.TagID != nil {
+		builder = builder.Where(sq.Expr("EXISTS (SELECT 1 FROM url_tags WHERE url_tags.url_id = url.id AND url_tags.tag_id = ?)", *filter.TagID))
+	}
+	if filter.Alias != "" {
+		builder = builder.Where(sq.Eq{"alias": filter.Alias})
+	}
+	if filter.Url != "" {
+		builder = builder.Where(sq.Eq{"url": filter.Url})
+	}
+	builder = builder.OrderBy("created_at DESC", "id")
+	if filter.Limit > 0 {
+		builder = builder.Limit(filter.Limit).Offset(filter.Offset)
+	}
+	query, args, err := builder.ToSql()
+
+	if err != nil {
+		log.Error("error", logger.Err(err))
 		return nil, err
 	}
 
 	rows, err := conn.Query(ctx, query, args...)
 	if err != nil {
-		log.Error("error", err.Error())
+		log.Error("error", logger.Err(err))
 		return nil, err
 	}
 
 	urls := make([]models.Url, 0, 100)
 	defer rows.Close()
 	for rows.Next() {
-		var url models.Url
-		if err := rows.Scan(&url.ID, &url.Url, &url.Alias, &url.CreatedAt, &url.UpdatedAt); err != nil {
-			log.Error("error", err.Error())
+		url, err := scanUrl(rows)
+		if err != nil {
+			log.Error("error", logger.Err(err))
 			return nil, err
 		}
-		urls = append(urls, url)
+		urls = append(urls, *url)
 	}
 	if err := rows.Err(); err != nil {
-		log.Error("error", err.Error())
+		log.Error("error", logger.Err(err))
 		return nil, err
 	}
 	log.Info("getting all urls repo")
@@ -100,41 +399,54 @@ func (r *Repository) GetAllUrl(ctx context.Context) ([]models.Url, error) {
 	log := r.l.With(slog.String("op", op))
 	conn, err := r.primaryDB.Acquire(ctx)
 	if err != nil {
-		log.Error("error", err.Error())
+		log.Error("error", logger.Err(err))
 		return nil, err
 	}
 	defer conn.Release()
 
 	query, args, err := sq.
-		Select("id, url,alias, created_at, updated_at").
+		Select(urlColumns).
 		From("url").
 		ToSql()
 
 	if err != nil {
-		log.Error("error", err.Error())
+		log.Error("error", logger.Err(err))
 		return nil, err
 	}
 
 	rows, err := conn.Query(ctx, query, args...)
 	if err != nil {
-		log.Error("error", err.Error())
+		log.Error("error", logger.Err(err))
 		return nil, err
 	}
 
 	urls := make([]models.Url, 0, 100)
 	defer rows.Close()
 	for rows.Next() {
-		var url models.Url
-		if err := rows.Scan(&url.ID, &url.Url, &url.Alias, &url.CreatedAt, &url.UpdatedAt); err != nil {
-			log.Error("error", err.Error())
+		url, err := scanUrl(rows)
+		if err != nil {
+			log.Error("error", logger.Err(err))
 			return nil, err
 		}
-		urls = append(urls, url)
+		urls = append(urls, *url)
 	}
 	if err := rows.Err(); err != nil {
-		log.Error("error", err.Error())
+		log.Error("error", logger.Err(err))
 		return nil, err
 	}
 	log.Info("getting all urls repo")
 	return urls, nil
 }
+
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]struct{}, len(ids))
+	out := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	return out
+}
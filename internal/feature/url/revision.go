@@ -0,0 +1,130 @@
+package url
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SetActor stores the user changing links in tx, the url_revisions trigger
+// reads it to fill changed_by.
+func (r *Repository) SetActor(ctx context.Context, userId uuid.UUID, tx pgx.Tx) error {
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.user_id', $1, true)", userId.String()); err != nil {
+		r.l.Error("set actor error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) GetRevisions(ctx context.Context, urlId, userId uuid.UUID) ([]models.UrlRevision, error) {
+	const op = "Url.Repository.GetRevisions"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select("rev.id, rev.url_id, rev.changed_by, rev.before, rev.after, rev.created_at").
+		From("url_revisions rev").
+		Join("url ON url.id = rev.url_id").
+		Where(sq.Eq{"rev.url_id": urlId, "url.user_id": userId}).
+		OrderBy("rev.created_at DESC").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+
+	rows, err := r.primaryDB.Query(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := make([]models.UrlRevision, 0, 16)
+	for rows.Next() {
+		var rev models.UrlRevision
+		if err := rows.Scan(&rev.ID, &rev.UrlID, &rev.ChangedBy, &rev.Before, &rev.After, &rev.CreatedAt); err != nil {
+			log.Error("error", logger.Err(err))
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (r *Repository) GetRevision(ctx context.Context, id, urlId, userId uuid.UUID, tx pgx.Tx) (*models.UrlRevision, error) {
+	const op = "Url.Repository.GetRevision"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select("rev.id, rev.url_id, rev.changed_by, rev.before, rev.after, rev.created_at").
+		From("url_revisions rev").
+		Join("url ON url.id = rev.url_id").
+		Where(sq.Eq{"rev.id": id, "rev.url_id": urlId, "url.user_id": userId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+
+	var rev models.UrlRevision
+	if err := tx.QueryRow(ctx, query, args...).Scan(&rev.ID, &rev.UrlID, &rev.ChangedBy, &rev.Before, &rev.After, &rev.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.ErrorRevisionNotFound
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// RestoreRouting overwrites every routing field of the link with routing.
+func (r *Repository) RestoreRouting(ctx context.Context, id, userId uuid.UUID, routing models.UrlRouting, tx pgx.Tx) error {
+	const op = "Url.Repository.RestoreRouting"
+	log := r.l.With(slog.String("op", op))
+
+	if routing.TimeRules == nil {
+		routing.TimeRules = []models.TimeRule{}
+	}
+	query, args, err := sq.
+		Update("url").
+		Set("url", routing.Url).
+		Set("not_before", routing.NotBefore).
+		Set("timezone", routing.Timezone).
+		Set("time_rules", routing.TimeRules).
+		Set("fallback_url", routing.FallbackUrl).
+		Where(sq.Eq{"id": id, "user_id": userId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, query, args...)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "url_user_url_unique" {
+			return utils.ErrorUrlExists
+		}
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return utils.ErrorUrlNotFound
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package url
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+)
+
+const timeRuleLayout = "15:04"
+
+func LoadLocation(name, fallback string) (*time.Location, error) {
+	if name == "" {
+		name = fallback
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", utils.ErrorInvalidTimezone, name)
+	}
+	return loc, nil
+}
+
+// ValidateTimeRules checks the windows of rules and sends their urls through
+// ValidateUrl with blocked, like the url of the link itself.
+func ValidateTimeRules(rules []models.TimeRule, blocked []string) error {
+	for i, rule := range rules {
+		if err := ValidateUrl(rule.Url, blocked); err != nil {
+			return fmt.Errorf("time rule #%d: %w", i, err)
+		}
+		start, err := time.Parse(timeRuleLayout, rule.Start)
+		if err != nil {
+			return fmt.Errorf("%w #%d: start %q", utils.ErrorInvalidTimeRule, i, rule.Start)
+		}
+		end, err := time.Parse(timeRuleLayout, rule.End)
+		if err != nil {
+			return fmt.Errorf("%w #%d: end %q", utils.ErrorInvalidTimeRule, i, rule.End)
+		}
+		if start.Equal(end) {
+			return fmt.Errorf("%w #%d: empty window", utils.ErrorInvalidTimeRule, i)
+		}
+		for _, d := range rule.Days {
+			if d < time.Sunday || d > time.Saturday {
+				return fmt.Errorf("%w #%d: day %d", utils.ErrorInvalidTimeRule, i, d)
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveDestination picks where a visitor of u should go at the moment now.
+// It returns ErrorUrlNotActive before NotBefore and ErrorUrlExpired from
+// ExpiresAt on, otherwise the url of the first matching time rule or the
+// link's own url, which is swapped for the fallback while the health checker
+// marks it broken.
+func ResolveDestination(u *models.Url, loc *time.Location, now time.Time) (string, error) {
+	if u.NotBefore != nil && now.Before(*u.NotBefore) {
+		return "", utils.ErrorUrlNotActive
+	}
+	if u.ExpiresAt != nil && !now.Before(*u.ExpiresAt) {
+		return "", utils.ErrorUrlExpired
+	}
+	local := now.In(loc)
+	minutes := local.Hour()*60 + local.Minute()
+	for _, rule := range u.TimeRules {
+		if ruleMatches(rule, local.Weekday(), minutes) {
+			return rule.Url, nil
+		}
+	}
+	if u.Broken && u.FallbackUrl != nil {
+		return *u.FallbackUrl, nil
+	}
+	return u.Url, nil
+}
+
+func ruleMatches(rule models.TimeRule, day time.Weekday, minutes int) bool {
+	start, end := ruleMinutes(rule.Start), ruleMinutes(rule.End)
+	if start < 0 || end < 0 {
+		return false
+	}
+	if start < end {
+		return minutes >= start && minutes < end && ruleHasDay(rule, day)
+	}
+	// the window wraps past midnight, the early hours belong to the day before
+	if minutes >= start {
+		return ruleHasDay(rule, day)
+	}
+	if minutes < end {
+		return ruleHasDay(rule, (day+6)%7)
+	}
+	return false
+}
+
+func ruleHasDay(rule models.TimeRule, day time.Weekday) bool {
+	if len(rule.Days) == 0 {
+		return true
+	}
+	for _, d := range rule.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleMinutes(value string) int {
+	t, err := time.Parse(timeRuleLayout, value)
+	if err != nil {
+		return -1
+	}
+	return t.Hour()*60 + t.Minute()
+}
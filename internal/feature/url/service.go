@@ -4,29 +4,64 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"time"
 
+	"github.com/Sanchir01/go-shortener/internal/config"
+	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
 	"github.com/Sanchir01/go-shortener/internal/domain/models"
 	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type UrlService interface {
-	CreateUrl(ctx context.Context, userId uuid.UUID, url, alise string, tx pgx.Tx) error
-	GetUrlByUserId(ctx context.Context, userId uuid.UUID) ([]models.Url, error)
+	CreateUrl(ctx context.Context, userId uuid.UUID, alias string, p CreateUrlParams, tx pgx.Tx) (*uuid.UUID, error)
+	UpdateUrl(ctx context.Context, id, userId uuid.UUID, p UpdateUrlParams, tx pgx.Tx) error
+	SetUrlTags(ctx context.Context, urlId, userId uuid.UUID, tagIDs []uuid.UUID, tx pgx.Tx) error
+	SetActor(ctx context.Context, userId uuid.UUID, tx pgx.Tx) error
+	GetRevisions(ctx context.Context, urlId, userId uuid.UUID) ([]models.UrlRevision, error)
+	GetRevision(ctx context.Context, id, urlId, userId uuid.UUID, tx pgx.Tx) (*models.UrlRevision, error)
+	RestoreRouting(ctx context.Context, id, userId uuid.UUID, routing models.UrlRouting, tx pgx.Tx) error
+	IsDomainVerified(ctx context.Context, domainId, userId uuid.UUID, tx pgx.Tx) (bool, error)
+	IsFolderOwned(ctx context.Context, folderId, userId uuid.UUID, tx pgx.Tx) (bool, error)
+	GetUrlByAlias(ctx context.Context, host, alias string) (*models.Url, error)
+	GetUrlByID(ctx context.Context, id uuid.UUID) (*models.Url, error)
+	GetUrlByUserId(ctx context.Context, userId uuid.UUID, filter UrlFilter) ([]models.Url, error)
+	DeleteUrl(ctx context.Context, id, userId uuid.UUID) (string, error)
 	GetAllUrl(ctx context.Context) ([]models.Url, error)
 }
+
+// EventPublisher receives link events. Publish must not block, it is called
+// on the request path.
+type EventPublisher interface {
+	Publish(ctx context.Context, e models.LinkEvent)
+}
+
+// Publishers fans an event out to every publisher in the list.
+type Publishers []EventPublisher
+
+func (p Publishers) Publish(ctx context.Context, e models.LinkEvent) {
+	for _, publisher := range p {
+		publisher.Publish(ctx, e)
+	}
+}
+
 type Service struct {
 	repo      UrlService
 	primaryDB *pgxpool.Pool
+	redirect  config.Redirect
+	events    EventPublisher
 	l         *slog.Logger
 }
 
-func NewService(repo UrlService, primaryDB *pgxpool.Pool, l *slog.Logger) *Service {
+func NewService(repo UrlService, primaryDB *pgxpool.Pool, redirect config.Redirect, events EventPublisher, l *slog.Logger) *Service {
 	return &Service{
 		repo:      repo,
 		primaryDB: primaryDB,
+		redirect:  redirect,
+		events:    events,
 		l:         l,
 	}
 }
@@ -43,10 +78,10 @@ func (s *Service) GetAllUrl(ctx context.Context) ([]models.Url, error) {
 	log.Info("Getting all URLs completed service")
 	return urls, nil
 }
-func (s *Service) GetUrlByUser(ctx context.Context, userId uuid.UUID) ([]models.Url, error) {
+func (s *Service) GetUrlByUser(ctx context.Context, userId uuid.UUID, filter UrlFilter) ([]models.Url, error) {
 	const op = "Url.Service.GetAllUrl"
 	log := s.l.With(slog.String("op", op))
-	urls, err := s.repo.GetUrlByUserId(ctx, userId)
+	urls, err := s.repo.GetUrlByUserId(ctx, userId, filter)
 	if err != nil {
 		log.Error("get all users urls error", logger.Err(err))
 		return nil, err
@@ -54,20 +89,83 @@ func (s *Service) GetUrlByUser(ctx context.Context, userId uuid.UUID) ([]models.
 	log.Info("get users complete")
 	return urls, nil
 }
-func (s *Service) CreateUrl(ctx context.Context, userId uuid.UUID, url string) error {
+
+// CheckUrl tells whether CreateUrl would accept rawUrl as a destination
+// without creating anything.
+func (s *Service) CheckUrl(rawUrl string) error {
+	return ValidateUrl(rawUrl, s.redirect.BlockedHosts)
+}
+
+// GetUserUrlByAlias finds a link of the user by alias, preferring the shared
+// domain when the alias is also used on a custom one.
+func (s *Service) GetUserUrlByAlias(ctx context.Context, userId uuid.UUID, alias string) (*models.Url, error) {
+	urls, err := s.GetUrlByUser(ctx, userId, UrlFilter{Alias: alias})
+	if err != nil {
+		return nil, err
+	}
+	if len(urls) == 0 {
+		return nil, utils.ErrorUrlNotFound
+	}
+	for i := range urls {
+		if urls[i].DomainID == nil {
+			return &urls[i], nil
+		}
+	}
+	return &urls[0], nil
+}
+
+// GetSharedUrl finds a link on the shared domain by alias.
+func (s *Service) GetSharedUrl(ctx context.Context, alias string) (*models.Url, error) {
+	// no branded domain has an empty host
+	return s.repo.GetUrlByAlias(ctx, "", alias)
+}
+
+func (s *Service) CreateUrl(ctx context.Context, userId uuid.UUID, p CreateUrlParams) (string, error) {
 	const op = "Url.Service.CreateUrl"
 	log := s.l.With(slog.String("op", op))
 
+	if err := ValidateUrl(p.Url, s.redirect.BlockedHosts); err != nil {
+		log.Info("rejected url", logger.Err(err))
+		return "", err
+	}
+	if p.FallbackUrl != nil {
+		if err := ValidateUrl(*p.FallbackUrl, s.redirect.BlockedHosts); err != nil {
+			log.Info("rejected fallback url", logger.Err(err))
+			return "", err
+		}
+	}
+	if p.Alias != "" {
+		if err := ValidateAlias(p.Alias); err != nil {
+			log.Info("rejected alias", logger.Err(err))
+			return "", err
+		}
+	}
+	if err := ValidateExpiry(p.ExpiresAt, p.NotBefore, time.Now()); err != nil {
+		log.Info("rejected expiry", logger.Err(err))
+		return "", err
+	}
+	if _, err := LoadLocation(p.Timezone, s.redirect.Timezone); err != nil {
+		log.Error("invalid timezone", logger.Err(err))
+		return "", err
+	}
+	if err := ValidateTimeRules(p.TimeRules, s.redirect.BlockedHosts); err != nil {
+		log.Error("invalid time rules", logger.Err(err))
+		return "", err
+	}
+	if p.TimeRules == nil {
+		p.TimeRules = []models.TimeRule{}
+	}
+
 	conn, err := s.primaryDB.Acquire(ctx)
 	if err != nil {
 		log.Error("errir init acquire", logger.Err(err))
-		return err
+		return "", err
 	}
 	defer conn.Release()
 	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		log.Error("error init tx", logger.Err(err))
-		return err
+		return "", err
 	}
 
 	defer func() {
@@ -80,17 +178,305 @@ func (s *Service) CreateUrl(ctx context.Context, userId uuid.UUID, url string) e
 			}
 		}
 	}()
-	alias := NewRandomString(10)
-	if err := s.repo.CreateUrl(ctx, userId, url, alias, tx); err != nil {
+	if err = s.repo.SetActor(ctx, userId, tx); err != nil {
+		return "", err
+	}
+	if p.DomainID != nil {
+		if err = s.checkDomain(ctx, *p.DomainID, userId, tx); err != nil {
+			log.Error("domain check error", logger.Err(err))
+			return "", err
+		}
+	}
+	if p.FolderID != nil {
+		if err = s.checkFolder(ctx, *p.FolderID, userId, tx); err != nil {
+			log.Error("folder check error", logger.Err(err))
+			return "", err
+		}
+	}
+	alias := p.Alias
+	if alias == "" {
+		alias = NewRandomString(10)
+	}
+	id, err := s.repo.CreateUrl(ctx, userId, alias, p, tx)
+	if err != nil {
 		log.Error("create url error", logger.Err(err))
-		return err
+		return "", err
+	}
+	if err = s.repo.SetUrlTags(ctx, *id, userId, p.TagIDs, tx); err != nil {
+		log.Error("set url tags error", logger.Err(err))
+		return "", err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
 		log.Error("commit error", logger.Err(err))
-		return err
+		return "", err
 	}
+	s.publish(ctx, contextkey.EventLinkCreated, userId, *id, alias, map[string]any{"url": p.Url})
 
 	log.Info("Creating URL completed service")
+	return alias, nil
+}
+
+func (s *Service) UpdateUrl(ctx context.Context, id, userId uuid.UUID, p UpdateUrlParams) (*models.Url, error) {
+	const op = "Url.Service.UpdateUrl"
+	log := s.l.With(slog.String("op", op))
+
+	if p.Url != nil {
+		if err := ValidateUrl(*p.Url, s.redirect.BlockedHosts); err != nil {
+			log.Info("rejected url", logger.Err(err))
+			return nil, err
+		}
+	}
+	// an empty fallback removes it
+	if p.FallbackUrl != nil && *p.FallbackUrl != "" {
+		if err := ValidateUrl(*p.FallbackUrl, s.redirect.BlockedHosts); err != nil {
+			log.Info("rejected fallback url", logger.Err(err))
+			return nil, err
+		}
+	}
+	if p.Timezone != nil {
+		if _, err := LoadLocation(*p.Timezone, s.redirect.Timezone); err != nil {
+			log.Error("invalid timezone", logger.Err(err))
+			return nil, err
+		}
+	}
+	if p.TimeRules != nil {
+		if err := ValidateTimeRules(*p.TimeRules, s.redirect.BlockedHosts); err != nil {
+			log.Error("invalid time rules", logger.Err(err))
+			return nil, err
+		}
+	}
+	if p.NotBefore != nil {
+		// expires_at is set on creation only, check the new start against it
+		stored, err := s.repo.GetUrlByID(ctx, id)
+		if err != nil {
+			log.Error("get url error", logger.Err(err))
+			return nil, err
+		}
+		if stored.UserID != userId {
+			return nil, utils.ErrorUrlNotFound
+		}
+		if err := ValidateExpiry(stored.ExpiresAt, p.NotBefore, time.Now()); err != nil {
+			log.Info("rejected expiry", logger.Err(err))
+			return nil, err
+		}
+	}
+
+	conn, err := s.primaryDB.Acquire(ctx)
+	if err != nil {
+		log.Error("error init acquire", logger.Err(err))
+		return nil, err
+	}
+	defer conn.Release()
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		log.Error("error init tx", logger.Err(err))
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Error("rollback error", logger.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.repo.SetActor(ctx, userId, tx); err != nil {
+		return nil, err
+	}
+	if p.DomainID != nil && *p.DomainID != uuid.Nil {
+		if err = s.checkDomain(ctx, *p.DomainID, userId, tx); err != nil {
+			log.Error("domain check error", logger.Err(err))
+			return nil, err
+		}
+	}
+	if p.FolderID != nil && *p.FolderID != uuid.Nil {
+		if err = s.checkFolder(ctx, *p.FolderID, userId, tx); err != nil {
+			log.Error("folder check error", logger.Err(err))
+			return nil, err
+		}
+	}
+	if err = s.repo.UpdateUrl(ctx, id, userId, p, tx); err != nil {
+		log.Error("update url error", logger.Err(err))
+		return nil, err
+	}
+	if p.TagIDs != nil {
+		if err = s.repo.SetUrlTags(ctx, id, userId, *p.TagIDs, tx); err != nil {
+			log.Error("set url tags error", logger.Err(err))
+			return nil, err
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Error("commit error", logger.Err(err))
+		return nil, err
+	}
+
+	url, err := s.repo.GetUrlByID(ctx, id)
+	if err != nil {
+		log.Error("get url error", logger.Err(err))
+		return nil, err
+	}
+	s.publish(ctx, contextkey.EventLinkUpdated, userId, url.ID, url.Alias, map[string]any{"url": url.Url})
+	log.Info("Updating URL completed service")
+	return url, nil
+}
+
+func (s *Service) GetRevisions(ctx context.Context, urlId, userId uuid.UUID) ([]models.UrlRevision, error) {
+	const op = "Url.Service.GetRevisions"
+	log := s.l.With(slog.String("op", op))
+
+	revisions, err := s.repo.GetRevisions(ctx, urlId, userId)
+	if err != nil {
+		log.Error("get revisions error", logger.Err(err))
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// Rollback brings the routing of the link back to the state right after the
+// given revision. The rollback itself is recorded as a new revision.
+func (s *Service) Rollback(ctx context.Context, urlId, revisionId, userId uuid.UUID) (*models.Url, error) {
+	const op = "Url.Service.Rollback"
+	log := s.l.With(slog.String("op", op))
+
+	conn, err := s.primaryDB.Acquire(ctx)
+	if err != nil {
+		log.Error("error init acquire", logger.Err(err))
+		return nil, err
+	}
+	defer conn.Release()
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		log.Error("error init tx", logger.Err(err))
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Error("rollback error", logger.Err(rollbackErr))
+			}
+		}
+	}()
+
+	rev, err := s.repo.GetRevision(ctx, revisionId, urlId, userId, tx)
+	if err != nil {
+		log.Error("get revision error", logger.Err(err))
+		return nil, err
+	}
+	// the revision may predate the blocked hosts or the expiry of the link
+	var stored *models.Url
+	if stored, err = s.repo.GetUrlByID(ctx, urlId); err != nil {
+		log.Error("get url error", logger.Err(err))
+		return nil, err
+	}
+	if err = ValidateRouting(rev.After, stored.ExpiresAt, s.redirect.BlockedHosts, s.redirect.Timezone, time.Now()); err != nil {
+		log.Info("rejected revision", logger.Err(err))
+		return nil, err
+	}
+	if err = s.repo.SetActor(ctx, userId, tx); err != nil {
+		return nil, err
+	}
+	if err = s.repo.RestoreRouting(ctx, urlId, userId, rev.After, tx); err != nil {
+		log.Error("restore routing error", logger.Err(err))
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Error("commit error", logger.Err(err))
+		return nil, err
+	}
+
+	url, err := s.repo.GetUrlByID(ctx, urlId)
+	if err != nil {
+		log.Error("get url error", logger.Err(err))
+		return nil, err
+	}
+	s.publish(ctx, contextkey.EventLinkUpdated, userId, url.ID, url.Alias, map[string]any{
+		"url":         url.Url,
+		"revision_id": revisionId,
+	})
+	log.Info("url rolled back", slog.String("revision_id", revisionId.String()))
+	return url, nil
+}
+
+func (s *Service) DeleteUrl(ctx context.Context, id, userId uuid.UUID) error {
+	const op = "Url.Service.DeleteUrl"
+	log := s.l.With(slog.String("op", op))
+
+	alias, err := s.repo.DeleteUrl(ctx, id, userId)
+	if err != nil {
+		log.Error("delete url error", logger.Err(err))
+		return err
+	}
+	s.publish(ctx, contextkey.EventLinkDeleted, userId, id, alias, nil)
+	return nil
+}
+
+// Clicked reports a redirect of url to destination.
+func (s *Service) Clicked(ctx context.Context, url *models.Url, destination, referer, userAgent string) {
+	s.publish(ctx, contextkey.EventLinkClicked, url.UserID, url.ID, url.Alias, map[string]any{
+		"destination": destination,
+		"referer":     referer,
+		"user_agent":  userAgent,
+	})
+}
+
+func (s *Service) publish(ctx context.Context, event contextkey.EventType, userId, urlId uuid.UUID, alias string, data map[string]any) {
+	if s.events == nil {
+		return
+	}
+	s.events.Publish(ctx, models.LinkEvent{
+		ID:         uuid.New(),
+		Type:       event,
+		UserID:     userId,
+		UrlID:      urlId,
+		Alias:      alias,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	})
+}
+
+func (s *Service) checkDomain(ctx context.Context, domainId, userId uuid.UUID, tx pgx.Tx) error {
+	ok, err := s.repo.IsDomainVerified(ctx, domainId, userId, tx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return utils.ErrorDomainNotVerified
+	}
+	return nil
+}
+
+// checkFolder refuses folders of other users, the foreign key alone lets a
+// link be filed into any folder.
+func (s *Service) checkFolder(ctx context.Context, folderId, userId uuid.UUID, tx pgx.Tx) error {
+	ok, err := s.repo.IsFolderOwned(ctx, folderId, userId, tx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return utils.ErrorFolderNotFound
+	}
 	return nil
 }
+
+// Resolve returns the destination the alias points to on host at the moment now.
+func (s *Service) Resolve(ctx context.Context, host, alias string, now time.Time) (string, *models.Url, error) {
+	const op = "Url.Service.Resolve"
+	log := s.l.With(slog.String("op", op))
+
+	url, err := s.repo.GetUrlByAlias(ctx, host, alias)
+	if err != nil {
+		log.Error("get url by alias error", logger.Err(err))
+		return "", nil, err
+	}
+	loc, err := LoadLocation(url.Timezone, s.redirect.Timezone)
+	if err != nil {
+		log.Error("invalid timezone", logger.Err(err))
+		return "", nil, err
+	}
+	destination, err := ResolveDestination(url, loc, now)
+	if err != nil {
+		return "", url, err
+	}
+	return destination, url, nil
+}
@@ -0,0 +1,72 @@
+package url
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+)
+
+// ValidateUrl accepts absolute http and https urls whose host is not one of
+// blocked or their subdomain.
+func ValidateUrl(raw string, blocked []string) error {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("%w: %s", utils.ErrorInvalidUrl, raw)
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for _, b := range blocked {
+		b = strings.ToLower(b)
+		if host == b || strings.HasSuffix(host, "."+b) {
+			return fmt.Errorf("%w: %s", utils.ErrorUrlBlocked, host)
+		}
+	}
+	return nil
+}
+
+// ValidateAlias accepts custom aliases made of the characters of generated
+// ones.
+func ValidateAlias(alias string) error {
+	if !validAlias(alias) {
+		return fmt.Errorf("%w: %s", utils.ErrorInvalidAlias, alias)
+	}
+	return nil
+}
+
+// ValidateExpiry accepts a missing expiry or one after now and notBefore.
+func ValidateExpiry(expiresAt, notBefore *time.Time, now time.Time) error {
+	if expiresAt == nil {
+		return nil
+	}
+	if !expiresAt.After(now) || (notBefore != nil && !expiresAt.After(*notBefore)) {
+		return utils.ErrorInvalidExpiry
+	}
+	return nil
+}
+
+// ValidateRouting runs the checks UpdateUrl does on each field over a whole
+// routing, like the one a rollback restores. expiresAt is the stored expiry
+// of the link.
+func ValidateRouting(rt models.UrlRouting, expiresAt *time.Time, blocked []string, timezone string, now time.Time) error {
+	if err := ValidateUrl(rt.Url, blocked); err != nil {
+		return err
+	}
+	if rt.FallbackUrl != nil && *rt.FallbackUrl != "" {
+		if err := ValidateUrl(*rt.FallbackUrl, blocked); err != nil {
+			return err
+		}
+	}
+	if _, err := LoadLocation(rt.Timezone, timezone); err != nil {
+		return err
+	}
+	if err := ValidateTimeRules(rt.TimeRules, blocked); err != nil {
+		return err
+	}
+	if rt.NotBefore != nil {
+		return ValidateExpiry(expiresAt, rt.NotBefore, now)
+	}
+	return nil
+}
@@ -16,6 +16,10 @@ type DatabaseUser struct {
 	UpdatedAt time.Time `db:"updated_at"`
 	Role      string    `db:"role"`
 	Version   int64     `db:"version"`
+	// EmailVerified is set once the user followed the verification link.
+	EmailVerified bool `db:"email_verified"`
+	// TwoFactorEnabled makes the login ask for a TOTP code.
+	TwoFactorEnabled bool `db:"two_factor_enabled"`
 }
 type AuthRequest struct {
 	Email    string `json:"email" validate:"required"`
@@ -23,23 +27,11 @@ type AuthRequest struct {
 	Password string `json:"password" validate:"required,min=6"`
 }
 
-type GoogleRegisterRequest struct {
-	Code string `json:"code" validate:"required"`
-}
 type RegisterParams struct {
 	Email    *string
-	TGID     *int64
 	Password *string
 	Title    string
 }
-type GoogleTokenResponse struct {
-	AccessToken  string `json:"access_token"`
-	TokenType    string `json:"token_type"`
-	ExpiresIn    int    `json:"expires_in"`
-	RefreshToken string `json:"refresh_token"`
-	Scope        string `json:"scope"`
-	IDToken      string `json:"id_token"`
-}
 type AuthResponse struct {
 	api.Response
 	Email    string
@@ -55,4 +47,8 @@ type LoginResponse struct {
 	api.Response
 	Email    string `json:"email"`
 	Username string `json:"username" `
+	// TwoFactorRequired means no cookies were set yet, send Challenge with a
+	// code to /auth/2fa/verify.
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	Challenge         string `json:"challenge,omitempty"`
 }
@@ -7,19 +7,26 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/Sanchir01/go-shortener/internal/config"
+	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
 	"github.com/Sanchir01/go-shortener/pkg/logger"
 	"github.com/Sanchir01/go-shortener/pkg/utils"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 
 	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 )
 
 type Handler struct {
-	Service HandlerUser
-	log     *slog.Logger
+	Service  HandlerUser
+	sessions SessionIssuer
+	verifier EmailVerifier
+	twoFA    TwoFactorChallenger
+	cfg      config.Accounts
+	log      *slog.Logger
 }
 
 //go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=HandlerUser
@@ -28,16 +35,35 @@ type HandlerUser interface {
 	Login(ctx context.Context, email, password string) (*DatabaseUser, error)
 }
 
-func NewHandler(s HandlerUser, lg *slog.Logger) *Handler {
+// SessionIssuer starts a server-side session and sets its cookies.
+type SessionIssuer interface {
+	Issue(w http.ResponseWriter, r *http.Request, userId uuid.UUID, role string) error
+}
+
+// EmailVerifier mails the link that confirms the email of a new account.
+type EmailVerifier interface {
+	SendVerification(ctx context.Context, userId uuid.UUID, email string) error
+}
+
+// TwoFactorChallenger starts the second login step for users with 2FA.
+type TwoFactorChallenger interface {
+	StartChallenge(ctx context.Context, userId uuid.UUID) (string, error)
+}
+
+func NewHandler(s HandlerUser, sessions SessionIssuer, verifier EmailVerifier, twoFA TwoFactorChallenger, cfg config.Accounts, lg *slog.Logger) *Handler {
 	return &Handler{
-		Service: s,
-		log:     lg,
+		Service:  s,
+		sessions: sessions,
+		verifier: verifier,
+		twoFA:    twoFA,
+		cfg:      cfg,
+		log:      lg,
 	}
 }
 
 // @Summary Auth
 // @Tags auth
-// @Description register user
+// @Description register user and mail an email verification link. No session is started while unverified accounts are limited
 // @Accept json
 // @Produce json
 // @Param input body LoginRequest true "login body"
@@ -55,13 +81,13 @@ func (h *Handler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	if err := render.DecodeJSON(r.Body, &req); err != nil {
 		log.Error("failed to decode request body", slog.Any("err", err))
 		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, api.Error("Ошибка при валидации данных"))
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidBody))
 		return
 	}
 	if err := validator.New().Struct(req); err != nil {
 		log.Error("invalid request", logger.Err(err))
 		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, api.Error("invalid request"))
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidRequest))
 		return
 	}
 	id, err := h.Service.Register(r.Context(), RegisterParams{
@@ -72,21 +98,33 @@ func (h *Handler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	if errors.Is(err, utils.ErrorUserAlreadyExists) {
 		log.Error("user already exists", logger.Err(err))
 		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, api.Error("Пользователь с таким email или username уже существует"))
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrUserExists))
 		return
 	}
 	if err != nil {
 		log.Error("failed to register user", logger.Err(err))
 		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, api.Error("ошибка регистрации"))
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrRegister))
 		return
 	}
 	log.Info("login success")
 
-	if err = AddCookieTokens(*id, w, "user", "localhost"); err != nil {
+	if err := h.verifier.SendVerification(r.Context(), *id, req.Email); err != nil {
+		log.Error("failed to send verification email", logger.Err(err))
+	}
+	if h.cfg.RequireVerifiedEmail {
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, AuthResponse{
+			Response: api.OK(),
+			Email:    req.Email,
+			Username: req.Username,
+		})
+		return
+	}
+	if err = h.sessions.Issue(w, r, *id, string(contextkey.RoleUser)); err != nil {
 		log.Error("register cookie errors", logger.Err(err))
 		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, api.Error("failed to register cookie"))
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrCookie))
 		return
 	}
 	render.Status(r, http.StatusCreated)
@@ -99,12 +137,12 @@ func (h *Handler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 
 // @Summary Login
 // @Tags auth
-// @Description login user
+// @Description login user. With 2FA enabled no cookies are set, the response carries a challenge for /auth/2fa/verify
 // @Accept json
 // @Produce json
 // @Param input body LoginRequest true "auth body"
 // @Success 200 {object}  LoginResponse
-// @Failure 400,404 {object}  api.Response
+// @Failure 400,403,404,429 {object}  api.Response
 // @Failure 500 {object}  api.Response
 // @Router /login [post]
 func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
@@ -114,37 +152,64 @@ func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	if err := render.DecodeJSON(r.Body, &req); err != nil {
 		log.Error("failed to decode request body", slog.Any("err", err))
 		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, api.Error("Ошибка при валидации данных"))
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidBody))
 		return
 	}
 	if err := validator.New().Struct(req); err != nil {
 		log.Error("invalid request", logger.Err(err))
-		render.JSON(w, r, api.Error("invalid request"))
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidRequest))
 		return
 	}
 	user, err := h.Service.Login(r.Context(), req.Email, req.Password)
 	if errors.Is(err, utils.ErrorUserNotFound) {
 		log.Error("invalid login", logger.Err(err))
 		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, api.Error("Неправильный логин"))
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidCredentials))
 		return
 	}
 	if errors.Is(err, utils.ErrorInvalidPassword) {
 		log.Error("invalid password", logger.Err(err))
 		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, api.Error("Неправильный логин"))
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidCredentials))
+		return
+	}
+	if errors.Is(err, utils.ErrorEmailNotVerified) {
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrEmailNotVerified))
 		return
 	}
 	if err != nil {
 		log.Error("failed to login", logger.Err(err))
 		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, api.Error("internal server error"))
-		return
-	}
-	if err = AddCookieTokens(user.ID, w, user.Role, "localhost"); err != nil {
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInternal))
+		return
+	}
+	if user.TwoFactorEnabled {
+		challenge, err := h.twoFA.StartChallenge(r.Context(), user.ID)
+		if errors.Is(err, utils.ErrorTwoFactorLocked) {
+			render.Status(r, http.StatusTooManyRequests)
+			render.JSON(w, r, api.FailErr(r.Context(), err))
+			return
+		}
+		if err != nil {
+			log.Error("failed to start two-factor challenge", logger.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInternal))
+			return
+		}
+		render.JSON(w, r, LoginResponse{
+			Response:          api.OK(),
+			Email:             user.Email,
+			Username:          user.Name,
+			TwoFactorRequired: true,
+			Challenge:         challenge,
+		})
+		return
+	}
+	if err = h.sessions.Issue(w, r, user.ID, user.Role); err != nil {
 		log.Error("register cookie errors", logger.Err(err))
 		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, api.Error("failed to register cookie"))
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrCookie))
 		return
 	}
 	render.JSON(w, r, LoginResponse{
@@ -153,82 +218,3 @@ func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		Username: user.Name,
 	})
 }
-
-// @Summary GogleLogin
-// @Tags auth
-// @Description google login user
-// @Accept json
-// @Produce json
-// @Success 200 {object}  api.Response
-// @Failure 400,404 {object}  api.Response
-// @Failure 500 {object}  api.Response
-// @Router /google [get]
-func (h *Handler) GoogleLogin(w http.ResponseWriter, r *http.Request) {
-	const op = "User.Handler.GoogleLogin"
-	log := h.log.With(slog.String("op", op))
-
-	url, err := utils.GetUrlGoogleString()
-	if err != nil {
-		log.Error("failed to generate google oauth url", logger.Err(err))
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, api.Error("internal server error"))
-		return
-	}
-
-	log.Info("success get url", slog.String("url", url))
-	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
-}
-
-// @Summary GogleRegister
-// @Tags auth
-// @Description google register user
-// @Accept json
-// @Produce json
-// @Param input body GoogleRegisterRequest true "auth body"
-// @Success 200 {object}  api.Response
-// @Failure 400,404 {object}  api.Response
-// @Failure 500 {object}  api.Response
-// @Router /google [get]
-func (h *Handler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
-	const op = "User.Handler.GoogleCallback"
-	log := h.log.With(slog.String("op", op))
-
-	var req GoogleRegisterRequest
-	if err := render.DecodeJSON(r.Body, &req); err != nil {
-		log.Error("failed to decode request body", slog.Any("err", err))
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, api.Error("Ошибка при валидации данных"))
-		return
-	}
-	if err := validator.New().Struct(req); err != nil {
-		log.Error("invalid request", logger.Err(err))
-		render.JSON(w, r, api.Error("invalid request"))
-		return
-	}
-
-	// Exchange code for token
-	data, err := utils.ExchangeGoogleCodeForToken(r.Context(), req.Code)
-	if err != nil {
-		log.Error("failed to exchange code for token", logger.Err(err))
-		render.Status(r, http.StatusBadGateway)
-		render.JSON(w, r, api.Error("failed to exchange authorization code"))
-		return
-	}
-
-	log.Info("successfully exchanged code for token")
-	if _, err := h.Service.Register(r.Context(), RegisterParams{
-		Email: &data.Email,
-		Title: data.Name,
-	}); err != nil {
-		log.Error("failed to register user", logger.Err(err))
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, api.Error("failed to register user"))
-		return
-	}
-
-	render.Status(r, http.StatusCreated)
-	render.JSON(w, r, map[string]interface{}{
-		"status":  "success",
-		"message": "Google authentication successful",
-	})
-}
@@ -1,13 +1,13 @@
 package user
 
 import (
-	"errors"
 	"net/http"
 
 	"log/slog"
 	"os"
 	"time"
 
+	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
@@ -15,22 +15,37 @@ import (
 type Claims struct {
 	ID   uuid.UUID `json:"id"`
 	Role string    `json:"role"`
+	// SessionID is the server-side session the access token was issued for.
+	SessionID uuid.UUID `json:"sid,omitempty"`
+	// Scopes limits what the request may do. It is nil for browser sessions,
+	// which may do everything their role allows, and set for api keys.
+	Scopes []contextkey.Scope `json:"scopes,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJwtToken(id uuid.UUID, role string, expire time.Time) (string, error) {
+// TokenSigner signs access tokens with the current signing key.
+type TokenSigner interface {
+	Sign(claims jwt.Claims) (string, error)
+}
+
+// TokenParser verifies access tokens against the published signing keys.
+type TokenParser interface {
+	Parse(tokenString string, claims jwt.Claims) error
+}
+
+func GenerateJwtToken(signer TokenSigner, id, sessionId uuid.UUID, role string, expire time.Time) (string, error) {
 	claim := &Claims{
-		ID:   id,
-		Role: role,
+		ID:        id,
+		Role:      role,
+		SessionID: sessionId,
 		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   id.String(),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			ExpiresAt: jwt.NewNumericDate(expire),
 		},
 	}
 
-	secretKey := []byte(os.Getenv("JWT_SECRET"))
-	tokens := jwt.NewWithClaims(jwt.SigningMethodHS256, claim)
-	tokenString, err := tokens.SignedString(secretKey)
-
+	tokenString, err := signer.Sign(claim)
 	if err != nil {
 		slog.Error("GenerateJwtToken err:", slog.Any("err", err))
 		return "", err
@@ -38,62 +53,26 @@ func GenerateJwtToken(id uuid.UUID, role string, expire time.Time) (string, erro
 	return tokenString, nil
 }
 
-func ParseToken(tokenString string) (*Claims, error) {
-
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
-
-	if err != nil {
+func ParseToken(parser TokenParser, tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	if err := parser.Parse(tokenString, claims); err != nil {
 		return nil, err
 	}
-
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
-	}
-
-	return nil, errors.New("invalid token")
-
+	return claims, nil
 }
-func AddCookieTokens(id uuid.UUID, w http.ResponseWriter, role string, domain string) error {
-	expirationTimeAccess := time.Now().Add(4 * time.Hour)
-	expirationTimeRefresh := time.Now().Add(14 * 24 * time.Hour)
-	refreshToken, err := GenerateJwtToken(id, role, expirationTimeRefresh)
-	if err != nil {
-		return err
-	}
-	accessToken, err := GenerateJwtToken(id, role, expirationTimeAccess)
-	if err != nil {
-		return err
-	}
-	http.SetCookie(w, GenerateCookie("accessToken", expirationTimeAccess, false, accessToken, domain))
-	http.SetCookie(w, GenerateCookie("refreshToken", expirationTimeRefresh, true, refreshToken, domain))
 
-	return nil
+// SetAuthCookies stores the access token and the opaque refresh token of a
+// session in cookies.
+func SetAuthCookies(w http.ResponseWriter, access string, accessExpire time.Time, refresh string, refreshExpire time.Time, domain string) {
+	http.SetCookie(w, GenerateCookie("accessToken", accessExpire, false, access, domain))
+	http.SetCookie(w, GenerateCookie("refreshToken", refreshExpire, true, refresh, domain))
 }
-func NewAccessToken(tokenString string, threshold time.Duration, w http.ResponseWriter, domain string) (string, error) {
-	claims, err := ParseToken(tokenString)
-	if err != nil {
-		return "", err
-	}
 
-	remainingTime := time.Until(claims.ExpiresAt.Time)
-	if remainingTime > threshold {
-		return tokenString, nil
-	}
-
-	newExpire := time.Now().Add(4 * time.Hour)
-	newToken, err := GenerateJwtToken(claims.ID, claims.Role, newExpire)
-	if err != nil {
-		return "", err
-	}
-
-	http.SetCookie(w, GenerateCookie("accessToken", newExpire, false, newToken, domain))
-	return newToken, nil
+func ClearAuthCookies(w http.ResponseWriter, domain string) {
+	http.SetCookie(w, GenerateCookie("accessToken", time.Unix(0, 0), false, "", domain))
+	http.SetCookie(w, GenerateCookie("refreshToken", time.Unix(0, 0), true, "", domain))
 }
+
 func GenerateCookie(name string, expire time.Time, httpOnly bool, value string, domain string) *http.Cookie {
 	cookie := &http.Cookie{
 		Name:        name,
@@ -3,11 +3,10 @@ package user
 import (
 	"context"
 	"errors"
-	"fmt"
 	"log/slog"
 
 	sq "github.com/Masterminds/squirrel"
-	"github.com/Sanchir01/currency-wallet/pkg/utils"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -31,7 +30,8 @@ func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*Databas
 	defer conn.Release()
 
 	query, arg, err := sq.
-		Select("id, email,title, version,password").
+		Select(`id, email, title, version, password, role::text, email_verified_at IS NOT NULL,
+			EXISTS(SELECT 1 FROM user_totp t WHERE t.user_id = users.id AND t.enabled_at IS NOT NULL)`).
 		From("public.users").
 		Where(sq.Eq{"email": email}).
 		PlaceholderFormat(sq.Dollar).
@@ -40,7 +40,7 @@ func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*Databas
 		return nil, err
 	}
 	var userDB DatabaseUser
-	if err := conn.QueryRow(ctx, query, arg...).Scan(&userDB.ID, &userDB.Email, &userDB.Name, &userDB.Version, &userDB.Password); err != nil {
+	if err := conn.QueryRow(ctx, query, arg...).Scan(&userDB.ID, &userDB.Email, &userDB.Name, &userDB.Version, &userDB.Password, &userDB.Role, &userDB.EmailVerified, &userDB.TwoFactorEnabled); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, utils.ErrorUserNotFound
 		}
@@ -66,7 +66,7 @@ func (r *Repository) CreateUser(ctx context.Context, email, username string, pas
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == "23505" {
-				return nil, fmt.Errorf("пользователь с таким email или username уже существует")
+				return nil, utils.ErrorUserAlreadyExists
 			}
 		}
 
@@ -74,29 +74,23 @@ func (r *Repository) CreateUser(ctx context.Context, email, username string, pas
 	}
 	return &id, nil
 }
-func (r *Repository) CreateUserByTG(ctx context.Context, tg_id int64, username string, tx pgx.Tx) (*uuid.UUID, error) {
 
-	r.l.Info("tg id: ", tg_id, "username", username)
-	query, arg, err := sq.
-		Insert("users").
-		Columns("tg_id", "title").
-		Values(tg_id, "user").
-		Suffix("RETURNING id").
-		PlaceholderFormat(sq.Dollar).
-		ToSql()
-	if err != nil {
-		return nil, utils.ErrorQueryString
-	}
+// GetOrCreateUserByTG returns the account of the Telegram user and creates
+// it on first use.
+func (r *Repository) GetOrCreateUserByTG(ctx context.Context, tgId int64, title string) (*uuid.UUID, error) {
+	r.l.Info("get or create user by tg", slog.Int64("tg_id", tgId), slog.String("title", title))
+	const query = `
+		WITH created AS (
+			INSERT INTO users (tg_id, title) VALUES ($1, $2)
+			ON CONFLICT (tg_id) DO NOTHING
+			RETURNING id
+		)
+		SELECT id FROM created
+		UNION ALL
+		SELECT id FROM users WHERE tg_id = $1
+		LIMIT 1`
 	var id uuid.UUID
-
-	if err := tx.QueryRow(ctx, query, arg...).Scan(&id); err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			if pgErr.Code == "23505" {
-				return nil, fmt.Errorf("пользователь с таким email или username уже существует")
-			}
-		}
-
+	if err := r.primaryDB.QueryRow(ctx, query, tgId, title).Scan(&id); err != nil {
 		return nil, err
 	}
 	return &id, nil
@@ -109,7 +103,7 @@ func (r *Repository) GetUserByID(ctx context.Context, id uuid.UUID) (*DatabaseUs
 	defer conn.Release()
 
 	query, arg, err := sq.
-		Select("id, email, title, version").
+		Select("id, COALESCE(email, ''), title, version, role::text").
 		From("public.users").
 		Where(sq.Eq{"id": id}).
 		PlaceholderFormat(sq.Dollar).
@@ -118,7 +112,10 @@ func (r *Repository) GetUserByID(ctx context.Context, id uuid.UUID) (*DatabaseUs
 		return nil, err
 	}
 	var userDB DatabaseUser
-	if err := conn.QueryRow(ctx, query, arg...).Scan(&userDB.ID, &userDB.Email, &userDB.Version); err != nil {
+	if err := conn.QueryRow(ctx, query, arg...).Scan(&userDB.ID, &userDB.Email, &userDB.Name, &userDB.Version, &userDB.Role); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.ErrorUserNotFound
+		}
 		return nil, err
 	}
 	return &userDB, nil
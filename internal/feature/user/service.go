@@ -5,8 +5,9 @@ import (
 	"errors"
 	"log/slog"
 
-	"github.com/Sanchir01/currency-wallet/pkg/utils"
+	"github.com/Sanchir01/go-shortener/internal/config"
 	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -16,19 +17,21 @@ type Service struct {
 	repository ServiceUser
 	log        *slog.Logger
 	primaryDB  *pgxpool.Pool
+	cfg        config.Accounts
 }
 
 type ServiceUser interface {
 	CreateUser(ctx context.Context, email, username string, password []byte, tx pgx.Tx) (*uuid.UUID, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (*DatabaseUser, error)
 	GetUserByEmail(ctx context.Context, email string) (*DatabaseUser, error)
-	CreateUserByTG(ctx context.Context, tg_id int64, username string, tx pgx.Tx) (*uuid.UUID, error)
+	GetOrCreateUserByTG(ctx context.Context, tgId int64, title string) (*uuid.UUID, error)
 }
 
-func NewService(r ServiceUser, db *pgxpool.Pool, l *slog.Logger) *Service {
+func NewService(r ServiceUser, db *pgxpool.Pool, cfg config.Accounts, l *slog.Logger) *Service {
 	return &Service{
 		repository: r,
 		primaryDB:  db,
+		cfg:        cfg,
 		log:        l,
 	}
 }
@@ -72,13 +75,6 @@ func (s *Service) Register(ctx context.Context, p RegisterParams) (*uuid.UUID, e
 			log.Error("error creating user", logger.Err(err))
 			return nil, err
 		}
-	case p.TGID != nil:
-		id, err := s.repository.CreateUserByTG(ctx, *p.TGID, p.Title, tx)
-		if err != nil {
-			log.Error("error creating user", logger.Err(err))
-			return nil, err
-		}
-		log.Info("user id", id)
 	}
 
 	if err := tx.Commit(ctx); err != nil {
@@ -88,6 +84,21 @@ func (s *Service) Register(ctx context.Context, p RegisterParams) (*uuid.UUID, e
 	return user, nil
 }
 
+// TelegramUser returns the account of the bot user, /start creates it once.
+func (s *Service) TelegramUser(ctx context.Context, tgId int64, title string) (*uuid.UUID, error) {
+	const op = "User.Service.TelegramUser"
+	log := s.log.With(slog.String("op", op))
+	if title == "" {
+		title = "user"
+	}
+	id, err := s.repository.GetOrCreateUserByTG(ctx, tgId, title)
+	if err != nil {
+		log.Error("error getting user by tg", logger.Err(err))
+		return nil, err
+	}
+	return id, nil
+}
+
 func (s *Service) Login(ctx context.Context, email, password string) (*DatabaseUser, error) {
 	const op = "User.Service.Login"
 	log := s.log.With(slog.String("op", op))
@@ -101,6 +112,10 @@ func (s *Service) Login(ctx context.Context, email, password string) (*DatabaseU
 		log.Error("invalid password")
 		return nil, utils.ErrorInvalidPassword
 	}
+	if s.cfg.RequireVerifiedEmail && !user.EmailVerified {
+		log.Info("login with unverified email")
+		return nil, utils.ErrorEmailNotVerified
+	}
 	log.Info("user service logged in user")
 	return user, nil
 }
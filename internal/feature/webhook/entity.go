@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/google/uuid"
+)
+
+type CreateWebhookRequest struct {
+	Url    string                 `json:"url" validate:"required,http_url"`
+	Events []contextkey.EventType `json:"events" validate:"required,min=1,dive,oneof=link.created link.updated link.deleted link.clicked"`
+}
+
+type UpdateWebhookRequest struct {
+	Url    *string                 `json:"url,omitempty" validate:"omitempty,http_url"`
+	Events *[]contextkey.EventType `json:"events,omitempty" validate:"omitempty,min=1,dive,oneof=link.created link.updated link.deleted link.clicked"`
+	Active *bool                   `json:"active,omitempty"`
+}
+
+type WebhookResponse struct {
+	api.Response
+	Webhook models.Webhook `json:"webhook"`
+}
+
+// CreateWebhookResponse is the only place the signing secret is shown.
+type CreateWebhookResponse struct {
+	api.Response
+	Webhook models.Webhook `json:"webhook"`
+	Secret  string         `json:"secret"`
+}
+
+type GetAllWebhooksResponse struct {
+	api.Response
+	Webhooks []models.Webhook `json:"webhooks"`
+}
+
+type GetDeliveriesResponse struct {
+	api.Response
+	Deliveries []models.WebhookDelivery `json:"deliveries"`
+}
+
+// pendingDelivery is a claimed delivery with everything needed to send it.
+type pendingDelivery struct {
+	ID       uuid.UUID
+	Event    string
+	Payload  []byte
+	Attempts int
+	Url      string
+	Secret   string
+}
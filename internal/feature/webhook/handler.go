@@ -0,0 +1,322 @@
+package webhook
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/internal/handlers/customiddleware"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	service *Service
+	l       *slog.Logger
+}
+
+func NewHandler(service *Service, l *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		l:       l,
+	}
+}
+
+// @Summary  CreateWebhookHandler
+// @Tags webhook
+// @Description Subscribe an endpoint to link events, the signing secret is returned only once
+// @Accept json
+// @Produce json
+// @Param input body CreateWebhookRequest true "webhook body"
+// @Success 201 {object}  CreateWebhookResponse
+// @Failure 400,401 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /webhook [post]
+func (h *Handler) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Webhook.Handler.CreateWebhook"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	var req CreateWebhookRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		log.Error("failed to decode request body", slog.Any("err", err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidBody))
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		log.Error("invalid request", logger.Err(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidRequest))
+		return
+	}
+	webhook, secret, err := h.service.CreateWebhook(r.Context(), claims.ID, req)
+	if errors.Is(err, utils.ErrorInvalidWebhookUrl) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	}
+	if err != nil {
+		log.Error("failed to create webhook", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrCreateWebhook))
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, CreateWebhookResponse{
+		Response: api.OK(),
+		Webhook:  *webhook,
+		Secret:   secret,
+	})
+}
+
+// @Summary  GetWebhooksHandler
+// @Tags webhook
+// @Description Get all webhooks of the user
+// @Produce json
+// @Success 200 {object}  GetAllWebhooksResponse
+// @Failure 401 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /webhook [get]
+func (h *Handler) GetWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Webhook.Handler.GetWebhooks"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	webhooks, err := h.service.GetWebhooks(r.Context(), claims.ID)
+	if err != nil {
+		log.Error("failed to get webhooks", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrGetWebhooks))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, GetAllWebhooksResponse{
+		Response: api.OK(),
+		Webhooks: webhooks,
+	})
+}
+
+// @Summary  UpdateWebhookHandler
+// @Tags webhook
+// @Description Change url, events or pause the webhook
+// @Accept json
+// @Produce json
+// @Param id path string true "webhook id"
+// @Param input body UpdateWebhookRequest true "fields to change"
+// @Success 200 {object}  WebhookResponse
+// @Failure 400,404 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /webhook/{id} [patch]
+func (h *Handler) UpdateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Webhook.Handler.UpdateWebhook"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidWebhookID))
+		return
+	}
+	var req UpdateWebhookRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		log.Error("failed to decode request body", slog.Any("err", err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidBody))
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		log.Error("invalid request", logger.Err(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidRequest))
+		return
+	}
+	webhook, err := h.service.UpdateWebhook(r.Context(), id, claims.ID, req)
+	switch {
+	case errors.Is(err, utils.ErrorNothingToUpdate), errors.Is(err, utils.ErrorInvalidWebhookUrl):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	case errors.Is(err, utils.ErrorWebhookNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	case err != nil:
+		log.Error("failed to update webhook", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrUpdateWebhook))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, WebhookResponse{
+		Response: api.OK(),
+		Webhook:  *webhook,
+	})
+}
+
+// @Summary  DeleteWebhookHandler
+// @Tags webhook
+// @Description Delete webhook together with its delivery log
+// @Produce json
+// @Param id path string true "webhook id"
+// @Success 200 {object}  api.Response
+// @Failure 400,404 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /webhook/{id} [delete]
+func (h *Handler) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Webhook.Handler.DeleteWebhook"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidWebhookID))
+		return
+	}
+	err = h.service.DeleteWebhook(r.Context(), id, claims.ID)
+	if errors.Is(err, utils.ErrorWebhookNotFound) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	}
+	if err != nil {
+		log.Error("failed to delete webhook", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrDeleteWebhook))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, api.OK())
+}
+
+// @Summary  GetDeliveriesHandler
+// @Tags webhook
+// @Description Last deliveries of the webhook, status=dead lists the dead letters
+// @Produce json
+// @Param id path string true "webhook id"
+// @Param status query string false "pending, delivered or dead"
+// @Success 200 {object}  GetDeliveriesResponse
+// @Failure 400 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /webhook/{id}/deliveries [get]
+func (h *Handler) GetDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Webhook.Handler.GetDeliveries"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidWebhookID))
+		return
+	}
+	status := r.URL.Query().Get("status")
+	switch models.WebhookDeliveryStatus(status) {
+	case "", models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead:
+	default:
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidStatus))
+		return
+	}
+	deliveries, err := h.service.GetDeliveries(r.Context(), id, claims.ID, status)
+	if err != nil {
+		log.Error("failed to get deliveries", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrGetDeliveries))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, GetDeliveriesResponse{
+		Response:   api.OK(),
+		Deliveries: deliveries,
+	})
+}
+
+// @Summary  RetryDeliveryHandler
+// @Tags webhook
+// @Description Put a delivery back into the queue, usually one from the dead letters
+// @Produce json
+// @Param id path string true "webhook id"
+// @Param deliveryId path string true "delivery id"
+// @Success 200 {object}  api.Response
+// @Failure 400,404 {object}  api.Response
+// @Failure 500 {object}  api.Response
+// @Router /webhook/{id}/deliveries/{deliveryId}/retry [post]
+func (h *Handler) RetryDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "Webhook.Handler.RetryDelivery"
+	log := h.l.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	claims, err := customiddleware.GetJWTClaimsFromCtx(r.Context())
+	if err != nil {
+		customiddleware.Unauthorized(w, r)
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidWebhookID))
+		return
+	}
+	deliveryId, err := uuid.Parse(chi.URLParam(r, "deliveryId"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrInvalidDeliveryID))
+		return
+	}
+	err = h.service.Redeliver(r.Context(), deliveryId, id, claims.ID)
+	if errors.Is(err, utils.ErrorDeliveryNotFound) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, api.FailErr(r.Context(), err))
+		return
+	}
+	if err != nil {
+		log.Error("failed to retry delivery", logger.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, api.Fail(r.Context(), i18n.ErrRetryDelivery))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, api.OK())
+}
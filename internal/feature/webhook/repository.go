@@ -0,0 +1,382 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	webhookColumns  = "id, user_id, url, secret, events, active, created_at"
+	deliveryColumns = "id, webhook_id, event, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at"
+)
+
+// claimDueQuery leases due deliveries by moving next_attempt_at forward, so a
+// crashed worker's deliveries become due again once the lease expires.
+const claimDueQuery = `
+WITH claimed AS (
+	UPDATE webhook_deliveries d
+	SET next_attempt_at = now() + $2::interval
+	WHERE d.id IN (
+		SELECT id FROM webhook_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= now()
+		ORDER BY next_attempt_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING d.id, d.webhook_id, d.event, d.payload, d.attempts
+)
+SELECT c.id, c.event, c.payload, c.attempts, w.url, w.secret
+FROM claimed c
+JOIN webhooks w ON w.id = c.webhook_id`
+
+type Repository struct {
+	primaryDB *pgxpool.Pool
+	l         *slog.Logger
+}
+
+func NewRepository(primaryDB *pgxpool.Pool, l *slog.Logger) *Repository {
+	return &Repository{
+		primaryDB: primaryDB,
+		l:         l,
+	}
+}
+
+func scanWebhook(row pgx.Row) (*models.Webhook, error) {
+	var w models.Webhook
+	var events []string
+	if err := row.Scan(&w.ID, &w.UserID, &w.Url, &w.Secret, &events, &w.Active, &w.CreatedAt); err != nil {
+		return nil, err
+	}
+	w.Events = make([]contextkey.EventType, 0, len(events))
+	for _, e := range events {
+		w.Events = append(w.Events, contextkey.EventType(e))
+	}
+	return &w, nil
+}
+
+func eventStrings(events []contextkey.EventType) []string {
+	out := make([]string, 0, len(events))
+	for _, e := range events {
+		out = append(out, string(e))
+	}
+	return out
+}
+
+func (r *Repository) CreateWebhook(ctx context.Context, userId uuid.UUID, url, secret string, events []contextkey.EventType) (*models.Webhook, error) {
+	const op = "Webhook.Repository.CreateWebhook"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Insert("webhooks").
+		Columns("user_id", "url", "secret", "events").
+		Values(userId, url, secret, eventStrings(events)).
+		Suffix("RETURNING " + webhookColumns).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	w, err := scanWebhook(r.primaryDB.QueryRow(ctx, query, args...))
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return w, nil
+}
+
+func (r *Repository) GetWebhooksByUserId(ctx context.Context, userId uuid.UUID) ([]models.Webhook, error) {
+	const op = "Webhook.Repository.GetWebhooksByUserId"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Select(webhookColumns).
+		From("webhooks").
+		Where(sq.Eq{"user_id": userId}).
+		OrderBy("created_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	rows, err := r.primaryDB.Query(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := make([]models.Webhook, 0, 4)
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			log.Error("error", logger.Err(err))
+			return nil, err
+		}
+		webhooks = append(webhooks, *w)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *Repository) UpdateWebhook(ctx context.Context, id, userId uuid.UUID, req UpdateWebhookRequest) (*models.Webhook, error) {
+	const op = "Webhook.Repository.UpdateWebhook"
+	log := r.l.With(slog.String("op", op))
+
+	builder := sq.
+		Update("webhooks").
+		Where(sq.Eq{"id": id, "user_id": userId}).
+		Suffix("RETURNING " + webhookColumns).
+		PlaceholderFormat(sq.Dollar)
+	if req.Url != nil {
+		builder = builder.Set("url", *req.Url)
+	}
+	if req.Events != nil {
+		builder = builder.Set("events", eventStrings(*req.Events))
+	}
+	if req.Active != nil {
+		builder = builder.Set("active", *req.Active)
+	}
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	w, err := scanWebhook(r.primaryDB.QueryRow(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.ErrorWebhookNotFound
+		}
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return w, nil
+}
+
+func (r *Repository) DeleteWebhook(ctx context.Context, id, userId uuid.UUID) error {
+	const op = "Webhook.Repository.DeleteWebhook"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Delete("webhooks").
+		Where(sq.Eq{"id": id, "user_id": userId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	tag, err := r.primaryDB.Exec(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return utils.ErrorWebhookNotFound
+	}
+	return nil
+}
+
+// CreateDeliveries queues payload for every active webhook of the user that
+// subscribed to event.
+func (r *Repository) CreateDeliveries(ctx context.Context, userId uuid.UUID, event contextkey.EventType, payload []byte) (int64, error) {
+	const op = "Webhook.Repository.CreateDeliveries"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Insert("webhook_deliveries").
+		Columns("webhook_id", "event", "payload").
+		Select(sq.
+			Select("id").
+			Column("?::text", string(event)).
+			Column("?::jsonb", string(payload)).
+			From("webhooks").
+			Where(sq.Eq{"user_id": userId, "active": true}).
+			Where("?::text = ANY(events)", string(event))).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return 0, utils.ErrorQueryString
+	}
+	tag, err := r.primaryDB.Exec(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *Repository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]pendingDelivery, error) {
+	const op = "Webhook.Repository.ClaimDue"
+	log := r.l.With(slog.String("op", op))
+
+	rows, err := r.primaryDB.Query(ctx, claimDueQuery, limit, lease)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]pendingDelivery, 0, limit)
+	for rows.Next() {
+		var d pendingDelivery
+		if err := rows.Scan(&d.ID, &d.Event, &d.Payload, &d.Attempts, &d.Url, &d.Secret); err != nil {
+			log.Error("error", logger.Err(err))
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *Repository) MarkDelivered(ctx context.Context, id uuid.UUID, attempts, statusCode int) error {
+	const op = "Webhook.Repository.MarkDelivered"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Update("webhook_deliveries").
+		Set("status", models.DeliveryDelivered).
+		Set("attempts", attempts).
+		Set("last_status_code", statusCode).
+		Set("last_error", "").
+		Set("delivered_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := r.primaryDB.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+// MarkFailed records a failed attempt, the delivery is retried at next or
+// dead-lettered when dead is set.
+func (r *Repository) MarkFailed(ctx context.Context, id uuid.UUID, attempts, statusCode int, lastError string, next time.Time, dead bool) error {
+	const op = "Webhook.Repository.MarkFailed"
+	log := r.l.With(slog.String("op", op))
+
+	status := models.DeliveryPending
+	if dead {
+		status = models.DeliveryDead
+	}
+	query, args, err := sq.
+		Update("webhook_deliveries").
+		Set("status", status).
+		Set("attempts", attempts).
+		Set("last_status_code", statusCode).
+		Set("last_error", lastError).
+		Set("next_attempt_at", next).
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	if _, err := r.primaryDB.Exec(ctx, query, args...); err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) GetDeliveries(ctx context.Context, webhookId, userId uuid.UUID, status string, limit uint64) ([]models.WebhookDelivery, error) {
+	const op = "Webhook.Repository.GetDeliveries"
+	log := r.l.With(slog.String("op", op))
+
+	builder := sq.
+		Select(deliveryColumns).
+		From("webhook_deliveries").
+		Where(sq.Eq{"webhook_id": webhookId}).
+		Where(sq.Expr("EXISTS (SELECT 1 FROM webhooks WHERE webhooks.id = webhook_id AND webhooks.user_id = ?)", userId)).
+		OrderBy("created_at DESC").
+		Limit(limit).
+		PlaceholderFormat(sq.Dollar)
+	if status != "" {
+		builder = builder.Where(sq.Eq{"status": status})
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, utils.ErrorQueryString
+	}
+	rows, err := r.primaryDB.Query(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]models.WebhookDelivery, 0, limit)
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(
+			&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt,
+		); err != nil {
+			log.Error("error", logger.Err(err))
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("error", logger.Err(err))
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// RequeueDelivery puts a dead or delivered delivery back into the queue.
+func (r *Repository) RequeueDelivery(ctx context.Context, id, webhookId, userId uuid.UUID) error {
+	const op = "Webhook.Repository.RequeueDelivery"
+	log := r.l.With(slog.String("op", op))
+
+	query, args, err := sq.
+		Update("webhook_deliveries").
+		Set("status", models.DeliveryPending).
+		Set("attempts", 0).
+		Set("next_attempt_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id, "webhook_id": webhookId}).
+		Where(sq.Expr("EXISTS (SELECT 1 FROM webhooks WHERE webhooks.id = webhook_id AND webhooks.user_id = ?)", userId)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return utils.ErrorQueryString
+	}
+	tag, err := r.primaryDB.Exec(ctx, query, args...)
+	if err != nil {
+		log.Error("error", logger.Err(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return utils.ErrorDeliveryNotFound
+	}
+	return nil
+}
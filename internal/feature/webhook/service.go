@@ -0,0 +1,261 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/config"
+	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/logger"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+)
+
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, userId uuid.UUID, url, secret string, events []contextkey.EventType) (*models.Webhook, error)
+	GetWebhooksByUserId(ctx context.Context, userId uuid.UUID) ([]models.Webhook, error)
+	UpdateWebhook(ctx context.Context, id, userId uuid.UUID, req UpdateWebhookRequest) (*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id, userId uuid.UUID) error
+	CreateDeliveries(ctx context.Context, userId uuid.UUID, event contextkey.EventType, payload []byte) (int64, error)
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]pendingDelivery, error)
+	MarkDelivered(ctx context.Context, id uuid.UUID, attempts, statusCode int) error
+	MarkFailed(ctx context.Context, id uuid.UUID, attempts, statusCode int, lastError string, next time.Time, dead bool) error
+	GetDeliveries(ctx context.Context, webhookId, userId uuid.UUID, status string, limit uint64) ([]models.WebhookDelivery, error)
+	RequeueDelivery(ctx context.Context, id, webhookId, userId uuid.UUID) error
+}
+
+type Service struct {
+	repo   WebhookService
+	sender *Sender
+	queue  chan models.LinkEvent
+	cfg    config.Webhooks
+	l      *slog.Logger
+}
+
+func NewService(repo WebhookService, sender *Sender, cfg config.Webhooks, l *slog.Logger) *Service {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	return &Service{
+		repo:   repo,
+		sender: sender,
+		queue:  make(chan models.LinkEvent, cfg.QueueSize),
+		cfg:    cfg,
+		l:      l,
+	}
+}
+
+// Publish hands the event over to the background worker without waiting.
+// Events are dropped when the queue is full so callers never block.
+func (s *Service) Publish(_ context.Context, e models.LinkEvent) {
+	select {
+	case s.queue <- e:
+	default:
+		s.l.Warn("webhook queue is full, event dropped",
+			slog.String("event", string(e.Type)),
+			slog.String("url_id", e.UrlID.String()),
+		)
+	}
+}
+
+// Run stores published events as deliveries and sends due deliveries until
+// ctx is done.
+func (s *Service) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		s.enqueueLoop(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		s.dispatchLoop(ctx)
+	}()
+	wg.Wait()
+}
+
+func (s *Service) enqueueLoop(ctx context.Context) {
+	const op = "Webhook.Service.enqueueLoop"
+	log := s.l.With(slog.String("op", op))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-s.queue:
+			payload, err := json.Marshal(e)
+			if err != nil {
+				log.Error("marshal event error", logger.Err(err))
+				continue
+			}
+			if _, err := s.repo.CreateDeliveries(ctx, e.UserID, e.Type, payload); err != nil {
+				log.Error("create deliveries error", logger.Err(err))
+			}
+		}
+	}
+}
+
+func (s *Service) dispatchLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		s.DispatchDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue sends one batch of due deliveries.
+func (s *Service) DispatchDue(ctx context.Context) {
+	const op = "Webhook.Service.DispatchDue"
+	log := s.l.With(slog.String("op", op))
+
+	deliveries, err := s.repo.ClaimDue(ctx, s.cfg.Workers*4, 2*s.cfg.Timeout+time.Minute)
+	if err != nil {
+		log.Error("claim deliveries error", logger.Err(err))
+		return
+	}
+
+	sem := make(chan struct{}, s.cfg.Workers)
+	var wg sync.WaitGroup
+	for _, d := range deliveries {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(d pendingDelivery) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			s.deliver(ctx, d)
+		}(d)
+	}
+	wg.Wait()
+}
+
+func (s *Service) deliver(ctx context.Context, d pendingDelivery) {
+	log := s.l.With(slog.String("delivery_id", d.ID.String()))
+
+	attempts := d.Attempts + 1
+	status, err := s.sender.Send(ctx, d.Url, d.Secret, d.Event, d.ID.String(), d.Payload)
+	if err == nil {
+		if err := s.repo.MarkDelivered(ctx, d.ID, attempts, status); err != nil {
+			log.Error("mark delivered error", logger.Err(err))
+		}
+		return
+	}
+
+	dead := attempts >= s.cfg.MaxAttempts
+	next := time.Now().Add(Backoff(attempts, s.cfg.BaseBackoff, s.cfg.MaxBackoff))
+	if err := s.repo.MarkFailed(ctx, d.ID, attempts, status, err.Error(), next, dead); err != nil {
+		log.Error("mark failed error", logger.Err(err))
+	}
+	if dead {
+		log.Warn("webhook delivery moved to dead letter", slog.Int("attempts", attempts), logger.Err(err))
+	}
+}
+
+func (s *Service) CreateWebhook(ctx context.Context, userId uuid.UUID, req CreateWebhookRequest) (*models.Webhook, string, error) {
+	const op = "Webhook.Service.CreateWebhook"
+	log := s.l.With(slog.String("op", op))
+
+	if err := ValidateTarget(req.Url); err != nil {
+		log.Info("rejected webhook url", logger.Err(err))
+		return nil, "", err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		log.Error("generate secret error", logger.Err(err))
+		return nil, "", err
+	}
+	w, err := s.repo.CreateWebhook(ctx, userId, req.Url, secret, req.Events)
+	if err != nil {
+		log.Error("create webhook error", logger.Err(err))
+		return nil, "", err
+	}
+	return w, secret, nil
+}
+
+func (s *Service) GetWebhooks(ctx context.Context, userId uuid.UUID) ([]models.Webhook, error) {
+	const op = "Webhook.Service.GetWebhooks"
+	log := s.l.With(slog.String("op", op))
+
+	webhooks, err := s.repo.GetWebhooksByUserId(ctx, userId)
+	if err != nil {
+		log.Error("get webhooks error", logger.Err(err))
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (s *Service) UpdateWebhook(ctx context.Context, id, userId uuid.UUID, req UpdateWebhookRequest) (*models.Webhook, error) {
+	const op = "Webhook.Service.UpdateWebhook"
+	log := s.l.With(slog.String("op", op))
+
+	if req.Url == nil && req.Events == nil && req.Active == nil {
+		return nil, utils.ErrorNothingToUpdate
+	}
+	if req.Url != nil {
+		if err := ValidateTarget(*req.Url); err != nil {
+			log.Info("rejected webhook url", logger.Err(err))
+			return nil, err
+		}
+	}
+	w, err := s.repo.UpdateWebhook(ctx, id, userId, req)
+	if err != nil {
+		log.Error("update webhook error", logger.Err(err))
+		return nil, err
+	}
+	return w, nil
+}
+
+func (s *Service) DeleteWebhook(ctx context.Context, id, userId uuid.UUID) error {
+	const op = "Webhook.Service.DeleteWebhook"
+	log := s.l.With(slog.String("op", op))
+
+	if err := s.repo.DeleteWebhook(ctx, id, userId); err != nil {
+		log.Error("delete webhook error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (s *Service) GetDeliveries(ctx context.Context, webhookId, userId uuid.UUID, status string) ([]models.WebhookDelivery, error) {
+	const op = "Webhook.Service.GetDeliveries"
+	log := s.l.With(slog.String("op", op))
+
+	deliveries, err := s.repo.GetDeliveries(ctx, webhookId, userId, status, 100)
+	if err != nil {
+		log.Error("get deliveries error", logger.Err(err))
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (s *Service) Redeliver(ctx context.Context, id, webhookId, userId uuid.UUID) error {
+	const op = "Webhook.Service.Redeliver"
+	log := s.l.With(slog.String("op", op))
+
+	if err := s.repo.RequeueDelivery(ctx, id, webhookId, userId); err != nil {
+		log.Error("requeue delivery error", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
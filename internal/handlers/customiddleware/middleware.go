@@ -5,10 +5,15 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
 	"github.com/Sanchir01/go-shortener/internal/feature/user"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -39,52 +44,161 @@ var requesDuration = prometheus.NewHistogramVec(
 	[]string{"method", "path"},
 )
 
+var (
+	errNoClaims = errors.New("no JWT claims found in context")
+	// errRevocationUnknown means the session of a valid access token could
+	// not be checked against revocations at all.
+	errRevocationUnknown = errors.New("session revocation state unknown")
+)
+
 func GetJWTClaimsFromCtx(ctx context.Context) (*user.Claims, error) {
 	claims, ok := ctx.Value(contextkey.UserIDCtxKey).(*user.Claims)
-	if !ok {
-		return nil, errors.New("no JWT claims found in context")
+	if !ok || claims == nil {
+		return nil, errNoClaims
 	}
 	return claims, nil
 }
 
-func AuthMiddleware(domain string) func(http.Handler) http.Handler {
+func WithJWTClaims(ctx context.Context, claims *user.Claims) context.Context {
+	return context.WithValue(ctx, contextkey.UserIDCtxKey, claims)
+}
+
+// AuthMiddleware requires a valid session. Requests without one are answered
+// with 401 and never reach the handler, requests whose session can not be
+// checked with 503.
+func AuthMiddleware(auth Authenticators) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			access, err := r.Cookie("refreshToken")
+			claims, err := auth.authenticate(w, r)
+			if errors.Is(err, errRevocationUnknown) {
+				render.Status(r, http.StatusServiceUnavailable)
+				render.JSON(w, r, api.Fail(r.Context(), i18n.ErrUnavailable))
+				return
+			}
 			if err != nil {
-				refresh, err := r.Cookie("accessToken")
-				if err != nil {
-					next.ServeHTTP(w, r)
-					return
-				}
-				accessToken, err := user.NewAccessToken(refresh.Value, 0, w, domain)
-				if err != nil {
-					next.ServeHTTP(w, r)
-					return
-				}
-				token, err := user.ParseToken(accessToken)
-				if err != nil {
-					slog.Error("failed parse token middleware", slog.Any("err", err))
-					next.ServeHTTP(w, r)
-					return
-				}
-
-				ctx := context.WithValue(r.Context(), contextkey.UserIDCtxKey, token)
-				next.ServeHTTP(w, r.WithContext(ctx))
+				Unauthorized(w, r)
 				return
 			}
+			next.ServeHTTP(w, r.WithContext(WithJWTClaims(r.Context(), claims)))
+		})
+	}
+}
 
-			validAccessToken, err := user.ParseToken(access.Value)
+// OptionalAuthMiddleware puts the claims into the context when the request
+// carries a valid session and lets anonymous requests through otherwise.
+func OptionalAuthMiddleware(auth Authenticators) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := auth.authenticate(w, r)
 			if err != nil {
-				slog.Error("failed parse token middleware", slog.Any("err", err))
 				next.ServeHTTP(w, r)
 				return
 			}
-			ctx := context.WithValue(r.Context(), contextkey.UserIDCtxKey, validAccessToken)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, r.WithContext(WithJWTClaims(r.Context(), claims)))
 		})
 	}
 }
+
+// Language puts the language the client prefers by Accept-Language into the
+// context for localized errors, English when it names no supported one.
+func Language(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang := i18n.Negotiate(r.Header.Get("Accept-Language"), i18n.EN)
+		w.Header().Set("Content-Language", string(lang))
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r.WithContext(i18n.WithLang(r.Context(), lang)))
+	})
+}
+
+// Unauthorized writes the error every protected route answers with.
+func Unauthorized(w http.ResponseWriter, r *http.Request) {
+	render.Status(r, http.StatusUnauthorized)
+	render.JSON(w, r, api.Fail(r.Context(), i18n.ErrUnauthorized))
+}
+
+// KeyAuthenticator resolves personal api keys sent as bearer tokens.
+type KeyAuthenticator interface {
+	IsKey(token string) bool
+	AuthenticateKey(ctx context.Context, key string) (*user.Claims, error)
+}
+
+// SessionRefresher rotates the refresh token of a session and sets a new
+// access token cookie.
+type SessionRefresher interface {
+	Refresh(w http.ResponseWriter, r *http.Request, refreshToken string) (*user.Claims, error)
+}
+
+// RevocationChecker tells whether the session an access token was issued for
+// has been revoked since.
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, sessionId uuid.UUID) (bool, error)
+}
+
+type Authenticators struct {
+	Tokens   user.TokenParser
+	Keys     KeyAuthenticator
+	Sessions SessionRefresher
+	Revoked  RevocationChecker
+}
+
+// authenticate reads the access token from the Authorization header or the
+// accessToken cookie. When it is missing or expired the refreshToken cookie
+// is exchanged for new tokens. Bearer api keys are checked by Keys and never
+// fall back to cookies. An access token of a revoked session is treated as
+// missing, the refresh then fails as well since the database knows about the
+// revocation. A token whose session cannot be checked fails with
+// errRevocationUnknown and never rotates the refresh token.
+func (a Authenticators) authenticate(w http.ResponseWriter, r *http.Request) (*user.Claims, error) {
+	access := accessToken(r)
+	if a.Keys != nil && a.Keys.IsKey(access) {
+		return a.Keys.AuthenticateKey(r.Context(), access)
+	}
+	if access != "" && a.Tokens != nil {
+		claims, err := user.ParseToken(a.Tokens, access)
+		if err == nil {
+			active, err := a.sessionActive(r.Context(), claims)
+			if err != nil {
+				return nil, err
+			}
+			if active {
+				return claims, nil
+			}
+		}
+	}
+	refresh, err := r.Cookie("refreshToken")
+	if err != nil || refresh.Value == "" || a.Sessions == nil {
+		return nil, errNoClaims
+	}
+	claims, err := a.Sessions.Refresh(w, r, refresh.Value)
+	if err != nil {
+		slog.Debug("failed refresh session", slog.Any("err", err))
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (a Authenticators) sessionActive(ctx context.Context, claims *user.Claims) (bool, error) {
+	if claims.SessionID == uuid.Nil || a.Revoked == nil {
+		return true, nil
+	}
+	revoked, err := a.Revoked.IsRevoked(ctx, claims.SessionID)
+	if err != nil {
+		slog.Warn("failed to check session revocation", slog.Any("err", err))
+		return false, errRevocationUnknown
+	}
+	return !revoked, nil
+}
+
+func accessToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	if cookie, err := r.Cookie("accessToken"); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
 func PrometheusMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
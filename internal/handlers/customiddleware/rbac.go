@@ -0,0 +1,84 @@
+package customiddleware
+
+import (
+	"net/http"
+	"slices"
+
+	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/go-chi/render"
+)
+
+// Permission matrix, every authenticated route is guarded by one of these.
+var (
+	// RolesMember may manage their own links, tags, folders, domains and webhooks.
+	RolesMember = []contextkey.UserRole{contextkey.RoleUser, contextkey.RoleModerator, contextkey.RoleAdmin}
+	// RolesStaff may look at data of every user.
+	RolesStaff = []contextkey.UserRole{contextkey.RoleModerator, contextkey.RoleAdmin}
+	// RolesAdmin may do anything.
+	RolesAdmin = []contextkey.UserRole{contextkey.RoleAdmin}
+)
+
+// RequireRole lets the request through only when Claims.Role is one of roles.
+// It answers 401 without claims and 403 for any other role. Tokens issued
+// before roles were loaded at login carry no role and count as plain users.
+func RequireRole(roles ...contextkey.UserRole) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := GetJWTClaimsFromCtx(r.Context())
+			if err != nil {
+				Unauthorized(w, r)
+				return
+			}
+			role := contextkey.UserRole(claims.Role)
+			if role == "" {
+				role = contextkey.RoleUser
+			}
+			if !slices.Contains(roles, role) {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, api.Fail(r.Context(), i18n.ErrForbidden))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireScope checks the scopes of api keys. Browser sessions carry no
+// scopes and pass, they are limited by RequireRole only.
+func RequireScope(scope contextkey.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := GetJWTClaimsFromCtx(r.Context())
+			if err != nil {
+				Unauthorized(w, r)
+				return
+			}
+			if claims.Scopes != nil && !slices.Contains(claims.Scopes, scope) {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, api.Fail(r.Context(), i18n.ErrMissingScope, scope))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession keeps api keys away from account settings such as managing
+// the keys themselves.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, err := GetJWTClaimsFromCtx(r.Context())
+		if err != nil {
+			Unauthorized(w, r)
+			return
+		}
+		if claims.Scopes != nil {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, api.Fail(r.Context(), i18n.ErrApiKeyNotAllowed))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
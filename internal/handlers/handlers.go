@@ -6,6 +6,7 @@ import (
 
 	_ "github.com/Sanchir01/go-shortener/docs"
 	"github.com/Sanchir01/go-shortener/internal/app"
+	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
 	"github.com/Sanchir01/go-shortener/internal/handlers/customiddleware"
 	"github.com/Sanchir01/go-shortener/pkg/logger"
 	"github.com/go-chi/chi/v5"
@@ -14,27 +15,110 @@ import (
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
-func StartHTTTPHandlers(handlers *app.Handlers, domain string, l *slog.Logger) http.Handler {
+func StartHTTTPHandlers(handlers *app.Handlers, authenticators customiddleware.Authenticators, l *slog.Logger) http.Handler {
 	router := chi.NewRouter()
 	custommiddleware(router, l)
+
+	auth := customiddleware.AuthMiddleware(authenticators)
+	optionalAuth := customiddleware.OptionalAuthMiddleware(authenticators)
+	member := customiddleware.RequireRole(customiddleware.RolesMember...)
+	admin := customiddleware.RequireRole(customiddleware.RolesAdmin...)
+	read := customiddleware.RequireScope(contextkey.ScopeLinksRead)
+	write := customiddleware.RequireScope(contextkey.ScopeLinksWrite)
+	stats := customiddleware.RequireScope(contextkey.ScopeStatsRead)
+	session := customiddleware.RequireSession
+
 	router.Route("/api/v1", func(r chi.Router) {
 		r.Route("/auth", func(r chi.Router) {
 			r.Post("/register", handlers.UserHandler.RegisterHandler)
 			r.Post("/login", handlers.UserHandler.LoginHandler)
+			r.Post("/verify-email", handlers.AccountHandler.VerifyEmailHandler)
+			r.Post("/verify-email/resend", handlers.AccountHandler.ResendVerificationHandler)
+			r.Post("/password/forgot", handlers.AccountHandler.ForgotPasswordHandler)
+			r.Post("/password/reset", handlers.AccountHandler.ResetPasswordHandler)
 			r.Group(func(r chi.Router) {
-				r.Use(customiddleware.AuthMiddleware(domain))
+				r.Use(auth, member, session)
+				r.Post("/logout", handlers.SessionHandler.LogoutHandler)
+				r.Post("/logout-all", handlers.SessionHandler.LogoutAllHandler)
+				r.Get("/sessions", handlers.SessionHandler.GetSessionsHandler)
+				r.Delete("/sessions/{id}", handlers.SessionHandler.RevokeSessionHandler)
+			})
+			r.Route("/2fa", func(r chi.Router) {
+				r.Post("/verify", handlers.TwoFactor.VerifyHandler)
+				r.Group(func(r chi.Router) {
+					r.Use(auth, member, session)
+					r.Post("/enroll", handlers.TwoFactor.EnrollHandler)
+					r.Post("/confirm", handlers.TwoFactor.ConfirmHandler)
+					r.Post("/disable", handlers.TwoFactor.DisableHandler)
+				})
 			})
-			r.Route("/google", func(r chi.Router) {
-				r.Get("/", handlers.UserHandler.GoogleLogin)
-				r.Post("/register", handlers.UserHandler.GoogleCallback)
+			r.Post("/telegram/widget", handlers.Telegram.WidgetLoginHandler)
+			r.Post("/telegram/webapp", handlers.Telegram.WebAppLoginHandler)
+			r.Get("/providers", handlers.OAuthHandler.GetProvidersHandler)
+			r.Get("/{provider}", handlers.OAuthHandler.LoginHandler)
+			r.With(optionalAuth).Post("/callback", handlers.OAuthHandler.CallbackHandler)
+			r.Route("/identities", func(r chi.Router) {
+				r.Use(auth, member, session)
+				r.Get("/", handlers.OAuthHandler.GetIdentitiesHandler)
+				r.Get("/{provider}/link", handlers.OAuthHandler.LinkHandler)
+				r.Delete("/{provider}", handlers.OAuthHandler.UnlinkHandler)
 			})
 		})
 		r.Route("/url", func(r chi.Router) {
-			r.Use(customiddleware.AuthMiddleware(domain))
-			r.Post("/save", handlers.UrlHandler.CreateUrlHandler)
-			r.Get("/all", handlers.UrlHandler.GetAllUrlHandler)
-			r.Get("/", handlers.UrlHandler.GetAllUrlByUserId)
-
+			r.Use(auth, member)
+			r.With(write).Post("/save", handlers.UrlHandler.CreateUrlHandler)
+			r.With(admin, read).Get("/all", handlers.UrlHandler.GetAllUrlHandler)
+			r.With(read).Get("/", handlers.UrlHandler.GetAllUrlByUserId)
+			r.With(write).Patch("/{id}", handlers.UrlHandler.UpdateUrlHandler)
+			r.With(write).Delete("/{id}", handlers.UrlHandler.DeleteUrlHandler)
+			r.With(stats).Get("/clicks", handlers.ClickHandler.StreamClicksHandler)
+			r.With(stats).Get("/{id}/clicks", handlers.ClickHandler.StreamUrlClicksHandler)
+			r.With(read).Get("/{id}/health", handlers.HealthHandler.GetUrlHealthHandler)
+			r.With(read).Get("/{id}/revisions", handlers.UrlHandler.GetRevisionsHandler)
+			r.With(write).Post("/{id}/revisions/{revisionId}/rollback", handlers.UrlHandler.RollbackHandler)
+		})
+		r.Route("/tag", func(r chi.Router) {
+			r.Use(auth, member)
+			r.With(write).Post("/", handlers.TagHandler.CreateTagHandler)
+			r.With(read).Get("/", handlers.TagHandler.GetTagsHandler)
+			r.With(write).Patch("/{id}", handlers.TagHandler.UpdateTagHandler)
+			r.With(write).Delete("/{id}", handlers.TagHandler.DeleteTagHandler)
+		})
+		r.Route("/folder", func(r chi.Router) {
+			r.Use(auth, member)
+			r.With(write).Post("/", handlers.FolderHandler.CreateFolderHandler)
+			r.With(read).Get("/", handlers.FolderHandler.GetFoldersHandler)
+			r.With(write).Patch("/{id}", handlers.FolderHandler.UpdateFolderHandler)
+			r.With(write).Delete("/{id}", handlers.FolderHandler.DeleteFolderHandler)
+		})
+		r.Route("/domain", func(r chi.Router) {
+			r.Use(auth, member)
+			r.With(write).Post("/", handlers.DomainHandler.CreateDomainHandler)
+			r.With(read).Get("/", handlers.DomainHandler.GetDomainsHandler)
+			r.With(write).Post("/{id}/verify", handlers.DomainHandler.VerifyDomainHandler)
+			r.With(write).Delete("/{id}", handlers.DomainHandler.DeleteDomainHandler)
+		})
+		r.Route("/webhook", func(r chi.Router) {
+			r.Use(auth, member, session)
+			r.Post("/", handlers.WebhookHandler.CreateWebhookHandler)
+			r.Get("/", handlers.WebhookHandler.GetWebhooksHandler)
+			r.Patch("/{id}", handlers.WebhookHandler.UpdateWebhookHandler)
+			r.Delete("/{id}", handlers.WebhookHandler.DeleteWebhookHandler)
+			r.Get("/{id}/deliveries", handlers.WebhookHandler.GetDeliveriesHandler)
+			r.Post("/{id}/deliveries/{deliveryId}/retry", handlers.WebhookHandler.RetryDeliveryHandler)
+		})
+		r.Route("/apikey", func(r chi.Router) {
+			r.Use(auth, member, session)
+			r.Post("/", handlers.ApiKeyHandler.CreateApiKeyHandler)
+			r.Get("/", handlers.ApiKeyHandler.GetApiKeysHandler)
+			r.Delete("/{id}", handlers.ApiKeyHandler.RevokeApiKeyHandler)
+		})
+		r.Post("/bot/webhook", handlers.TelegramBot.WebhookHandler)
+		r.Route("/telegram", func(r chi.Router) {
+			r.Use(auth, member, session)
+			r.Post("/code", handlers.Telegram.CreateLinkCodeHandler)
+			r.Post("/link", handlers.Telegram.LinkHandler)
+			r.Delete("/", handlers.Telegram.UnlinkHandler)
 		})
 		r.Get("/hello", func(w http.ResponseWriter, r *http.Request) {
 			w.Write([]byte("Hello, World!"))
@@ -43,11 +127,15 @@ func StartHTTTPHandlers(handlers *app.Handlers, domain string, l *slog.Logger) h
 	router.Get("/swagger/*", httpSwagger.Handler(
 		httpSwagger.URL("/swagger/doc.json"),
 	))
+	router.Get("/.well-known/jwks.json", handlers.JWKSHandler.JWKSHandler)
+	router.Get("/qr/{alias}", handlers.UrlHandler.QRHandler)
+	router.Get("/{alias}", handlers.UrlHandler.RedirectHandler)
 	return router
 }
 func custommiddleware(router *chi.Mux, l *slog.Logger) {
 	router.Use(middleware.RequestID, middleware.Recoverer)
 	router.Use(middleware.RealIP)
+	router.Use(customiddleware.Language)
 	router.Use(logger.NewMiddlewareLogger(l))
 	router.Use(customiddleware.PrometheusMiddleware)
 }
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE url ADD COLUMN IF NOT EXISTS not_before TIMESTAMPTZ DEFAULT NULL;
+ALTER TABLE url ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT '';
+ALTER TABLE url ADD COLUMN IF NOT EXISTS time_rules JSONB NOT NULL DEFAULT '[]';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE url DROP COLUMN IF EXISTS time_rules;
+ALTER TABLE url DROP COLUMN IF EXISTS timezone;
+ALTER TABLE url DROP COLUMN IF EXISTS not_before;
+-- +goose StatementEnd
@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS folders(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    user_id UUID NOT NULL,
+    title TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT folders_user_title_unique UNIQUE (user_id, title)
+);
+
+CREATE TRIGGER trg_set_updated_at
+    BEFORE UPDATE ON folders
+    FOR EACH ROW
+EXECUTE FUNCTION set_updated_at();
+
+CREATE TABLE IF NOT EXISTS tags(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    user_id UUID NOT NULL,
+    title TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT tags_user_title_unique UNIQUE (user_id, title)
+);
+
+CREATE TABLE IF NOT EXISTS url_tags(
+    url_id UUID NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (url_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS idx_url_tags_tag ON url_tags(tag_id);
+
+ALTER TABLE url ADD COLUMN IF NOT EXISTS folder_id UUID REFERENCES folders(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_url_user ON url(user_id);
+CREATE INDEX IF NOT EXISTS idx_url_folder ON url(folder_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE url DROP COLUMN IF EXISTS folder_id;
+DROP TABLE IF EXISTS url_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS folders;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS domains(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    user_id UUID NOT NULL,
+    host TEXT NOT NULL UNIQUE,
+    token TEXT NOT NULL,
+    verified_at TIMESTAMPTZ DEFAULT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE url ADD COLUMN IF NOT EXISTS domain_id UUID REFERENCES domains(id) ON DELETE CASCADE;
+
+ALTER TABLE url DROP CONSTRAINT IF EXISTS url_alias_key;
+CREATE UNIQUE INDEX IF NOT EXISTS url_alias_shared_unique ON url(alias) WHERE domain_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS url_alias_domain_unique ON url(domain_id, alias) WHERE domain_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS url_alias_domain_unique;
+DROP INDEX IF EXISTS url_alias_shared_unique;
+ALTER TABLE url DROP COLUMN IF EXISTS domain_id;
+ALTER TABLE url ADD CONSTRAINT url_alias_key UNIQUE (alias);
+DROP TABLE IF EXISTS domains;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE url ADD COLUMN IF NOT EXISTS fallback_url TEXT DEFAULT NULL;
+
+CREATE TABLE IF NOT EXISTS url_health(
+    url_id UUID PRIMARY KEY REFERENCES url(id) ON DELETE CASCADE,
+    status_code INT NOT NULL DEFAULT 0,
+    latency_ms BIGINT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    consecutive_failures INT NOT NULL DEFAULT 0,
+    broken BOOLEAN NOT NULL DEFAULT FALSE,
+    failing_since TIMESTAMPTZ DEFAULT NULL,
+    checked_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS url_health;
+ALTER TABLE url DROP COLUMN IF EXISTS fallback_url;
+-- +goose StatementEnd
@@ -0,0 +1,64 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS url_revisions(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    url_id UUID NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+    changed_by UUID DEFAULT NULL,
+    before JSONB DEFAULT NULL,
+    after JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp()
+);
+CREATE INDEX IF NOT EXISTS idx_url_revisions_url ON url_revisions(url_id, created_at DESC);
+
+CREATE OR REPLACE FUNCTION url_routing(u url)
+    RETURNS JSONB AS $$
+BEGIN
+    RETURN jsonb_build_object(
+        'url', u.url,
+        'not_before', u.not_before,
+        'timezone', u.timezone,
+        'time_rules', u.time_rules,
+        'fallback_url', u.fallback_url
+    );
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- the application sets app.user_id with set_config(..., true) inside the
+-- transaction that changes the link
+CREATE OR REPLACE FUNCTION record_url_revision()
+    RETURNS TRIGGER AS $$
+DECLARE
+    old_routing JSONB;
+BEGIN
+    IF TG_OP = 'UPDATE' THEN
+        old_routing = url_routing(OLD);
+        IF old_routing = url_routing(NEW) THEN
+            RETURN NEW;
+        END IF;
+    END IF;
+    INSERT INTO url_revisions(url_id, changed_by, before, after)
+    VALUES (
+        NEW.id,
+        NULLIF(current_setting('app.user_id', true), '')::uuid,
+        old_routing,
+        url_routing(NEW)
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_record_url_revision ON url;
+
+CREATE TRIGGER trg_record_url_revision
+    AFTER INSERT OR UPDATE ON url
+    FOR EACH ROW
+EXECUTE FUNCTION record_url_revision();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS trg_record_url_revision ON url;
+DROP FUNCTION IF EXISTS record_url_revision();
+DROP FUNCTION IF EXISTS url_routing(url);
+DROP TABLE IF EXISTS url_revisions;
+-- +goose StatementEnd
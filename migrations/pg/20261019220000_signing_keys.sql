@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- keys the access tokens are signed with, shared by every instance. A key
+-- that was replaced keeps verifying tokens until retires_at.
+CREATE TABLE IF NOT EXISTS signing_keys(
+    kid TEXT PRIMARY KEY,
+    algorithm TEXT NOT NULL,
+    private_key BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    retires_at TIMESTAMPTZ DEFAULT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_signing_keys_created ON signing_keys(created_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS signing_keys;
+-- +goose StatementEnd
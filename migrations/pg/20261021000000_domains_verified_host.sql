@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- an unverified claim must not keep a host from its real owner, only one
+-- verified domain per host
+ALTER TABLE domains DROP CONSTRAINT IF EXISTS domains_host_key;
+ALTER TABLE domains ADD CONSTRAINT domains_user_host_unique UNIQUE (user_id, host);
+CREATE UNIQUE INDEX IF NOT EXISTS domains_verified_host_unique ON domains(host) WHERE verified_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS domains_verified_host_unique;
+ALTER TABLE domains DROP CONSTRAINT IF EXISTS domains_user_host_unique;
+DELETE FROM domains d WHERE verified_at IS NULL
+    AND EXISTS (SELECT 1 FROM domains o WHERE o.host = d.host AND o.id <> d.id);
+ALTER TABLE domains ADD CONSTRAINT domains_host_key UNIQUE (host);
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- deleting a branded domain must not take its links, revisions and stats
+-- with it
+ALTER TABLE url DROP CONSTRAINT IF EXISTS url_domain_id_fkey;
+ALTER TABLE url ADD CONSTRAINT url_domain_id_fkey
+    FOREIGN KEY (domain_id) REFERENCES domains(id) ON DELETE RESTRICT;
+ALTER TABLE domains ADD CONSTRAINT domains_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE domains DROP CONSTRAINT IF EXISTS domains_user_id_fkey;
+ALTER TABLE url DROP CONSTRAINT IF EXISTS url_domain_id_fkey;
+ALTER TABLE url ADD CONSTRAINT url_domain_id_fkey
+    FOREIGN KEY (domain_id) REFERENCES domains(id) ON DELETE CASCADE;
+-- +goose StatementEnd
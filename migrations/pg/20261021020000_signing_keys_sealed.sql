@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- private keys are stored encrypted from now on. The plain ones are dropped,
+-- the first instance to start creates a sealed key and access tokens signed
+-- with the old ones are renewed through their refresh tokens.
+DELETE FROM signing_keys;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM signing_keys;
+-- +goose StatementEnd
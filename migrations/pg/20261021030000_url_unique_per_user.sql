@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- a destination is unique per owner, not across the whole service: anyone
+-- shortening a popular url must get a link of their own
+ALTER TABLE url DROP CONSTRAINT IF EXISTS url_url_unique;
+ALTER TABLE url ADD CONSTRAINT url_user_url_unique UNIQUE (user_id, url);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE url DROP CONSTRAINT IF EXISTS url_user_url_unique;
+ALTER TABLE url ADD CONSTRAINT url_url_unique UNIQUE (url);
+-- +goose StatementEnd
@@ -1,7 +1,18 @@
 package api
 
+import (
+	"context"
+	"errors"
+
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+)
+
+// Response is the envelope of every answer. Failed ones carry a stable Code
+// and Error, its text in the language of the request.
 type Response struct {
 	Status string `json:"status"`
+	Code   string `json:"code,omitempty"`
 	Error  string `json:"error,omitempty"`
 }
 
@@ -22,3 +33,59 @@ func Error(msg string) Response {
 		Error:  msg,
 	}
 }
+
+// Fail answers with code and its text in the language of ctx.
+func Fail(ctx context.Context, code i18n.Key, args ...any) Response {
+	return Response{
+		Status: StatusError,
+		Code:   string(code),
+		Error:  i18n.T(i18n.FromContext(ctx), code, args...),
+	}
+}
+
+// errorCodes are the service errors handlers pass on to clients.
+var errorCodes = []struct {
+	err  error
+	code i18n.Key
+}{
+	{utils.ErrorUrlNotFound, i18n.ErrUrlNotFound},
+	{utils.ErrorUrlNotActive, i18n.ErrUrlNotActive},
+	{utils.ErrorUrlExpired, i18n.ErrUrlExpired},
+	{utils.ErrorUrlExists, i18n.ErrUrlExists},
+	{utils.ErrorInvalidUrl, i18n.ErrInvalidUrl},
+	{utils.ErrorUrlBlocked, i18n.ErrUrlBlocked},
+	{utils.ErrorInvalidAlias, i18n.ErrInvalidAlias},
+	{utils.ErrorAliasTaken, i18n.ErrAliasTaken},
+	{utils.ErrorInvalidExpiry, i18n.ErrInvalidExpiry},
+	{utils.ErrorInvalidTimeRule, i18n.ErrInvalidTimeRule},
+	{utils.ErrorInvalidTimezone, i18n.ErrInvalidTimezone},
+	{utils.ErrorTagNotFound, i18n.ErrTagNotFound},
+	{utils.ErrorTagAlreadyExists, i18n.ErrTagExists},
+	{utils.ErrorFolderNotFound, i18n.ErrFolderNotFound},
+	{utils.ErrorFolderExists, i18n.ErrFolderExists},
+	{utils.ErrorDomainNotFound, i18n.ErrDomainNotFound},
+	{utils.ErrorDomainExists, i18n.ErrDomainExists},
+	{utils.ErrorDomainNotVerified, i18n.ErrDomainNotVerified},
+	{utils.ErrorInvalidDomain, i18n.ErrInvalidDomain},
+	{utils.ErrorDomainInUse, i18n.ErrDomainInUse},
+	{utils.ErrorRevisionNotFound, i18n.ErrRevisionNotFound},
+	{utils.ErrorWebhookNotFound, i18n.ErrWebhookNotFound},
+	{utils.ErrorInvalidWebhookUrl, i18n.ErrInvalidWebhookUrl},
+	{utils.ErrorDeliveryNotFound, i18n.ErrDeliveryNotFound},
+	{utils.ErrorNothingToUpdate, i18n.ErrNothingToUpdate},
+	{utils.ErrorApiKeyNotFound, i18n.ErrApiKeyNotFound},
+	{utils.ErrorInvalidExpiresAt, i18n.ErrInvalidExpiresAt},
+	{utils.ErrorSessionNotFound, i18n.ErrSessionNotFound},
+	{utils.ErrorTwoFactorLocked, i18n.ErrTwoFactorLocked},
+}
+
+// FailErr answers with the code of a service error, unknown errors are
+// internal ones.
+func FailErr(ctx context.Context, err error) Response {
+	for _, e := range errorCodes {
+		if errors.Is(err, e.err) {
+			return Fail(ctx, e.code)
+		}
+	}
+	return Fail(ctx, i18n.ErrInternal)
+}
@@ -0,0 +1,240 @@
+package i18n
+
+// Codes of API errors, returned in the "code" field next to the text.
+const (
+	ErrInvalidBody              Key = "invalid_body"
+	ErrInvalidRequest           Key = "invalid_request"
+	ErrInternal                 Key = "internal_error"
+	ErrUnavailable              Key = "service_unavailable"
+	ErrUnauthorized             Key = "unauthorized"
+	ErrForbidden                Key = "forbidden"
+	ErrMissingScope             Key = "missing_scope"
+	ErrApiKeyNotAllowed         Key = "api_key_not_allowed"
+	ErrNotFound                 Key = "not_found"
+	ErrCookie                   Key = "cookie_failed"
+	ErrUserExists               Key = "user_exists"
+	ErrRegister                 Key = "register_failed"
+	ErrInvalidCredentials       Key = "invalid_credentials"
+	ErrInvalidPassword          Key = "invalid_password"
+	ErrLogout                   Key = "logout_failed"
+	ErrLoginAgain               Key = "login_again"
+	ErrInvalidToken             Key = "invalid_token"
+	ErrEmailNotVerified         Key = "email_not_verified"
+	ErrVerifyEmail              Key = "verify_email_failed"
+	ErrSendVerificationEmail    Key = "send_verification_email_failed"
+	ErrSendPasswordResetEmail   Key = "send_password_reset_email_failed"
+	ErrResetPassword            Key = "reset_password_failed"
+	ErrInvalidSessionID         Key = "invalid_session_id"
+	ErrGetSessions              Key = "get_sessions_failed"
+	ErrRevokeSession            Key = "revoke_session_failed"
+	ErrInvalidCode              Key = "invalid_code"
+	ErrTwoFactorLocked          Key = "two_factor_locked"
+	ErrTwoFactorEnabled         Key = "two_factor_enabled"
+	ErrTwoFactorDisabled        Key = "two_factor_disabled"
+	ErrTwoFactorPassword        Key = "two_factor_password_required"
+	ErrEnrollmentNotStarted     Key = "enrollment_not_started"
+	ErrStartEnrollment          Key = "start_enrollment_failed"
+	ErrEnableTwoFactor          Key = "enable_two_factor_failed"
+	ErrDisableTwoFactor         Key = "disable_two_factor_failed"
+	ErrUnknownProvider          Key = "unknown_provider"
+	ErrInvalidOAuthState        Key = "invalid_oauth_state"
+	ErrExchangeCode             Key = "exchange_code_failed"
+	ErrProviderEmailNotVerified Key = "provider_email_not_verified"
+	ErrSignInFailed             Key = "sign_in_failed"
+	ErrIdentityNotFound         Key = "identity_not_found"
+	ErrIdentityLinked           Key = "identity_linked"
+	ErrLastLoginMethod          Key = "last_login_method"
+	ErrInvalidTelegramAuth      Key = "invalid_telegram_auth"
+	ErrInvalidLinkCode          Key = "invalid_link_code"
+	ErrTelegramTaken            Key = "telegram_taken"
+	ErrOtherTelegram            Key = "other_telegram_linked"
+	ErrTelegramNotLinked        Key = "telegram_not_linked"
+	ErrInvalidUpdate            Key = "invalid_update"
+	ErrInvalidSecretToken       Key = "invalid_secret_token"
+	ErrInvalidUrlID             Key = "invalid_url_id"
+	ErrInvalidAlias             Key = "invalid_alias"
+	ErrUrlNotFound              Key = "url_not_found"
+	ErrUrlExpired               Key = "url_expired"
+	ErrUrlNotActive             Key = "url_not_active"
+	ErrUrlNotChecked            Key = "url_not_checked"
+	ErrUrlExists                Key = "url_exists"
+	ErrAliasTaken               Key = "alias_taken"
+	ErrInvalidUrl               Key = "invalid_url"
+	ErrUrlBlocked               Key = "url_blocked"
+	ErrInvalidExpiry            Key = "invalid_expiry"
+	ErrInvalidTimeRule          Key = "invalid_time_rule"
+	ErrInvalidTimezone          Key = "invalid_timezone"
+	ErrNothingToUpdate          Key = "nothing_to_update"
+	ErrGetUrls                  Key = "get_urls_failed"
+	ErrCreateUrl                Key = "create_url_failed"
+	ErrUpdateUrl                Key = "update_url_failed"
+	ErrDeleteUrl                Key = "delete_url_failed"
+	ErrGetUrlHealth             Key = "get_url_health_failed"
+	ErrInvalidRevisionID        Key = "invalid_revision_id"
+	ErrRevisionNotFound         Key = "revision_not_found"
+	ErrGetRevisions             Key = "get_revisions_failed"
+	ErrRollbackUrl              Key = "rollback_url_failed"
+	ErrInvalidTagID             Key = "invalid_tag_id"
+	ErrTagNotFound              Key = "tag_not_found"
+	ErrTagExists                Key = "tag_exists"
+	ErrGetTags                  Key = "get_tags_failed"
+	ErrCreateTag                Key = "create_tag_failed"
+	ErrUpdateTag                Key = "update_tag_failed"
+	ErrDeleteTag                Key = "delete_tag_failed"
+	ErrInvalidFolderID          Key = "invalid_folder_id"
+	ErrFolderNotFound           Key = "folder_not_found"
+	ErrFolderExists             Key = "folder_exists"
+	ErrGetFolders               Key = "get_folders_failed"
+	ErrCreateFolder             Key = "create_folder_failed"
+	ErrUpdateFolder             Key = "update_folder_failed"
+	ErrDeleteFolder             Key = "delete_folder_failed"
+	ErrInvalidDomainID          Key = "invalid_domain_id"
+	ErrInvalidDomain            Key = "invalid_domain"
+	ErrDomainNotFound           Key = "domain_not_found"
+	ErrDomainExists             Key = "domain_exists"
+	ErrDomainNotVerified        Key = "domain_not_verified"
+	ErrDomainInUse              Key = "domain_in_use"
+	ErrGetDomains               Key = "get_domains_failed"
+	ErrCreateDomain             Key = "create_domain_failed"
+	ErrVerifyDomain             Key = "verify_domain_failed"
+	ErrDeleteDomain             Key = "delete_domain_failed"
+	ErrInvalidWebhookID         Key = "invalid_webhook_id"
+	ErrWebhookNotFound          Key = "webhook_not_found"
+	ErrInvalidWebhookUrl        Key = "invalid_webhook_url"
+	ErrGetWebhooks              Key = "get_webhooks_failed"
+	ErrCreateWebhook            Key = "create_webhook_failed"
+	ErrUpdateWebhook            Key = "update_webhook_failed"
+	ErrDeleteWebhook            Key = "delete_webhook_failed"
+	ErrInvalidDeliveryID        Key = "invalid_delivery_id"
+	ErrDeliveryNotFound         Key = "delivery_not_found"
+	ErrInvalidStatus            Key = "invalid_status"
+	ErrGetDeliveries            Key = "get_deliveries_failed"
+	ErrRetryDelivery            Key = "retry_delivery_failed"
+	ErrSubscribe                Key = "subscribe_failed"
+	ErrInvalidApiKeyID          Key = "invalid_api_key_id"
+	ErrApiKeyNotFound           Key = "api_key_not_found"
+	ErrInvalidExpiresAt         Key = "invalid_expires_at"
+	ErrGetApiKeys               Key = "get_api_keys_failed"
+	ErrCreateApiKey             Key = "create_api_key_failed"
+	ErrRevokeApiKey             Key = "revoke_api_key_failed"
+	ErrSessionNotFound          Key = "session_not_found"
+)
+
+var apiMessages = map[Key]map[Lang]string{
+	ErrInvalidBody:              {RU: "Ошибка при валидации данных", EN: "Request body could not be decoded"},
+	ErrInvalidRequest:           {RU: "Некорректный запрос", EN: "Invalid request"},
+	ErrInternal:                 {RU: "Внутренняя ошибка сервера", EN: "Internal server error"},
+	ErrUnavailable:              {RU: "Сервис временно недоступен", EN: "Service temporarily unavailable"},
+	ErrUnauthorized:             {RU: "Требуется авторизация", EN: "Unauthorized"},
+	ErrForbidden:                {RU: "Доступ запрещён", EN: "Forbidden"},
+	ErrMissingScope:             {RU: "У API-ключа нет права %s", EN: "API key lacks scope %s"},
+	ErrApiKeyNotAllowed:         {RU: "Недоступно при входе по API-ключу", EN: "Not allowed with an API key"},
+	ErrNotFound:                 {RU: "Не найдено", EN: "Not found"},
+	ErrCookie:                   {RU: "Не удалось сохранить сессию", EN: "Failed to set session cookies"},
+	ErrUserExists:               {RU: "Пользователь с таким email или username уже существует", EN: "User with this email or username already exists"},
+	ErrRegister:                 {RU: "Ошибка регистрации", EN: "Registration failed"},
+	ErrInvalidCredentials:       {RU: "Неправильный логин или пароль", EN: "Invalid login or password"},
+	ErrInvalidPassword:          {RU: "Неверный пароль", EN: "Invalid password"},
+	ErrLogout:                   {RU: "Не удалось выйти", EN: "Failed to log out"},
+	ErrLoginAgain:               {RU: "Войдите заново", EN: "Sign in again"},
+	ErrInvalidToken:             {RU: "Токен неверный или устарел", EN: "Invalid or expired token"},
+	ErrEmailNotVerified:         {RU: "Email не подтверждён", EN: "Email is not verified"},
+	ErrVerifyEmail:              {RU: "Не удалось подтвердить email", EN: "Failed to verify email"},
+	ErrSendVerificationEmail:    {RU: "Не удалось отправить письмо для подтверждения", EN: "Failed to send verification email"},
+	ErrSendPasswordResetEmail:   {RU: "Не удалось отправить письмо для сброса пароля", EN: "Failed to send password reset email"},
+	ErrResetPassword:            {RU: "Не удалось сбросить пароль", EN: "Failed to reset password"},
+	ErrInvalidSessionID:         {RU: "Некорректный id сессии", EN: "Invalid session id"},
+	ErrGetSessions:              {RU: "Не удалось получить сессии", EN: "Failed to get sessions"},
+	ErrRevokeSession:            {RU: "Не удалось завершить сессию", EN: "Failed to revoke session"},
+	ErrInvalidCode:              {RU: "Неверный код", EN: "Invalid code"},
+	ErrTwoFactorLocked:          {RU: "Слишком много неверных кодов, попробуйте позже", EN: "Too many wrong codes, try again later"},
+	ErrTwoFactorEnabled:         {RU: "Двухфакторная аутентификация уже включена", EN: "Two-factor authentication is already enabled"},
+	ErrTwoFactorDisabled:        {RU: "Двухфакторная аутентификация не включена", EN: "Two-factor authentication is not enabled"},
+	ErrTwoFactorPassword:        {RU: "Для двухфакторной аутентификации нужен аккаунт с паролем", EN: "Two-factor authentication needs an account with a password"},
+	ErrEnrollmentNotStarted:     {RU: "Сначала начните подключение", EN: "Start the enrollment first"},
+	ErrStartEnrollment:          {RU: "Не удалось начать подключение двухфакторной аутентификации", EN: "Failed to start two-factor enrollment"},
+	ErrEnableTwoFactor:          {RU: "Не удалось включить двухфакторную аутентификацию", EN: "Failed to enable two-factor authentication"},
+	ErrDisableTwoFactor:         {RU: "Не удалось отключить двухфакторную аутентификацию", EN: "Failed to disable two-factor authentication"},
+	ErrUnknownProvider:          {RU: "Неизвестный провайдер", EN: "Unknown provider"},
+	ErrInvalidOAuthState:        {RU: "Состояние входа неверное или устарело, начните вход заново", EN: "Invalid or expired state, start the login again"},
+	ErrExchangeCode:             {RU: "Не удалось обменять код авторизации", EN: "Failed to exchange authorization code"},
+	ErrProviderEmailNotVerified: {RU: "Email у провайдера не подтверждён", EN: "Provider email is not verified"},
+	ErrSignInFailed:             {RU: "Не удалось войти", EN: "Sign-in failed"},
+	ErrIdentityNotFound:         {RU: "Внешний аккаунт не найден", EN: "Identity not found"},
+	ErrIdentityLinked:           {RU: "Внешний аккаунт привязан к другому пользователю", EN: "External account is linked to another user"},
+	ErrLastLoginMethod:          {RU: "Сначала задайте пароль или привяжите другой аккаунт", EN: "Set a password or link another account first"},
+	ErrInvalidTelegramAuth:      {RU: "Неверные данные входа через Telegram", EN: "Invalid Telegram auth data"},
+	ErrInvalidLinkCode:          {RU: "Код неверный или устарел", EN: "Invalid or expired code"},
+	ErrTelegramTaken:            {RU: "Telegram привязан к другому пользователю", EN: "Telegram account is linked to another user"},
+	ErrOtherTelegram:            {RU: "Сначала отвяжите текущий Telegram", EN: "Unlink the current Telegram account first"},
+	ErrTelegramNotLinked:        {RU: "Telegram не привязан", EN: "Telegram account is not linked"},
+	ErrInvalidUpdate:            {RU: "Некорректное обновление", EN: "Invalid update"},
+	ErrInvalidSecretToken:       {RU: "Неверный секретный токен", EN: "Invalid secret token"},
+	ErrInvalidUrlID:             {RU: "Некорректный id ссылки", EN: "Invalid url id"},
+	ErrInvalidAlias:             {RU: "Алиас может содержать только латинские буквы, цифры, _ и -, до 64 символов", EN: "Alias may contain only latin letters, digits, _ and - up to 64 characters"},
+	ErrUrlNotFound:              {RU: "Ссылка не найдена", EN: "Url not found"},
+	ErrUrlExpired:               {RU: "Срок действия ссылки истёк", EN: "Url has expired"},
+	ErrUrlNotActive:             {RU: "Ссылка ещё не активна", EN: "Url is not active yet"},
+	ErrUrlNotChecked:            {RU: "Ссылка ещё не проверялась", EN: "Url was not checked yet"},
+	ErrUrlExists:                {RU: "Эта ссылка уже сокращена", EN: "Url is already shortened"},
+	ErrAliasTaken:               {RU: "Этот алиас уже занят", EN: "Alias is already taken"},
+	ErrInvalidUrl:               {RU: "Некорректная ссылка, отправьте адрес целиком, например https://example.com", EN: "Invalid url, send the whole address like https://example.com"},
+	ErrUrlBlocked:               {RU: "Ссылки на этот домен сокращать нельзя", EN: "Links to this domain are not allowed"},
+	ErrInvalidExpiry:            {RU: "Срок действия должен быть в будущем и позже not_before", EN: "Expiry must be in the future and after not_before"},
+	ErrInvalidTimeRule:          {RU: "Некорректное правило расписания", EN: "Invalid time rule"},
+	ErrInvalidTimezone:          {RU: "Некорректный часовой пояс", EN: "Invalid timezone"},
+	ErrNothingToUpdate:          {RU: "Нечего обновлять", EN: "Nothing to update"},
+	ErrGetUrls:                  {RU: "Не удалось получить ссылки", EN: "Failed to get urls"},
+	ErrCreateUrl:                {RU: "Не удалось создать ссылку", EN: "Failed to create url"},
+	ErrUpdateUrl:                {RU: "Не удалось изменить ссылку", EN: "Failed to update url"},
+	ErrDeleteUrl:                {RU: "Не удалось удалить ссылку", EN: "Failed to delete url"},
+	ErrGetUrlHealth:             {RU: "Не удалось получить состояние ссылки", EN: "Failed to get url health"},
+	ErrInvalidRevisionID:        {RU: "Некорректный id ревизии", EN: "Invalid revision id"},
+	ErrRevisionNotFound:         {RU: "Ревизия не найдена", EN: "Revision not found"},
+	ErrGetRevisions:             {RU: "Не удалось получить ревизии", EN: "Failed to get revisions"},
+	ErrRollbackUrl:              {RU: "Не удалось откатить ссылку", EN: "Failed to roll back url"},
+	ErrInvalidTagID:             {RU: "Некорректный id тега", EN: "Invalid tag id"},
+	ErrTagNotFound:              {RU: "Тег не найден", EN: "Tag not found"},
+	ErrTagExists:                {RU: "Тег с таким названием уже есть", EN: "Tag with this title already exists"},
+	ErrGetTags:                  {RU: "Не удалось получить теги", EN: "Failed to get tags"},
+	ErrCreateTag:                {RU: "Не удалось создать тег", EN: "Failed to create tag"},
+	ErrUpdateTag:                {RU: "Не удалось изменить тег", EN: "Failed to update tag"},
+	ErrDeleteTag:                {RU: "Не удалось удалить тег", EN: "Failed to delete tag"},
+	ErrInvalidFolderID:          {RU: "Некорректный id папки", EN: "Invalid folder id"},
+	ErrFolderNotFound:           {RU: "Папка не найдена", EN: "Folder not found"},
+	ErrFolderExists:             {RU: "Папка с таким названием уже есть", EN: "Folder with this title already exists"},
+	ErrGetFolders:               {RU: "Не удалось получить папки", EN: "Failed to get folders"},
+	ErrCreateFolder:             {RU: "Не удалось создать папку", EN: "Failed to create folder"},
+	ErrUpdateFolder:             {RU: "Не удалось изменить папку", EN: "Failed to update folder"},
+	ErrDeleteFolder:             {RU: "Не удалось удалить папку", EN: "Failed to delete folder"},
+	ErrInvalidDomainID:          {RU: "Некорректный id домена", EN: "Invalid domain id"},
+	ErrInvalidDomain:            {RU: "Некорректный домен", EN: "Invalid domain"},
+	ErrDomainNotFound:           {RU: "Домен не найден", EN: "Domain not found"},
+	ErrDomainExists:             {RU: "Домен уже зарегистрирован", EN: "Domain is already registered"},
+	ErrDomainNotVerified:        {RU: "Домен не подтверждён", EN: "Domain is not verified"},
+	ErrDomainInUse:              {RU: "На домене остались ссылки", EN: "Domain still has links"},
+	ErrGetDomains:               {RU: "Не удалось получить домены", EN: "Failed to get domains"},
+	ErrCreateDomain:             {RU: "Не удалось добавить домен", EN: "Failed to create domain"},
+	ErrVerifyDomain:             {RU: "Не удалось подтвердить домен", EN: "Failed to verify domain"},
+	ErrDeleteDomain:             {RU: "Не удалось удалить домен", EN: "Failed to delete domain"},
+	ErrInvalidWebhookID:         {RU: "Некорректный id вебхука", EN: "Invalid webhook id"},
+	ErrWebhookNotFound:          {RU: "Вебхук не найден", EN: "Webhook not found"},
+	ErrInvalidWebhookUrl:        {RU: "Адрес вебхука должен быть публичным http или https адресом", EN: "Webhook url must be a public http or https address"},
+	ErrGetWebhooks:              {RU: "Не удалось получить вебхуки", EN: "Failed to get webhooks"},
+	ErrCreateWebhook:            {RU: "Не удалось создать вебхук", EN: "Failed to create webhook"},
+	ErrUpdateWebhook:            {RU: "Не удалось изменить вебхук", EN: "Failed to update webhook"},
+	ErrDeleteWebhook:            {RU: "Не удалось удалить вебхук", EN: "Failed to delete webhook"},
+	ErrInvalidDeliveryID:        {RU: "Некорректный id доставки", EN: "Invalid delivery id"},
+	ErrDeliveryNotFound:         {RU: "Доставка вебхука не найдена", EN: "Webhook delivery not found"},
+	ErrInvalidStatus:            {RU: "Некорректный статус", EN: "Invalid status"},
+	ErrGetDeliveries:            {RU: "Не удалось получить доставки", EN: "Failed to get deliveries"},
+	ErrRetryDelivery:            {RU: "Не удалось повторить доставку", EN: "Failed to retry delivery"},
+	ErrSubscribe:                {RU: "Не удалось подписаться", EN: "Failed to subscribe"},
+	ErrInvalidApiKeyID:          {RU: "Некорректный id API-ключа", EN: "Invalid API key id"},
+	ErrApiKeyNotFound:           {RU: "API-ключ не найден", EN: "API key not found"},
+	ErrInvalidExpiresAt:         {RU: "expires_at должен быть в будущем", EN: "expires_at must be in the future"},
+	ErrGetApiKeys:               {RU: "Не удалось получить API-ключи", EN: "Failed to get API keys"},
+	ErrCreateApiKey:             {RU: "Не удалось создать API-ключ", EN: "Failed to create API key"},
+	ErrRevokeApiKey:             {RU: "Не удалось отозвать API-ключ", EN: "Failed to revoke API key"},
+	ErrSessionNotFound:          {RU: "Сессия не найдена", EN: "Session not found"},
+}
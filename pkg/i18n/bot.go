@@ -0,0 +1,222 @@
+package i18n
+
+// Messages of the Telegram bot.
+const (
+	BotCmdStart    Key = "bot.cmd.start"
+	BotCmdHelp     Key = "bot.cmd.help"
+	BotCmdPing     Key = "bot.cmd.ping"
+	BotCmdShort    Key = "bot.cmd.short"
+	BotCmdNew      Key = "bot.cmd.new"
+	BotCmdCancel   Key = "bot.cmd.cancel"
+	BotCmdMyLinks  Key = "bot.cmd.mylinks"
+	BotCmdStats    Key = "bot.cmd.stats"
+	BotCmdEdit     Key = "bot.cmd.edit"
+	BotCmdDelete   Key = "bot.cmd.delete"
+	BotCmdLink     Key = "bot.cmd.link"
+	BotCmdLanguage Key = "bot.cmd.language"
+
+	BotStart          Key = "bot.start"
+	BotStartFailed    Key = "bot.start_failed"
+	BotHelp           Key = "bot.help"
+	BotPong           Key = "bot.pong"
+	BotUnknownCommand Key = "bot.unknown_command"
+	BotCommandFailed  Key = "bot.command_failed"
+	BotUserNotFound   Key = "bot.user_not_found"
+	BotLinkBroken     Key = "bot.link_broken"
+
+	BotLanguagePrompt Key = "bot.language.prompt"
+	BotLanguageName   Key = "bot.language.name"
+	BotLanguageSet    Key = "bot.language.set"
+
+	BotLinkCode        Key = "bot.link.code"
+	BotLinkCodeFailed  Key = "bot.link.code_failed"
+	BotLinked          Key = "bot.link.linked"
+	BotInvalidLinkCode Key = "bot.link.invalid_code"
+	BotTelegramTaken   Key = "bot.link.telegram_taken"
+	BotOtherTelegram   Key = "bot.link.other_telegram"
+	BotLinkFailed      Key = "bot.link.failed"
+
+	BotShortUsage    Key = "bot.short.usage"
+	BotShortLink     Key = "bot.short.link"
+	BotShortenFailed Key = "bot.short.failed"
+	BotInvalidUrl    Key = "bot.url.invalid"
+	BotUrlBlocked    Key = "bot.url.blocked"
+	BotUrlExists     Key = "bot.url.exists"
+	BotUrlNotFound   Key = "bot.url.not_found"
+	BotInvalidAlias  Key = "bot.url.invalid_alias"
+	BotAliasTaken    Key = "bot.url.alias_taken"
+	BotInvalidExpiry Key = "bot.url.invalid_expiry"
+
+	BotLinksFailed      Key = "bot.links.failed"
+	BotNoLinks          Key = "bot.links.none"
+	BotEmptyPage        Key = "bot.links.empty_page"
+	BotLinksPage        Key = "bot.links.page"
+	BotBack             Key = "bot.button.back"
+	BotForward          Key = "bot.button.forward"
+	BotCancelButton     Key = "bot.button.cancel"
+	BotButtonExpired    Key = "bot.button.expired"
+	BotNoSuchLink       Key = "bot.links.no_such_link"
+	BotLinkLookupFailed Key = "bot.links.lookup_failed"
+	BotStatsUsage       Key = "bot.stats.usage"
+	BotStatsFailed      Key = "bot.stats.failed"
+	BotStatsClicks      Key = "bot.stats.clicks"
+	BotStatsReferrers   Key = "bot.stats.referrers"
+	BotDeleteUsage      Key = "bot.delete.usage"
+	BotDeleteQuestion   Key = "bot.delete.question"
+	BotDeleteButton     Key = "bot.delete.button"
+	BotDeleted          Key = "bot.delete.done"
+	BotEditUsage        Key = "bot.edit.usage"
+	BotEditQuestion     Key = "bot.edit.question"
+	BotEditButton       Key = "bot.edit.button"
+	BotEdited           Key = "bot.edit.done"
+	BotCanceled         Key = "bot.confirm.canceled"
+
+	BotInlineHint   Key = "bot.inline.hint"
+	BotInlineQR     Key = "bot.inline.qr"
+	BotInlineQRLink Key = "bot.inline.qr_link"
+
+	BotNewCanceled     Key = "bot.new.canceled"
+	BotNothingToCancel Key = "bot.new.nothing_to_cancel"
+	BotNewUseButtons   Key = "bot.new.use_buttons"
+	BotNewExpired      Key = "bot.new.expired"
+	BotNewAskUrl       Key = "bot.new.ask_url"
+	BotNewAskAlias     Key = "bot.new.ask_alias"
+	BotNewAskExpiry    Key = "bot.new.ask_expiry"
+	BotNewConfirm      Key = "bot.new.confirm"
+	BotNewOptions      Key = "bot.new.options"
+	BotNewSummary      Key = "bot.new.summary"
+	BotNewRandomAlias  Key = "bot.new.random_alias"
+	BotNewNoExpiry     Key = "bot.new.no_expiry"
+	BotNewExpiresAt    Key = "bot.new.expires_at"
+	BotNewCustomAlias  Key = "bot.new.button.custom_alias"
+	BotNewExpiry       Key = "bot.new.button.expiry"
+	BotNewContinue     Key = "bot.new.button.continue"
+	BotNewCreate       Key = "bot.new.button.create"
+	BotNewRandom       Key = "bot.new.button.random"
+	BotNewNever        Key = "bot.new.button.never"
+	BotNewExpiry1Day   Key = "bot.new.button.expiry_1"
+	BotNewExpiry7Days  Key = "bot.new.button.expiry_7"
+	BotNewExpiry30Days Key = "bot.new.button.expiry_30"
+
+	BotNoUrlsInMessage Key = "bot.bulk.no_urls_in_message"
+	BotNoUrlsInFile    Key = "bot.bulk.no_urls_in_file"
+	BotFileTooLarge    Key = "bot.bulk.file_too_large"
+	BotDownloadFailed  Key = "bot.bulk.download_failed"
+	BotBulkLimited     Key = "bot.bulk.limited"
+	BotBulkFailed      Key = "bot.bulk.failed"
+	BotBulkCaption     Key = "bot.bulk.caption"
+	BotBulkUrlFailed   Key = "bot.bulk.url_failed"
+)
+
+var botMessages = map[Key]map[Lang]string{
+	BotCmdStart:    {RU: "Начать", EN: "Start"},
+	BotCmdHelp:     {RU: "Помощь", EN: "Help"},
+	BotCmdPing:     {RU: "Проверка связи", EN: "Check the connection"},
+	BotCmdShort:    {RU: "Сократить ссылку: /short <url>", EN: "Shorten a link: /short <url>"},
+	BotCmdNew:      {RU: "Создать ссылку со своим алиасом или сроком", EN: "Create a link with a custom alias or expiry"},
+	BotCmdCancel:   {RU: "Отменить создание ссылки", EN: "Cancel creating a link"},
+	BotCmdMyLinks:  {RU: "Мои ссылки", EN: "My links"},
+	BotCmdStats:    {RU: "Статистика: /stats <alias>", EN: "Statistics: /stats <alias>"},
+	BotCmdEdit:     {RU: "Изменить адрес: /edit <alias> <url>", EN: "Change the destination: /edit <alias> <url>"},
+	BotCmdDelete:   {RU: "Удалить ссылку: /delete <alias>", EN: "Delete a link: /delete <alias>"},
+	BotCmdLink:     {RU: "Связать с аккаунтом на сайте", EN: "Link with your web account"},
+	BotCmdLanguage: {RU: "Язык", EN: "Language"},
+
+	BotStart: {
+		RU: "Привет! Доступные команды:\n/start — Начать\n/help — Помощь\n/ping — Проверка\n/short <url> — Сократить ссылку\n/new — Создать ссылку с настройками\n/mylinks — Мои ссылки\n/stats <alias> — Статистика\n/edit <alias> <url> — Изменить адрес\n/delete <alias> — Удалить ссылку\n/link — Связать с аккаунтом на сайте\n/language — Язык",
+		EN: "Hi! Available commands:\n/start — Start\n/help — Help\n/ping — Check\n/short <url> — Shorten a link\n/new — Create a link with options\n/mylinks — My links\n/stats <alias> — Statistics\n/edit <alias> <url> — Change the destination\n/delete <alias> — Delete a link\n/link — Link with your web account\n/language — Language",
+	},
+	BotStartFailed: {RU: "Произошла ошибка, нажмите /start еще раз", EN: "Something went wrong, press /start again"},
+	BotHelp: {
+		RU: "Помощь:\n/short <url> — отправьте ссылку, чтобы получить короткий вариант.\n/new — создать ссылку по шагам: свой алиас, срок действия. /cancel — прервать.\nПерешлите сообщение со ссылками или отправьте файл .txt/.csv — все ссылки в нём будут сокращены.\n/mylinks — список ваших ссылок.\n/stats <alias> — переходы и источники.\n/edit <alias> <url> и /delete <alias> — изменить или удалить ссылку, с подтверждением.\n/link — получить код, чтобы связать Telegram с аккаунтом на сайте. /link <код> — ввести код с сайта.\n/language — выбрать язык.",
+		EN: "Help:\n/short <url> — send a link to get a short one.\n/new — create a link step by step: custom alias, expiry. /cancel — stop.\nForward a message with links or send a .txt/.csv file — every link in it is shortened.\n/mylinks — your links.\n/stats <alias> — clicks and where they come from.\n/edit <alias> <url> and /delete <alias> — change or delete a link, with a confirmation.\n/link — get a code to link Telegram with your web account. /link <code> — enter the code from the site.\n/language — choose the language.",
+	},
+	BotPong:           {RU: "pong", EN: "pong"},
+	BotUnknownCommand: {RU: "Неизвестная команда. Введите /help", EN: "Unknown command. Type /help"},
+	BotCommandFailed:  {RU: "Не удалось выполнить команду, попробуйте еще раз", EN: "Could not run the command, try again"},
+	BotUserNotFound:   {RU: "Не удалось найти ваш аккаунт, попробуйте еще раз", EN: "Could not find your account, try again"},
+	BotLinkBroken:     {RU: "Ссылка %s перестала открываться (%s): %s", EN: "Link %s stopped opening (%s): %s"},
+
+	BotLanguagePrompt: {RU: "Выберите язык", EN: "Choose the language"},
+	BotLanguageName:   {RU: "Русский", EN: "English"},
+	BotLanguageSet:    {RU: "Язык: русский", EN: "Language: English"},
+
+	BotLinkCode:        {RU: "Введите код %s в настройках аккаунта на сайте. Код действует до %s UTC", EN: "Enter the code %s in the account settings on the site. The code is valid until %s UTC"},
+	BotLinkCodeFailed:  {RU: "Не удалось создать код, попробуйте еще раз", EN: "Could not create a code, try again"},
+	BotLinked:          {RU: "Telegram связан с аккаунтом на сайте, ссылки из бота теперь видны там", EN: "Telegram is linked with your web account, links from the bot are shown there now"},
+	BotInvalidLinkCode: {RU: "Код неверный или устарел, создайте новый на сайте", EN: "The code is wrong or expired, create a new one on the site"},
+	BotTelegramTaken:   {RU: "Этот Telegram уже связан с другим аккаунтом на сайте", EN: "This Telegram is already linked with another web account"},
+	BotOtherTelegram:   {RU: "Аккаунт на сайте уже связан с другим Telegram", EN: "The web account is already linked with another Telegram"},
+	BotLinkFailed:      {RU: "Не удалось связать аккаунты, попробуйте еще раз", EN: "Could not link the accounts, try again"},
+
+	BotShortUsage:    {RU: "Использование: /short <url>", EN: "Usage: /short <url>"},
+	BotShortLink:     {RU: "Короткая ссылка: %s", EN: "Short link: %s"},
+	BotShortenFailed: {RU: "Не удалось сократить ссылку, попробуйте еще раз", EN: "Could not shorten the link, try again"},
+	BotInvalidUrl:    {RU: "Это не похоже на ссылку. Отправьте адрес целиком, например https://example.com", EN: "This does not look like a link. Send the whole address, like https://example.com"},
+	BotUrlBlocked:    {RU: "Ссылки на этот домен сокращать нельзя", EN: "Links to this domain cannot be shortened"},
+	BotUrlExists:     {RU: "Эта ссылка уже сокращена", EN: "This link is already shortened"},
+	BotUrlNotFound:   {RU: "Ссылка не найдена, возможно она уже удалена", EN: "Link not found, it may have been deleted"},
+	BotInvalidAlias:  {RU: "Алиас может содержать только латинские буквы, цифры, _ и -, до 64 символов", EN: "An alias may contain only latin letters, digits, _ and -, up to 64 characters"},
+	BotAliasTaken:    {RU: "Этот алиас уже занят, выберите другой", EN: "This alias is taken, choose another one"},
+	BotInvalidExpiry: {RU: "Срок действия должен быть в будущем", EN: "The expiry must be in the future"},
+
+	BotLinksFailed:      {RU: "Не удалось загрузить ссылки, попробуйте еще раз", EN: "Could not load the links, try again"},
+	BotNoLinks:          {RU: "У вас пока нет ссылок. Сократите первую: /short <url>", EN: "You have no links yet. Shorten the first one: /short <url>"},
+	BotEmptyPage:        {RU: "На этой странице ссылок нет", EN: "There are no links on this page"},
+	BotLinksPage:        {RU: "Ваши ссылки, страница %d:\n", EN: "Your links, page %d:\n"},
+	BotBack:             {RU: "« Назад", EN: "« Back"},
+	BotForward:          {RU: "Вперёд »", EN: "Next »"},
+	BotCancelButton:     {RU: "Отмена", EN: "Cancel"},
+	BotButtonExpired:    {RU: "Кнопка устарела, повторите команду", EN: "The button has expired, repeat the command"},
+	BotNoSuchLink:       {RU: "У вас нет ссылки %s. Список ссылок: /mylinks", EN: "You have no link %s. Your links: /mylinks"},
+	BotLinkLookupFailed: {RU: "Не удалось найти ссылку, попробуйте еще раз", EN: "Could not find the link, try again"},
+	BotStatsUsage:       {RU: "Использование: /stats <alias>", EN: "Usage: /stats <alias>"},
+	BotStatsFailed:      {RU: "Не удалось получить статистику, попробуйте еще раз", EN: "Could not get the statistics, try again"},
+	BotStatsClicks:      {RU: "%s\nПереходов (примерно): %d\n", EN: "%s\nClicks (approx.): %d\n"},
+	BotStatsReferrers:   {RU: "\nОткуда переходят:\n", EN: "\nWhere they come from:\n"},
+	BotDeleteUsage:      {RU: "Использование: /delete <alias>", EN: "Usage: /delete <alias>"},
+	BotDeleteQuestion:   {RU: "Удалить %s → %s?", EN: "Delete %s → %s?"},
+	BotDeleteButton:     {RU: "Удалить", EN: "Delete"},
+	BotDeleted:          {RU: "Ссылка %s удалена", EN: "Link %s is deleted"},
+	BotEditUsage:        {RU: "Использование: /edit <alias> <новый url>", EN: "Usage: /edit <alias> <new url>"},
+	BotEditQuestion:     {RU: "Заменить адрес %s?\n%s\n→ %s", EN: "Change the destination of %s?\n%s\n→ %s"},
+	BotEditButton:       {RU: "Заменить", EN: "Change"},
+	BotEdited:           {RU: "Ссылка %s теперь ведёт на %s", EN: "Link %s now leads to %s"},
+	BotCanceled:         {RU: "Отменено", EN: "Canceled"},
+
+	BotInlineHint:   {RU: "Вставьте ссылку целиком, чтобы сократить её", EN: "Paste the whole link to shorten it"},
+	BotInlineQR:     {RU: "QR-код", EN: "QR code"},
+	BotInlineQRLink: {RU: "QR-код и ссылка", EN: "QR code and link"},
+
+	BotNewCanceled:     {RU: "Создание ссылки отменено", EN: "Creating the link is canceled"},
+	BotNothingToCancel: {RU: "Нечего отменять", EN: "Nothing to cancel"},
+	BotNewUseButtons:   {RU: "Выберите вариант кнопками или отмените: /cancel", EN: "Choose with the buttons or cancel: /cancel"},
+	BotNewExpired:      {RU: "Диалог устарел, начните заново: /new", EN: "The dialog has expired, start again: /new"},
+	BotNewAskUrl:       {RU: "Отправьте ссылку, которую нужно сократить", EN: "Send the link to shorten"},
+	BotNewAskAlias:     {RU: "Отправьте свой алиас: латинские буквы, цифры, _ и -, до 64 символов", EN: "Send your alias: latin letters, digits, _ and -, up to 64 characters"},
+	BotNewAskExpiry:    {RU: "Сколько будет работать ссылка?", EN: "How long should the link work?"},
+	BotNewConfirm:      {RU: "Создать ссылку?\n\n%s", EN: "Create the link?\n\n%s"},
+	BotNewOptions:      {RU: "%s\n\nНастройте ссылку или нажмите «Далее»", EN: "%s\n\nSet up the link or press “Continue”"},
+	BotNewSummary:      {RU: "Адрес: %s\nАлиас: %s\nСрок: %s", EN: "Destination: %s\nAlias: %s\nExpiry: %s"},
+	BotNewRandomAlias:  {RU: "случайный", EN: "random"},
+	BotNewNoExpiry:     {RU: "бессрочно", EN: "never"},
+	BotNewExpiresAt:    {RU: "до %s UTC", EN: "until %s UTC"},
+	BotNewCustomAlias:  {RU: "Свой алиас", EN: "Custom alias"},
+	BotNewExpiry:       {RU: "Срок действия", EN: "Expiry"},
+	BotNewContinue:     {RU: "Далее", EN: "Continue"},
+	BotNewCreate:       {RU: "Создать", EN: "Create"},
+	BotNewRandom:       {RU: "Случайный", EN: "Random"},
+	BotNewNever:        {RU: "Бессрочно", EN: "Never"},
+	BotNewExpiry1Day:   {RU: "1 день", EN: "1 day"},
+	BotNewExpiry7Days:  {RU: "7 дней", EN: "7 days"},
+	BotNewExpiry30Days: {RU: "30 дней", EN: "30 days"},
+
+	BotNoUrlsInMessage: {RU: "В сообщении нет ссылок", EN: "There are no links in the message"},
+	BotNoUrlsInFile:    {RU: "В файле нет ссылок", EN: "There are no links in the file"},
+	BotFileTooLarge:    {RU: "Файл слишком большой, максимум %d КБ", EN: "The file is too large, the limit is %d KB"},
+	BotDownloadFailed:  {RU: "Не удалось загрузить файл, попробуйте еще раз", EN: "Could not download the file, try again"},
+	BotBulkLimited:     {RU: "Сокращены первые %d ссылок из %d", EN: "Shortened the first %d links of %d"},
+	BotBulkFailed:      {RU: "Не удалось сократить ссылок: %d", EN: "Links not shortened: %d"},
+	BotBulkCaption:     {RU: "Короткие ссылки: %d", EN: "Short links: %d"},
+	BotBulkUrlFailed:   {RU: "Не удалось сократить ссылку", EN: "Could not shorten the link"},
+}
@@ -0,0 +1,128 @@
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Lang string
+
+const (
+	RU Lang = "ru"
+	EN Lang = "en"
+)
+
+// Languages are the languages every message has a text in.
+var Languages = []Lang{RU, EN}
+
+// Key names a message. The keys of API errors are also the codes clients
+// match on, so they must never change.
+type Key string
+
+// catalog holds the texts of every message by language.
+var catalog = merge(apiMessages, botMessages)
+
+func merge(parts ...map[Key]map[Lang]string) map[Key]map[Lang]string {
+	all := make(map[Key]map[Lang]string)
+	for _, part := range parts {
+		for key, texts := range part {
+			if _, ok := all[key]; ok {
+				panic("i18n: duplicate message " + string(key))
+			}
+			all[key] = texts
+		}
+	}
+	return all
+}
+
+// Parse returns the supported language of a tag like "en-US".
+func Parse(tag string) (Lang, bool) {
+	base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	for _, lang := range Languages {
+		if Lang(base) == lang {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+// Negotiate picks the supported language a client prefers most in an
+// Accept-Language header, or fallback.
+func Negotiate(header string, fallback Lang) Lang {
+	type choice struct {
+		lang Lang
+		q    float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		lang, ok := Parse(tag)
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if v, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			choices = append(choices, choice{lang, q})
+		}
+	}
+	if len(choices) == 0 {
+		return fallback
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+	return choices[0].lang
+}
+
+// T returns the text of key in lang, formatted with args. A text missing in
+// lang falls back to English, an unknown key to the key itself.
+func T(lang Lang, key Key, args ...any) string {
+	texts, ok := catalog[key]
+	if !ok {
+		return string(key)
+	}
+	text, ok := texts[lang]
+	if !ok {
+		text = texts[EN]
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+// Missing lists the keys without a text in one of the Languages.
+func Missing() []string {
+	var missing []string
+	for key, texts := range catalog {
+		for _, lang := range Languages {
+			if texts[lang] == "" {
+				missing = append(missing, string(lang)+":"+string(key))
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+type langKey struct{}
+
+func WithLang(ctx context.Context, lang Lang) context.Context {
+	return context.WithValue(ctx, langKey{}, lang)
+}
+
+// FromContext returns the language of the request, English by default.
+func FromContext(ctx context.Context) Lang {
+	if lang, ok := ctx.Value(langKey{}).(Lang); ok {
+		return lang
+	}
+	return EN
+}
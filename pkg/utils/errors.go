@@ -8,4 +8,46 @@ var (
 	ErrorUserNotFound      = errors.New("user not found")
 	ErrorInvalidPassword   = errors.New("invalid password")
 	ErrorNotFoundRows      = errors.New("error finding rows")
+	ErrorUrlNotFound       = errors.New("url not found")
+	ErrorUrlNotActive      = errors.New("url is not active yet")
+	ErrorUrlExpired        = errors.New("url has expired")
+	ErrorUrlExists         = errors.New("url is already shortened")
+	ErrorInvalidUrl        = errors.New("invalid url")
+	ErrorUrlBlocked        = errors.New("links to this domain are not allowed")
+	ErrorInvalidAlias      = errors.New("alias may contain only letters, digits, _ and - up to 64 characters")
+	ErrorAliasTaken        = errors.New("alias is already taken")
+	ErrorInvalidExpiry     = errors.New("expires_at must be in the future and after not_before")
+	ErrorInvalidTimeRule   = errors.New("invalid time rule")
+	ErrorInvalidTimezone   = errors.New("invalid timezone")
+	ErrorTagNotFound       = errors.New("tag not found")
+	ErrorTagAlreadyExists  = errors.New("tag with this title already exists")
+	ErrorFolderNotFound    = errors.New("folder not found")
+	ErrorFolderExists      = errors.New("folder with this title already exists")
+	ErrorDomainNotFound    = errors.New("domain not found")
+	ErrorDomainExists      = errors.New("domain already registered")
+	ErrorDomainNotVerified = errors.New("domain is not verified")
+	ErrorInvalidDomain     = errors.New("invalid domain")
+	ErrorDomainInUse       = errors.New("domain still has links")
+	ErrorRevisionNotFound  = errors.New("revision not found")
+	ErrorWebhookNotFound   = errors.New("webhook not found")
+	ErrorInvalidWebhookUrl = errors.New("webhook url must be a public http or https address")
+	ErrorDeliveryNotFound  = errors.New("webhook delivery not found")
+	ErrorNothingToUpdate   = errors.New("nothing to update")
+	ErrorApiKeyNotFound    = errors.New("api key not found")
+	ErrorInvalidApiKey     = errors.New("invalid api key")
+	ErrorInvalidExpiresAt  = errors.New("expires_at must be in the future")
+	ErrorSessionNotFound   = errors.New("session not found")
+	ErrorInvalidSession    = errors.New("invalid or expired session")
+	ErrorSessionReused     = errors.New("refresh token reuse detected, session revoked")
+	ErrorInvalidToken      = errors.New("invalid or expired token")
+	ErrorEmailNotVerified  = errors.New("email is not verified")
+	ErrorTwoFactorEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrorTwoFactorDisabled = errors.New("two-factor authentication is not enabled")
+	ErrorInvalidCode       = errors.New("invalid two-factor code")
+	ErrorInvalidChallenge  = errors.New("invalid or expired two-factor challenge")
+	ErrorTwoFactorLocked   = errors.New("too many wrong two-factor codes")
+	ErrorPasswordRequired  = errors.New("account has no password")
+	ErrorIdentityNotFound  = errors.New("identity not found")
+	ErrorIdentityLinked    = errors.New("external account is linked to another user")
+	ErrorLastLoginMethod   = errors.New("cannot remove the last way to sign in")
 )
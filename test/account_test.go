@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/feature/account"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+const resetPurpose = "reset_password"
+
+func accountUser(t *testing.T, tx pgx.Tx) (uuid.UUID, string) {
+	t.Helper()
+	email := uuid.NewString() + "@example.com"
+	var userId uuid.UUID
+	require.NoError(t, tx.QueryRow(context.Background(),
+		`INSERT INTO users (title, email, password) VALUES ('reset', $1, 'hash') RETURNING id`, email,
+	).Scan(&userId))
+	return userId, email
+}
+
+// Test_Account_Token_Lifecycle runs against TEST_DATABASE_URL, see testTx.
+func Test_Account_Token_Lifecycle(t *testing.T) {
+	pool, tx := testTx(t)
+	ctx := context.Background()
+	repo := account.NewRepository(pool, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	userId, email := accountUser(t, tx)
+	hour := time.Now().Add(time.Hour)
+
+	// issuing a new link retires the one sent before
+	require.NoError(t, repo.CreateToken(ctx, userId, resetPurpose, email, "first", hour, tx))
+	require.NoError(t, repo.InvalidateTokens(ctx, userId, resetPurpose, tx))
+	require.NoError(t, repo.CreateToken(ctx, userId, resetPurpose, email, "second", hour, tx))
+	_, err := repo.ConsumeToken(ctx, "first", resetPurpose, tx)
+	require.ErrorIs(t, err, utils.ErrorInvalidToken)
+
+	// a token only works for the purpose it was issued for
+	_, err = repo.ConsumeToken(ctx, "second", "verify_email", tx)
+	require.ErrorIs(t, err, utils.ErrorInvalidToken)
+
+	consumed, err := repo.ConsumeToken(ctx, "second", resetPurpose, tx)
+	require.NoError(t, err)
+	require.NotNil(t, consumed)
+
+	// single use
+	_, err = repo.ConsumeToken(ctx, "second", resetPurpose, tx)
+	require.ErrorIs(t, err, utils.ErrorInvalidToken)
+
+	require.NoError(t, repo.CreateToken(ctx, userId, resetPurpose, email, "expired", time.Now().Add(-time.Second), tx))
+	_, err = repo.ConsumeToken(ctx, "expired", resetPurpose, tx)
+	require.ErrorIs(t, err, utils.ErrorInvalidToken)
+
+	_, err = repo.ConsumeToken(ctx, "unknown", resetPurpose, tx)
+	require.ErrorIs(t, err, utils.ErrorInvalidToken)
+}
+
+// Test_Account_Reset_Revokes_Sessions_With_The_Token checks that the token
+// and the sessions go together: undoing the reset keeps both.
+func Test_Account_Reset_Revokes_Sessions_With_The_Token(t *testing.T) {
+	pool, tx := testTx(t)
+	ctx := context.Background()
+	repo := account.NewRepository(pool, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	userId, email := accountUser(t, tx)
+	for range 2 {
+		_, err := tx.Exec(ctx, `INSERT INTO sessions (user_id, expires_at) VALUES ($1, now() + interval '1 day')`, userId)
+		require.NoError(t, err)
+	}
+	require.NoError(t, repo.CreateToken(ctx, userId, resetPurpose, email, "reset", time.Now().Add(time.Hour), tx))
+
+	active := func() int {
+		var n int
+		require.NoError(t, tx.QueryRow(ctx,
+			`SELECT count(*) FROM sessions WHERE user_id = $1 AND revoked_at IS NULL`, userId,
+		).Scan(&n))
+		return n
+	}
+
+	reset, err := tx.Begin(ctx)
+	require.NoError(t, err)
+	_, err = repo.ConsumeToken(ctx, "reset", resetPurpose, reset)
+	require.NoError(t, err)
+	revoked, err := repo.RevokeSessions(ctx, userId, reset)
+	require.NoError(t, err)
+	require.Len(t, revoked, 2)
+	require.NoError(t, reset.Rollback(ctx))
+	require.Equal(t, 2, active())
+
+	reset, err = tx.Begin(ctx)
+	require.NoError(t, err)
+	consumed, err := repo.ConsumeToken(ctx, "reset", resetPurpose, reset)
+	require.NoError(t, err)
+	require.Equal(t, userId, consumed.UserID)
+	require.NoError(t, repo.SetPassword(ctx, userId, []byte("new hash"), reset))
+	revoked, err = repo.RevokeSessions(ctx, userId, reset)
+	require.NoError(t, err)
+	require.Len(t, revoked, 2)
+	require.NoError(t, reset.Commit(ctx))
+	require.Zero(t, active())
+
+	_, err = repo.ConsumeToken(ctx, "reset", resetPurpose, tx)
+	require.ErrorIs(t, err, utils.ErrorInvalidToken)
+}
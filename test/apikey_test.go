@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/internal/feature/apikey"
+	"github.com/Sanchir01/go-shortener/internal/feature/user"
+	"github.com/Sanchir01/go-shortener/internal/handlers/customiddleware"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKeys keeps key owners by lookup prefix.
+type fakeKeys struct {
+	owners  map[string]*apikey.KeyOwner
+	created int
+}
+
+func (f *fakeKeys) CreateApiKey(_ context.Context, userId uuid.UUID, name, prefix, _ string, scopes []contextkey.Scope, expiresAt *time.Time) (*models.ApiKey, error) {
+	f.created++
+	return &models.ApiKey{UserID: userId, Name: name, Prefix: prefix, Scopes: scopes, ExpiresAt: expiresAt}, nil
+}
+
+func (f *fakeKeys) GetApiKeysByUserId(context.Context, uuid.UUID) ([]models.ApiKey, error) {
+	return nil, nil
+}
+
+func (f *fakeKeys) RevokeApiKey(context.Context, uuid.UUID, uuid.UUID) error {
+	return nil
+}
+
+func (f *fakeKeys) GetKeyOwner(_ context.Context, prefix string) (*apikey.KeyOwner, error) {
+	owner, ok := f.owners[prefix]
+	if !ok {
+		return nil, utils.ErrorApiKeyNotFound
+	}
+	return owner, nil
+}
+
+func (f *fakeKeys) TouchApiKey(context.Context, string) error {
+	return nil
+}
+
+func Test_ApiKey_Split(t *testing.T) {
+	key, prefix, err := apikey.GenerateKey()
+	require.NoError(t, err)
+	require.True(t, apikey.IsKey(key))
+	got, ok := apikey.SplitKey(key)
+	require.True(t, ok)
+	require.Equal(t, prefix, got)
+
+	for _, bad := range []string{"", "gsk_", "gsk__secret", "gsk_abcd_", "eyJhbGciOiJFZERTQSJ9.e30.sig"} {
+		_, ok := apikey.SplitKey(bad)
+		require.False(t, ok, bad)
+	}
+}
+
+func Test_ApiKey_Scopes_Validation(t *testing.T) {
+	validate := validator.New()
+	valid := apikey.CreateApiKeyRequest{Name: "ci", Scopes: []contextkey.Scope{contextkey.ScopeLinksRead, contextkey.ScopeStatsRead}}
+	require.NoError(t, validate.Struct(valid))
+
+	for _, scopes := range [][]contextkey.Scope{nil, {}, {"links:delete"}, {contextkey.ScopeLinksRead, "admin"}} {
+		req := apikey.CreateApiKeyRequest{Name: "ci", Scopes: scopes}
+		require.Error(t, validate.Struct(req), "%v", scopes)
+	}
+}
+
+func Test_ApiKey_Create_Expiry(t *testing.T) {
+	repo := &fakeKeys{}
+	service := apikey.NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	scopes := []contextkey.Scope{contextkey.ScopeLinksRead}
+
+	for _, expiresAt := range []time.Time{time.Now().Add(-time.Hour), time.Now()} {
+		_, _, err := service.CreateApiKey(context.Background(), uuid.New(), apikey.CreateApiKeyRequest{Name: "ci", Scopes: scopes, ExpiresAt: &expiresAt})
+		require.ErrorIs(t, err, utils.ErrorInvalidExpiresAt)
+	}
+	require.Zero(t, repo.created)
+
+	future := time.Now().Add(time.Hour)
+	for _, expiresAt := range []*time.Time{nil, &future} {
+		apiKey, key, err := service.CreateApiKey(context.Background(), uuid.New(), apikey.CreateApiKeyRequest{Name: "ci", Scopes: scopes, ExpiresAt: expiresAt})
+		require.NoError(t, err)
+		require.True(t, apikey.IsKey(key))
+		require.Equal(t, expiresAt, apiKey.ExpiresAt)
+	}
+	require.Equal(t, 2, repo.created)
+}
+
+func Test_ApiKey_Authenticate(t *testing.T) {
+	key, prefix, err := apikey.GenerateKey()
+	require.NoError(t, err)
+	userId := uuid.New()
+	past := time.Now().Add(-time.Minute)
+	owner := &apikey.KeyOwner{
+		UserID: userId,
+		Role:   string(contextkey.RoleUser),
+		Hash:   apikey.HashKey(key),
+		Scopes: []contextkey.Scope{contextkey.ScopeLinksRead},
+	}
+	repo := &fakeKeys{owners: map[string]*apikey.KeyOwner{prefix: owner}}
+	service := apikey.NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	claims, err := service.AuthenticateKey(context.Background(), key)
+	require.NoError(t, err)
+	require.Equal(t, userId, claims.ID)
+	require.Equal(t, []contextkey.Scope{contextkey.ScopeLinksRead}, claims.Scopes)
+
+	// same prefix, other secret
+	_, err = service.AuthenticateKey(context.Background(), prefix+"_"+"00")
+	require.ErrorIs(t, err, utils.ErrorInvalidApiKey)
+	_, err = service.AuthenticateKey(context.Background(), "gsk_ffffffff_00")
+	require.ErrorIs(t, err, utils.ErrorInvalidApiKey)
+
+	owner.ExpiresAt = &past
+	_, err = service.AuthenticateKey(context.Background(), key)
+	require.ErrorIs(t, err, utils.ErrorInvalidApiKey)
+
+	owner.ExpiresAt, owner.RevokedAt = nil, &past
+	_, err = service.AuthenticateKey(context.Background(), key)
+	require.ErrorIs(t, err, utils.ErrorInvalidApiKey)
+
+	// a key stored without scopes may do nothing, it does not pass as a session
+	owner.RevokedAt, owner.Scopes = nil, nil
+	claims, err = service.AuthenticateKey(context.Background(), key)
+	require.NoError(t, err)
+	require.NotNil(t, claims.Scopes)
+	require.Empty(t, claims.Scopes)
+}
+
+func Test_RBAC_RequireScope(t *testing.T) {
+	write := customiddleware.RequireScope(contextkey.ScopeLinksWrite)
+	cases := []struct {
+		name   string
+		scopes []contextkey.Scope
+		want   int
+	}{
+		{"session", nil, http.StatusOK},
+		{"key with scope", []contextkey.Scope{contextkey.ScopeLinksRead, contextkey.ScopeLinksWrite}, http.StatusOK},
+		{"key without scope", []contextkey.Scope{contextkey.ScopeLinksRead}, http.StatusForbidden},
+		{"key without scopes", []contextkey.Scope{}, http.StatusForbidden},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.want, guardStatus(write, &user.Claims{Scopes: c.scopes}), c.name)
+	}
+	require.Equal(t, http.StatusUnauthorized, guardStatus(write, nil))
+
+	require.Equal(t, http.StatusOK, guardStatus(customiddleware.RequireSession, &user.Claims{}))
+	require.Equal(t, http.StatusForbidden, guardStatus(customiddleware.RequireSession, &user.Claims{Scopes: []contextkey.Scope{}}))
+}
@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tgbot "github.com/Sanchir01/go-shortener/internal/bot"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/stretchr/testify/require"
+)
+
+const conversationKey = "bot:conversation:5001"
+
+func Test_Bot_New_Steps(t *testing.T) {
+	b, api, links, mr := newRedisBot(t, tgbot.ModeWebhook)
+
+	sendText(t, b, 5001, "/new")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotNewAskUrl), last(api.messages()))
+	require.Equal(t, []string{"new:cancel"}, api.buttons(t))
+	require.True(t, mr.Exists(conversationKey))
+
+	// a wrong url keeps the step
+	sendText(t, b, 5001, "not a link")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotInvalidUrl), last(api.messages()))
+	sendText(t, b, 5001, "https://example.com/new")
+	require.True(t, strings.HasPrefix(last(api.messages()), "Destination: https://example.com/new\nAlias: random\nExpiry: never"))
+	require.Equal(t, []string{"new:alias", "new:expiry", "new:done", "new:cancel"}, api.buttons(t))
+
+	// options wait for a button
+	sendText(t, b, 5001, "https://example.com/other")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotNewUseButtons), last(api.messages()))
+
+	press(t, b, 5001, "new:alias")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotNewAskAlias), last(api.edits()))
+	require.Equal(t, []string{"new:random", "new:back", "new:cancel"}, api.buttons(t))
+	sendText(t, b, 5001, "a/b")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotInvalidAlias), last(api.messages()))
+	sendText(t, b, 5001, "my-link")
+	require.Contains(t, last(api.messages()), "Alias: my-link")
+
+	press(t, b, 5001, "new:expiry")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotNewAskExpiry), last(api.edits()))
+	require.Equal(t, []string{"new:expires:1", "new:expires:7", "new:expires:30", "new:expires:0", "new:back"}, api.buttons(t))
+	press(t, b, 5001, "new:expires:7")
+	require.Contains(t, last(api.edits()), "Expiry: until ")
+
+	// create is only taken at the confirmation
+	press(t, b, 5001, "new:create")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotButtonExpired), last(api.callbackAnswers()))
+	require.Empty(t, links.created)
+
+	press(t, b, 5001, "new:done")
+	require.True(t, strings.HasPrefix(last(api.edits()), "Create the link?"))
+	require.Equal(t, []string{"new:create", "new:back", "new:cancel"}, api.buttons(t))
+	press(t, b, 5001, "new:create")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotShortLink, "https://sho.rt/my-link"), last(api.edits()))
+	require.Equal(t, []string{"https://example.com/new"}, links.created)
+	require.Equal(t, []string{"my-link"}, links.aliases)
+	require.NotNil(t, links.expires[0])
+	require.WithinDuration(t, time.Now().Add(7*24*time.Hour), *links.expires[0], time.Minute)
+	require.False(t, mr.Exists(conversationKey))
+
+	// a second press does not create it twice
+	press(t, b, 5001, "new:create")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotNewExpired), last(api.callbackAnswers()))
+	require.Len(t, links.created, 1)
+}
+
+func Test_Bot_New_Taken_Alias_Back_To_Options(t *testing.T) {
+	b, api, links, mr := newRedisBot(t, tgbot.ModeWebhook)
+	seedLinks(links, 1)
+
+	sendText(t, b, 5001, "/new https://example.com/new")
+	press(t, b, 5001, "new:alias")
+	sendText(t, b, 5001, "link1")
+	press(t, b, 5001, "new:done")
+	press(t, b, 5001, "new:create")
+	require.True(t, strings.HasPrefix(last(api.edits()), i18n.T(i18n.EN, i18n.BotAliasTaken)))
+	require.Equal(t, []string{"new:alias", "new:expiry", "new:done", "new:cancel"}, api.buttons(t))
+	require.True(t, mr.Exists(conversationKey))
+
+	press(t, b, 5001, "new:random")
+	press(t, b, 5001, "new:done")
+	press(t, b, 5001, "new:create")
+	require.Equal(t, []string{"https://example.com/new"}, links.created)
+	require.Equal(t, []string{"abc123"}, links.aliases)
+}
+
+func Test_Bot_New_Cancel(t *testing.T) {
+	b, api, links, mr := newRedisBot(t, tgbot.ModeWebhook)
+
+	sendText(t, b, 5001, "/new https://example.com/new")
+	press(t, b, 5001, "new:alias")
+	sendText(t, b, 5001, "/cancel")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotNewCanceled), last(api.messages()))
+	require.False(t, mr.Exists(conversationKey))
+	sendText(t, b, 5001, "/cancel")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotNothingToCancel), last(api.messages()))
+
+	// buttons of the dropped dialog are stale
+	press(t, b, 5001, "new:done")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotNewExpired), last(api.callbackAnswers()))
+
+	sendText(t, b, 5001, "/new https://example.com/new")
+	press(t, b, 5001, "new:cancel")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotNewCanceled), last(api.edits()))
+	require.False(t, mr.Exists(conversationKey))
+	require.Empty(t, links.created)
+}
+
+func Test_Bot_New_Expires(t *testing.T) {
+	b, api, links, mr := newRedisBot(t, tgbot.ModeWebhook)
+
+	sendText(t, b, 5001, "/new https://example.com/new")
+	// every step renews the ttl
+	mr.FastForward(50 * time.Second)
+	press(t, b, 5001, "new:done")
+	mr.FastForward(50 * time.Second)
+	require.True(t, mr.Exists(conversationKey))
+
+	mr.FastForward(time.Minute)
+	press(t, b, 5001, "new:create")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotNewExpired), last(api.callbackAnswers()))
+	require.Empty(t, links.created)
+}
+
+func Test_Bot_New_Other_User(t *testing.T) {
+	b, api, _, mr := newRedisBot(t, tgbot.ModeWebhook)
+
+	sendText(t, b, 5001, "/new")
+	// the dialog belongs to its chat, other chats have none
+	sendText(t, b, intruder, "/cancel")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotNothingToCancel), last(api.messages()))
+	press(t, b, intruder, "new:cancel")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotNewExpired), last(api.callbackAnswers()))
+	require.True(t, mr.Exists(conversationKey))
+}
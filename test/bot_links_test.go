@@ -0,0 +1,209 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	tgbot "github.com/Sanchir01/go-shortener/internal/bot"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const intruder = 6001
+
+var updateId = 815471000
+
+// sendText posts a message of the Telegram user from, with an English app.
+func sendText(t *testing.T, b *tgbot.TGBot, from int64, text string) {
+	t.Helper()
+	updateId++
+	body, err := json.Marshal(map[string]any{
+		"update_id": updateId,
+		"message": map[string]any{
+			"message_id": updateId,
+			"from":       map[string]any{"id": from, "is_bot": false, "first_name": "Ivan", "language_code": "en"},
+			"chat":       map[string]any{"id": from, "type": "private"},
+			"date":       1792400010,
+			"text":       text,
+			"entities":   commandEntity(text),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, postBody(b, body, webhookSecret))
+}
+
+func commandEntity(text string) []map[string]any {
+	if !strings.HasPrefix(text, "/") {
+		return nil
+	}
+	command, _, _ := strings.Cut(text, " ")
+	return []map[string]any{{"offset": 0, "length": len(command), "type": "bot_command"}}
+}
+
+// press posts a press of the button with data by the Telegram user from.
+func press(t *testing.T, b *tgbot.TGBot, from int64, data string) {
+	t.Helper()
+	updateId++
+	body, err := json.Marshal(map[string]any{
+		"update_id": updateId,
+		"callback_query": map[string]any{
+			"id":            strconv.Itoa(updateId),
+			"from":          map[string]any{"id": from, "is_bot": false, "first_name": "Ivan", "language_code": "en"},
+			"chat_instance": "1",
+			"data":          data,
+			"message": map[string]any{
+				"message_id": 99,
+				"chat":       map[string]any{"id": from, "type": "private"},
+				"date":       1792400000,
+				"text":       "buttons",
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, postBody(b, body, webhookSecret))
+}
+
+func seedLinks(links *fakeLinks, n int) {
+	for i := 1; i <= n; i++ {
+		links.stored = append(links.stored, models.Url{
+			ID:     uuid.New(),
+			UserID: links.userId,
+			Alias:  fmt.Sprintf("link%d", i),
+			Url:    fmt.Sprintf("https://example.com/%d", i),
+		})
+	}
+}
+
+func last(items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+	return items[len(items)-1]
+}
+
+func Test_Bot_MyLinks_Pages(t *testing.T) {
+	b, api, links := newWebhookBot(t, tgbot.ModeWebhook)
+	seedLinks(links, 7)
+
+	sendText(t, b, 5001, "/mylinks")
+	first := last(api.messages())
+	require.True(t, strings.HasPrefix(first, i18n.T(i18n.EN, i18n.BotLinksPage, 1)))
+	require.Contains(t, first, "1. https://sho.rt/link7\nhttps://example.com/7")
+	require.Contains(t, first, "5. https://sho.rt/link3")
+	require.NotContains(t, first, "link2")
+	require.Equal(t, []string{"links:1"}, api.buttons(t))
+
+	press(t, b, 5001, "links:1")
+	second := last(api.edits())
+	require.True(t, strings.HasPrefix(second, i18n.T(i18n.EN, i18n.BotLinksPage, 2)))
+	require.Contains(t, second, "6. https://sho.rt/link2")
+	require.Contains(t, second, "7. https://sho.rt/link1")
+	require.Equal(t, []string{"links:0"}, api.buttons(t))
+
+	press(t, b, 5001, "links:0")
+	require.Equal(t, first, last(api.edits()))
+
+	// a page past the end offers the way back
+	press(t, b, 5001, "links:5")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotEmptyPage), last(api.edits()))
+	require.Equal(t, []string{"links:4"}, api.buttons(t))
+
+	// broken page numbers are only acknowledged
+	edits := len(api.edits())
+	press(t, b, 5001, "links:-1")
+	press(t, b, 5001, "links:x")
+	require.Len(t, api.edits(), edits)
+	require.Len(t, api.callbackAnswers(), 5)
+}
+
+func Test_Bot_MyLinks_Only_Own_Links(t *testing.T) {
+	b, api, links := newWebhookBot(t, tgbot.ModeWebhook)
+	seedLinks(links, 7)
+
+	sendText(t, b, intruder, "/mylinks")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotNoLinks), last(api.messages()))
+	// pressing a copied page button shows their own, empty page
+	press(t, b, intruder, "links:1")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotEmptyPage), last(api.edits()))
+}
+
+func Test_Bot_Delete_Confirm(t *testing.T) {
+	b, api, links := newWebhookBot(t, tgbot.ModeWebhook)
+	seedLinks(links, 2)
+
+	sendText(t, b, 5001, "/delete link1")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotDeleteQuestion, "https://sho.rt/link1", "https://example.com/1"), last(api.messages()))
+	buttons := api.buttons(t)
+	require.Len(t, buttons, 2)
+	require.True(t, strings.HasPrefix(buttons[0], "confirm:"))
+	require.True(t, strings.HasPrefix(buttons[1], "cancel:"))
+	require.Empty(t, links.deleted)
+
+	// somebody else pressing the button changes nothing
+	press(t, b, intruder, buttons[0])
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotButtonExpired), last(api.callbackAnswers()))
+	require.Empty(t, links.deleted)
+
+	press(t, b, 5001, buttons[0])
+	require.Equal(t, []string{"link1"}, links.deleted)
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotDeleted, "https://sho.rt/link1"), last(api.edits()))
+
+	// the button works once
+	press(t, b, 5001, buttons[0])
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotButtonExpired), last(api.callbackAnswers()))
+	require.Equal(t, []string{"link1"}, links.deleted)
+}
+
+func Test_Bot_Edit_Confirm_And_Cancel(t *testing.T) {
+	b, api, links := newWebhookBot(t, tgbot.ModeWebhook)
+	seedLinks(links, 1)
+
+	sendText(t, b, 5001, "/edit link1 https://example.org/new")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotEditQuestion, "https://sho.rt/link1", "https://example.com/1", "https://example.org/new"), last(api.messages()))
+	buttons := api.buttons(t)
+
+	press(t, b, 5001, buttons[1])
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotCanceled), last(api.edits()))
+	press(t, b, 5001, buttons[0])
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotButtonExpired), last(api.callbackAnswers()))
+	require.Empty(t, links.updated)
+
+	sendText(t, b, 5001, "/edit link1 https://example.org/new")
+	press(t, b, 5001, api.buttons(t)[0])
+	require.Equal(t, []string{"link1"}, links.updated)
+	require.Equal(t, "https://example.org/new", links.stored[0].Url)
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotEdited, "https://sho.rt/link1", "https://example.org/new"), last(api.edits()))
+}
+
+func Test_Bot_Edit_And_New_Check_Blocked_Hosts(t *testing.T) {
+	b, api, links := newWebhookBot(t, tgbot.ModeWebhook)
+	seedLinks(links, 1)
+
+	sendText(t, b, 5001, "/edit link1 https://blocked.example/phish")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotUrlBlocked), last(api.messages()))
+	require.Empty(t, api.buttons(t))
+
+	sendText(t, b, 5001, "/new https://blocked.example/phish")
+	require.Equal(t, i18n.T(i18n.EN, i18n.BotUrlBlocked), last(api.messages()))
+	require.Empty(t, api.buttons(t))
+}
+
+func Test_Bot_Commands_Scoped_To_Owner(t *testing.T) {
+	b, api, links := newWebhookBot(t, tgbot.ModeWebhook)
+	seedLinks(links, 1)
+	noSuchLink := i18n.T(i18n.EN, i18n.BotNoSuchLink, "link1")
+
+	for _, command := range []string{"/delete link1", "/edit link1 https://example.org/new", "/stats link1"} {
+		sendText(t, b, intruder, command)
+		require.Equal(t, noSuchLink, last(api.messages()), command)
+		require.Empty(t, api.buttons(t), command)
+	}
+	require.Empty(t, links.deleted)
+	require.Empty(t, links.updated)
+}
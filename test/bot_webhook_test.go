@@ -0,0 +1,467 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbot "github.com/Sanchir01/go-shortener/internal/bot"
+	"github.com/Sanchir01/go-shortener/internal/config"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/internal/feature/clickstream"
+	"github.com/Sanchir01/go-shortener/internal/feature/url"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	botToken      = "123456:webhook-test"
+	webhookSecret = "webhook-secret"
+)
+
+// fakeBotAPI answers the Bot API methods the bot calls, serves the files in
+// testdata/telegram and records the messages, edits, buttons and documents
+// it sends.
+type fakeBotAPI struct {
+	srv *httptest.Server
+
+	mu        sync.Mutex
+	sent      []string
+	markups   []string
+	edited    []string
+	answered  []string
+	documents []string
+	inline    []string
+}
+
+func newFakeBotAPI(t *testing.T) *fakeBotAPI {
+	f := &fakeBotAPI{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/bot"+botToken+"/getMe", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"ok":true,"result":{"id":123456,"is_bot":true,"first_name":"Shortener","username":"go_shortener_bot"}}`)
+	})
+	mux.HandleFunc("/bot"+botToken+"/sendMessage", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		f.mu.Lock()
+		f.sent = append(f.sent, r.FormValue("text"))
+		f.markups = append(f.markups, r.FormValue("reply_markup"))
+		f.mu.Unlock()
+		io.WriteString(w, `{"ok":true,"result":{"message_id":99,"date":1792400000,"chat":{"id":5001,"type":"private"}}}`)
+	})
+	mux.HandleFunc("/bot"+botToken+"/editMessageText", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		f.mu.Lock()
+		f.edited = append(f.edited, r.FormValue("text"))
+		f.markups = append(f.markups, r.FormValue("reply_markup"))
+		f.mu.Unlock()
+		io.WriteString(w, `{"ok":true,"result":{"message_id":99,"date":1792400000,"chat":{"id":5001,"type":"private"}}}`)
+	})
+	mux.HandleFunc("/bot"+botToken+"/answerCallbackQuery", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		f.mu.Lock()
+		f.answered = append(f.answered, r.FormValue("text"))
+		f.mu.Unlock()
+		io.WriteString(w, `{"ok":true,"result":true}`)
+	})
+	mux.HandleFunc("/bot"+botToken+"/sendDocument", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		file, _, err := r.FormFile("document")
+		require.NoError(t, err)
+		data, err := io.ReadAll(file)
+		require.NoError(t, err)
+		f.mu.Lock()
+		f.documents = append(f.documents, string(data))
+		f.mu.Unlock()
+		io.WriteString(w, `{"ok":true,"result":{"message_id":100,"date":1792400000,"chat":{"id":5001,"type":"private"}}}`)
+	})
+	mux.HandleFunc("/bot"+botToken+"/answerInlineQuery", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		f.mu.Lock()
+		f.inline = append(f.inline, r.FormValue("results"))
+		f.mu.Unlock()
+		io.WriteString(w, `{"ok":true,"result":true}`)
+	})
+	mux.HandleFunc("/bot"+botToken+"/getFile", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"ok":true,"result":{"file_id":"BQACAgIAAxkBAAIB","file_unique_id":"AgADlinks","file_path":"documents/links.csv"}}`)
+	})
+	mux.Handle("/file/bot"+botToken+"/documents/", http.StripPrefix("/file/bot"+botToken+"/documents/", http.FileServer(http.Dir("testdata/telegram"))))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"ok":true,"result":true}`)
+	})
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeBotAPI) messages() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.sent...)
+}
+
+func (f *fakeBotAPI) edits() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.edited...)
+}
+
+func (f *fakeBotAPI) callbackAnswers() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.answered...)
+}
+
+// buttons returns the callback data of the inline keyboard sent last, with
+// a message or an edit.
+func (f *fakeBotAPI) buttons(t *testing.T) []string {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.markups) == 0 || f.markups[len(f.markups)-1] == "" {
+		return nil
+	}
+	var markup struct {
+		InlineKeyboard [][]struct {
+			CallbackData string `json:"callback_data"`
+		} `json:"inline_keyboard"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(f.markups[len(f.markups)-1]), &markup))
+	var data []string
+	for _, row := range markup.InlineKeyboard {
+		for _, button := range row {
+			data = append(data, button.CallbackData)
+		}
+	}
+	return data
+}
+
+func (f *fakeBotAPI) inlineAnswers() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.inline...)
+}
+
+func (f *fakeBotAPI) sentDocuments() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.documents...)
+}
+
+// fakeLinks creates links for one known user with aliases abc123, abc124 and
+// so on, or the alias asked for. A url is shortened once per user like with
+// url_user_url_unique. With race set the next CreateUrl loses against a
+// concurrent request that stores the link first. Links of blocked.example
+// are refused like with redirect.blocked_hosts.
+type fakeLinks struct {
+	userId  uuid.UUID
+	created []string
+	aliases []string
+	expires []*time.Time
+	stored  []models.Url
+	race    bool
+	updated []string
+	deleted []string
+}
+
+func (f *fakeLinks) CheckUrl(rawUrl string) error {
+	return url.ValidateUrl(rawUrl, []string{"blocked.example"})
+}
+
+func (f *fakeLinks) CreateUrl(_ context.Context, userId uuid.UUID, p url.CreateUrlParams) (string, error) {
+	if userId != f.userId {
+		return "", errors.New("link created for another user")
+	}
+	for _, link := range f.stored {
+		if link.Url == p.Url {
+			return "", utils.ErrorUrlExists
+		}
+		if p.Alias != "" && link.Alias == p.Alias {
+			return "", utils.ErrorAliasTaken
+		}
+	}
+	if f.race {
+		f.race = false
+		f.stored = append(f.stored, models.Url{ID: uuid.New(), UserID: userId, Url: p.Url, Alias: "raced1"})
+		return "", utils.ErrorUrlExists
+	}
+	f.created = append(f.created, p.Url)
+	alias := p.Alias
+	if alias == "" {
+		alias = "abc" + strconv.Itoa(122+len(f.created))
+	}
+	f.aliases = append(f.aliases, alias)
+	f.expires = append(f.expires, p.ExpiresAt)
+	f.stored = append(f.stored, models.Url{ID: uuid.New(), UserID: userId, Url: p.Url, Alias: alias})
+	return alias, nil
+}
+
+// GetUrlByUser pages through the links of the user newest first.
+func (f *fakeLinks) GetUrlByUser(_ context.Context, userId uuid.UUID, filter url.UrlFilter) ([]models.Url, error) {
+	var found []models.Url
+	for i := len(f.stored) - 1; i >= 0; i-- {
+		link := f.stored[i]
+		if link.UserID == userId && (filter.Url == "" || link.Url == filter.Url) {
+			found = append(found, link)
+		}
+	}
+	if filter.Offset >= uint64(len(found)) {
+		return nil, nil
+	}
+	found = found[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < uint64(len(found)) {
+		found = found[:filter.Limit]
+	}
+	return found, nil
+}
+
+func (f *fakeLinks) GetUserUrlByAlias(_ context.Context, userId uuid.UUID, alias string) (*models.Url, error) {
+	for _, link := range f.stored {
+		if link.UserID == userId && link.Alias == alias {
+			return &link, nil
+		}
+	}
+	return nil, utils.ErrorUrlNotFound
+}
+
+func (f *fakeLinks) UpdateUrl(_ context.Context, id, userId uuid.UUID, p url.UpdateUrlParams) (*models.Url, error) {
+	for i, link := range f.stored {
+		if link.ID == id && link.UserID == userId {
+			f.stored[i].Url = *p.Url
+			f.updated = append(f.updated, link.Alias)
+			return &f.stored[i], nil
+		}
+	}
+	return nil, utils.ErrorUrlNotFound
+}
+
+func (f *fakeLinks) DeleteUrl(_ context.Context, id, userId uuid.UUID) error {
+	for i, link := range f.stored {
+		if link.ID == id && link.UserID == userId {
+			f.stored = append(f.stored[:i], f.stored[i+1:]...)
+			f.deleted = append(f.deleted, link.Alias)
+			return nil
+		}
+	}
+	return utils.ErrorUrlNotFound
+}
+
+// fakeTelegramUsers maps the Telegram users 5001 and 5002 of the fixtures to
+// userId and gives every other Telegram user an account of their own.
+type fakeTelegramUsers struct {
+	userId uuid.UUID
+	others map[int64]uuid.UUID
+}
+
+func (f *fakeTelegramUsers) TelegramUser(_ context.Context, tgId int64, _ string) (*uuid.UUID, error) {
+	if tgId == 5001 || tgId == 5002 {
+		return &f.userId, nil
+	}
+	id, ok := f.others[tgId]
+	if !ok {
+		id = uuid.New()
+		f.others[tgId] = id
+	}
+	return &id, nil
+}
+
+type fakeLinker struct{}
+
+func (fakeLinker) LinkFromBot(context.Context, int64, string) error {
+	return nil
+}
+
+func (fakeLinker) CreateBotCode(context.Context, int64) (string, time.Time, error) {
+	return "", time.Time{}, nil
+}
+
+type fakeClickStats struct{}
+
+func (fakeClickStats) LiveCounts(context.Context, uuid.UUID, int64) (*clickstream.LiveCounts, error) {
+	return &clickstream.LiveCounts{}, nil
+}
+
+func newWebhookBot(t *testing.T, mode string) (*tgbot.TGBot, *fakeBotAPI, *fakeLinks) {
+	b, api, links, _ := newRedisBot(t, mode)
+	return b, api, links
+}
+
+// newRedisBot also returns the in-memory Redis behind the confirmations,
+// dialogs and languages of the bot.
+func newRedisBot(t *testing.T, mode string) (*tgbot.TGBot, *fakeBotAPI, *fakeLinks, *miniredis.Miniredis) {
+	t.Setenv("BOT_TOKEN", botToken)
+	t.Setenv("TELEGRAM_WEBHOOK_SECRET", webhookSecret)
+	api := newFakeBotAPI(t)
+	links := &fakeLinks{userId: uuid.New()}
+	mr, rdb := testRedis(t)
+	b, err := tgbot.New(
+		context.Background(),
+		config.Telegram{
+			Mode:            mode,
+			WebhookURL:      "https://sho.rt/api/v1/bot/webhook",
+			ServerURL:       api.srv.URL,
+			BulkMaxUrls:     10,
+			BulkMaxFileSize: 1 << 20,
+		},
+		links,
+		&fakeTelegramUsers{userId: links.userId, others: map[int64]uuid.UUID{}},
+		fakeLinker{},
+		fakeClickStats{},
+		tgbot.NewConfirmStore(rdb, time.Minute),
+		tgbot.NewConversationStore(rdb, time.Minute),
+		tgbot.NewLanguageStore(rdb),
+		"https://sho.rt",
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+	require.NoError(t, err)
+	return b, api, links, mr
+}
+
+func postUpdate(t *testing.T, b *tgbot.TGBot, fixture, secret string) int {
+	body, err := os.ReadFile("testdata/telegram/" + fixture)
+	require.NoError(t, err)
+	return postBody(b, body, secret)
+}
+
+func postBody(b *tgbot.TGBot, body []byte, secret string) int {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/bot/webhook", bytes.NewReader(body))
+	req.Header.Set("X-Telegram-Bot-Api-Secret-Token", secret)
+	rec := httptest.NewRecorder()
+	b.WebhookHandler(rec, req)
+	return rec.Code
+}
+
+func Test_Bot_Webhook_Ping(t *testing.T) {
+	b, api, _ := newWebhookBot(t, tgbot.ModeWebhook)
+	require.Equal(t, http.StatusOK, postUpdate(t, b, "message_ping.json", webhookSecret))
+	require.Equal(t, []string{"pong"}, api.messages())
+}
+
+func Test_Bot_Webhook_Short(t *testing.T) {
+	b, api, links := newWebhookBot(t, tgbot.ModeWebhook)
+	require.Equal(t, http.StatusOK, postUpdate(t, b, "message_short.json", webhookSecret))
+	require.Equal(t, []string{"https://example.com/docs?page=1"}, links.created)
+	require.Equal(t, []string{"Короткая ссылка: https://sho.rt/abc123"}, api.messages())
+}
+
+func Test_Bot_Webhook_Short_Reuses_The_Senders_Link(t *testing.T) {
+	b, api, links := newWebhookBot(t, tgbot.ModeWebhook)
+	require.Equal(t, http.StatusOK, postUpdate(t, b, "message_short.json", webhookSecret))
+	require.Equal(t, http.StatusOK, postUpdate(t, b, "message_short.json", webhookSecret))
+	require.Equal(t, []string{"https://example.com/docs?page=1"}, links.created)
+	require.Equal(t, []string{
+		"Короткая ссылка: https://sho.rt/abc123",
+		"Короткая ссылка: https://sho.rt/abc123",
+	}, api.messages())
+}
+
+func Test_Bot_Webhook_Short_Lost_Race(t *testing.T) {
+	b, api, links := newWebhookBot(t, tgbot.ModeWebhook)
+	links.race = true
+	require.Equal(t, http.StatusOK, postUpdate(t, b, "message_short.json", webhookSecret))
+	require.Empty(t, links.created)
+	require.Equal(t, []string{"Короткая ссылка: https://sho.rt/raced1"}, api.messages())
+}
+
+func Test_Bot_Webhook_Short_English(t *testing.T) {
+	b, api, _ := newWebhookBot(t, tgbot.ModeWebhook)
+	require.Equal(t, http.StatusOK, postUpdate(t, b, "message_short_en.json", webhookSecret))
+	require.Equal(t, []string{"Short link: https://sho.rt/abc123"}, api.messages())
+}
+
+func Test_Bot_Webhook_Rejects_Wrong_Secret(t *testing.T) {
+	b, api, _ := newWebhookBot(t, tgbot.ModeWebhook)
+	require.Equal(t, http.StatusUnauthorized, postUpdate(t, b, "message_ping.json", "guess"))
+	require.Equal(t, http.StatusUnauthorized, postUpdate(t, b, "message_ping.json", ""))
+	require.Empty(t, api.messages())
+}
+
+func Test_Bot_Webhook_Disabled_In_Polling_Mode(t *testing.T) {
+	b, api, _ := newWebhookBot(t, tgbot.ModePolling)
+	require.Equal(t, http.StatusNotFound, postUpdate(t, b, "message_ping.json", webhookSecret))
+	require.Empty(t, api.messages())
+}
+
+func Test_Bot_Forwarded_Message_Rewritten(t *testing.T) {
+	b, api, links := newWebhookBot(t, tgbot.ModeWebhook)
+	require.Equal(t, http.StatusOK, postUpdate(t, b, "message_forwarded.json", webhookSecret))
+	require.Equal(t, []string{"https://example.com/v2/notes", "https://docs.example.com/start"}, links.created)
+	require.Equal(t, []string{
+		"Release notes: https://sho.rt/abc123. Docs (https://sho.rt/abc124) and again https://sho.rt/abc123!",
+	}, api.messages())
+}
+
+func Test_Bot_Forwarded_Message_Reuses_Links(t *testing.T) {
+	b, api, links := newWebhookBot(t, tgbot.ModeWebhook)
+	require.Equal(t, http.StatusOK, postUpdate(t, b, "message_short.json", webhookSecret))
+	links.race = true
+	require.Equal(t, http.StatusOK, postUpdate(t, b, "message_forwarded.json", webhookSecret))
+	require.Equal(t, []string{"https://example.com/docs?page=1", "https://docs.example.com/start"}, links.created)
+	require.Equal(t, []string{
+		"Короткая ссылка: https://sho.rt/abc123",
+		"Release notes: https://sho.rt/raced1. Docs (https://sho.rt/abc124) and again https://sho.rt/raced1!",
+	}, api.messages())
+}
+
+func Test_Bot_File_Shortened_To_CSV(t *testing.T) {
+	b, api, links := newWebhookBot(t, tgbot.ModeWebhook)
+	require.Equal(t, http.StatusOK, postUpdate(t, b, "document_links.json", webhookSecret))
+	require.Equal(t, []string{"https://shop.example.com/catalog?sort=new", "https://blog.example.com/"}, links.created)
+	require.Equal(t, []string{
+		"url,short_url,error\n" +
+			"https://shop.example.com/catalog?sort=new,https://sho.rt/abc123,\n" +
+			"https://blog.example.com/,https://sho.rt/abc124,\n",
+	}, api.sentDocuments())
+	require.Empty(t, api.messages())
+}
+
+// inlineAlias posts the inline query fixture and returns the alias of the
+// first result.
+func inlineAlias(t *testing.T, b *tgbot.TGBot, api *fakeBotAPI) string {
+	t.Helper()
+	require.Equal(t, http.StatusOK, postUpdate(t, b, "inline_query.json", webhookSecret))
+	var results []struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(last(api.inlineAnswers())), &results))
+	require.NotEmpty(t, results)
+	_, alias, _ := strings.Cut(results[0].ID, ":")
+	return alias
+}
+
+func Test_Bot_Inline_Creates_Link_When_Answering(t *testing.T) {
+	b, api, links := newWebhookBot(t, tgbot.ModeWebhook)
+	alias := inlineAlias(t, b, api)
+	require.Equal(t, []string{"https://example.com/docs?page=1"}, links.created)
+	require.Equal(t, []string{alias}, links.aliases)
+
+	// the same query is answered with the same link
+	require.Equal(t, alias, inlineAlias(t, b, api))
+	require.Len(t, links.created, 1)
+	answers := api.inlineAnswers()
+	require.Equal(t, answers[0], answers[1])
+}
+
+func Test_Bot_Inline_Reuses_The_Users_Link(t *testing.T) {
+	b, api, links := newWebhookBot(t, tgbot.ModeWebhook)
+	links.stored = append(links.stored, models.Url{ID: uuid.New(), UserID: links.userId, Alias: "mine", Url: "https://example.com/docs?page=1"})
+	require.Equal(t, "mine", inlineAlias(t, b, api))
+	require.Empty(t, links.created)
+
+	b, api, links = newWebhookBot(t, tgbot.ModeWebhook)
+	links.race = true
+	require.Equal(t, "raced1", inlineAlias(t, b, api))
+	require.Empty(t, links.created)
+}
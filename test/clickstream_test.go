@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/config"
+	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/internal/feature/clickstream"
+	"github.com/Sanchir01/go-shortener/internal/feature/user"
+	"github.com/Sanchir01/go-shortener/internal/handlers/customiddleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var clicksConfig = config.Clicks{
+	QueueSize:   16,
+	Heartbeat:   20 * time.Millisecond,
+	Retry:       time.Second,
+	HistorySize: 100,
+	HistoryTTL:  time.Hour,
+}
+
+func newBroker(t *testing.T, cfg config.Clicks) *clickstream.Broker {
+	t.Helper()
+	_, rdb := testRedis(t)
+	return clickstream.NewBroker(rdb, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func click(userId, urlId uuid.UUID) models.LinkEvent {
+	return models.LinkEvent{ID: uuid.New(), Type: contextkey.EventLinkClicked, UserID: userId, UrlID: urlId}
+}
+
+func receive(t *testing.T, messages <-chan clickstream.Message) clickstream.Message {
+	t.Helper()
+	select {
+	case msg := <-messages:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("no click received")
+		return clickstream.Message{}
+	}
+}
+
+func Test_ClickStream_Live_And_Replay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	broker := newBroker(t, clicksConfig)
+	go broker.Run(ctx)
+
+	owner, urlId := uuid.New(), uuid.New()
+	live, err := broker.Subscribe(ctx, owner, "")
+	require.NoError(t, err)
+	events := []models.LinkEvent{click(owner, urlId), click(owner, urlId), click(owner, urlId)}
+	ids := make([]string, len(events))
+	for i, e := range events {
+		broker.Publish(ctx, e)
+		msg := receive(t, live)
+		require.Equal(t, e.ID, msg.Event.ID)
+		ids[i] = msg.ID
+	}
+	require.Negative(t, clickstream.CompareIDs(ids[0], ids[1]))
+
+	// a reconnect with Last-Event-ID gets what came after it, in order
+	replayCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	replay, err := broker.Subscribe(replayCtx, owner, ids[0])
+	require.NoError(t, err)
+	require.Equal(t, ids[1], receive(t, replay).ID)
+	require.Equal(t, ids[2], receive(t, replay).ID)
+
+	// clicks of other owners never show up
+	broker.Publish(ctx, click(uuid.New(), uuid.New()))
+	next := click(owner, urlId)
+	broker.Publish(ctx, next)
+	require.Equal(t, next.ID, receive(t, replay).Event.ID)
+}
+
+func Test_ClickStream_Publish_Never_Blocks(t *testing.T) {
+	cfg := clicksConfig
+	cfg.QueueSize = 1
+	broker := newBroker(t, cfg)
+
+	// nothing drains the queue, the redirect path must still go on
+	done := make(chan struct{})
+	go func() {
+		for range 100 {
+			broker.Publish(context.Background(), click(uuid.New(), uuid.New()))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publish blocked on a full queue")
+	}
+}
+
+func Test_ClickStream_CompareIDs(t *testing.T) {
+	require.Zero(t, clickstream.CompareIDs("1-1", "1-1"))
+	require.Negative(t, clickstream.CompareIDs("1-9", "1-10"))
+	require.Negative(t, clickstream.CompareIDs("9-0", "10-0"))
+	require.Positive(t, clickstream.CompareIDs("10-0", "9-5"))
+}
+
+// readEvents collects SSE lines until n lines starting with prefix were seen.
+func readEvents(t *testing.T, body io.Reader, prefix string, n int) []string {
+	t.Helper()
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		sc := bufio.NewScanner(body)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+	}()
+	var seen []string
+	deadline := time.After(2 * time.Second)
+	for len(seen) < n {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatalf("stream closed after %v", seen)
+			}
+			if strings.HasPrefix(line, prefix) {
+				seen = append(seen, line)
+			}
+		case <-deadline:
+			t.Fatalf("timed out waiting for %q, got %v", prefix, seen)
+		}
+	}
+	return seen
+}
+
+func Test_ClickStream_Handler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	broker := newBroker(t, clicksConfig)
+	go broker.Run(ctx)
+
+	owner, urlId, otherUrl := uuid.New(), uuid.New(), uuid.New()
+	h := clickstream.NewHandler(broker, clicksConfig, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := &user.Claims{ID: owner}
+			next.ServeHTTP(w, r.WithContext(customiddleware.WithJWTClaims(r.Context(), claims)))
+		})
+	})
+	r.Get("/url/clicks", h.StreamClicksHandler)
+	r.Get("/url/{id}/clicks", h.StreamUrlClicksHandler)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	t.Run("heartbeat", func(t *testing.T) {
+		res, err := http.Get(srv.URL + "/url/clicks")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+		readEvents(t, res.Body, ": heartbeat", 2)
+	})
+
+	t.Run("one link", func(t *testing.T) {
+		res, err := http.Get(srv.URL + "/url/" + urlId.String() + "/clicks")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		// headers are sent once the subscription is in place
+
+		broker.Publish(ctx, click(owner, otherUrl))
+		want := click(owner, urlId)
+		broker.Publish(ctx, want)
+		data := readEvents(t, res.Body, "data: ", 1)
+		require.Contains(t, data[0], want.ID.String())
+		require.NotContains(t, data[0], otherUrl.String())
+	})
+
+	t.Run("bad link id", func(t *testing.T) {
+		res, err := http.Get(srv.URL + "/url/nope/clicks")
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
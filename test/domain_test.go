@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/feature/customdomain"
+	"github.com/Sanchir01/go-shortener/pkg/netguard"
+	"github.com/stretchr/testify/require"
+)
+
+const verificationPath = "/.well-known/go-shortener-verification"
+
+func newDomainStandIn(t *testing.T, token string) string {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc(verificationPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(token + "\n"))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	return u.Host
+}
+
+func Test_Domain_Verify(t *testing.T) {
+	host := newDomainStandIn(t, "go-shortener-verification=abc")
+	// the stand-in listens on loopback, which the default client refuses
+	verifier := customdomain.NewVerifier(&http.Client{Timeout: 5 * time.Second}, "http", verificationPath, 0)
+
+	require.NoError(t, verifier.Verify(context.Background(), host, "go-shortener-verification=abc"))
+	require.ErrorIs(t,
+		verifier.Verify(context.Background(), host, "go-shortener-verification=other"),
+		customdomain.ErrorTokenMismatch,
+	)
+}
+
+func Test_Domain_Verify_MissingToken(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	verifier := customdomain.NewVerifier(srv.Client(), "http", verificationPath, 0)
+	require.Error(t, verifier.Verify(context.Background(), u.Host, "token"))
+}
+
+func Test_Domain_Verify_RefusesInternal(t *testing.T) {
+	var hits int
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Write([]byte("token"))
+	}))
+	t.Cleanup(internal.Close)
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL+verificationPath, http.StatusFound)
+	}))
+	t.Cleanup(redirect.Close)
+
+	verifier := customdomain.NewVerifier(nil, "http", verificationPath, time.Second)
+	port := strconv.Itoa(internal.Listener.Addr().(*net.TCPAddr).Port)
+	require.ErrorIs(t, verifier.Verify(context.Background(), "localhost:"+port, "token"), netguard.ErrForbiddenAddress)
+
+	u, err := url.Parse(redirect.URL)
+	require.NoError(t, err)
+	// a public host redirecting inside gets its 302 back, not the target
+	client := netguard.NewClient(time.Second)
+	client.Transport = http.DefaultTransport
+	require.Error(t, customdomain.NewVerifier(client, "http", verificationPath, 0).Verify(context.Background(), u.Host, "token"))
+	require.Zero(t, hits)
+}
+
+func Test_Domain_NormalizeHost(t *testing.T) {
+	require.Equal(t, "go.company.com", customdomain.NormalizeHost("Go.Company.com:443"))
+	require.Equal(t, "go.company.com", customdomain.NormalizeHost("go.company.com."))
+}
+
+func Test_Netguard_Allowed(t *testing.T) {
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1"} {
+		require.False(t, netguard.Allowed(netip.MustParseAddr(ip)), ip)
+	}
+	for _, ip := range []string{"93.184.216.34", "1.1.1.1", "2606:4700:4700::1111"} {
+		require.True(t, netguard.Allowed(netip.MustParseAddr(ip)), ip)
+	}
+}
@@ -0,0 +1,40 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/feature/healthcheck"
+	"github.com/Sanchir01/go-shortener/pkg/netguard"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Healthcheck_Check(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	checker := healthcheck.NewChecker(srv.Client(), time.Second, 0, "test")
+	res := checker.Check(context.Background(), srv.URL)
+	require.True(t, res.Healthy())
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func Test_Healthcheck_RefusesInternal(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	t.Cleanup(srv.Close)
+
+	res := healthcheck.NewChecker(nil, time.Second, time.Millisecond, "test").Check(context.Background(), srv.URL)
+	require.False(t, res.Healthy())
+	require.ErrorIs(t, res.Err, netguard.ErrForbiddenAddress)
+	require.Zero(t, hits)
+}
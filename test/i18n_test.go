@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sanchir01/go-shortener/internal/handlers/customiddleware"
+	"github.com/Sanchir01/go-shortener/pkg/api"
+	"github.com/Sanchir01/go-shortener/pkg/i18n"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_I18n_Catalog_Complete(t *testing.T) {
+	require.Empty(t, i18n.Missing())
+}
+
+func Test_I18n_Negotiate(t *testing.T) {
+	cases := map[string]i18n.Lang{
+		"":                            i18n.EN,
+		"ru":                          i18n.RU,
+		"ru-RU,ru;q=0.9":              i18n.RU,
+		"en-US,en;q=0.9,ru;q=0.8":     i18n.EN,
+		"de-DE,ru;q=0.5,en;q=0.7":     i18n.EN,
+		"de":                          i18n.EN,
+		"ru;q=0,en;q=0.1":             i18n.EN,
+		"ru;q=oops,en;q=0.2":          i18n.EN,
+		"fr;q=1, RU-ru;q=0.3, *;q=.1": i18n.RU,
+	}
+	for header, want := range cases {
+		require.Equal(t, want, i18n.Negotiate(header, i18n.EN), header)
+	}
+	require.Equal(t, i18n.RU, i18n.Negotiate("de", i18n.RU))
+}
+
+func Test_I18n_Fail_Localized(t *testing.T) {
+	h := customiddleware.Language(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		customiddleware.Unauthorized(w, r)
+	}))
+	for lang, text := range map[string]string{"ru-RU": "Требуется авторизация", "en": "Unauthorized", "": "Unauthorized"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Language", lang)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		var resp api.Response
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Equal(t, string(i18n.ErrUnauthorized), resp.Code)
+		require.Equal(t, text, resp.Error)
+		require.Contains(t, rec.Header().Values("Vary"), "Accept-Language")
+	}
+}
+
+func Test_I18n_FailErr_Codes(t *testing.T) {
+	ctx := i18n.WithLang(context.Background(), i18n.RU)
+	require.Equal(t, string(i18n.ErrAliasTaken), api.FailErr(ctx, utils.ErrorAliasTaken).Code)
+	// unknown errors do not leak their text
+	resp := api.FailErr(ctx, errors.New("pq: connection reset"))
+	require.Equal(t, string(i18n.ErrInternal), resp.Code)
+	require.Equal(t, i18n.T(i18n.RU, i18n.ErrInternal), resp.Error)
+}
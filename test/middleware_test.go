@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/feature/user"
+	"github.com/Sanchir01/go-shortener/internal/handlers/customiddleware"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRefresher counts refresh rotations and answers with claims when the
+// refresh token is known.
+type fakeRefresher struct {
+	token  string
+	claims *user.Claims
+	calls  int
+}
+
+func (f *fakeRefresher) Refresh(w http.ResponseWriter, r *http.Request, refreshToken string) (*user.Claims, error) {
+	f.calls++
+	if refreshToken != f.token {
+		return nil, errors.New("unknown refresh token")
+	}
+	return f.claims, nil
+}
+
+// claimsHandler answers 200 and remembers the claims the request reached it
+// with, nil for anonymous requests.
+type claimsHandler struct {
+	reached bool
+	claims  *user.Claims
+}
+
+func (h *claimsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.reached = true
+	h.claims, _ = customiddleware.GetJWTClaimsFromCtx(r.Context())
+	w.WriteHeader(http.StatusOK)
+}
+
+func serveAuth(mw func(http.Handler) http.Handler, req *http.Request) (*httptest.ResponseRecorder, *claimsHandler) {
+	next := &claimsHandler{}
+	rec := httptest.NewRecorder()
+	mw(next).ServeHTTP(rec, req)
+	return rec, next
+}
+
+func accessTokenFor(t *testing.T, userId uuid.UUID, expire time.Time) string {
+	t.Helper()
+	token, err := user.GenerateJwtToken(hmacTokens{}, userId, uuid.Nil, "user", expire)
+	require.NoError(t, err)
+	return token
+}
+
+func Test_AuthMiddleware_Rejects_Anonymous(t *testing.T) {
+	refresher := &fakeRefresher{token: "refresh"}
+	auth := customiddleware.AuthMiddleware(customiddleware.Authenticators{Tokens: hmacTokens{}, Sessions: refresher})
+
+	requests := map[string]*http.Request{
+		"no credentials": httptest.NewRequest(http.MethodGet, "/", nil),
+	}
+	garbage := httptest.NewRequest(http.MethodGet, "/", nil)
+	garbage.Header.Set("Authorization", "Bearer not-a-jwt")
+	requests["malformed token"] = garbage
+
+	expired := httptest.NewRequest(http.MethodGet, "/", nil)
+	expired.AddCookie(&http.Cookie{Name: "accessToken", Value: accessTokenFor(t, uuid.New(), time.Now().Add(-time.Minute))})
+	requests["expired token"] = expired
+
+	unknown := httptest.NewRequest(http.MethodGet, "/", nil)
+	unknown.AddCookie(&http.Cookie{Name: "refreshToken", Value: "stolen"})
+	requests["unknown refresh token"] = unknown
+
+	for name, req := range requests {
+		rec, next := serveAuth(auth, req)
+		require.Equal(t, http.StatusUnauthorized, rec.Code, name)
+		require.False(t, next.reached, name)
+		require.Contains(t, rec.Body.String(), "unauthorized", name)
+	}
+}
+
+func Test_AuthMiddleware_Accepts_Sessions(t *testing.T) {
+	userId := uuid.New()
+	refresher := &fakeRefresher{token: "refresh", claims: &user.Claims{ID: userId}}
+	auth := customiddleware.AuthMiddleware(customiddleware.Authenticators{Tokens: hmacTokens{}, Sessions: refresher})
+
+	bearer := httptest.NewRequest(http.MethodGet, "/", nil)
+	bearer.Header.Set("Authorization", "Bearer "+accessTokenFor(t, userId, time.Now().Add(time.Minute)))
+	rec, next := serveAuth(auth, bearer)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, userId, next.claims.ID)
+	require.Zero(t, refresher.calls)
+
+	// an expired access token is replaced through the refresh token
+	expired := httptest.NewRequest(http.MethodGet, "/", nil)
+	expired.AddCookie(&http.Cookie{Name: "accessToken", Value: accessTokenFor(t, userId, time.Now().Add(-time.Minute))})
+	expired.AddCookie(&http.Cookie{Name: "refreshToken", Value: "refresh"})
+	rec, next = serveAuth(auth, expired)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, userId, next.claims.ID)
+	require.Equal(t, 1, refresher.calls)
+}
+
+func Test_OptionalAuthMiddleware(t *testing.T) {
+	userId := uuid.New()
+	refresher := &fakeRefresher{token: "refresh", claims: &user.Claims{ID: userId}}
+	optional := customiddleware.OptionalAuthMiddleware(customiddleware.Authenticators{Tokens: hmacTokens{}, Sessions: refresher})
+
+	rec, next := serveAuth(optional, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.True(t, next.reached)
+	require.Nil(t, next.claims)
+
+	// broken credentials do not turn the request away, it goes on anonymous
+	garbage := httptest.NewRequest(http.MethodGet, "/", nil)
+	garbage.Header.Set("Authorization", "Bearer not-a-jwt")
+	garbage.AddCookie(&http.Cookie{Name: "refreshToken", Value: "stolen"})
+	rec, next = serveAuth(optional, garbage)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.True(t, next.reached)
+	require.Nil(t, next.claims)
+
+	signed := httptest.NewRequest(http.MethodGet, "/", nil)
+	signed.AddCookie(&http.Cookie{Name: "accessToken", Value: accessTokenFor(t, userId, time.Now().Add(time.Minute))})
+	rec, next = serveAuth(optional, signed)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, next.claims)
+	require.Equal(t, userId, next.claims.ID)
+}
+
+// keyAuth treats every token starting with "sk_" as an api key.
+type keyAuth struct{ claims *user.Claims }
+
+func (k keyAuth) IsKey(token string) bool { return len(token) > 3 && token[:3] == "sk_" }
+
+func (k keyAuth) AuthenticateKey(ctx context.Context, key string) (*user.Claims, error) {
+	if key != "sk_valid" {
+		return nil, errors.New("unknown key")
+	}
+	return k.claims, nil
+}
+
+func Test_AuthMiddleware_Api_Keys_Skip_Cookies(t *testing.T) {
+	userId := uuid.New()
+	refresher := &fakeRefresher{token: "refresh", claims: &user.Claims{ID: uuid.New()}}
+	auth := customiddleware.AuthMiddleware(customiddleware.Authenticators{
+		Tokens:   hmacTokens{},
+		Keys:     keyAuth{claims: &user.Claims{ID: userId}},
+		Sessions: refresher,
+	})
+
+	valid := httptest.NewRequest(http.MethodGet, "/", nil)
+	valid.Header.Set("Authorization", "Bearer sk_valid")
+	rec, next := serveAuth(auth, valid)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, userId, next.claims.ID)
+
+	// a bad key is not rescued by the session cookie next to it
+	bad := httptest.NewRequest(http.MethodGet, "/", nil)
+	bad.Header.Set("Authorization", "Bearer sk_revoked")
+	bad.AddCookie(&http.Cookie{Name: "refreshToken", Value: "refresh"})
+	rec, next = serveAuth(auth, bad)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.False(t, next.reached)
+	require.Zero(t, refresher.calls)
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/Sanchir01/go-shortener/internal/feature/oauth"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// Test_OAuth_Takeover_Drops_Squatter_Credentials runs against
+// TEST_DATABASE_URL, see testTx.
+func Test_OAuth_Takeover_Drops_Squatter_Credentials(t *testing.T) {
+	pool, tx := testTx(t)
+	ctx := context.Background()
+
+	// someone registered the victim's email without proving the mailbox
+	var userId uuid.UUID
+	require.NoError(t, tx.QueryRow(ctx,
+		`INSERT INTO users (title, email, password, tg_id) VALUES ('squatter', $1, 'hash', $2) RETURNING id`,
+		uuid.NewString()+"@example.com", int64(uuid.New().ID()),
+	).Scan(&userId))
+	seed := []string{
+		`INSERT INTO api_keys (user_id, name, prefix, hash, scopes) VALUES ($1, 'k', $1::text, 'h', '{links:read}')`,
+		`INSERT INTO user_totp (user_id, secret, enabled_at) VALUES ($1, 'secret', now())`,
+		`INSERT INTO user_recovery_codes (user_id, hash) VALUES ($1, 'code')`,
+		`INSERT INTO user_identities (provider, subject, user_id) VALUES ('github', $1::text, $1)`,
+		`INSERT INTO sessions (user_id, expires_at) VALUES ($1, now() + interval '1 day')`,
+	}
+	for _, q := range seed {
+		_, err := tx.Exec(ctx, q, userId)
+		require.NoError(t, err, q)
+	}
+
+	repo := oauth.NewRepository(pool, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	revoked, err := repo.DropCredentials(ctx, userId, tx)
+	require.NoError(t, err)
+	require.Len(t, revoked, 1)
+	require.NoError(t, repo.VerifyEmail(ctx, userId, true, tx))
+
+	var left int
+	require.NoError(t, tx.QueryRow(ctx, `SELECT
+		(SELECT count(*) FROM api_keys WHERE user_id = $1) +
+		(SELECT count(*) FROM user_totp WHERE user_id = $1) +
+		(SELECT count(*) FROM user_recovery_codes WHERE user_id = $1) +
+		(SELECT count(*) FROM user_identities WHERE user_id = $1) +
+		(SELECT count(*) FROM sessions WHERE user_id = $1 AND revoked_at IS NULL) +
+		(SELECT count(*) FROM users WHERE id = $1 AND (password IS NOT NULL OR tg_id IS NOT NULL))`,
+		userId,
+	).Scan(&left))
+	require.Zero(t, left)
+}
@@ -0,0 +1,271 @@
+package tests
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/config"
+	"github.com/Sanchir01/go-shortener/internal/feature/oauth"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	googleClientID = "client-id.apps.googleusercontent.com"
+	googleKid      = "test-key"
+)
+
+// fakeGoogle serves discovery, the token and jwks endpoints and a GitHub
+// style user-info API. The token endpoint checks
+// the PKCE verifier against the challenge from the auth url and answers with
+// the id_token built by idToken.
+type fakeGoogle struct {
+	t   *testing.T
+	key *rsa.PrivateKey
+	srv *httptest.Server
+
+	mu        sync.Mutex
+	challenge string
+	idToken   func() string
+}
+
+func newFakeGoogle(t *testing.T) *fakeGoogle {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	f := &fakeGoogle{t: t, key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 f.srv.URL,
+			"authorization_endpoint": f.srv.URL + "/auth",
+			"token_endpoint":         f.srv.URL + "/token",
+			"jwks_uri":               f.srv.URL + "/certs",
+		})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": 583231, "login": "octocat", "name": "The Octocat", "email": null}`))
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[
+			{"email": "other@example.com", "primary": false, "verified": true},
+			{"email": "octocat@example.com", "primary": true, "verified": true}
+		]`))
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if r.PostForm.Get("client_id") != googleClientID ||
+			oauth.PKCEChallenge(r.PostForm.Get("code_verifier")) != f.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     f.idToken(),
+			"scope":        "openid email profile",
+		})
+	})
+	mux.HandleFunc("/certs", func(w http.ResponseWriter, r *http.Request) {
+		pub := key.PublicKey
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": googleKid,
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			}},
+		})
+	})
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeGoogle) provider(cfg config.OAuthProvider) *oauth.Provider {
+	p, err := oauth.NewProvider("test", cfg, oauth.Credentials{
+		ClientID:     googleClientID,
+		ClientSecret: "secret",
+		RedirectURI:  "http://localhost:3000/oauth/callback",
+	}, f.srv.Client())
+	require.NoError(f.t, err)
+	return p
+}
+
+func (f *fakeGoogle) oidc() *oauth.Provider {
+	return f.provider(config.OAuthProvider{
+		Issuer: f.srv.URL,
+		Scopes: []string{"openid", "email", "profile"},
+	})
+}
+
+func (f *fakeGoogle) github() *oauth.Provider {
+	return f.provider(config.OAuthProvider{
+		Type:        oauth.TypeOAuth2,
+		AuthURL:     f.srv.URL + "/auth",
+		TokenURL:    f.srv.URL + "/token",
+		UserInfoURL: f.srv.URL + "/user",
+		EmailsURL:   f.srv.URL + "/user/emails",
+		Mapping:     config.OAuthMapping{Subject: "id"},
+	})
+}
+
+func (f *fakeGoogle) sign(key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = googleKid
+	signed, err := token.SignedString(key)
+	require.NoError(f.t, err)
+	return signed
+}
+
+func (f *fakeGoogle) claims(nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            f.srv.URL,
+		"aud":            googleClientID,
+		"sub":            "1234567890",
+		"email":          "user@gmail.com",
+		"email_verified": true,
+		"name":           "Test User",
+		"nonce":          nonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	}
+}
+
+// start runs the redirect half of the flow and returns the verifier.
+func (f *fakeGoogle) start(p *oauth.Provider, nonce string) string {
+	verifier, challenge, err := oauth.NewPKCE()
+	require.NoError(f.t, err)
+	raw, err := p.AuthURL(context.Background(), "state", challenge, nonce)
+	require.NoError(f.t, err)
+	authURL, err := url.Parse(raw)
+	require.NoError(f.t, err)
+	require.Equal(f.t, f.srv.URL+"/auth", authURL.Scheme+"://"+authURL.Host+authURL.Path)
+	q := authURL.Query()
+	require.Equal(f.t, "S256", q.Get("code_challenge_method"))
+	require.Equal(f.t, "state", q.Get("state"))
+
+	f.mu.Lock()
+	f.challenge = q.Get("code_challenge")
+	f.mu.Unlock()
+	return verifier
+}
+
+func Test_OAuth_OIDC_Discovery_Exchange_And_Identity(t *testing.T) {
+	f := newFakeGoogle(t)
+	p := f.oidc()
+	f.idToken = func() string { return f.sign(f.key, f.claims("nonce-1")) }
+
+	verifier := f.start(p, "nonce-1")
+	token, err := p.Exchange(context.Background(), "code", verifier)
+	require.NoError(t, err)
+
+	id, err := p.Identity(context.Background(), token, "nonce-1")
+	require.NoError(t, err)
+	require.Equal(t, "test", id.Provider)
+	require.Equal(t, "1234567890", id.Subject)
+	require.Equal(t, "user@gmail.com", id.Email)
+	require.True(t, id.EmailVerified)
+	require.Equal(t, "Test User", id.Name)
+}
+
+func Test_OAuth_Exchange_WrongVerifier(t *testing.T) {
+	f := newFakeGoogle(t)
+	p := f.oidc()
+	f.idToken = func() string { return f.sign(f.key, f.claims("nonce")) }
+
+	f.start(p, "nonce")
+	_, err := p.Exchange(context.Background(), "code", "not-the-verifier")
+	require.Error(t, err)
+}
+
+func Test_OAuth_OAuth2_UserInfo_Mapping(t *testing.T) {
+	f := newFakeGoogle(t)
+	p := f.github()
+	f.idToken = func() string { return "" }
+
+	verifier := f.start(p, "")
+	token, err := p.Exchange(context.Background(), "code", verifier)
+	require.NoError(t, err)
+
+	id, err := p.Identity(context.Background(), token, "")
+	require.NoError(t, err)
+	require.Equal(t, "583231", id.Subject)
+	require.Equal(t, "octocat@example.com", id.Email)
+	require.True(t, id.EmailVerified)
+	require.Equal(t, "The Octocat", id.Name)
+}
+
+func Test_OAuth_Registry_Skips_Providers_Without_Credentials(t *testing.T) {
+	t.Setenv("TEST_OAUTH_CLIENT_ID", googleClientID)
+	r, err := oauth.NewRegistry(config.OAuth{Providers: map[string]config.OAuthProvider{
+		"keycloak": {Issuer: "https://sso.example.com/realms/main", ClientIDEnv: "TEST_OAUTH_CLIENT_ID"},
+		"gitlab":   {Issuer: "https://gitlab.com", ClientIDEnv: "TEST_OAUTH_MISSING_ID"},
+	}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	require.Equal(t, []string{"keycloak"}, r.Names())
+	_, err = r.Get("gitlab")
+	require.ErrorIs(t, err, oauth.ErrUnknownProvider)
+}
+
+func Test_OAuth_VerifyIDToken_Rejects(t *testing.T) {
+	f := newFakeGoogle(t)
+	p := f.oidc()
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	cases := map[string]struct {
+		token string
+		nonce string
+	}{
+		"wrong nonce": {f.sign(f.key, f.claims("nonce")), "other-nonce"},
+		"foreign key": {f.sign(other, f.claims("nonce")), "nonce"},
+		"wrong audience": {f.sign(f.key, func() jwt.MapClaims {
+			c := f.claims("nonce")
+			c["aud"] = "someone-else"
+			return c
+		}()), "nonce"},
+		"wrong issuer": {f.sign(f.key, func() jwt.MapClaims {
+			c := f.claims("nonce")
+			c["iss"] = "https://evil.example.com"
+			return c
+		}()), "nonce"},
+		"expired": {f.sign(f.key, func() jwt.MapClaims {
+			c := f.claims("nonce")
+			c["iat"] = time.Now().Add(-2 * time.Hour).Unix()
+			c["exp"] = time.Now().Add(-time.Hour).Unix()
+			return c
+		}()), "nonce"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := p.VerifyIDToken(context.Background(), tc.token, tc.nonce)
+			require.ErrorIs(t, err, oauth.ErrInvalidIDToken)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
+	"github.com/Sanchir01/go-shortener/internal/feature/user"
+	"github.com/Sanchir01/go-shortener/internal/handlers/customiddleware"
+	"github.com/stretchr/testify/require"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+// guardStatus runs guard with claims in the context, nil claims mean an
+// anonymous request.
+func guardStatus(guard func(http.Handler) http.Handler, claims *user.Claims) int {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if claims != nil {
+		req = req.WithContext(customiddleware.WithJWTClaims(req.Context(), claims))
+	}
+	rec := httptest.NewRecorder()
+	guard(okHandler).ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func Test_RBAC_RequireRole(t *testing.T) {
+	member := customiddleware.RequireRole(customiddleware.RolesMember...)
+	staff := customiddleware.RequireRole(customiddleware.RolesStaff...)
+	admin := customiddleware.RequireRole(customiddleware.RolesAdmin...)
+	cases := []struct {
+		role   string
+		member int
+		staff  int
+		admin  int
+	}{
+		{"", http.StatusOK, http.StatusForbidden, http.StatusForbidden},
+		{string(contextkey.RoleUser), http.StatusOK, http.StatusForbidden, http.StatusForbidden},
+		{string(contextkey.RoleModerator), http.StatusOK, http.StatusOK, http.StatusForbidden},
+		{string(contextkey.RoleAdmin), http.StatusOK, http.StatusOK, http.StatusOK},
+		{"root", http.StatusForbidden, http.StatusForbidden, http.StatusForbidden},
+	}
+	for _, c := range cases {
+		claims := &user.Claims{Role: c.role}
+		require.Equal(t, c.member, guardStatus(member, claims), "member %q", c.role)
+		require.Equal(t, c.staff, guardStatus(staff, claims), "staff %q", c.role)
+		require.Equal(t, c.admin, guardStatus(admin, claims), "admin %q", c.role)
+	}
+	require.Equal(t, http.StatusUnauthorized, guardStatus(member, nil))
+	require.Equal(t, http.StatusUnauthorized, guardStatus(staff, nil))
+	require.Equal(t, http.StatusUnauthorized, guardStatus(admin, nil))
+}
@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/Sanchir01/go-shortener/internal/feature/url"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// Test_Url_Revision_Rollback runs against TEST_DATABASE_URL, see testTx.
+func Test_Url_Revision_Rollback(t *testing.T) {
+	pool, tx := testTx(t)
+	ctx := context.Background()
+	repo := url.NewRepository(pool, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	owner, stranger := uuid.New(), uuid.New()
+	require.NoError(t, repo.SetActor(ctx, owner, tx))
+
+	var urlId uuid.UUID
+	require.NoError(t, tx.QueryRow(ctx, `INSERT INTO url (alias, url, user_id) VALUES ($1, 'https://first.example.com', $2) RETURNING id`,
+		"r"+uuid.NewString()[:8], owner).Scan(&urlId))
+	_, err := tx.Exec(ctx, `UPDATE url SET url = 'https://second.example.com' WHERE id = $1`, urlId)
+	require.NoError(t, err)
+
+	var revId uuid.UUID
+	require.NoError(t, tx.QueryRow(ctx,
+		`SELECT id FROM url_revisions WHERE url_id = $1 AND before IS NOT NULL ORDER BY created_at DESC LIMIT 1`,
+		urlId).Scan(&revId))
+
+	rev, err := repo.GetRevision(ctx, revId, urlId, owner, tx)
+	require.NoError(t, err)
+	require.NotNil(t, rev.ChangedBy)
+	require.Equal(t, owner, *rev.ChangedBy)
+	require.NotNil(t, rev.Before)
+	require.Equal(t, "https://first.example.com", rev.Before.Url)
+	require.Equal(t, "https://second.example.com", rev.After.Url)
+
+	// another user can neither read the revision nor restore the link
+	_, err = repo.GetRevision(ctx, revId, urlId, stranger, tx)
+	require.ErrorIs(t, err, utils.ErrorRevisionNotFound)
+	require.ErrorIs(t, repo.RestoreRouting(ctx, urlId, stranger, *rev.Before, tx), utils.ErrorUrlNotFound)
+
+	require.NoError(t, repo.RestoreRouting(ctx, urlId, owner, *rev.Before, tx))
+	var current string
+	require.NoError(t, tx.QueryRow(ctx, `SELECT url FROM url WHERE id = $1`, urlId).Scan(&current))
+	require.Equal(t, "https://first.example.com", current)
+
+	// the rollback is itself recorded, so it can be undone
+	var revisions int
+	require.NoError(t, tx.QueryRow(ctx, `SELECT count(*) FROM url_revisions WHERE url_id = $1`, urlId).Scan(&revisions))
+	require.Equal(t, 3, revisions)
+
+	// restoring the routing the link already has records nothing
+	require.NoError(t, repo.RestoreRouting(ctx, urlId, owner, *rev.Before, tx))
+	require.NoError(t, tx.QueryRow(ctx, `SELECT count(*) FROM url_revisions WHERE url_id = $1`, urlId).Scan(&revisions))
+	require.Equal(t, 3, revisions)
+}
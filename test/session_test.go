@@ -0,0 +1,162 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/config"
+	"github.com/Sanchir01/go-shortener/internal/feature/session"
+	"github.com/Sanchir01/go-shortener/internal/feature/user"
+	"github.com/Sanchir01/go-shortener/internal/handlers/customiddleware"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// hmacTokens signs and parses access tokens with a shared secret.
+type hmacTokens struct{}
+
+var hmacSecret = []byte("test-secret")
+
+func (hmacTokens) Sign(claims jwt.Claims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(hmacSecret)
+}
+
+func (hmacTokens) Parse(tokenString string, claims jwt.Claims) error {
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(*jwt.Token) (any, error) { return hmacSecret, nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	return err
+}
+
+type fakeRevocations struct {
+	denied map[uuid.UUID]bool
+	err    error
+}
+
+func (f fakeRevocations) IsRevoked(ctx context.Context, id uuid.UUID) (bool, error) {
+	return f.denied[id], f.err
+}
+
+func authStatus(t *testing.T, revoked customiddleware.RevocationChecker, sessionId uuid.UUID) int {
+	t.Helper()
+	token, err := user.GenerateJwtToken(hmacTokens{}, uuid.New(), sessionId, "user", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	auth := customiddleware.AuthMiddleware(customiddleware.Authenticators{Tokens: hmacTokens{}, Revoked: revoked})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	auth(okHandler).ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func Test_Session_Revoked_Access_Token_Rejected(t *testing.T) {
+	active, revoked := uuid.New(), uuid.New()
+	denylist := fakeRevocations{denied: map[uuid.UUID]bool{revoked: true}}
+
+	require.Equal(t, http.StatusOK, authStatus(t, denylist, active))
+	require.Equal(t, http.StatusUnauthorized, authStatus(t, denylist, revoked))
+	// tokens from before sessions carry no session to check
+	require.Equal(t, http.StatusOK, authStatus(t, denylist, uuid.Nil))
+	// an unknown revocation state does not let the token through
+	require.Equal(t, http.StatusServiceUnavailable, authStatus(t, fakeRevocations{err: errors.New("redis down")}, active))
+}
+
+func Test_Session_Unknown_Revocation_Does_Not_Refresh(t *testing.T) {
+	token, err := user.GenerateJwtToken(hmacTokens{}, uuid.New(), uuid.New(), "user", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	refresher := &fakeRefresher{token: "refresh", claims: &user.Claims{ID: uuid.New()}}
+	authenticators := customiddleware.Authenticators{
+		Tokens:   hmacTokens{},
+		Sessions: refresher,
+		Revoked:  fakeRevocations{err: errors.New("redis down")},
+	}
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: "accessToken", Value: token})
+		req.AddCookie(&http.Cookie{Name: "refreshToken", Value: "refresh"})
+		return req
+	}
+
+	rec, next := serveAuth(customiddleware.AuthMiddleware(authenticators), newRequest())
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.False(t, next.reached)
+	require.Empty(t, rec.Result().Cookies())
+
+	rec, next = serveAuth(customiddleware.OptionalAuthMiddleware(authenticators), newRequest())
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Nil(t, next.claims)
+	require.Empty(t, rec.Result().Cookies())
+
+	require.Zero(t, refresher.calls)
+}
+
+// sessionLookup answers revocation lookups from the database side only, the
+// other repository methods are never called.
+type sessionLookup struct {
+	session.SessionService
+	revoked map[uuid.UUID]bool
+	err     error
+}
+
+func (s sessionLookup) IsSessionRevoked(_ context.Context, id uuid.UUID) (bool, error) {
+	return s.revoked[id], s.err
+}
+
+type brokenDenylist struct{}
+
+func (brokenDenylist) Deny(context.Context, ...uuid.UUID) error { return errors.New("redis down") }
+
+func (brokenDenylist) IsDenied(context.Context, uuid.UUID) (bool, error) {
+	return false, errors.New("redis down")
+}
+
+func Test_Session_IsRevoked_Falls_Back_To_Database(t *testing.T) {
+	active, revoked := uuid.New(), uuid.New()
+	l := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := sessionLookup{revoked: map[uuid.UUID]bool{revoked: true}}
+	service := session.NewService(repo, nil, hmacTokens{}, brokenDenylist{}, config.Sessions{}, "", l)
+
+	got, err := service.IsRevoked(context.Background(), active)
+	require.NoError(t, err)
+	require.False(t, got)
+	got, err = service.IsRevoked(context.Background(), revoked)
+	require.NoError(t, err)
+	require.True(t, got)
+
+	repo.err = errors.New("postgres down")
+	service = session.NewService(repo, nil, hmacTokens{}, brokenDenylist{}, config.Sessions{}, "", l)
+	_, err = service.IsRevoked(context.Background(), active)
+	require.Error(t, err)
+}
+
+func Test_Session_Refresh_Rotation(t *testing.T) {
+	now := time.Now()
+	grace := 10 * time.Second
+	at := func(d time.Duration) *time.Time {
+		ts := now.Add(d)
+		return &ts
+	}
+	cases := []struct {
+		name  string
+		token session.TokenSession
+		want  session.Rotation
+	}{
+		{"first use", session.TokenSession{ExpiresAt: now.Add(time.Hour)}, session.RotationIssue},
+		{"reuse inside grace", session.TokenSession{ExpiresAt: now.Add(time.Hour), UsedAt: at(-grace / 2)}, session.RotationGrace},
+		{"reuse at grace end", session.TokenSession{ExpiresAt: now.Add(time.Hour), UsedAt: at(-grace)}, session.RotationGrace},
+		{"reuse after grace", session.TokenSession{ExpiresAt: now.Add(time.Hour), UsedAt: at(-grace - time.Second)}, session.RotationReused},
+		{"expired", session.TokenSession{ExpiresAt: now.Add(-time.Second)}, session.RotationInvalid},
+		{"revoked", session.TokenSession{ExpiresAt: now.Add(time.Hour), RevokedAt: at(-time.Minute)}, session.RotationInvalid},
+		{"revoked after reuse", session.TokenSession{ExpiresAt: now.Add(time.Hour), UsedAt: at(-time.Hour), RevokedAt: at(-time.Minute)}, session.RotationInvalid},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.want, c.token.Rotation(now, grace), c.name)
+	}
+}
@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/config"
+	"github.com/Sanchir01/go-shortener/internal/feature/signingkey"
+	"github.com/Sanchir01/go-shortener/internal/feature/user"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSigningKeys serves keys newest first like the repository, only
+// GetKeys is used by Reload.
+type fakeSigningKeys struct {
+	keys []signingkey.StoredKey
+}
+
+func (f *fakeSigningKeys) LockKeys(context.Context, pgx.Tx) error {
+	return nil
+}
+
+func (f *fakeSigningKeys) GetNewestKey(context.Context, pgx.Tx) (*signingkey.StoredKey, error) {
+	return nil, nil
+}
+
+func (f *fakeSigningKeys) CreateKey(context.Context, *signingkey.StoredKey, pgx.Tx) error {
+	return nil
+}
+
+func (f *fakeSigningKeys) RetireKeys(context.Context, string, time.Time, pgx.Tx) error {
+	return nil
+}
+
+func (f *fakeSigningKeys) GetKeys(context.Context) ([]signingkey.StoredKey, error) {
+	return f.keys, nil
+}
+
+var testSealer = func() *signingkey.Sealer {
+	sealer, err := signingkey.NewSealer(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	if err != nil {
+		panic(err)
+	}
+	return sealer
+}()
+
+// storedKey returns a key sealed as the repository keeps it.
+func storedKey(t *testing.T, algorithm string, createdAt time.Time) signingkey.StoredKey {
+	t.Helper()
+	key, err := signingkey.GenerateKey(algorithm)
+	require.NoError(t, err)
+	key.CreatedAt = createdAt
+	sealed, err := testSealer.Seal(*key)
+	require.NoError(t, err)
+	return sealed
+}
+
+func kid(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &user.Claims{})
+	require.NoError(t, err)
+	id, _ := parsed.Header["kid"].(string)
+	return id
+}
+
+func Test_SigningKey_RotationDue(t *testing.T) {
+	now := time.Now()
+	key := signingkey.StoredKey{Algorithm: signingkey.AlgorithmEdDSA, CreatedAt: now.Add(-time.Hour)}
+	require.False(t, key.RotationDue(signingkey.AlgorithmEdDSA, 2*time.Hour, now))
+	require.True(t, key.RotationDue(signingkey.AlgorithmEdDSA, time.Hour, now))
+	require.True(t, key.RotationDue(signingkey.AlgorithmRS256, 2*time.Hour, now))
+}
+
+func Test_SigningKey_Rotation_Retirement_JWKS(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	reload := time.Minute
+	repo := &fakeSigningKeys{}
+	manager := signingkey.NewManager(repo, nil, testSealer, config.Jwt{ReloadInterval: reload}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	old := storedKey(t, signingkey.AlgorithmEdDSA, now.Add(-24*time.Hour))
+	repo.keys = []signingkey.StoredKey{old}
+	require.NoError(t, manager.Reload(ctx))
+	oldToken, err := user.GenerateJwtToken(manager, uuid.New(), uuid.New(), "user", now.Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, old.ID, kid(t, oldToken))
+
+	// a fresh key is published through the JWKS before it signs anything
+	retires := now.Add(time.Hour)
+	old.RetiresAt = &retires
+	next := storedKey(t, signingkey.AlgorithmRS256, now)
+	repo.keys = []signingkey.StoredKey{next, old}
+	require.NoError(t, manager.Reload(ctx))
+	token, err := user.GenerateJwtToken(manager, uuid.New(), uuid.New(), "user", now.Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, old.ID, kid(t, token))
+	jwks := manager.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	require.Equal(t, next.ID, jwks.Keys[0].Kid)
+	require.Equal(t, "RSA", jwks.Keys[0].Kty)
+	require.Equal(t, "OKP", jwks.Keys[1].Kty)
+	require.Equal(t, "Ed25519", jwks.Keys[1].Crv)
+
+	next.CreatedAt = now.Add(-2 * reload)
+	repo.keys = []signingkey.StoredKey{next, old}
+	require.NoError(t, manager.Reload(ctx))
+	token, err = user.GenerateJwtToken(manager, uuid.New(), uuid.New(), "user", now.Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, next.ID, kid(t, token))
+	_, err = user.ParseToken(manager, token)
+	require.NoError(t, err)
+	// tokens of the replaced key verify during its grace period
+	_, err = user.ParseToken(manager, oldToken)
+	require.NoError(t, err)
+
+	retired := now.Add(-time.Second)
+	old.RetiresAt = &retired
+	repo.keys = []signingkey.StoredKey{next, old}
+	require.NoError(t, manager.Reload(ctx))
+	_, err = user.ParseToken(manager, oldToken)
+	require.Error(t, err)
+	jwks = manager.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	require.Equal(t, next.ID, jwks.Keys[0].Kid)
+}
+
+func Test_SigningKey_Sealer(t *testing.T) {
+	for _, bad := range []string{"", "not base64", base64.StdEncoding.EncodeToString(make([]byte, 16))} {
+		_, err := signingkey.NewSealer(bad)
+		require.Error(t, err, bad)
+	}
+
+	plain, err := signingkey.GenerateKey(signingkey.AlgorithmEdDSA)
+	require.NoError(t, err)
+	sealed, err := testSealer.Seal(*plain)
+	require.NoError(t, err)
+	require.NotContains(t, string(sealed.PrivateKey), string(plain.PrivateKey))
+
+	opened, err := testSealer.Open(sealed)
+	require.NoError(t, err)
+	require.Equal(t, plain.PrivateKey, opened.PrivateKey)
+
+	// the ciphertext is bound to its key id
+	moved := sealed
+	moved.ID = "other"
+	_, err = testSealer.Open(moved)
+	require.Error(t, err)
+
+	other, err := signingkey.NewSealer(base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+	require.NoError(t, err)
+	_, err = other.Open(sealed)
+	require.Error(t, err)
+}
+
+func Test_SigningKey_Reload_Skips_Plain_Keys(t *testing.T) {
+	plain, err := signingkey.GenerateKey(signingkey.AlgorithmEdDSA)
+	require.NoError(t, err)
+	repo := &fakeSigningKeys{keys: []signingkey.StoredKey{*plain}}
+	manager := signingkey.NewManager(repo, nil, testSealer, config.Jwt{ReloadInterval: time.Minute}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.ErrorIs(t, manager.Reload(context.Background()), signingkey.ErrNoSigningKey)
+
+	sealed := storedKey(t, signingkey.AlgorithmEdDSA, time.Now().Add(-time.Hour))
+	repo.keys = []signingkey.StoredKey{*plain, sealed}
+	require.NoError(t, manager.Reload(context.Background()))
+	jwks := manager.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	require.Equal(t, sealed.ID, jwks.Keys[0].Kid)
+}
+
+func Test_Config_Grace_Period(t *testing.T) {
+	cfg := config.Config{
+		Sessions: config.Sessions{AccessTTL: 15 * time.Minute},
+		Jwt:      config.Jwt{GracePeriod: 16 * time.Minute, ReloadInterval: time.Minute},
+	}
+	require.NoError(t, cfg.Validate())
+
+	cfg.Jwt.GracePeriod = 15*time.Minute + 59*time.Second
+	require.Error(t, cfg.Validate())
+
+	cfg.Jwt.GracePeriod = 24 * time.Hour
+	cfg.Sessions.AccessTTL = 24 * time.Hour
+	require.Error(t, cfg.Validate())
+}
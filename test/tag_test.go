@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/internal/feature/folder"
+	"github.com/Sanchir01/go-shortener/internal/feature/tag"
+	"github.com/Sanchir01/go-shortener/internal/feature/url"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTitles records the titles tags and folders are stored with.
+type fakeTitles struct {
+	titles []string
+}
+
+func (f *fakeTitles) save(title string) {
+	f.titles = append(f.titles, title)
+}
+
+type fakeTags struct{ fakeTitles }
+
+func (f *fakeTags) CreateTag(_ context.Context, userId uuid.UUID, title string) (*models.Tag, error) {
+	f.save(title)
+	return &models.Tag{}, nil
+}
+
+func (f *fakeTags) GetTagsByUserId(context.Context, uuid.UUID) ([]models.Tag, error) {
+	return nil, nil
+}
+
+func (f *fakeTags) UpdateTag(_ context.Context, _, _ uuid.UUID, title string) (*models.Tag, error) {
+	f.save(title)
+	return &models.Tag{}, nil
+}
+
+func (f *fakeTags) DeleteTag(context.Context, uuid.UUID, uuid.UUID) error {
+	return nil
+}
+
+type fakeFolders struct{ fakeTitles }
+
+func (f *fakeFolders) CreateFolder(_ context.Context, userId uuid.UUID, title string) (*models.Folder, error) {
+	f.save(title)
+	return &models.Folder{}, nil
+}
+
+func (f *fakeFolders) GetFoldersByUserId(context.Context, uuid.UUID) ([]models.Folder, error) {
+	return nil, nil
+}
+
+func (f *fakeFolders) UpdateFolder(_ context.Context, _, _ uuid.UUID, title string) (*models.Folder, error) {
+	f.save(title)
+	return &models.Folder{}, nil
+}
+
+func (f *fakeFolders) DeleteFolder(context.Context, uuid.UUID, uuid.UUID) error {
+	return nil
+}
+
+func Test_Tag_Folder_Titles(t *testing.T) {
+	ctx := context.Background()
+	l := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tags, folders := &fakeTags{}, &fakeFolders{}
+	tagService, folderService := tag.NewService(tags, l), folder.NewService(folders, l)
+
+	_, err := tagService.CreateTag(ctx, uuid.New(), "  work ")
+	require.NoError(t, err)
+	_, err = tagService.UpdateTag(ctx, uuid.New(), uuid.New(), "home\n")
+	require.NoError(t, err)
+	_, err = folderService.CreateFolder(ctx, uuid.New(), " docs")
+	require.NoError(t, err)
+	_, err = folderService.UpdateFolder(ctx, uuid.New(), uuid.New(), "docs ")
+	require.NoError(t, err)
+	require.Equal(t, []string{"work", "home"}, tags.titles)
+	require.Equal(t, []string{"docs", "docs"}, folders.titles)
+
+	validate := validator.New()
+	require.NoError(t, validate.Struct(tag.TagRequest{Title: "work"}))
+	require.Error(t, validate.Struct(tag.TagRequest{Title: ""}))
+	require.Error(t, validate.Struct(tag.TagRequest{Title: strings.Repeat("a", 65)}))
+}
+
+// Test_Url_Folder_Tag_Ownership runs against TEST_DATABASE_URL, see testTx.
+func Test_Url_Folder_Tag_Ownership(t *testing.T) {
+	pool, tx := testTx(t)
+	ctx := context.Background()
+
+	owner, stranger := uuid.New(), uuid.New()
+	var urlId, ownTag, strangerTag, strangerFolder uuid.UUID
+	require.NoError(t, tx.QueryRow(ctx, `INSERT INTO url (alias, url, user_id) VALUES ($1, 'https://example.com', $2) RETURNING id`,
+		"t"+uuid.NewString()[:8], owner).Scan(&urlId))
+	require.NoError(t, tx.QueryRow(ctx, `INSERT INTO tags (user_id, title) VALUES ($1, 'mine') RETURNING id`, owner).Scan(&ownTag))
+	require.NoError(t, tx.QueryRow(ctx, `INSERT INTO tags (user_id, title) VALUES ($1, 'theirs') RETURNING id`, stranger).Scan(&strangerTag))
+	require.NoError(t, tx.QueryRow(ctx, `INSERT INTO folders (user_id, title) VALUES ($1, 'theirs') RETURNING id`, stranger).Scan(&strangerFolder))
+
+	repo := url.NewRepository(pool, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	owned, err := repo.IsFolderOwned(ctx, strangerFolder, owner, tx)
+	require.NoError(t, err)
+	require.False(t, owned)
+	owned, err = repo.IsFolderOwned(ctx, strangerFolder, stranger, tx)
+	require.NoError(t, err)
+	require.True(t, owned)
+
+	require.NoError(t, repo.SetUrlTags(ctx, urlId, owner, []uuid.UUID{ownTag, ownTag}, tx))
+	require.ErrorIs(t, repo.SetUrlTags(ctx, urlId, owner, []uuid.UUID{ownTag, strangerTag}, tx), utils.ErrorTagNotFound)
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/feature/telegram"
+	"github.com/stretchr/testify/require"
+)
+
+const tgBotToken = "123456:test-token"
+
+// tgSign returns the hash Telegram computes over fields with secret.
+func tgSign(fields map[string]string, secret []byte) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	lines := make([]string, len(keys))
+	for i, k := range keys {
+		lines[i] = k + "=" + fields[k]
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func widgetFields(authDate time.Time) map[string]string {
+	fields := map[string]string{
+		"id":         "42",
+		"first_name": "Ivan",
+		"username":   "ivan",
+		"auth_date":  strconv.FormatInt(authDate.Unix(), 10),
+	}
+	secret := sha256.Sum256([]byte(tgBotToken))
+	fields["hash"] = tgSign(fields, secret[:])
+	return fields
+}
+
+func Test_Telegram_VerifyWidget(t *testing.T) {
+	v := telegram.NewVerifier(tgBotToken, time.Hour)
+	u, err := v.VerifyWidget(widgetFields(time.Now()))
+	require.NoError(t, err)
+	require.Equal(t, int64(42), u.ID)
+	require.Equal(t, "ivan", u.Title())
+}
+
+func Test_Telegram_VerifyWidget_Rejects(t *testing.T) {
+	v := telegram.NewVerifier(tgBotToken, time.Hour)
+
+	tampered := widgetFields(time.Now())
+	tampered["id"] = "43"
+	_, err := v.VerifyWidget(tampered)
+	require.ErrorIs(t, err, telegram.ErrInvalidAuthData)
+
+	_, err = v.VerifyWidget(widgetFields(time.Now().Add(-2 * time.Hour)))
+	require.ErrorIs(t, err, telegram.ErrInvalidAuthData)
+
+	_, err = telegram.NewVerifier("654321:other", time.Hour).VerifyWidget(widgetFields(time.Now()))
+	require.ErrorIs(t, err, telegram.ErrInvalidAuthData)
+
+	_, err = telegram.NewVerifier("", time.Hour).VerifyWidget(widgetFields(time.Now()))
+	require.ErrorIs(t, err, telegram.ErrInvalidAuthData)
+}
+
+func Test_Telegram_VerifyWebApp(t *testing.T) {
+	fields := map[string]string{
+		"query_id":  "AAH",
+		"user":      `{"id":42,"first_name":"Ivan","last_name":"Petrov"}`,
+		"auth_date": strconv.FormatInt(time.Now().Unix(), 10),
+	}
+	mac := hmac.New(sha256.New, []byte("WebAppData"))
+	mac.Write([]byte(tgBotToken))
+	fields["hash"] = tgSign(fields, mac.Sum(nil))
+	values := url.Values{}
+	for k, v := range fields {
+		values.Set(k, v)
+	}
+
+	v := telegram.NewVerifier(tgBotToken, time.Hour)
+	u, err := v.VerifyWebApp(values.Encode())
+	require.NoError(t, err)
+	require.Equal(t, int64(42), u.ID)
+	require.Equal(t, "Ivan Petrov", u.Title())
+
+	// the widget secret must not validate Mini App data
+	values.Set("hash", widgetFields(time.Now())["hash"])
+	_, err = v.VerifyWebApp(values.Encode())
+	require.ErrorIs(t, err, telegram.ErrInvalidAuthData)
+}
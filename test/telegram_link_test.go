@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/Sanchir01/go-shortener/internal/feature/telegram"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Telegram_PlanLink(t *testing.T) {
+	const tgId int64 = 42
+	other := int64(7)
+	linked := tgId
+	userId, botId := uuid.New(), uuid.New()
+
+	web := telegram.Account{ID: userId, HasEmail: true, HasPassword: true}
+	bot := telegram.Account{ID: botId, TGID: &linked}
+
+	tests := []struct {
+		name     string
+		accounts []telegram.Account
+		err      error
+		plan     telegram.LinkPlan
+	}{
+		{"unknown user", []telegram.Account{bot}, utils.ErrorUserNotFound, telegram.LinkPlan{}},
+		{"fresh telegram id", []telegram.Account{web}, nil, telegram.LinkPlan{}},
+		{"bot account is merged", []telegram.Account{web, bot}, nil, telegram.LinkPlan{Merge: &bot}},
+		{"already linked", []telegram.Account{{ID: userId, TGID: &linked, HasEmail: true}}, nil, telegram.LinkPlan{Linked: true}},
+		{"user has another telegram", []telegram.Account{{ID: userId, TGID: &other, HasEmail: true}, bot}, telegram.ErrOtherTelegram, telegram.LinkPlan{}},
+		{"telegram account has an email", []telegram.Account{web, {ID: botId, TGID: &linked, HasEmail: true}}, telegram.ErrTelegramTaken, telegram.LinkPlan{}},
+		{"telegram account has a password", []telegram.Account{web, {ID: botId, TGID: &linked, HasPassword: true}}, telegram.ErrTelegramTaken, telegram.LinkPlan{}},
+		{"telegram account has an identity", []telegram.Account{web, {ID: botId, TGID: &linked, HasIdentities: true}}, telegram.ErrTelegramTaken, telegram.LinkPlan{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := telegram.PlanLink(tt.accounts, userId, tgId)
+			require.ErrorIs(t, err, tt.err)
+			require.Equal(t, tt.plan, plan)
+		})
+	}
+}
+
+func Test_Telegram_Account_BotOnly(t *testing.T) {
+	require.True(t, telegram.Account{}.BotOnly())
+	require.False(t, telegram.Account{HasEmail: true}.BotOnly())
+	require.False(t, telegram.Account{HasPassword: true}.BotOnly())
+	require.False(t, telegram.Account{HasIdentities: true}.BotOnly())
+}
+
+// Test_Telegram_Merge_Title_Collisions runs against TEST_DATABASE_URL, see
+// testTx.
+func Test_Telegram_Merge_Title_Collisions(t *testing.T) {
+	pool, tx := testTx(t)
+	ctx := context.Background()
+
+	var from, into uuid.UUID
+	require.NoError(t, tx.QueryRow(ctx, `INSERT INTO users (title) VALUES ('bot') RETURNING id`).Scan(&from))
+	require.NoError(t, tx.QueryRow(ctx, `INSERT INTO users (title) VALUES ('web') RETURNING id`).Scan(&into))
+
+	var fromWork, fromHome, intoWork, fromNews, fromSolo, intoNews, urlId uuid.UUID
+	require.NoError(t, tx.QueryRow(ctx, `INSERT INTO folders (user_id, title) VALUES ($1, 'work') RETURNING id`, from).Scan(&fromWork))
+	require.NoError(t, tx.QueryRow(ctx, `INSERT INTO folders (user_id, title) VALUES ($1, 'home') RETURNING id`, from).Scan(&fromHome))
+	require.NoError(t, tx.QueryRow(ctx, `INSERT INTO folders (user_id, title) VALUES ($1, 'work') RETURNING id`, into).Scan(&intoWork))
+	require.NoError(t, tx.QueryRow(ctx, `INSERT INTO tags (user_id, title) VALUES ($1, 'news') RETURNING id`, from).Scan(&fromNews))
+	require.NoError(t, tx.QueryRow(ctx, `INSERT INTO tags (user_id, title) VALUES ($1, 'solo') RETURNING id`, from).Scan(&fromSolo))
+	require.NoError(t, tx.QueryRow(ctx, `INSERT INTO tags (user_id, title) VALUES ($1, 'news') RETURNING id`, into).Scan(&intoNews))
+	require.NoError(t, tx.QueryRow(ctx, `INSERT INTO url (alias, url, user_id, folder_id) VALUES ($1, 'https://example.com', $2, $3) RETURNING id`,
+		"m"+uuid.NewString()[:8], from, fromWork).Scan(&urlId))
+	_, err := tx.Exec(ctx, `INSERT INTO url_tags (url_id, tag_id) VALUES ($1, $2), ($1, $3)`, urlId, fromNews, fromSolo)
+	require.NoError(t, err)
+
+	repo := telegram.NewRepository(pool, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, repo.Merge(ctx, from, into, tx))
+
+	// the link moves into the folder of the same title the target already had
+	var owner, folderId uuid.UUID
+	require.NoError(t, tx.QueryRow(ctx, `SELECT user_id, folder_id FROM url WHERE id = $1`, urlId).Scan(&owner, &folderId))
+	require.Equal(t, into, owner)
+	require.Equal(t, intoWork, folderId)
+
+	// colliding folders and tags are folded, the rest change owner
+	var folders, tags int
+	require.NoError(t, tx.QueryRow(ctx, `SELECT count(*) FROM folders WHERE user_id = $1`, into).Scan(&folders))
+	require.Equal(t, 2, folders)
+	var homeOwner uuid.UUID
+	require.NoError(t, tx.QueryRow(ctx, `SELECT user_id FROM folders WHERE id = $1`, fromHome).Scan(&homeOwner))
+	require.Equal(t, into, homeOwner)
+	require.NoError(t, tx.QueryRow(ctx, `SELECT count(*) FROM tags WHERE user_id = $1`, into).Scan(&tags))
+	require.Equal(t, 2, tags)
+
+	rows, err := tx.Query(ctx, `SELECT tag_id FROM url_tags WHERE url_id = $1`, urlId)
+	require.NoError(t, err)
+	var tagIds []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		require.NoError(t, rows.Scan(&id))
+		tagIds = append(tagIds, id)
+	}
+	require.NoError(t, rows.Err())
+	require.ElementsMatch(t, []uuid.UUID{intoNews, fromSolo}, tagIds)
+
+	var users int
+	require.NoError(t, tx.QueryRow(ctx, `SELECT count(*) FROM users WHERE id = $1`, from).Scan(&users))
+	require.Zero(t, users)
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/config"
+	"github.com/Sanchir01/go-shortener/internal/feature/twofactor"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFailures is an in-memory twofactor.Failures.
+type fakeFailures struct {
+	count map[uuid.UUID]int
+	max   int
+	err   error
+}
+
+func (f *fakeFailures) Locked(ctx context.Context, userId uuid.UUID) (bool, error) {
+	return f.count[userId] >= f.max, f.err
+}
+
+func (f *fakeFailures) Fail(ctx context.Context, userId uuid.UUID) error {
+	f.count[userId]++
+	return nil
+}
+
+func (f *fakeFailures) Reset(ctx context.Context, userId uuid.UUID) error {
+	delete(f.count, userId)
+	return nil
+}
+
+func Test_TwoFactor_Lockout_Refuses_Challenge(t *testing.T) {
+	locked := uuid.New()
+	failures := &fakeFailures{count: map[uuid.UUID]int{locked: 3}, max: 3}
+	service := twofactor.NewService(nil, nil, failures, config.TwoFactor{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	_, err := service.StartChallenge(context.Background(), locked)
+	require.ErrorIs(t, err, utils.ErrorTwoFactorLocked)
+
+	// the lockout fails closed when the counter can not be read
+	failures.err = errors.New("redis down")
+	_, err = service.StartChallenge(context.Background(), uuid.New())
+	require.ErrorIs(t, err, utils.ErrorTwoFactorLocked)
+}
+
+// rfcSecret is the RFC 6238 SHA1 test key "12345678901234567890" in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func Test_TwoFactor_VerifyTOTP(t *testing.T) {
+	at := func(unix int64) time.Time { return time.Unix(unix, 0) }
+
+	step, ok := twofactor.VerifyTOTP(rfcSecret, "287082", at(59), 0)
+	require.True(t, ok)
+	require.EqualValues(t, 1, step)
+	step, ok = twofactor.VerifyTOTP(rfcSecret, "081804", at(1111111109), 0)
+	require.True(t, ok)
+	require.EqualValues(t, 37037036, step)
+
+	// a used step can not be replayed, a later one still works
+	_, ok = twofactor.VerifyTOTP(rfcSecret, "081804", at(1111111109), 37037036)
+	require.False(t, ok)
+	step, ok = twofactor.VerifyTOTP(rfcSecret, "050471", at(1111111111), 37037036)
+	require.True(t, ok)
+	require.EqualValues(t, 37037037, step)
+
+	// one period of clock drift is accepted, two are not
+	_, ok = twofactor.VerifyTOTP(rfcSecret, "287082", at(89), 0)
+	require.True(t, ok)
+	_, ok = twofactor.VerifyTOTP(rfcSecret, "287082", at(90), 0)
+	require.False(t, ok)
+
+	for _, code := range []string{"287083", "28708", "2870820", ""} {
+		_, ok = twofactor.VerifyTOTP(rfcSecret, code, at(59), 0)
+		require.False(t, ok, code)
+	}
+}
+
+func Test_TwoFactor_RecoveryCode_Hash(t *testing.T) {
+	code, hash, err := twofactor.NewRecoveryCode()
+	require.NoError(t, err)
+	require.Regexp(t, `^[0-9a-f]{5}-[0-9a-f]{5}$`, code)
+	require.Equal(t, hash, twofactor.HashRecoveryCode(code))
+	require.Equal(t, hash, twofactor.HashRecoveryCode(" "+strings.ToUpper(strings.ReplaceAll(code, "-", ""))+" "))
+
+	other, _, err := twofactor.NewRecoveryCode()
+	require.NoError(t, err)
+	require.NotEqual(t, hash, twofactor.HashRecoveryCode(other))
+}
+
+// Test_TwoFactor_RecoveryCode_SingleUse runs against TEST_DATABASE_URL, see
+// testTx.
+func Test_TwoFactor_RecoveryCode_SingleUse(t *testing.T) {
+	pool, tx := testTx(t)
+	ctx := context.Background()
+
+	var userId uuid.UUID
+	require.NoError(t, tx.QueryRow(ctx, `INSERT INTO users (title) VALUES ('two-factor') RETURNING id`).Scan(&userId))
+	code, hash, err := twofactor.NewRecoveryCode()
+	require.NoError(t, err)
+	_, err = tx.Exec(ctx, `INSERT INTO user_recovery_codes (user_id, hash) VALUES ($1, $2)`, userId, hash)
+	require.NoError(t, err)
+
+	repo := twofactor.NewRepository(pool, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, repo.UseRecoveryCode(ctx, userId, twofactor.HashRecoveryCode(code), tx))
+	require.ErrorIs(t, repo.UseRecoveryCode(ctx, userId, twofactor.HashRecoveryCode(code), tx), utils.ErrorInvalidCode)
+	require.ErrorIs(t, repo.UseRecoveryCode(ctx, uuid.New(), hash, tx), utils.ErrorInvalidCode)
+}
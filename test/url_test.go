@@ -1 +1,140 @@
 package tests
+
+import (
+	"bytes"
+	"context"
+	"image/jpeg"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/config"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/internal/feature/url"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Url_Validate(t *testing.T) {
+	blocked := []string{"sho.rt"}
+	require.NoError(t, url.ValidateUrl("https://example.com/a?b=c", blocked))
+	require.ErrorIs(t, url.ValidateUrl("example.com", blocked), utils.ErrorInvalidUrl)
+	require.ErrorIs(t, url.ValidateUrl("ftp://example.com", blocked), utils.ErrorInvalidUrl)
+	require.ErrorIs(t, url.ValidateUrl("https://sho.rt/abc", blocked), utils.ErrorUrlBlocked)
+	require.ErrorIs(t, url.ValidateUrl("http://WWW.Sho.Rt./abc", blocked), utils.ErrorUrlBlocked)
+	require.NoError(t, url.ValidateUrl("https://notsho.rt", blocked))
+}
+
+func Test_Url_QRCode(t *testing.T) {
+	img, err := url.QRCode("https://sho.rt/abc", 256)
+	require.NoError(t, err)
+	decoded, err := jpeg.Decode(bytes.NewReader(img))
+	require.NoError(t, err)
+	require.Equal(t, 256, decoded.Bounds().Dx())
+}
+
+// sharedAliases knows the shared links by alias, the rest of the repository
+// is not used.
+type sharedAliases struct {
+	url.UrlService
+	aliases map[string]bool
+}
+
+func (s sharedAliases) GetUrlByAlias(ctx context.Context, host, alias string) (*models.Url, error) {
+	if host != "" || !s.aliases[alias] {
+		return nil, utils.ErrorUrlNotFound
+	}
+	return &models.Url{Alias: alias}, nil
+}
+
+func Test_Url_QRHandler(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, nil))
+	service := url.NewService(sharedAliases{aliases: map[string]bool{"abc": true}}, nil, config.Redirect{}, nil, l)
+	router := chi.NewRouter()
+	router.Get("/qr/{alias}", url.NewHandler(service, config.Redirect{}, "https://sho.rt", l).QRHandler)
+
+	for alias, status := range map[string]int{
+		"abc":     http.StatusOK,
+		"unknown": http.StatusNotFound,
+		"a.b":     http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/qr/"+alias, nil))
+		require.Equal(t, status, rec.Code, alias)
+	}
+}
+
+func Test_Url_ValidateAlias(t *testing.T) {
+	require.NoError(t, url.ValidateAlias("my-link_2"))
+	require.ErrorIs(t, url.ValidateAlias(""), utils.ErrorInvalidAlias)
+	require.ErrorIs(t, url.ValidateAlias("a/b"), utils.ErrorInvalidAlias)
+	require.ErrorIs(t, url.ValidateAlias("ссылка"), utils.ErrorInvalidAlias)
+}
+
+func Test_Url_Expiry(t *testing.T) {
+	now := time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	require.NoError(t, url.ValidateExpiry(nil, nil, now))
+	require.NoError(t, url.ValidateExpiry(&future, &past, now))
+	require.ErrorIs(t, url.ValidateExpiry(&past, nil, now), utils.ErrorInvalidExpiry)
+	require.ErrorIs(t, url.ValidateExpiry(&future, &future, now), utils.ErrorInvalidExpiry)
+
+	link := &models.Url{Url: "https://example.com", ExpiresAt: &future}
+	dest, err := url.ResolveDestination(link, time.UTC, now)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com", dest)
+	_, err = url.ResolveDestination(link, time.UTC, future)
+	require.ErrorIs(t, err, utils.ErrorUrlExpired)
+}
+
+func Test_Url_FindUrls(t *testing.T) {
+	text := "See https://en.wikipedia.org/wiki/Go_(language), (https://go.dev/doc) and https://go.dev/doc."
+	require.Equal(t, []string{"https://en.wikipedia.org/wiki/Go_(language)", "https://go.dev/doc"}, url.FindUrls(text))
+	require.Empty(t, url.FindUrls("no links, only example.com"))
+}
+
+func Test_Url_ValidateTimeRules(t *testing.T) {
+	blocked := []string{"sho.rt"}
+	rule := models.TimeRule{Start: "09:00", End: "18:00", Url: "https://example.com/day"}
+	require.NoError(t, url.ValidateTimeRules([]models.TimeRule{rule}, blocked))
+
+	rule.Url = "javascript:alert(1)"
+	require.ErrorIs(t, url.ValidateTimeRules([]models.TimeRule{rule}, blocked), utils.ErrorInvalidUrl)
+	rule.Url = "ftp://example.com/file"
+	require.ErrorIs(t, url.ValidateTimeRules([]models.TimeRule{rule}, blocked), utils.ErrorInvalidUrl)
+	rule.Url = "https://sho.rt/loop"
+	require.ErrorIs(t, url.ValidateTimeRules([]models.TimeRule{rule}, blocked), utils.ErrorUrlBlocked)
+}
+
+func Test_Url_ValidateRouting(t *testing.T) {
+	blocked := []string{"sho.rt"}
+	now := time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC)
+	expires, later := now.Add(time.Hour), now.Add(2*time.Hour)
+	fallback := "https://sho.rt/fallback"
+
+	rt := models.UrlRouting{Url: "https://example.com"}
+	require.NoError(t, url.ValidateRouting(rt, &expires, blocked, "UTC", now))
+
+	// a revision recorded before the host was blocked
+	rt.Url = "https://sho.rt/loop"
+	require.ErrorIs(t, url.ValidateRouting(rt, nil, blocked, "UTC", now), utils.ErrorUrlBlocked)
+	rt.Url = "https://example.com"
+	rt.FallbackUrl = &fallback
+	require.ErrorIs(t, url.ValidateRouting(rt, nil, blocked, "UTC", now), utils.ErrorUrlBlocked)
+	rt.FallbackUrl = nil
+	rt.TimeRules = []models.TimeRule{{Start: "09:00", End: "18:00", Url: "https://sho.rt/day"}}
+	require.ErrorIs(t, url.ValidateRouting(rt, nil, blocked, "UTC", now), utils.ErrorUrlBlocked)
+	rt.TimeRules = nil
+	rt.Timezone = "Mars/Olympus"
+	require.ErrorIs(t, url.ValidateRouting(rt, nil, blocked, "UTC", now), utils.ErrorInvalidTimezone)
+	rt.Timezone = ""
+
+	// a start the link would expire before
+	rt.NotBefore = &later
+	require.ErrorIs(t, url.ValidateRouting(rt, &expires, blocked, "UTC", now), utils.ErrorInvalidExpiry)
+	require.NoError(t, url.ValidateRouting(rt, nil, blocked, "UTC", now))
+}
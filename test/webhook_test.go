@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Sanchir01/go-shortener/internal/config"
+	contextkey "github.com/Sanchir01/go-shortener/internal/domain/constants"
+	"github.com/Sanchir01/go-shortener/internal/domain/models"
+	"github.com/Sanchir01/go-shortener/internal/feature/webhook"
+	"github.com/Sanchir01/go-shortener/pkg/netguard"
+	"github.com/Sanchir01/go-shortener/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+func newWebhookReceiver(t *testing.T, status int) (*httptest.Server, <-chan receivedWebhook) {
+	t.Helper()
+	received := make(chan receivedWebhook, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedWebhook{header: r.Header.Clone(), body: body}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, received
+}
+
+func Test_Webhook_Send_Signed(t *testing.T) {
+	srv, received := newWebhookReceiver(t, http.StatusNoContent)
+	sender := webhook.NewSender(srv.Client(), 0)
+	payload := []byte(`{"type":"link.created"}`)
+
+	status, err := sender.Send(context.Background(), srv.URL, "secret", "link.created", "delivery-1", payload)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, status)
+
+	got := <-received
+	require.Equal(t, payload, got.body)
+	require.Equal(t, "link.created", got.header.Get(webhook.HeaderEvent))
+	require.Equal(t, "delivery-1", got.header.Get(webhook.HeaderDelivery))
+
+	ts, err := strconv.ParseInt(got.header.Get(webhook.HeaderTimestamp), 10, 64)
+	require.NoError(t, err)
+	signature := got.header.Get(webhook.HeaderSignature)
+	require.True(t, webhook.Verify("secret", signature, ts, got.body, 5*time.Minute, time.Now()))
+	require.False(t, webhook.Verify("other", signature, ts, got.body, 5*time.Minute, time.Now()))
+	require.False(t, webhook.Verify("secret", signature, ts, got.body, 5*time.Minute, time.Now().Add(time.Hour)))
+}
+
+func Test_Webhook_Send_ErrorStatus(t *testing.T) {
+	srv, _ := newWebhookReceiver(t, http.StatusInternalServerError)
+	sender := webhook.NewSender(srv.Client(), 0)
+
+	status, err := sender.Send(context.Background(), srv.URL, "secret", "link.clicked", "delivery-2", []byte(`{}`))
+	require.Error(t, err)
+	require.Equal(t, http.StatusInternalServerError, status)
+}
+
+func Test_Webhook_Backoff(t *testing.T) {
+	base, max := time.Second, 10*time.Second
+	require.Equal(t, time.Second, webhook.Backoff(1, base, max))
+	require.Equal(t, 2*time.Second, webhook.Backoff(2, base, max))
+	require.Equal(t, 8*time.Second, webhook.Backoff(4, base, max))
+	require.Equal(t, max, webhook.Backoff(5, base, max))
+	require.Equal(t, max, webhook.Backoff(60, base, max))
+}
+
+func Test_Webhook_Publish_DoesNotBlock(t *testing.T) {
+	service := webhook.NewService(nil, nil, config.Webhooks{QueueSize: 1}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	done := make(chan struct{})
+	go func() {
+		for range 3 {
+			service.Publish(context.Background(), models.LinkEvent{Type: contextkey.EventLinkClicked})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a full queue")
+	}
+}
+
+func Test_Webhook_ValidateTarget(t *testing.T) {
+	require.NoError(t, webhook.ValidateTarget("https://hooks.example.com/in"))
+	for _, raw := range []string{
+		"file:///etc/passwd",
+		"gopher://example.com",
+		"http://localhost:8080/hook",
+		"http://127.0.0.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hook",
+		"http://10.0.0.5/hook",
+	} {
+		require.ErrorIs(t, webhook.ValidateTarget(raw), utils.ErrorInvalidWebhookUrl, raw)
+	}
+}
+
+func Test_Webhook_Send_RefusesInternal(t *testing.T) {
+	srv, received := newWebhookReceiver(t, http.StatusNoContent)
+	sender := webhook.NewSender(nil, time.Second)
+
+	status, err := sender.Send(context.Background(), srv.URL, "secret", "link.created", "delivery-1", []byte(`{}`))
+	require.ErrorIs(t, err, netguard.ErrForbiddenAddress)
+	require.Zero(t, status)
+	require.Empty(t, received)
+}